@@ -799,7 +799,9 @@ func (m callMsg) Data() []byte                  { return m.CallMsg.Data }
 func (m callMsg) AccessList() types2.AccessList { return m.CallMsg.AccessList }
 func (m callMsg) IsFree() bool                  { return false }
 func (m callMsg) IsSystemTx() bool              { return false }
+func (m callMsg) IsDepositTx() bool             { return false }
 func (m callMsg) Mint() *uint256.Int            { return new(uint256.Int) }
+func (m callMsg) RollupDataGas() uint64         { return 0 }
 
 // filterBackend implements filters.Backend to support filtering for logs without
 // taking bloom-bits acceleration structures into account.
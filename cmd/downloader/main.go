@@ -13,18 +13,26 @@ import (
 	"github.com/c2h5oh/datasize"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/holiman/uint256"
+	chain2 "github.com/ledgerwatch/erigon-lib/chain"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/datadir"
 	"github.com/ledgerwatch/erigon-lib/downloader"
 	downloadercfg2 "github.com/ledgerwatch/erigon-lib/downloader/downloadercfg"
 	proto_downloader "github.com/ledgerwatch/erigon-lib/gointerfaces/downloader"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon/cmd/downloader/downloadernat"
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/common/paths"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/p2p/nat"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/debug"
 	logging2 "github.com/ledgerwatch/erigon/turbo/logging"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapcfg"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/pelletier/go-toml/v2"
 	"github.com/spf13/cobra"
@@ -49,8 +57,12 @@ var (
 	torrentMaxPeers                int
 	torrentConnsPerFile            int
 	targetFile                     string
+	preverifiedTargetFile          string
 	disableIPV6                    bool
 	disableIPV4                    bool
+	chain                          string
+	preverifiedPath                string
+	quarantine                     bool
 )
 
 func init() {
@@ -79,6 +91,19 @@ func init() {
 	}
 
 	rootCmd.AddCommand(printTorrentHashes)
+
+	withDataDir(printPreverified)
+	printPreverified.Flags().StringVar(&preverifiedTargetFile, "targetfile", "", "write output to file, overwriting it")
+	if err := printPreverified.MarkFlagFilename("targetfile"); err != nil {
+		panic(err)
+	}
+	rootCmd.AddCommand(printPreverified)
+
+	withDataDir(verifySnapshots)
+	verifySnapshots.Flags().StringVar(&chain, utils.ChainFlag.Name, utils.ChainFlag.Value, utils.ChainFlag.Usage)
+	verifySnapshots.Flags().StringVar(&preverifiedPath, utils.SnapPreverifiedFlag.Name, "", utils.SnapPreverifiedFlag.Usage)
+	verifySnapshots.Flags().BoolVar(&quarantine, "quarantine", false, "Move the corrupted segments to the quarantine folder of the snapshots, and remove the corrupted indices")
+	rootCmd.AddCommand(verifySnapshots)
 }
 
 func withDataDir(cmd *cobra.Command) {
@@ -202,22 +227,10 @@ var printTorrentHashes = &cobra.Command{
 			}
 		}
 
-		res := map[string]string{}
-		files, err := downloader.AllTorrentPaths(dirs.Snap)
+		res, err := torrentHashes(dirs.Snap)
 		if err != nil {
 			return err
 		}
-		for _, torrentFilePath := range files {
-			mi, err := metainfo.LoadFromFile(torrentFilePath)
-			if err != nil {
-				return err
-			}
-			info, err := mi.UnmarshalInfo()
-			if err != nil {
-				return err
-			}
-			res[info.Name] = mi.HashInfoBytes().String()
-		}
 		serialized, err := toml.Marshal(res)
 		if err != nil {
 			return err
@@ -247,7 +260,150 @@ var printTorrentHashes = &cobra.Command{
 	},
 }
 
-// nolint
+var printPreverified = &cobra.Command{
+	Use:     "preverified",
+	Short:   "Generate the preverified snapshots TOML (names and info-hashes) of a custom chain from its snapshot directory",
+	Example: "go run ./cmd/downloader preverified --datadir <your_datadir> --targetfile <chain>.toml",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dirs := datadir.New(datadirCli)
+		if _, err := downloader.BuildTorrentFilesIfNeed(cmd.Context(), dirs.Snap); err != nil {
+			return err
+		}
+		res, err := torrentHashes(dirs.Snap)
+		if err != nil {
+			return err
+		}
+		// only the segments are preverified, their indices are built locally
+		for name := range res {
+			if filepath.Ext(name) != ".seg" {
+				delete(res, name)
+			}
+		}
+		serialized, err := toml.Marshal(res)
+		if err != nil {
+			return err
+		}
+
+		if preverifiedTargetFile == "" {
+			fmt.Printf("%s\n", serialized)
+			return nil
+		}
+		return os.WriteFile(preverifiedTargetFile, serialized, 0644) // nolint
+	},
+}
+
+var verifySnapshots = &cobra.Command{
+	Use:     "verify_snapshots",
+	Short:   "Verify the snapshots against the preverified info-hashes and their indices, and report or quarantine the corrupted files",
+	Example: "go run ./cmd/downloader verify_snapshots --datadir <your_datadir> --chain <chain> --quarantine",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dirs := datadir.New(datadirCli)
+		ctx := cmd.Context()
+		chainConfig := params.ChainConfigByChainName(chain)
+		if chainConfig == nil { // custom chain, its config is stored with its genesis
+			var err error
+			if chainConfig, err = chainConfigFromDB(ctx, dirs.Chaindata); err != nil {
+				return fmt.Errorf("chain %s: %w", chain, err)
+			}
+		}
+		chainID, _ := uint256.FromBig(chainConfig.ChainID)
+		if preverifiedPath != "" {
+			if err := snapcfg.LoadLocalCfg(chain, preverifiedPath); err != nil {
+				return fmt.Errorf("preverified snapshots: %w", err)
+			}
+		}
+
+		problems, err := snapshotsync.VerifyInfoHashes(ctx, dirs.Snap, snapcfg.KnownCfg(chain, nil, nil).Preverified)
+		if err != nil {
+			return err
+		}
+
+		files, _, err := snapshotsync.Segments(dirs.Snap)
+		if err != nil {
+			return err
+		}
+		list := make([]string, 0, len(files))
+		for _, f := range files {
+			_, fName := filepath.Split(f.Path)
+			list = append(list, fName)
+		}
+		snapshots := snapshotsync.NewRoSnapshots(ethconfig.NewSnapCfg(true, false, false), dirs.Snap)
+		defer snapshots.Close()
+		if err := snapshots.ReopenList(list, true); err != nil {
+			return err
+		}
+		segmentProblems, err := snapshotsync.VerifySegments(ctx, snapshots, *chainID)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, segmentProblems...)
+		snapshots.Close()
+
+		for _, p := range problems {
+			fmt.Printf("%s\n", p)
+		}
+		if len(problems) == 0 {
+			log.Info("No corrupted snapshots", "segments", len(list))
+			return nil
+		}
+		if !quarantine {
+			return fmt.Errorf("%d corrupted snapshot files, run with --quarantine to move or remove them", len(problems))
+		}
+		if err := snapshotsync.Quarantine(dirs.Snap, problems); err != nil {
+			return err
+		}
+		removePieceCompletionStorage(dirs.Snap)
+		log.Info("Quarantined the corrupted snapshots", "dir", filepath.Join(dirs.Snap, snapshotsync.QuarantineDir),
+			"rebuild", "erigon rebuilds the removed indices and downloads the preverified segments again on start")
+		return nil
+	},
+}
+
+// chainConfigFromDB reads the chain config stored with the genesis in the chaindata.
+func chainConfigFromDB(ctx context.Context, chaindata string) (*chain2.Config, error) {
+	db, err := mdbx.NewMDBX(log.New()).Path(chaindata).Label(kv.ChainDB).Readonly().Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	var chainConfig *chain2.Config
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		genesisHash, err := rawdb.ReadCanonicalHash(tx, 0)
+		if err != nil {
+			return err
+		}
+		chainConfig, err = rawdb.ReadChainConfig(tx, genesisHash)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if chainConfig == nil {
+		return nil, errors.New("no chain config in the db")
+	}
+	return chainConfig, nil
+}
+
+// torrentHashes returns the info-hashes of the .torrent files of snapDir by file name.
+func torrentHashes(snapDir string) (map[string]string, error) {
+	res := map[string]string{}
+	files, err := downloader.AllTorrentPaths(snapDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, torrentFilePath := range files {
+		mi, err := metainfo.LoadFromFile(torrentFilePath)
+		if err != nil {
+			return nil, err
+		}
+		info, err := mi.UnmarshalInfo()
+		if err != nil {
+			return nil, err
+		}
+		res[info.Name] = mi.HashInfoBytes().String()
+	}
+	return res, nil
+}
+
 func removePieceCompletionStorage(snapDir string) {
 	_ = os.RemoveAll(filepath.Join(snapDir, "db"))
 	_ = os.RemoveAll(filepath.Join(snapDir, ".torrent.db"))
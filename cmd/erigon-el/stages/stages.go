@@ -111,6 +111,7 @@ func NewStagedSync(ctx context.Context,
 				controlServer.Engine,
 				&vm.Config{},
 				notifications.Accumulator,
+				notifications.Events,
 				cfg.StateStream,
 				/*stateStream=*/ false,
 				cfg.HistoryV3,
@@ -122,7 +123,7 @@ func NewStagedSync(ctx context.Context,
 				agg,
 			),
 			stagedsync.StageHashStateCfg(db, dirs, cfg.HistoryV3, agg),
-			stagedsync.StageTrieCfg(db, true, true, false, dirs.Tmp, blockReader, controlServer.Hd, cfg.HistoryV3, agg),
+			stagedsync.StageTrieCfg(db, true, true, false, dirs.Tmp, blockReader, controlServer.Hd, cfg.HistoryV3, agg, nil),
 			stagedsync.StageHistoryCfg(db, cfg.Prune, dirs.Tmp),
 			stagedsync.StageLogIndexCfg(db, cfg.Prune, dirs.Tmp),
 			stagedsync.StageCallTracesCfg(db, cfg.Prune, 0, dirs.Tmp),
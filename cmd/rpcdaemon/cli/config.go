@@ -95,7 +95,11 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketEnabled, "ws", false, "Enable Websockets - Same port as HTTP")
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketCompression, "ws.compression", false, "Enable Websocket compression (RFC 7692)")
 	rootCmd.PersistentFlags().StringVar(&cfg.RpcAllowListFilePath, utils.RpcAccessListFlag.Name, "", "Specify granular (method-by-method) API allowlist")
+	rootCmd.PersistentFlags().StringVar(&cfg.RpcLimitsFilePath, utils.RpcLimitsFlag.Name, "", utils.RpcLimitsFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.RpcLimitsJWTSecretPath, utils.RpcLimitsJWTSecretFlag.Name, "", utils.RpcLimitsJWTSecretFlag.Usage)
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.RpcLimitsTrustedProxies, utils.RpcLimitsTrustedProxiesFlag.Name, nil, utils.RpcLimitsTrustedProxiesFlag.Usage)
 	rootCmd.PersistentFlags().UintVar(&cfg.RpcBatchConcurrency, utils.RpcBatchConcurrencyFlag.Name, 2, utils.RpcBatchConcurrencyFlag.Usage)
+	rootCmd.PersistentFlags().UintVar(&cfg.TraceChainConcurrency, utils.RpcTraceChainConcurrencyFlag.Name, utils.RpcTraceChainConcurrencyFlag.Value, utils.RpcTraceChainConcurrencyFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.RpcStreamingDisable, utils.RpcStreamingDisableFlag.Name, false, utils.RpcStreamingDisableFlag.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.DBReadConcurrency, utils.DBReadConcurrencyFlag.Name, utils.DBReadConcurrencyFlag.Value, utils.DBReadConcurrencyFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.TraceCompatibility, "trace.compat", false, "Bug for bug compatibility with OE for trace_ routines")
@@ -118,6 +122,19 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	rootCmd.PersistentFlags().DurationVar(&cfg.EvmCallTimeout, "rpc.evmtimeout", rpccfg.DefaultEvmCallTimeout, "Maximum amount of time to wait for the answer from EVM call.")
 	rootCmd.PersistentFlags().IntVar(&cfg.BatchLimit, utils.RpcBatchLimit.Name, utils.RpcBatchLimit.Value, utils.RpcBatchLimit.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.ReturnDataLimit, utils.RpcReturnDataLimit.Name, utils.RpcReturnDataLimit.Value, utils.RpcReturnDataLimit.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.MaxGetProofRewindBlockCount, utils.RpcMaxGetProofRewindBlockCount.Name, utils.RpcMaxGetProofRewindBlockCount.Value, utils.RpcMaxGetProofRewindBlockCount.Usage)
+	rootCmd.PersistentFlags().Uint64Var(&cfg.GetLogsMaxBlockRange, utils.RpcGetLogsMaxBlockRangeFlag.Name, utils.RpcGetLogsMaxBlockRangeFlag.Value, utils.RpcGetLogsMaxBlockRangeFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.GetLogsMaxResults, utils.RpcGetLogsMaxResultsFlag.Name, utils.RpcGetLogsMaxResultsFlag.Value, utils.RpcGetLogsMaxResultsFlag.Usage)
+	rootCmd.PersistentFlags().DurationVar(&cfg.RpcFiltersConfig.Timeout, utils.RpcFiltersTimeoutFlag.Name, utils.RpcFiltersTimeoutFlag.Value, utils.RpcFiltersTimeoutFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.RpcFiltersConfig.MaxFilters, utils.RpcFiltersMaxFlag.Name, utils.RpcFiltersMaxFlag.Value, utils.RpcFiltersMaxFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.RpcFiltersConfig.MaxLogs, utils.RpcFiltersMaxLogsFlag.Name, utils.RpcFiltersMaxLogsFlag.Value, utils.RpcFiltersMaxLogsFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.RpcFiltersConfig.MaxHeaders, utils.RpcFiltersMaxHeadersFlag.Name, utils.RpcFiltersMaxHeadersFlag.Value, utils.RpcFiltersMaxHeadersFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.RpcFiltersConfig.MaxTxs, utils.RpcFiltersMaxTxsFlag.Name, utils.RpcFiltersMaxTxsFlag.Value, utils.RpcFiltersMaxTxsFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.RollupSequencerHTTP, utils.RollupSequencerHTTPFlag.Name, "", utils.RollupSequencerHTTPFlag.Usage)
+	rootCmd.PersistentFlags().DurationVar(&cfg.RollupSequencerHTTPTimeout, utils.RollupSequencerHTTPTimeoutFlag.Name, utils.RollupSequencerHTTPTimeoutFlag.Value, utils.RollupSequencerHTTPTimeoutFlag.Usage)
+	rootCmd.PersistentFlags().BoolVar(&cfg.RollupKeepLocalTxs, utils.RollupKeepLocalTxsFlag.Name, false, utils.RollupKeepLocalTxsFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.RollupHistoricalRPC, utils.RollupHistoricalRPCFlag.Name, "", utils.RollupHistoricalRPCFlag.Usage)
+	rootCmd.PersistentFlags().DurationVar(&cfg.RollupHistoricalRPCTimeout, utils.RollupHistoricalRPCTimeoutFlag.Name, utils.RollupHistoricalRPCTimeoutFlag.Value, utils.RollupHistoricalRPCTimeoutFlag.Usage)
 
 	if err := rootCmd.MarkPersistentFlagFilename("rpc.accessList", "json"); err != nil {
 		panic(err)
@@ -243,7 +260,7 @@ func checkDbCompatibility(ctx context.Context, db kv.RoDB) error {
 }
 
 func EmbeddedServices(ctx context.Context,
-	erigonDB kv.RoDB, stateCacheCfg kvcache.CoherentConfig,
+	erigonDB kv.RoDB, stateCacheCfg kvcache.CoherentConfig, filtersCfg rpchelper.FiltersConfig,
 	blockReader services.FullBlockReader, ethBackendServer remote.ETHBACKENDServer, txPoolServer txpool.TxpoolServer,
 	miningServer txpool.MiningServer, stateDiffClient StateChangesClient,
 ) (eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, stateCache kvcache.Cache, ff *rpchelper.Filters, err error) {
@@ -264,7 +281,7 @@ func EmbeddedServices(ctx context.Context,
 	eth = rpcservices.NewRemoteBackend(directClient, erigonDB, blockReader)
 	txPool = direct.NewTxPoolClient(txPoolServer)
 	mining = direct.NewMiningClient(miningServer)
-	ff = rpchelper.New(ctx, eth, txPool, mining, func() {})
+	ff = rpchelper.New(ctx, eth, txPool, mining, func() {}, filtersCfg)
 
 	return
 }
@@ -479,7 +496,7 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		}
 	}()
 
-	ff = rpchelper.New(ctx, eth, txPool, mining, onNewSnapshot)
+	ff = rpchelper.New(ctx, eth, txPool, mining, onNewSnapshot, cfg.RpcFiltersConfig)
 	return db, borDb, eth, txPool, mining, stateCache, blockReader, ff, agg, err
 }
 
@@ -512,6 +529,26 @@ func startRegularRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rp
 	}
 	srv.SetAllowList(allowListForRPC)
 
+	limitsForRPC, err := parseLimitsForRPC(cfg.RpcLimitsFilePath)
+	if err != nil {
+		return err
+	}
+	if len(limitsForRPC) > 0 {
+		// Clients with a JWT signed with the limits secret are identified by its subject. It
+		// is not the Engine API secret, whose JWTs would let the clients call engine_*
+		var jwtSecret []byte
+		if cfg.RpcLimitsJWTSecretPath != "" {
+			if jwtSecret, err = readLimitsJWTSecret(cfg.RpcLimitsJWTSecretPath); err != nil {
+				return err
+			}
+		}
+		trustedProxies, err := rpc.ParseTrustedProxies(cfg.RpcLimitsTrustedProxies)
+		if err != nil {
+			return err
+		}
+		srv.SetLimits(limitsForRPC, jwtSecret, trustedProxies)
+	}
+
 	srv.SetBatchLimit(cfg.BatchLimit)
 
 	var defaultAPIList []rpc.API
@@ -682,6 +719,21 @@ func obtainJWTSecret(cfg httpcfg.HttpCfg) ([]byte, error) {
 	return jwtSecret, nil
 }
 
+// readLimitsJWTSecret loads the secret of the JWTs identifying the clients of the limits.
+// Unlike the Engine API secret, it is never generated.
+func readLimitsJWTSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the JWT secret of the limits: %w", err)
+	}
+	jwtSecret := common.FromHex(strings.TrimSpace(string(data)))
+	if len(jwtSecret) != 32 {
+		log.Error("Invalid JWT secret of the limits", "path", path, "length", len(jwtSecret))
+		return nil, errors.New("invalid JWT secret of the limits")
+	}
+	return jwtSecret, nil
+}
+
 func createHandler(cfg httpcfg.HttpCfg, apiList []rpc.API, httpHandler http.Handler, wsHandler http.Handler, jwtSecret []byte) (http.Handler, error) {
 	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// adding a healthcheck here
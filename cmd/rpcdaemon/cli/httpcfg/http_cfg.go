@@ -7,6 +7,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
 type HttpCfg struct {
@@ -32,8 +33,12 @@ type HttpCfg struct {
 	WebsocketEnabled         bool
 	WebsocketCompression     bool
 	RpcAllowListFilePath     string
+	RpcLimitsFilePath        string
+	RpcLimitsJWTSecretPath   string   // Secret of the JWTs identifying the clients of the limits, never the Engine API one
+	RpcLimitsTrustedProxies  []string // Proxies trusted to forward the IP address of the clients of the limits
 	RpcBatchConcurrency      uint
 	RpcStreamingDisable      bool
+	TraceChainConcurrency    uint // Maximum number of blocks traced at the same time by debug_traceChain
 	DBReadConcurrency        int
 	TraceCompatibility       bool // Bug for bug compatibility for trace_ routines with OpenEthereum
 	TxPoolApiAddr            string
@@ -63,4 +68,18 @@ type HttpCfg struct {
 
 	BatchLimit      int // Maximum number of requests in a batch
 	ReturnDataLimit int // Maximum number of bytes returned from calls (like eth_call)
+
+	MaxGetProofRewindBlockCount int // Maximum number of blocks eth_getProof rewinds the trie for
+
+	GetLogsMaxBlockRange uint64 // Maximum number of blocks queried at once by eth_getLogs, 0 is unlimited
+	GetLogsMaxResults    int    // Maximum number of logs returned at once by eth_getLogs, 0 is unlimited
+
+	RpcFiltersConfig rpchelper.FiltersConfig // Limits of the filters polled with eth_getFilterChanges
+
+	RollupSequencerHTTP        string        // Sequencer endpoint raw transactions are forwarded to
+	RollupSequencerHTTPTimeout time.Duration // Timeout of the forwarded requests
+	RollupKeepLocalTxs         bool          // Also add forwarded transactions to the local txpool
+
+	RollupHistoricalRPC        string        // Legacy node requests about pre-Bedrock blocks are relayed to
+	RollupHistoricalRPCTimeout time.Duration // Timeout of the relayed requests
 }
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// methodLimitsFile are the limits of a method in the limits file, for example:
+//
+//	{"limits": {"debug_traceBlockByNumber": {"rate": 1, "burst": 2, "timeout": "30s"}, "*": {"rate": 100, "maxResponseSize": 10000000}}}
+type methodLimitsFile struct {
+	Rate            float64 `json:"rate"`
+	Burst           int     `json:"burst"`
+	MaxResponseSize int     `json:"maxResponseSize"`
+	Timeout         string  `json:"timeout"`
+}
+
+type limitsFile struct {
+	Limits map[string]methodLimitsFile `json:"limits"`
+}
+
+func parseLimitsForRPC(path string) (rpc.Limits, error) {
+	path = strings.TrimSpace(path)
+	if path == "" { // no file is provided
+		return nil, nil
+	}
+
+	fileContents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var limitsFileObj limitsFile
+	if err = json.Unmarshal(fileContents, &limitsFileObj); err != nil {
+		return nil, err
+	}
+
+	limits := make(rpc.Limits, len(limitsFileObj.Limits))
+	for method, l := range limitsFileObj.Limits {
+		if l.Rate < 0 || l.Burst < 0 || l.MaxResponseSize < 0 {
+			return nil, fmt.Errorf("negative limit of %s", method)
+		}
+		var timeout time.Duration
+		if l.Timeout != "" {
+			if timeout, err = time.ParseDuration(l.Timeout); err != nil {
+				return nil, fmt.Errorf("timeout of %s: %w", method, err)
+			}
+		}
+		limits[method] = rpc.MethodLimits{
+			Rate:            l.Rate,
+			Burst:           l.Burst,
+			MaxResponseSize: l.MaxResponseSize,
+			Timeout:         timeout,
+		}
+	}
+	return limits, nil
+}
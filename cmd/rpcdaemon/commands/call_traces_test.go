@@ -8,6 +8,7 @@ import (
 	"github.com/holiman/uint256"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -18,6 +19,7 @@ import (
 
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/stages"
@@ -271,3 +273,50 @@ func TestFilterAddressIntersection(t *testing.T) {
 		require.Empty(t, blockNumbersFromTraces(t, stream.Buffer()))
 	})
 }
+
+func TestFilterCriteriaBeforeIndex(t *testing.T) {
+	m := stages.Mock(t)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	api := NewTraceAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, &httpcfg.HttpCfg{})
+
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 10, func(i int, block *core.BlockGen) {
+		signer := types.LatestSigner(m.ChainConfig)
+		txn, err := types.SignTx(types.NewTransaction(block.TxNonce(m.Address), common.Address{1}, new(uint256.Int), 21000, new(uint256.Int), nil), *signer, m.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(txn)
+	}, false /* intermediateHashes */)
+	require.NoError(t, err, "generate chain")
+
+	err = m.InsertChain(chain)
+	require.NoError(t, err, "inserting chain")
+
+	fromBlock, toBlock := uint64(1), uint64(10)
+	filter := func() []int {
+		stream := jsoniter.ConfigDefault.BorrowStream(nil)
+		defer jsoniter.ConfigDefault.ReturnStream(stream)
+
+		traceReq := TraceFilterRequest{
+			FromBlock: (*hexutil.Uint64)(&fromBlock),
+			ToBlock:   (*hexutil.Uint64)(&toBlock),
+			CallTypes: []string{CALL},
+		}
+		if err = api.Filter(context.Background(), traceReq, stream); err != nil {
+			t.Fatalf("trace_filter failed: %v", err)
+		}
+		return blockNumbersFromTraces(t, stream.Buffer())
+	}
+	require.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, filter())
+
+	// The blocks executed before the call criteria index was introduced are scanned
+	err = m.DB.Update(context.Background(), func(tx kv.RwTx) error {
+		if err := tx.ClearBucket(kv.CallCriteriaIndex); err != nil {
+			return err
+		}
+		return rawdb.WriteCallCriteriaStart(tx, 6)
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3, 4, 5}, filter())
+}
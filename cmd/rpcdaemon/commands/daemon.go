@@ -1,13 +1,20 @@
 package commands
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	libstate "github.com/ledgerwatch/erigon-lib/state"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/consensus"
 	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/proofs"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/services"
 )
@@ -15,14 +22,23 @@ import (
 // APIList describes the list of available RPC apis
 func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient,
 	filters *rpchelper.Filters, stateCache kvcache.Cache,
-	blockReader services.FullBlockReader, agg *libstate.AggregatorV3, cfg httpcfg.HttpCfg, engine consensus.EngineReader, proofDB kv.RwDB,
-) (list []rpc.API) {
-	base := NewBaseApiMM(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout, engine, proofDB)
-	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.ReturnDataLimit)
+	blockReader services.FullBlockReader, agg *libstate.AggregatorV3, cfg httpcfg.HttpCfg, engine consensus.EngineReader, proofStore *proofs.Store, rollup RollupServices,
+) (list []rpc.API, err error) {
+	base := NewRollupBaseApi(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout, engine, RollupOptions{
+		Proofs:               proofStore,
+		HistoricalRPCService: rollup.Historical,
+		LogsLimits:           LogsLimits{MaxBlockRange: cfg.GetLogsMaxBlockRange, MaxResults: cfg.GetLogsMaxResults},
+	})
+	ethImpl := NewRollupEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.ReturnDataLimit, EthRollupOptions{
+		MaxGetProofRewindBlockCount: cfg.MaxGetProofRewindBlockCount,
+		Dirs:                        cfg.Dirs,
+		SeqRPCService:               rollup.Sequencer,
+		KeepLocalTxs:                cfg.RollupKeepLocalTxs,
+	})
 	erigonImpl := NewErigonAPI(base, db, eth)
 	txpoolImpl := NewTxPoolAPI(base, db, txPool)
 	netImpl := NewNetAPIImpl(eth)
-	debugImpl := NewPrivateDebugAPI(base, db, cfg.Gascap)
+	debugImpl := NewPrivateDebugAPI(base, db, cfg.Gascap, cfg.TraceChainConcurrency)
 	traceImpl := NewTraceAPI(base, db, &cfg)
 	web3Impl := NewWeb3APIImpl(eth)
 	dbImpl := NewDBAPIImpl() /* deprecated */
@@ -30,6 +46,11 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 	parityImpl := NewParityAPIImpl(db)
 	borImpl := NewBorAPI(base, db, borDb) // bor (consensus) specific
 	otsImpl := NewOtterscanAPI(base, db)
+	// With a historical RPC, the results of the legacy node are relayed to the clients verbatim
+	var ethService, traceService interface{} = EthAPI(ethImpl), TraceAPI(traceImpl)
+	if rollup.Historical != nil {
+		ethService, traceService = &historicalEthAPI{ethImpl}, &historicalTraceAPI{traceImpl}
+	}
 
 	for _, enabledAPI := range cfg.API {
 		switch enabledAPI {
@@ -37,7 +58,7 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 			list = append(list, rpc.API{
 				Namespace: "eth",
 				Public:    true,
-				Service:   EthAPI(ethImpl),
+				Service:   ethService,
 				Version:   "1.0",
 			})
 		case "debug":
@@ -72,7 +93,7 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 			list = append(list, rpc.API{
 				Namespace: "trace",
 				Public:    true,
-				Service:   TraceAPI(traceImpl),
+				Service:   traceService,
 				Version:   "1.0",
 			})
 		case "db": /* Deprecated */
@@ -120,23 +141,88 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 		}
 	}
 
-	return list
+	return list, nil
+}
+
+// rollupServiceProbeTimeout bounds the request checking that a rollup service is reachable.
+const rollupServiceProbeTimeout = 10 * time.Second
+
+// RollupServices are the clients of the rollup services the APIs relay requests to, nil for those
+// that are not configured. They are dialed once and shared by APIList and AuthAPIList.
+type RollupServices struct {
+	Sequencer  *rpc.Client // Sequencer raw transactions are forwarded to
+	Historical *rpc.Client // Legacy node requests about pre-Bedrock blocks are relayed to
+}
+
+// DialRollupServices connects to the configured sequencer and historical RPC, and checks that they
+// are reachable by requesting their chain id.
+func DialRollupServices(ctx context.Context, cfg httpcfg.HttpCfg) (rollup RollupServices, err error) {
+	if cfg.RollupSequencerHTTP != "" {
+		if rollup.Sequencer, err = dialRollupService(ctx, cfg.RollupSequencerHTTP, cfg.RollupSequencerHTTPTimeout); err != nil {
+			return RollupServices{}, fmt.Errorf("sequencer %s: %w", cfg.RollupSequencerHTTP, err)
+		}
+	}
+	if cfg.RollupHistoricalRPC != "" {
+		if rollup.Historical, err = dialRollupService(ctx, cfg.RollupHistoricalRPC, cfg.RollupHistoricalRPCTimeout); err != nil {
+			rollup.Close()
+			return RollupServices{}, fmt.Errorf("historical RPC %s: %w", cfg.RollupHistoricalRPC, err)
+		}
+	}
+	return rollup, nil
+}
+
+func dialRollupService(ctx context.Context, endpoint string, timeout time.Duration) (*rpc.Client, error) {
+	client, err := rpc.DialHTTPWithClient(endpoint, &http.Client{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, rollupServiceProbeTimeout)
+	defer cancel()
+	var chainID hexutil.Big
+	if err := client.CallContext(ctx, &chainID, "eth_chainId"); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unreachable: %w", err)
+	}
+	return client, nil
+}
+
+// Close closes the clients of the rollup services.
+func (r RollupServices) Close() {
+	if r.Sequencer != nil {
+		r.Sequencer.Close()
+	}
+	if r.Historical != nil {
+		r.Historical.Close()
+	}
 }
 
 func AuthAPIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient,
 	filters *rpchelper.Filters, stateCache kvcache.Cache, blockReader services.FullBlockReader,
 	agg *libstate.AggregatorV3,
-	cfg httpcfg.HttpCfg, engine consensus.EngineReader, proofDB kv.RwDB,
-) (list []rpc.API) {
-	base := NewBaseApiMM(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout, engine, proofDB)
+	cfg httpcfg.HttpCfg, engine consensus.EngineReader, proofStore *proofs.Store, rollup RollupServices,
+) (list []rpc.API, err error) {
+	base := NewRollupBaseApi(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout, engine, RollupOptions{
+		Proofs:               proofStore,
+		HistoricalRPCService: rollup.Historical,
+		LogsLimits:           LogsLimits{MaxBlockRange: cfg.GetLogsMaxBlockRange, MaxResults: cfg.GetLogsMaxResults},
+	})
 
-	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.ReturnDataLimit)
+	ethImpl := NewRollupEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.ReturnDataLimit, EthRollupOptions{
+		MaxGetProofRewindBlockCount: cfg.MaxGetProofRewindBlockCount,
+		Dirs:                        cfg.Dirs,
+		SeqRPCService:               rollup.Sequencer,
+		KeepLocalTxs:                cfg.RollupKeepLocalTxs,
+	})
 	engineImpl := NewEngineAPI(base, db, eth, cfg.InternalCL)
+	var ethService interface{} = EthAPI(ethImpl)
+	if rollup.Historical != nil {
+		ethService = &historicalEthAPI{ethImpl}
+	}
 
 	list = append(list, rpc.API{
 		Namespace: "eth",
 		Public:    true,
-		Service:   EthAPI(ethImpl),
+		Service:   ethService,
 		Version:   "1.0",
 	}, rpc.API{
 		Namespace: "engine",
@@ -145,5 +231,5 @@ func AuthAPIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClien
 		Version:   "1.0",
 	})
 
-	return list
+	return list, nil
 }
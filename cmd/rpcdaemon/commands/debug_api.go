@@ -20,6 +20,7 @@ import (
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
 	"github.com/ledgerwatch/erigon/turbo/transactions"
+	"golang.org/x/sync/semaphore"
 )
 
 // AccountRangeMaxResults is the maximum number of results to be returned per call
@@ -36,6 +37,7 @@ type PrivateDebugAPI interface {
 	GetModifiedAccountsByHash(_ context.Context, startHash common.Hash, endHash *common.Hash) ([]common.Address, error)
 	TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *tracers.TraceConfig, stream *jsoniter.Stream) error
 	AccountAt(ctx context.Context, blockHash common.Hash, txIndex uint64, account common.Address) (*AccountResult, error)
+	TraceChain(ctx context.Context, start, end rpc.BlockNumber, config *tracers.TraceConfig) (*rpc.Subscription, error)
 }
 
 // PrivateDebugAPIImpl is implementation of the PrivateDebugAPI interface based on remote Db access
@@ -43,14 +45,22 @@ type PrivateDebugAPIImpl struct {
 	*BaseAPI
 	db     kv.RoDB
 	GasCap uint64
+
+	traceChainWorkers int
+	traceChainSem     *semaphore.Weighted // Limits the blocks traced at the same time by all the debug_traceChain subscriptions
 }
 
 // NewPrivateDebugAPI returns PrivateDebugAPIImpl instance
-func NewPrivateDebugAPI(base *BaseAPI, db kv.RoDB, gascap uint64) *PrivateDebugAPIImpl {
+func NewPrivateDebugAPI(base *BaseAPI, db kv.RoDB, gascap uint64, traceChainConcurrency uint) *PrivateDebugAPIImpl {
+	if traceChainConcurrency == 0 {
+		traceChainConcurrency = 1
+	}
 	return &PrivateDebugAPIImpl{
-		BaseAPI: base,
-		db:      db,
-		GasCap:  gascap,
+		BaseAPI:           base,
+		db:                db,
+		GasCap:            gascap,
+		traceChainWorkers: int(traceChainConcurrency),
+		traceChainSem:     semaphore.NewWeighted(int64(traceChainConcurrency)),
 	}
 }
 
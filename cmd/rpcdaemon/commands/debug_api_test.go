@@ -15,6 +15,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv/order"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	common2 "github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state/temporal"
 	"github.com/ledgerwatch/erigon/core/types"
@@ -57,7 +58,7 @@ func TestTraceBlockByNumber(t *testing.T) {
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
 	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 100_000)
-	api := NewPrivateDebugAPI(baseApi, m.DB, 0)
+	api := NewPrivateDebugAPI(baseApi, m.DB, 0, 1)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -99,6 +100,44 @@ func TestTraceBlockByNumber(t *testing.T) {
 	}
 }
 
+func TestTraceBlockByNumberAggregate(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
+	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 100_000)
+	api := NewPrivateDebugAPI(baseApi, m.DB, 0, 1)
+	tracer, aggregate := "profilerTracer", true
+	for _, tt := range debugTraceTransactionTests {
+		tx, err := ethApi.GetTransactionByHash(m.Ctx, common.HexToHash(tt.txHash))
+		require.NoError(t, err)
+		txcount, err := ethApi.GetBlockTransactionCountByHash(m.Ctx, *tx.BlockHash)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		err = api.TraceBlockByNumber(m.Ctx, rpc.BlockNumber(tx.BlockNumber.ToInt().Uint64()), &tracers.TraceConfig{Tracer: &tracer, Aggregate: &aggregate}, stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Flush())
+		var profile struct{ Transactions uint64 }
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &profile))
+		require.Equal(t, uint64(*txcount), profile.Transactions)
+	}
+
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	err := api.TraceBlockByNumber(m.Ctx, rpc.LatestBlockNumber, &tracers.TraceConfig{Aggregate: &aggregate}, stream)
+	require.Error(t, err)
+
+	// Other tracers don't aggregate over the transactions of the block
+	tracer = "callTracer"
+	buf.Reset()
+	stream = jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	err = api.TraceBlockByNumber(m.Ctx, rpc.LatestBlockNumber, &tracers.TraceConfig{Tracer: &tracer, Aggregate: &aggregate}, stream)
+	require.Error(t, err)
+}
+
 func TestTraceBlockByHash(t *testing.T) {
 	m, _, _ := rpcdaemontest.CreateTestSentry(t)
 	agg := m.HistoryV3Components()
@@ -106,7 +145,7 @@ func TestTraceBlockByHash(t *testing.T) {
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
 	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 100_000)
-	api := NewPrivateDebugAPI(baseApi, m.DB, 0)
+	api := NewPrivateDebugAPI(baseApi, m.DB, 0, 1)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -135,13 +174,54 @@ func TestTraceBlockByHash(t *testing.T) {
 	}
 }
 
+func TestTraceChain(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
+	api := NewPrivateDebugAPI(baseApi, m.DB, 0, 3)
+
+	srv := rpc.NewServer(50, false, true)
+	require.NoError(t, srv.RegisterName("debug", api))
+	client := rpc.DialInProc(srv)
+	defer client.Close()
+
+	// The blocks are notified in order, even though they are traced in parallel
+	results := make(chan TraceChainResult)
+	tracer := "callTracer"
+	sub, err := client.Subscribe(m.Ctx, "debug", results, "traceChain", rpc.BlockNumber(1), rpc.BlockNumber(6), &tracers.TraceConfig{Tracer: &tracer})
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+	for number := uint64(1); number <= 6; number++ {
+		select {
+		case result := <-results:
+			require.Empty(t, result.Error)
+			require.Equal(t, hexutil.Uint64(number), result.Block)
+			err := m.DB.View(m.Ctx, func(tx kv.Tx) error {
+				hash, err := rawdb.ReadCanonicalHash(tx, number)
+				require.Equal(t, hash, result.Hash)
+				return err
+			})
+			require.NoError(t, err)
+			var traces []json.RawMessage
+			require.NoError(t, json.Unmarshal(result.Traces, &traces))
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		}
+	}
+
+	_, err = client.Subscribe(m.Ctx, "debug", results, "traceChain", rpc.BlockNumber(6), rpc.BlockNumber(1), &tracers.TraceConfig{})
+	require.Error(t, err)
+}
+
 func TestTraceTransaction(t *testing.T) {
 	m, _, _ := rpcdaemontest.CreateTestSentry(t)
 	agg := m.HistoryV3Components()
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	api := NewPrivateDebugAPI(base, m.DB, 0)
+	api := NewPrivateDebugAPI(base, m.DB, 0, 1)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -174,7 +254,7 @@ func TestTraceTransactionNoRefund(t *testing.T) {
 	agg := m.HistoryV3Components()
 	api := NewPrivateDebugAPI(
 		NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine),
-		m.DB, 0)
+		m.DB, 0, 1)
 	for _, tt := range debugTraceTransactionNoRefundTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -208,7 +288,7 @@ func TestStorageRangeAt(t *testing.T) {
 	agg := m.HistoryV3Components()
 	api := NewPrivateDebugAPI(
 		NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine),
-		m.DB, 0)
+		m.DB, 0, 1)
 	t.Run("invalid addr", func(t *testing.T) {
 		var block4 *types.Block
 		err := m.DB.View(m.Ctx, func(tx kv.Tx) error {
@@ -305,7 +385,7 @@ func TestAccountRange(t *testing.T) {
 	agg := m.HistoryV3Components()
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	api := NewPrivateDebugAPI(base, m.DB, 0)
+	api := NewPrivateDebugAPI(base, m.DB, 0, 1)
 
 	t.Run("valid account", func(t *testing.T) {
 		addr := common.HexToAddress("0x537e697c7ab75a26f9ecf0ce810e3154dfcaaf55")
@@ -368,7 +448,7 @@ func TestGetModifiedAccountsByNumber(t *testing.T) {
 	agg := m.HistoryV3Components()
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	api := NewPrivateDebugAPI(base, m.DB, 0)
+	api := NewPrivateDebugAPI(base, m.DB, 0, 1)
 
 	t.Run("correct input", func(t *testing.T) {
 		n, n2 := rpc.BlockNumber(1), rpc.BlockNumber(2)
@@ -471,7 +551,7 @@ func TestAccountAt(t *testing.T) {
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	api := NewPrivateDebugAPI(base, m.DB, 0)
+	api := NewPrivateDebugAPI(base, m.DB, 0, 1)
 
 	var blockHash0, blockHash1, blockHash3, blockHash10, blockHash12 common.Hash
 	_ = m.DB.View(m.Ctx, func(tx kv.Tx) error {
@@ -15,16 +15,12 @@ import (
 	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
 	"github.com/ledgerwatch/erigon-lib/kv"
 
-	common2 "github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
-	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
-	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
-	"github.com/ledgerwatch/erigon/turbo/trie"
 )
 
 // ExecutionPayload represents an execution payload (aka block)
@@ -67,6 +63,7 @@ type PayloadAttributes struct {
 	Withdrawals           []*types.Withdrawal `json:"withdrawals"`
 	Transactions          []hexutil.Bytes     `json:"transactions"          gencodec:"required"`
 	NoTxPool              bool                `json:"noTxPool"              gencodec:"required"`
+	GasLimit              *hexutil.Uint64     `json:"gasLimit"`
 }
 
 // TransitionConfiguration represents the correct configurations of the CL and the EL
@@ -188,6 +185,10 @@ func (e *EngineImpl) forkchoiceUpdated(version uint32, ctx context.Context, fork
 			attributes.Version = 2
 			attributes.Withdrawals = privateapi.ConvertWithdrawalsToRpc(payloadAttributes.Withdrawals)
 		}
+		if payloadAttributes.GasLimit != nil {
+			gasLimit := uint64(*payloadAttributes.GasLimit)
+			attributes.GasLimit = &gasLimit
+		}
 	}
 	reply, err := e.api.EngineForkchoiceUpdated(ctx, &remote.EngineForkChoiceUpdatedRequest{
 		ForkchoiceState: &remote.EngineForkChoiceState{
@@ -221,58 +222,6 @@ func (e *EngineImpl) forkchoiceUpdated(version uint32, ctx context.Context, fork
 	return json, nil
 }
 
-func (e *EngineImpl) MMProof(ctx context.Context, BN uint64, BH common.Hash) error {
-	if BN == 0 {
-		return nil
-	}
-
-	tx, err := e.db.BeginRo(ctx)
-	if err != nil {
-		return err
-	}
-
-	pAddr := common.HexToAddress("0x4200000000000000000000000000000000000016")
-	pRL := trie.NewRetainList(0)
-	addrHash, err := common2.HashData(pAddr[:])
-	if err != nil {
-		return err
-	}
-
-	pRL.AddKey(addrHash[:])
-	loader := trie.NewFlatDBTrieLoader("mmProof")
-	if err := loader.Reset(pRL, nil, nil, true); err != nil {
-		return err
-	}
-
-	var accProof accounts.AccProofResult
-	accProof.Address = pAddr
-	loader.SetProofReturn(&accProof)
-
-	var quit <-chan struct{}
-	hash, err := loader.CalcTrieRoot(tx, []byte{}, quit)
-	if err != nil {
-		return err
-	}
-	log.Debug("MMGP engine_api ProofResult", "blockNum", BN-1, "blockHash", BH, "stateroot", hash, "result", accProof)
-	log.Debug("MMGP proof db", "db", e._proofDB)
-	proofDB := e._proofDB
-
-	blockKey := []byte(fmt.Sprint(BN - 1))
-
-	if err := proofDB.Update(context.Background(), func(tx kv.RwTx) (err error) {
-		dbVal, err := rlp.EncodeToBytes(accProof)
-		if err != nil {
-			return err
-		}
-		return tx.Put("AccountProof", blockKey, dbVal)
-	}); err != nil {
-		return err
-	}
-	log.Debug("MMGP engine_api wrote to proofDB", "BN", BN-1, "dbEntry", accProof)
-
-	return nil
-}
-
 // NewPayloadV1 processes new payloads (blocks) from the beacon chain without withdrawals.
 
 // See https://github.com/ethereum/execution-apis/blob/main/src/engine/paris.md#engine_newpayloadv1
@@ -332,12 +281,6 @@ func (e *EngineImpl) newPayload(version uint32, ctx context.Context, payload *Ex
 		log.Warn("NewPayload", "err", err)
 		return nil, err
 	}
-	if (uint64(payload.BlockNumber)-1)%20 == 0 {
-		pErr := e.MMProof(ctx, uint64(payload.BlockNumber), payload.BlockHash)
-		if pErr != nil {
-			log.Warn("MMDBG Proof pre-calculation failed", "Block", uint64(payload.BlockNumber), "err", pErr)
-		}
-	}
 	log.Debug("MMDBG <<< NewPayload Response", "BN", uint64(payload.BlockNumber), "res", res)
 	return convertPayloadStatus(ctx, e.db, res)
 }
@@ -0,0 +1,362 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/bitmapdb"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// defaultLogsPageSize is the size of the pages of erigon_getLogsPaginated without results limit.
+const defaultLogsPageSize = 10_000
+
+// LogsLimits limit the logs queries of eth_getLogs, erigon_getLogs and erigon_getLogsPaginated.
+type LogsLimits struct {
+	MaxBlockRange uint64 // Maximum number of blocks queried at once, 0 is unlimited
+	MaxResults    int    // Maximum number of logs returned at once, 0 is unlimited
+}
+
+// LogsCursor is the position of a log in the chain, where the logs queries resume.
+type LogsCursor struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+}
+
+// LogsLimitError is returned by the logs queries exceeding the limits. Its cursor is the
+// position of the first log which did not fit in the limits: the logs before it can be
+// queried at once, and erigon_getLogsPaginated resumes from it.
+type LogsLimitError struct {
+	Message string
+	Cursor  LogsCursor
+}
+
+func (e *LogsLimitError) Error() string {
+	return e.Message
+}
+
+// ErrorCode is the code of the "limit exceeded" error.
+func (e *LogsLimitError) ErrorCode() int {
+	return -32005
+}
+
+func (e *LogsLimitError) ErrorData() interface{} {
+	return map[string]interface{}{"cursor": e.Cursor}
+}
+
+// LogsPage is a page of logs returned by erigon_getLogsPaginated.
+type LogsPage struct {
+	Logs types.ErigonLogs `json:"logs"`
+	Next *LogsCursor      `json:"next"` // Cursor of the next page, nil on the last page
+}
+
+// checkLogsRange returns a LogsLimitError when the blocks from begin to end (included)
+// exceed the maximum block range.
+func (api *BaseAPI) checkLogsRange(begin, end uint64) error {
+	maxRange := api.logsLimits.MaxBlockRange
+	if maxRange == 0 || end-begin < maxRange {
+		return nil
+	}
+	return &LogsLimitError{
+		Message: fmt.Sprintf("block range %d-%d exceeds the limit of %d blocks", begin, end, maxRange),
+		Cursor:  LogsCursor{BlockNumber: hexutil.Uint64(begin + maxRange)},
+	}
+}
+
+// logsResultsError returns the LogsLimitError of the logs exceeding the maximum number of
+// results, where the first log beyond it is at logIndex in the block.
+func logsResultsError(maxResults int, blockNumber uint64, logIndex uint) error {
+	return &LogsLimitError{
+		Message: fmt.Sprintf("query returned more than %d results", maxResults),
+		Cursor:  LogsCursor{BlockNumber: hexutil.Uint64(blockNumber), LogIndex: hexutil.Uint(logIndex)},
+	}
+}
+
+// GetLogsPaginated implements erigon_getLogsPaginated. Returns a page of the logs matching a
+// given filter object, starting from the cursor or the beginning of the filter when nil. The
+// pages are cut by the maximum block range and results of eth_getLogs and the maximum number of
+// pruned blocks scanned, or the smaller page size when given; the cursor of the next page is
+// returned until the end of the filter is reached.
+func (api *ErigonImpl) GetLogsPaginated(ctx context.Context, crit filters.FilterCriteria, cursor *LogsCursor, pageSize *hexutil.Uint) (*LogsPage, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var begin, end uint64
+	if crit.BlockHash != nil {
+		number := rawdb.ReadHeaderNumber(tx, *crit.BlockHash)
+		if number == nil {
+			return nil, fmt.Errorf("block not found: %x", *crit.BlockHash)
+		}
+		begin = *number
+		end = *number
+	} else {
+		latest, err := rpchelper.GetLatestBlockNumber(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		begin = latest
+		if crit.FromBlock != nil {
+			if crit.FromBlock.Sign() >= 0 {
+				begin = crit.FromBlock.Uint64()
+			} else if !crit.FromBlock.IsInt64() || crit.FromBlock.Int64() != int64(rpc.LatestBlockNumber) {
+				return nil, fmt.Errorf("negative value for FromBlock: %v", crit.FromBlock)
+			}
+		}
+		end = latest
+		if crit.ToBlock != nil {
+			if crit.ToBlock.Sign() >= 0 {
+				end = crit.ToBlock.Uint64()
+			} else if !crit.ToBlock.IsInt64() || crit.ToBlock.Int64() != int64(rpc.LatestBlockNumber) {
+				return nil, fmt.Errorf("negative value for ToBlock: %v", crit.ToBlock)
+			}
+		}
+	}
+	if end < begin {
+		return nil, fmt.Errorf("end (%d) < begin (%d)", end, begin)
+	}
+	if end > roaring.MaxUint32 {
+		return nil, fmt.Errorf("end (%d) > MaxUint32", end)
+	}
+
+	from, fromIndex := begin, uint(0)
+	if cursor != nil {
+		from, fromIndex = uint64(cursor.BlockNumber), uint(cursor.LogIndex)
+		if from < begin || from > end {
+			return nil, fmt.Errorf("cursor block %d out of the range %d-%d", from, begin, end)
+		}
+	}
+	to := end
+	if maxRange := api.logsLimits.MaxBlockRange; maxRange > 0 && to-from >= maxRange {
+		to = from + maxRange - 1
+	}
+	size := api.logsLimits.MaxResults
+	if size == 0 {
+		size = defaultLogsPageSize
+	}
+	if pageSize != nil && *pageSize > 0 && int(*pageSize) < size {
+		size = int(*pageSize)
+	}
+
+	if api.historyV3(tx) {
+		return api.getLogsPaginatedV3(ctx, tx.(kv.TemporalTx), crit, from, fromIndex, to, end, size)
+	}
+
+	blockNumbers := bitmapdb.NewBitmap()
+	defer bitmapdb.ReturnToPool(blockNumbers)
+	if err := applyFilters(blockNumbers, tx, from, to, crit); err != nil {
+		return nil, err
+	}
+	if err := api.applyPrunedFilters(ctx, blockNumbers, tx, from, to, crit); err != nil {
+		var limitErr *LogsLimitError
+		if !errors.As(err, &limitErr) {
+			return nil, err
+		}
+		// Cut the page where too many pruned blocks would be scanned
+		cut := uint64(limitErr.Cursor.BlockNumber)
+		blockNumbers.RemoveRange(cut, to+1)
+		to = cut - 1
+		if err := api.applyPrunedFilters(ctx, blockNumbers, tx, from, to, crit); err != nil {
+			return nil, err
+		}
+	}
+
+	addrMap := make(map[common.Address]struct{}, len(crit.Addresses))
+	for _, v := range crit.Addresses {
+		addrMap[v] = struct{}{}
+	}
+	page := &LogsPage{Logs: types.ErigonLogs{}}
+	iter := blockNumbers.Iterator()
+	for iter.HasNext() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		blockNumber := uint64(iter.Next())
+		blockLogs, err := api.blockErigonLogs(ctx, tx, blockNumber, addrMap, crit.Topics)
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range blockLogs {
+			if blockNumber == from && log.Index < fromIndex {
+				continue
+			}
+			if len(page.Logs) == size {
+				page.Next = &LogsCursor{BlockNumber: hexutil.Uint64(blockNumber), LogIndex: hexutil.Uint(log.Index)}
+				return page, nil
+			}
+			page.Logs = append(page.Logs, log)
+		}
+	}
+	if to < end {
+		page.Next = &LogsCursor{BlockNumber: hexutil.Uint64(to + 1)}
+	}
+	return page, nil
+}
+
+// getLogsPaginatedV3 returns the page of the logs from the cursor up to block to with the history
+// v3, which does not store the log indexes within the block: they are counted by executing the
+// transactions of the block before the matching ones, like countLogsV3.
+func (api *ErigonImpl) getLogsPaginatedV3(ctx context.Context, tx kv.TemporalTx, crit filters.FilterCriteria, from uint64, fromIndex uint, to, end uint64, size int) (*LogsPage, error) {
+	txNumbers, err := applyFiltersV3(tx, from, to, crit)
+	if err != nil {
+		return nil, err
+	}
+	addrMap := make(map[common.Address]struct{}, len(crit.Addresses))
+	for _, v := range crit.Addresses {
+		addrMap[v] = struct{}{}
+	}
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	exec := txnExecutor(tx, chainConfig, api.engine(), api._blockReader, nil)
+
+	var header *types.Header
+	var blockHash common.Hash
+	var logIndex uint // index in the block of the first log of the transaction counted next
+	var counted int   // transactions of the block whose logs are counted
+	page := &LogsPage{Logs: types.ErigonLogs{}}
+	iter := MapTxNum2BlockNum(tx, txNumbers)
+	for iter.HasNext() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		txNum, blockNum, txIndex, isFinalTxn, blockNumChanged, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if isFinalTxn {
+			continue
+		}
+		if blockNumChanged {
+			if header, err = api._blockReader.HeaderByNumber(ctx, tx, blockNum); err != nil {
+				return nil, err
+			}
+			if header == nil {
+				return nil, fmt.Errorf("block header not found: %d", blockNum)
+			}
+			blockHash = header.Hash()
+			exec.changeBlock(header)
+			logIndex, counted = 0, 0
+		}
+
+		for ; counted < txIndex; counted++ {
+			txn, err := api._txnReader.TxnByIdxInBlock(ctx, tx, blockNum, counted)
+			if err != nil {
+				return nil, err
+			}
+			if txn == nil {
+				continue
+			}
+			rawLogs, _, err := exec.execTx(txNum-uint64(txIndex-counted), counted, txn)
+			if err != nil {
+				return nil, err
+			}
+			logIndex += uint(len(rawLogs))
+		}
+		counted = txIndex + 1
+		txn, err := api._txnReader.TxnByIdxInBlock(ctx, tx, blockNum, txIndex)
+		if err != nil {
+			return nil, err
+		}
+		if txn == nil {
+			continue
+		}
+		rawLogs, _, err := exec.execTx(txNum, txIndex, txn)
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range rawLogs {
+			log.Index = logIndex
+			logIndex++
+		}
+
+		for _, log := range types.Logs(rawLogs).Filter(addrMap, crit.Topics) {
+			if blockNum == from && log.Index < fromIndex {
+				continue
+			}
+			if len(page.Logs) == size {
+				page.Next = &LogsCursor{BlockNumber: hexutil.Uint64(blockNum), LogIndex: hexutil.Uint(log.Index)}
+				return page, nil
+			}
+			page.Logs = append(page.Logs, &types.ErigonLog{
+				Address:     log.Address,
+				Topics:      log.Topics,
+				Data:        log.Data,
+				BlockNumber: blockNum,
+				TxHash:      txn.Hash(),
+				TxIndex:     uint(txIndex),
+				BlockHash:   blockHash,
+				Index:       log.Index,
+				Timestamp:   header.Time,
+			})
+		}
+	}
+	if to < end {
+		page.Next = &LogsCursor{BlockNumber: hexutil.Uint64(to + 1)}
+	}
+	return page, nil
+}
+
+// blockErigonLogs returns the logs of the block matching the addresses and topics.
+func (api *ErigonImpl) blockErigonLogs(ctx context.Context, tx kv.Tx, blockNumber uint64, addrMap map[common.Address]struct{}, topics [][]common.Hash) (types.ErigonLogs, error) {
+	blockLogs, err := api.blockLogs(ctx, tx, blockNumber, addrMap, topics)
+	if err != nil {
+		return nil, err
+	}
+	if len(blockLogs) == 0 {
+		return nil, nil
+	}
+
+	header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block header not found: %d", blockNumber)
+	}
+	timestamp := header.Time
+
+	blockHash := header.Hash()
+	body, err := api._blockReader.BodyWithTransactions(ctx, tx, blockHash, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, fmt.Errorf("block not found %d", blockNumber)
+	}
+	erigonLogs := make(types.ErigonLogs, 0, len(blockLogs))
+	for _, log := range blockLogs {
+		erigonLog := &types.ErigonLog{}
+		erigonLog.BlockNumber = blockNumber
+		erigonLog.BlockHash = blockHash
+		if log.TxIndex == uint(len(body.Transactions)) {
+			erigonLog.TxHash = types.ComputeBorTxHash(blockNumber, blockHash)
+		} else {
+			erigonLog.TxHash = body.Transactions[log.TxIndex].Hash()
+		}
+		erigonLog.Timestamp = timestamp
+		erigonLog.Address = log.Address
+		erigonLog.Topics = log.Topics
+		erigonLog.Data = log.Data
+		erigonLog.Index = log.Index
+		erigonLog.Removed = log.Removed
+		erigonLog.TxIndex = log.TxIndex
+		erigonLogs = append(erigonLogs, erigonLog)
+	}
+	return erigonLogs, nil
+}
@@ -100,11 +100,17 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 	if end > roaring.MaxUint32 {
 		return nil, fmt.Errorf("end (%d) > MaxUint32", end)
 	}
+	if err := api.checkLogsRange(begin, end); err != nil {
+		return nil, err
+	}
 	blockNumbers := bitmapdb.NewBitmap()
 	defer bitmapdb.ReturnToPool(blockNumbers)
 	if err := applyFilters(blockNumbers, tx, begin, end, crit); err != nil {
 		return nil, err
 	}
+	if err := api.applyPrunedFilters(ctx, blockNumbers, tx, begin, end, crit); err != nil {
+		return nil, err
+	}
 	if blockNumbers.IsEmpty() {
 		return erigonLogs, nil
 	}
@@ -120,74 +126,13 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 		}
 
 		blockNumber := uint64(iter.Next())
-		var logIndex uint
-		var txIndex uint
-		var blockLogs []*types.Log
-		it, err := tx.Prefix(kv.Log, hexutility.EncodeTs(blockNumber))
-		if err != nil {
-			return nil, err
-		}
-		for it.HasNext() {
-			k, v, err := it.Next()
-			if err != nil {
-				return erigonLogs, err
-			}
-			var logs types.Logs
-			if err := cbor.Unmarshal(&logs, bytes.NewReader(v)); err != nil {
-				return erigonLogs, fmt.Errorf("receipt unmarshal failed:  %w", err)
-			}
-			for _, log := range logs {
-				log.Index = logIndex
-				logIndex++
-			}
-			filtered := logs.Filter(addrMap, crit.Topics)
-			if len(filtered) == 0 {
-				continue
-			}
-			txIndex = uint(binary.BigEndian.Uint32(k[8:]))
-			for _, log := range filtered {
-				log.TxIndex = txIndex
-			}
-			blockLogs = append(blockLogs, filtered...)
-		}
-		if len(blockLogs) == 0 {
-			continue
-		}
-
-		header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNumber)
-		if err != nil {
-			return nil, err
-		}
-		if header == nil {
-			return nil, fmt.Errorf("block header not found: %d", blockNumber)
-		}
-		timestamp := header.Time
-
-		blockHash := header.Hash()
-		body, err := api._blockReader.BodyWithTransactions(ctx, tx, blockHash, blockNumber)
+		blockLogs, err := api.blockErigonLogs(ctx, tx, blockNumber, addrMap, crit.Topics)
 		if err != nil {
 			return nil, err
 		}
-		if body == nil {
-			return nil, fmt.Errorf("block not found %d", blockNumber)
-		}
-		for _, log := range blockLogs {
-			erigonLog := &types.ErigonLog{}
-			erigonLog.BlockNumber = blockNumber
-			erigonLog.BlockHash = blockHash
-			if log.TxIndex == uint(len(body.Transactions)) {
-				erigonLog.TxHash = types.ComputeBorTxHash(blockNumber, blockHash)
-			} else {
-				erigonLog.TxHash = body.Transactions[log.TxIndex].Hash()
-			}
-			erigonLog.Timestamp = timestamp
-			erigonLog.Address = log.Address
-			erigonLog.Topics = log.Topics
-			erigonLog.Data = log.Data
-			erigonLog.Index = log.Index
-			erigonLog.Removed = log.Removed
-			erigonLog.TxIndex = log.TxIndex
-			erigonLogs = append(erigonLogs, erigonLog)
+		erigonLogs = append(erigonLogs, blockLogs...)
+		if maxResults := api.logsLimits.MaxResults; maxResults > 0 && len(erigonLogs) > maxResults {
+			return nil, logsResultsError(maxResults, erigonLogs[maxResults].BlockNumber, erigonLogs[maxResults].Index)
 		}
 	}
 
@@ -15,6 +15,7 @@ import (
 
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
@@ -233,3 +234,109 @@ func mockWithGenerator(t *testing.T, blocks int, generator func(int, *core.Block
 	}
 	return m
 }
+
+// mockWithLogs generates a chain of blocks with two transactions emitting two logs each,
+// from the contracts they create.
+func mockWithLogs(t *testing.T, blocks int) *stages.MockSentry {
+	signer := types.LatestSignerForChainID(nil)
+	// PUSH1 0 PUSH1 0 LOG0 PUSH1 0 PUSH1 0 LOG0
+	code := common.FromHex("0x60006000a060006000a0")
+	return mockWithGenerator(t, blocks, func(i int, block *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewContractCreation(block.TxNonce(testAddr), uint256.NewInt(0), 100_000, nil, code), *signer, testKey)
+			block.AddTx(tx)
+		}
+	})
+}
+
+func TestGetLogsLimits(t *testing.T) {
+	m := mockWithLogs(t, 3)
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	limits := LogsLimits{MaxBlockRange: 3, MaxResults: 2}
+	base := NewRollupBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, m.HistoryV3Components(), false, rpccfg.DefaultEvmCallTimeout, m.Engine, RollupOptions{LogsLimits: limits})
+	api := NewEthAPI(base, m.DB, nil, nil, nil, 5000000, 100_000)
+
+	// The blocks beyond the range limit are not queried
+	_, err := api.GetLogs(m.Ctx, filters.FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(3)})
+	var limitErr *LogsLimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LogsCursor{BlockNumber: 3}, limitErr.Cursor)
+
+	// The cursor of the logs beyond the results limit is the first log of the second transaction
+	_, err = api.GetLogs(m.Ctx, filters.FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(3)})
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LogsCursor{BlockNumber: 1, LogIndex: 2}, limitErr.Cursor)
+
+	// The cursor is within the block of the last transaction queried
+	contract := crypto.CreateAddress(testAddr, 3)
+	_, err = api.GetLogs(m.Ctx, filters.FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(3), Addresses: common.Addresses{contract, crypto.CreateAddress(testAddr, 4)}})
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LogsCursor{BlockNumber: 3, LogIndex: 0}, limitErr.Cursor)
+
+	// The queries within the limits succeed
+	logs, err := api.GetLogs(m.Ctx, filters.FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(3), Addresses: common.Addresses{contract}})
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	for _, log := range logs {
+		assert.Equal(t, uint64(2), log.BlockNumber)
+		assert.Equal(t, contract, log.Address)
+	}
+}
+
+func TestErigonGetLogsPaginated(t *testing.T) {
+	m := mockWithLogs(t, 3)
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	agg := m.HistoryV3Components()
+	crit := filters.FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(rpc.LatestBlockNumber.Int64())}
+
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil)
+	var expectedLogs types.ErigonLogs
+	if m.HistoryV3 {
+		// erigon_getLogs is not supported with the history v3, the logs are read in a single page
+		page, err := api.GetLogsPaginated(m.Ctx, crit, nil, nil)
+		require.NoError(t, err)
+		require.Nil(t, page.Next)
+		expectedLogs = page.Logs
+		for i, log := range expectedLogs {
+			assert.Equal(t, uint(i%4), log.Index)
+		}
+	} else {
+		var err error
+		expectedLogs, err = api.GetLogs(m.Ctx, crit)
+		require.NoError(t, err)
+	}
+	require.Len(t, expectedLogs, 12)
+
+	limits := LogsLimits{MaxBlockRange: 3, MaxResults: 2}
+	base := NewRollupBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, RollupOptions{LogsLimits: limits})
+	api = NewErigonAPI(base, m.DB, nil)
+
+	if !m.HistoryV3 {
+		// erigon_getLogs fails with the cursor of the logs beyond the limits
+		_, err := api.GetLogs(m.Ctx, crit)
+		var limitErr *LogsLimitError
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, LogsCursor{BlockNumber: 3}, limitErr.Cursor)
+		_, err = api.GetLogs(m.Ctx, filters.FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(3)})
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, LogsCursor{BlockNumber: 1, LogIndex: 2}, limitErr.Cursor)
+	}
+
+	// erigon_getLogsPaginated walks all the logs page by page, resuming within the blocks
+	var logs types.ErigonLogs
+	var cursor *LogsCursor
+	pageSize := hexutil.Uint(1)
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 1000)
+		page, err := api.GetLogsPaginated(m.Ctx, crit, cursor, &pageSize)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page.Logs), int(pageSize))
+		logs = append(logs, page.Logs...)
+		if page.Next == nil {
+			break
+		}
+		cursor = page.Next
+	}
+	assert.Equal(t, expectedLogs, logs)
+}
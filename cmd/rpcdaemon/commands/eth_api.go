@@ -14,6 +14,7 @@ import (
 	"github.com/ledgerwatch/log/v3"
 
 	"github.com/ledgerwatch/erigon-lib/chain"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
@@ -30,6 +31,7 @@ import (
 	ethFilters "github.com/ledgerwatch/erigon/eth/filters"
 	"github.com/ledgerwatch/erigon/rpc"
 	ethapi2 "github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
+	"github.com/ledgerwatch/erigon/turbo/proofs"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/services"
 )
@@ -85,13 +87,14 @@ type EthAPI interface {
 
 	// Sending related (see ./eth_call.go)
 	Call(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *ethapi2.StateOverrides) (hexutil.Bytes, error)
-	EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Uint64, error)
+	EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *ethapi2.StateOverrides, blockOverrides *ethapi2.BlockOverrides) (hexutil.Uint64, error)
 	SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error)
 	SendTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
 	Sign(ctx context.Context, _ common.Address, _ hexutil.Bytes) (hexutil.Bytes, error)
 	SignTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
 	GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumberOrHash) (*accounts.AccProofResult, error)
-	CreateAccessList(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, optimizeGas *bool) (*accessListResult, error)
+	CreateAccessList(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, optimizeGas *bool, overrides *ethapi2.StateOverrides, blockOverrides *ethapi2.BlockOverrides) (*accessListResult, error)
+	SimulateV1(ctx context.Context, opts SimulateOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]*SimulatedBlockResult, error)
 
 	// Mining related (see ./eth_mining.go)
 	Coinbase(ctx context.Context) (common.Address, error)
@@ -119,9 +122,22 @@ type BaseAPI struct {
 	_engine      consensus.EngineReader
 
 	evmCallTimeout time.Duration
-	_proofDB     kv.RwDB
+	_proofs        *proofs.Store
+
+	historicalRPCService *rpc.Client // Legacy node requests about pre-Bedrock blocks are relayed to
+
+	logsLimits LogsLimits
+}
+
+// RollupOptions are the services and limits of BaseAPI on the rollup nodes.
+type RollupOptions struct {
+	Proofs               *proofs.Store // Proofs retained for eth_getProof at past blocks
+	HistoricalRPCService *rpc.Client   // Legacy node serving the pre-Bedrock blocks
+	LogsLimits           LogsLimits    // Limits of the logs queries
 }
-func NewBaseApiMM(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader services.FullBlockReader, agg *libstate.AggregatorV3, singleNodeMode bool, evmCallTimeout time.Duration, engine consensus.EngineReader, proofDB kv.RwDB) *BaseAPI {
+
+// NewRollupBaseApi is NewBaseApi with the rollup options.
+func NewRollupBaseApi(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader services.FullBlockReader, agg *libstate.AggregatorV3, singleNodeMode bool, evmCallTimeout time.Duration, engine consensus.EngineReader, opts RollupOptions) *BaseAPI {
 	blocksLRUSize := 128 // ~32Mb
 	if !singleNodeMode {
 		blocksLRUSize = 512
@@ -131,10 +147,10 @@ func NewBaseApiMM(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader se
 		panic(err)
 	}
 
-	return &BaseAPI{filters: f, stateCache: stateCache, blocksLRU: blocksLRU, _blockReader: blockReader, _txnReader: blockReader, _agg: agg, evmCallTimeout: evmCallTimeout, _engine: engine, _proofDB: proofDB}
+	return &BaseAPI{filters: f, stateCache: stateCache, blocksLRU: blocksLRU, _blockReader: blockReader, _txnReader: blockReader, _agg: agg, evmCallTimeout: evmCallTimeout, _engine: engine, _proofs: opts.Proofs, historicalRPCService: opts.HistoricalRPCService, logsLimits: opts.LogsLimits}
 }
 func NewBaseApi(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader services.FullBlockReader, agg *libstate.AggregatorV3, singleNodeMode bool, evmCallTimeout time.Duration, engine consensus.EngineReader) *BaseAPI {
-	return NewBaseApiMM(f, stateCache, blockReader, agg, singleNodeMode, evmCallTimeout, engine, nil)
+	return NewRollupBaseApi(f, stateCache, blockReader, agg, singleNodeMode, evmCallTimeout, engine, RollupOptions{})
 }
 
 func (api *BaseAPI) chainConfig(tx kv.Tx) (*chain.Config, error) {
@@ -283,10 +299,29 @@ type APIImpl struct {
 	db              kv.RoDB
 	GasCap          uint64
 	ReturnDataLimit int
+
+	maxGetProofRewindBlockCount int
+	dirs                        datadir.Dirs
+
+	seqRPCService *rpc.Client // Sequencer raw transactions are forwarded to, nil on the sequencer itself
+	keepLocalTxs  bool        // Also add forwarded transactions to the local txpool
+}
+
+// EthRollupOptions are the services and limits of APIImpl on the rollup nodes.
+type EthRollupOptions struct {
+	MaxGetProofRewindBlockCount int          // Maximum number of blocks eth_getProof rewinds the trie for
+	Dirs                        datadir.Dirs // Temporary files of the trie rewinds
+	SeqRPCService               *rpc.Client  // Sequencer raw transactions are forwarded to, nil on the sequencer itself
+	KeepLocalTxs                bool         // Also add forwarded transactions to the local txpool
 }
 
 // NewEthAPI returns APIImpl instance
 func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, gascap uint64, returnDataLimit int) *APIImpl {
+	return NewRollupEthAPI(base, db, eth, txPool, mining, gascap, returnDataLimit, EthRollupOptions{})
+}
+
+// NewRollupEthAPI is NewEthAPI with the rollup options.
+func NewRollupEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, gascap uint64, returnDataLimit int, opts EthRollupOptions) *APIImpl {
 	if gascap == 0 {
 		gascap = uint64(math.MaxUint64 / 2)
 	}
@@ -300,6 +335,12 @@ func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpoo
 		gasCache:        NewGasPriceCache(),
 		GasCap:          gascap,
 		ReturnDataLimit: returnDataLimit,
+
+		maxGetProofRewindBlockCount: opts.MaxGetProofRewindBlockCount,
+		dirs:                        opts.Dirs,
+
+		seqRPCService: opts.SeqRPCService,
+		keepLocalTxs:  opts.KeepLocalTxs,
 	}
 }
 
@@ -4,17 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/big"
+
 	"github.com/holiman/uint256"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	types2 "github.com/ledgerwatch/erigon-lib/types"
 	"github.com/ledgerwatch/log/v3"
 	"google.golang.org/grpc"
-	"math/big"
 
-	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/state"
@@ -22,19 +23,16 @@ import (
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
-	//"github.com/ledgerwatch/erigon/eth/stagedsync"
-	//"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/eth/tracers/logger"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	ethapi2 "github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
+	"github.com/ledgerwatch/erigon/turbo/proofs"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/transactions"
-	"github.com/ledgerwatch/erigon/turbo/trie"
-	//	"google.golang.org/grpc"
-	//"math/bits"
-	//"github.com/ledgerwatch/erigon/cmd/rpctest/rpctest"
-	"github.com/ledgerwatch/erigon/rlp"
 )
 
 var latestNumOrHash = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
@@ -53,14 +51,21 @@ func (api *APIImpl) Call(ctx context.Context, args ethapi2.CallArgs, blockNrOrHa
 	}
 	engine := api.engine()
 
-	if args.Gas == nil || uint64(*args.Gas) == 0 {
-		args.Gas = (*hexutil.Uint64)(&api.GasCap)
-	}
-
 	blockNumber, hash, _, err := rpchelper.GetCanonicalBlockNumber(blockNrOrHash, tx, api.filters) // DoCall cannot be executed on non-canonical blocks
 	if err != nil {
 		return nil, err
 	}
+	if preBedrock, err := api.isPreBedrock(tx, blockNumber); err != nil {
+		return nil, err
+	} else if preBedrock {
+		var result hexutil.Bytes
+		err = api.relayToHistoricalBackend(ctx, &result, "eth_call", args, hexutil.EncodeUint64(blockNumber), overrides)
+		return result, err
+	}
+
+	if args.Gas == nil || uint64(*args.Gas) == 0 {
+		args.Gas = (*hexutil.Uint64)(&api.GasCap)
+	}
 	block, err := api.blockWithSenders(tx, hash, blockNumber)
 	if err != nil {
 		return nil, err
@@ -115,7 +120,7 @@ func headerByNumberOrHash(ctx context.Context, tx kv.Tx, blockNrOrHash rpc.Block
 }
 
 // EstimateGas implements eth_estimateGas. Returns an estimate of how much gas is necessary to allow the transaction to complete. The transaction will not be added to the blockchain.
-func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
+func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *ethapi2.StateOverrides, blockOverrides *ethapi2.BlockOverrides) (hexutil.Uint64, error) {
 	var args ethapi2.CallArgs
 	// if we actually get CallArgs here, we use them
 	if argsOrNil != nil {
@@ -142,11 +147,32 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs
 	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 	if blockNrOrHash != nil {
 		bNrOrHash = *blockNrOrHash
+
+		blockNum, _, _, err := rpchelper.GetBlockNumber(bNrOrHash, dbtx, api.filters)
+		if err != nil {
+			return 0, err
+		}
+		if preBedrock, err := api.isPreBedrock(dbtx, blockNum); err != nil {
+			return 0, err
+		} else if preBedrock {
+			relayArgs := []interface{}{argsOrNil, hexutil.EncodeUint64(blockNum)}
+			if overrides != nil || blockOverrides != nil {
+				relayArgs = append(relayArgs, overrides)
+			}
+			if blockOverrides != nil {
+				relayArgs = append(relayArgs, blockOverrides)
+			}
+			var result hexutil.Uint64
+			err = api.relayToHistoricalBackend(ctx, &result, "eth_estimateGas", relayArgs...)
+			return result, err
+		}
 	}
 
 	// Determine the highest gas limit can be used during the estimation.
 	if args.Gas != nil && uint64(*args.Gas) >= params.TxGas {
 		hi = uint64(*args.Gas)
+	} else if blockOverrides != nil && blockOverrides.GasLimit != nil {
+		hi = uint64(*blockOverrides.GasLimit)
 	} else {
 		// Retrieve the block to act as the gas ceiling
 		h, err := headerByNumberOrHash(ctx, dbtx, bNrOrHash, api)
@@ -192,6 +218,11 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs
 		if state == nil {
 			return 0, fmt.Errorf("can't get the current state")
 		}
+		if overrides != nil {
+			if err := overrides.Override(state); err != nil {
+				return 0, err
+			}
+		}
 
 		balance := state.GetBalance(*args.From) // from can't be nil
 		available := balance.ToBig()
@@ -251,7 +282,7 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs
 	}
 	header := block.HeaderNoCopy()
 
-	caller, err := transactions.NewReusableCaller(engine, stateReader, nil, header, args, api.GasCap, latestNumOrHash, dbtx, api._blockReader, chainConfig, api.evmCallTimeout)
+	caller, err := transactions.NewReusableCaller(engine, stateReader, overrides, blockOverrides, header, args, api.GasCap, latestNumOrHash, dbtx, api._blockReader, chainConfig, api.evmCallTimeout)
 	if err != nil {
 		return 0, err
 	}
@@ -308,8 +339,11 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs
 	return hexutil.Uint64(hi), nil
 }
 
+// GetProof implements eth_getProof. Proofs at the latest block are built from the state trie.
+// Proofs at past blocks are served from the proofs retained by the IntermediateHashes stage
+// when there are any, and are otherwise built by rewinding the trie to the block in memory.
+// Proofs at pre-Bedrock blocks come from the historical RPC.
 func (api *APIImpl) GetProof(ctx context.Context, address libcommon.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*accounts.AccProofResult, error) {
-
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -320,97 +354,73 @@ func (api *APIImpl) GetProof(ctx context.Context, address libcommon.Address, sto
 	if err != nil {
 		return nil, err
 	}
-
-	log.Debug("MMGP GetProof", "addr", address, "stor", storageKeys, "blockNrOrHash", blockNrOrHash, "mapped_BN", blockNr)
-	log.Debug("MMGP proofDB", "db", api._proofDB)
-
-	proofDB := api._proofDB
-	var accProof accounts.AccProofResult
-	accProof.Address = address
-
 	latestBlock, err := rpchelper.GetLatestBlockNumber(tx)
 	if err != nil {
 		return nil, err
 	}
-
-	if blockNr == latestBlock {
-		addrHash, err := common.HashData(address[:])
-		if err != nil {
-			return nil, err
-		}
-
-		rl := trie.NewRetainList(0)
-		rl.AddKey(addrHash[:])
-
-		loader := trie.NewFlatDBTrieLoader("getProof")
-		trace := true
-		if err := loader.Reset(rl, nil, nil, trace); err != nil {
+	if preBedrock, err := api.isPreBedrock(tx, blockNr); err != nil {
+		return nil, err
+	} else if preBedrock {
+		var result accounts.AccProofResult
+		if err := api.relayToHistoricalBackend(ctx, &result, "eth_getProof", address, storageKeys, hexutil.EncodeUint64(blockNr)); err != nil {
 			return nil, err
 		}
+		return &result, nil
+	}
 
-		// Fill in the Account fields here to reduce the code changes
-		// needed in turbo/trie/hashbuilder.go
+	if blockNr == latestBlock {
 		reader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, 0, api.filters, api.stateCache, api.historyV3(tx), "")
 		if err != nil {
 			return nil, err
 		}
-		a, err := reader.ReadAccountData(address)
-		log.Debug("MMDBG ReadAccountData", "err", err, "a", a)
-		if err != nil {
-			return nil, err
-		}
-		var inc uint64
-
-		if a != nil {
-			accProof.Balance = (*hexutil.Big)(a.Balance.ToBig())
-			accProof.CodeHash = a.CodeHash
-			accProof.Nonce = hexutil.Uint64(a.Nonce)
-			accProof.StorageHash = a.Root
-			inc = a.Incarnation
-		}
-
-		loader.SetProofReturn(&accProof)
-		trRoot, err := loader.CalcTrieRoot(tx, nil, nil)
-		if err != nil {
-			return nil, err
-		}
-		log.Debug("MMGP GetProof CalcTrieRoot", "err", err, "trRoot", trRoot, "result", accProof)
-
-		sp := make([]accounts.StorProofResult, len(storageKeys))
-		if len(storageKeys) > 0 {
-			for idx := range storageKeys {
-				sp[idx].Key = storageKeys[idx]
-			}
+		return proofs.Compute(tx, reader, address, storageKeys)
+	}
+	if blockNr > latestBlock {
+		return nil, fmt.Errorf("block %d is ahead of the latest block %d", blockNr, latestBlock)
+	}
 
-			err = loader.CalcStorageProof(tx, addrHash, inc, accProof.StorageHash, &sp)
-			log.Debug("MMGP StorageResult", "newSP", sp, "err", err)
+	if api._proofs != nil && api._proofs.Config().Enabled() {
+		accProof, err := api._proofs.Read(ctx, address, storageKeys, blockNr)
+		if !errors.Is(err, proofs.ErrNotRetained) {
+			return accProof, err
 		}
-		accProof.StorageProof = sp
-	} else if blockNr > 0 && address == libcommon.HexToAddress("0x4200000000000000000000000000000000000016") && len(storageKeys) == 0 {
-		// Will check for a cached account proof. Storage proofs aren't currently supported for cached results.
-		//block := uint64(blockNr)
+	}
+	return api.getProofAt(ctx, tx, address, storageKeys, blockNr)
+}
 
-		log.Debug("MMGP GetProof checking proofDB for", "BN", blockNr)
-		blockKey := []byte(fmt.Sprint(blockNr))
+// getProofAt builds the proof at a past block by rewinding the hashed state and the trie to
+// the block in a memory batch, which is dropped afterwards.
+func (api *APIImpl) getProofAt(ctx context.Context, tx kv.Tx, address libcommon.Address, storageKeys []string, blockNr uint64) (*accounts.AccProofResult, error) {
+	head, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return nil, err
+	}
+	if blockNr > head {
+		return nil, fmt.Errorf("block %d is ahead of the state trie at block %d", blockNr, head)
+	}
+	if head-blockNr > uint64(api.maxGetProofRewindBlockCount) {
+		return nil, fmt.Errorf("block %d is more than %d blocks behind the state trie at block %d", blockNr, api.maxGetProofRewindBlockCount, head)
+	}
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return nil, err
+	}
+	if pruneTo := pm.History.PruneTo(head); blockNr < pruneTo {
+		return nil, fmt.Errorf("state history of block %d is pruned, history is kept from block %d", blockNr, pruneTo)
+	}
 
-		if err = proofDB.View(context.Background(), func(tx kv.Tx) error {
-			val, err := tx.GetOne("AccountProof", blockKey)
-			log.Debug("MMGP proofDB check", "err", err, "key", blockKey, "val", hexutil.Bytes(val))
+	historyV3 := api.historyV3(tx)
+	reader, err := rpchelper.CreateStateReaderFromBlockNumber(ctx, tx, blockNr, false, 0, api.stateCache, historyV3, "")
+	if err != nil {
+		return nil, err
+	}
 
-			if err == nil {
-				err := rlp.DecodeBytes(val, &accProof)
-				log.Debug("MMGP proofDB decode", "err", err, "accProof", accProof)
-			}
-			return err
-		}); err != nil {
-			log.Debug("MMGP proofDB error", "err", err)
-			return nil, err
-		}
-	} else {
-		// Not a supported request.
-		return nil, fmt.Errorf(NotImplemented, "eth_getProof")
+	batch := memdb.NewMemoryBatch(tx, api.dirs.Tmp)
+	defer batch.Rollback()
+	if err = stagedsync.RewindTrie(ctx, "getProof", batch, head, blockNr, api.dirs, api._blockReader, historyV3, api._agg); err != nil {
+		return nil, err
 	}
-	return &accProof, nil
+	return proofs.Compute(batch, reader, address, storageKeys)
 }
 
 func (api *APIImpl) tryBlockFromLru(hash libcommon.Hash) *types.Block {
@@ -435,7 +445,7 @@ type accessListResult struct {
 // CreateAccessList implements eth_createAccessList. It creates an access list for the given transaction.
 // If the accesslist creation fails an error is returned.
 // If the transaction itself fails, an vmErr is returned.
-func (api *APIImpl) CreateAccessList(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, optimizeGas *bool) (*accessListResult, error) {
+func (api *APIImpl) CreateAccessList(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, optimizeGas *bool, overrides *ethapi2.StateOverrides, blockOverrides *ethapi2.BlockOverrides) (*accessListResult, error) {
 	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 	if blockNrOrHash != nil {
 		bNrOrHash = *blockNrOrHash
@@ -518,6 +528,11 @@ func (api *APIImpl) CreateAccessList(ctx context.Context, args ethapi2.CallArgs,
 	}
 	for {
 		state := state.New(stateReader)
+		if overrides != nil {
+			if err := overrides.Override(state); err != nil {
+				return nil, err
+			}
+		}
 		// Retrieve the current access list to expand
 		accessList := prevTracer.AccessList()
 		log.Trace("Creating access list", "input", accessList)
@@ -538,6 +553,9 @@ func (api *APIImpl) CreateAccessList(ctx context.Context, args ethapi2.CallArgs,
 		if header.BaseFee != nil {
 			baseFee, _ = uint256.FromBig(header.BaseFee)
 		}
+		if blockOverrides != nil && blockOverrides.BaseFee != nil {
+			baseFee = blockOverrides.BaseFee
+		}
 
 		msg, err = args.ToMessage(api.GasCap, baseFee)
 		if err != nil {
@@ -548,6 +566,9 @@ func (api *APIImpl) CreateAccessList(ctx context.Context, args ethapi2.CallArgs,
 		tracer := logger.NewAccessListTracer(accessList, *args.From, to, precompiles)
 		config := vm.Config{Tracer: tracer, Debug: true, NoBaseFee: true}
 		blockCtx := transactions.NewEVMBlockContext(engine, header, bNrOrHash.RequireCanonical, tx, api._blockReader)
+		if blockOverrides != nil {
+			blockOverrides.Override(&blockCtx)
+		}
 		txCtx := core.NewEVMTxContext(msg)
 
 		evm := vm.NewEVM(blockCtx, txCtx, state, chainConfig, config)
@@ -24,19 +24,9 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
-type BlockOverrides struct {
-	BlockNumber *hexutil.Uint64
-	Coinbase    *common.Address
-	Timestamp   *hexutil.Uint64
-	GasLimit    *hexutil.Uint
-	Difficulty  *hexutil.Uint
-	BaseFee     *uint256.Int
-	BlockHash   *map[uint64]common.Hash
-}
-
 type Bundle struct {
 	Transactions  []ethapi.CallArgs
-	BlockOverride BlockOverrides
+	BlockOverride ethapi.BlockOverrides
 }
 
 type StateContext struct {
@@ -44,32 +34,6 @@ type StateContext struct {
 	TransactionIndex *int
 }
 
-func blockHeaderOverride(blockCtx *evmtypes.BlockContext, blockOverride BlockOverrides, overrideBlockHash map[uint64]common.Hash) {
-	if blockOverride.BlockNumber != nil {
-		blockCtx.BlockNumber = uint64(*blockOverride.BlockNumber)
-	}
-	if blockOverride.BaseFee != nil {
-		blockCtx.BaseFee = blockOverride.BaseFee
-	}
-	if blockOverride.Coinbase != nil {
-		blockCtx.Coinbase = *blockOverride.Coinbase
-	}
-	if blockOverride.Difficulty != nil {
-		blockCtx.Difficulty = big.NewInt(int64(*blockOverride.Difficulty))
-	}
-	if blockOverride.Timestamp != nil {
-		blockCtx.Time = uint64(*blockOverride.Timestamp)
-	}
-	if blockOverride.GasLimit != nil {
-		blockCtx.GasLimit = uint64(*blockOverride.GasLimit)
-	}
-	if blockOverride.BlockHash != nil {
-		for blockNum, hash := range *blockOverride.BlockHash {
-			overrideBlockHash[blockNum] = hash
-		}
-	}
-}
-
 func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateContext StateContext, stateOverride *ethapi.StateOverrides, timeoutMilliSecondsPtr *int64) ([][]map[string]interface{}, error) {
 	var (
 		hash               common.Hash
@@ -77,11 +41,9 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 		evm                *vm.EVM
 		blockCtx           evmtypes.BlockContext
 		txCtx              evmtypes.TxContext
-		overrideBlockHash  map[uint64]common.Hash
 		baseFee            uint256.Int
 	)
 
-	overrideBlockHash = make(map[uint64]common.Hash)
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -146,9 +108,6 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 	}
 
 	getHash := func(i uint64) common.Hash {
-		if hash, ok := overrideBlockHash[i]; ok {
-			return hash
-		}
 		hash, err := rawdb.ReadCanonicalHash(tx, i)
 		if err != nil {
 			log.Debug("Can't get block hash by number", "number", i, "only-canonical", true)
@@ -241,29 +200,7 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 
 	for _, bundle := range bundles {
 		// first change blockContext
-		if bundle.BlockOverride.BlockNumber != nil {
-			blockCtx.BlockNumber = uint64(*bundle.BlockOverride.BlockNumber)
-		}
-		if bundle.BlockOverride.BaseFee != nil {
-			blockCtx.BaseFee = bundle.BlockOverride.BaseFee
-		}
-		if bundle.BlockOverride.Coinbase != nil {
-			blockCtx.Coinbase = *bundle.BlockOverride.Coinbase
-		}
-		if bundle.BlockOverride.Difficulty != nil {
-			blockCtx.Difficulty = big.NewInt(int64(*bundle.BlockOverride.Difficulty))
-		}
-		if bundle.BlockOverride.Timestamp != nil {
-			blockCtx.Time = uint64(*bundle.BlockOverride.Timestamp)
-		}
-		if bundle.BlockOverride.GasLimit != nil {
-			blockCtx.GasLimit = uint64(*bundle.BlockOverride.GasLimit)
-		}
-		if bundle.BlockOverride.BlockHash != nil {
-			for blockNum, hash := range *bundle.BlockOverride.BlockHash {
-				overrideBlockHash[blockNum] = hash
-			}
-		}
+		bundle.BlockOverride.Override(&blockCtx)
 		results := []map[string]interface{}{}
 		for _, txn := range bundle.Transactions {
 			if txn.Gas == nil || *(txn.Gas) == 0 {
@@ -14,18 +14,21 @@ import (
 
 	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
@@ -41,18 +44,68 @@ func TestEstimateGas(t *testing.T) {
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
 	mining := txpool.NewMiningClient(conn)
-	ff := rpchelper.New(ctx, nil, nil, mining, func() {})
+	ff := rpchelper.New(ctx, nil, nil, mining, func() {}, rpchelper.DefaultFiltersConfig)
 	api := NewEthAPI(NewBaseApi(ff, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000)
 	var from = libcommon.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
 	var to = libcommon.HexToAddress("0x0d3ab14bbad3d99f4203bd7a11acb94882050e7e")
 	if _, err := api.EstimateGas(context.Background(), &ethapi.CallArgs{
 		From: &from,
 		To:   &to,
-	}, nil); err != nil {
+	}, nil, nil, nil); err != nil {
 		t.Errorf("calling EstimateGas: %v", err)
 	}
 }
 
+func TestEstimateGasWithOverrides(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
+	mining := txpool.NewMiningClient(conn)
+	ff := rpchelper.New(ctx, nil, nil, mining, func() {}, rpchelper.DefaultFiltersConfig)
+	api := NewEthAPI(NewBaseApi(ff, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000)
+	from := libcommon.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	to := libcommon.HexToAddress("0x0d3ab14bbad3d99f4203bd7a11acb94882050e7e")
+	args := ethapi.CallArgs{From: &from, To: &to}
+	withCode := func(code string) *ethapi.StateOverrides {
+		c := hexutil.Bytes(common.FromHex(code))
+		return &ethapi.StateOverrides{to: ethapi.Account{Code: &c}}
+	}
+
+	// PUSH1 0 SLOAD PUSH1 11 JUMPI PUSH1 0 PUSH1 0 REVERT JUMPDEST STOP: reverts until slot 0 is set
+	overrides := withCode("0x600054600b5760006000fd5b00")
+	_, err := api.EstimateGas(ctx, &args, nil, overrides, nil)
+	require.ErrorIs(t, err, vm.ErrExecutionReverted)
+	accessList, err := api.CreateAccessList(ctx, args, nil, nil, overrides, nil)
+	require.NoError(t, err)
+	require.Equal(t, vm.ErrExecutionReverted.Error(), accessList.Error)
+
+	// Every execution of the binary search sees the overridden storage
+	approved := map[libcommon.Hash]uint256.Int{{}: *uint256.NewInt(1)}
+	account := (*overrides)[to]
+	account.StateDiff = &approved
+	(*overrides)[to] = account
+	gas, err := api.EstimateGas(ctx, &args, nil, overrides, nil)
+	require.NoError(t, err)
+	require.Greater(t, uint64(gas), params.TxGas)
+	accessList, err = api.CreateAccessList(ctx, args, nil, nil, overrides, nil)
+	require.NoError(t, err)
+	require.Empty(t, accessList.Error)
+
+	// PUSH2 1000 NUMBER LT PUSH1 9 JUMPI STOP JUMPDEST PUSH1 0 DUP1 REVERT: reverts before block 1000
+	overrides = withCode("0x6103e84310600957005b600080fd")
+	_, err = api.EstimateGas(ctx, &args, nil, overrides, nil)
+	require.ErrorIs(t, err, vm.ErrExecutionReverted)
+	blockNumber := hexutil.Uint64(1000)
+	blockOverrides := &ethapi.BlockOverrides{BlockNumber: &blockNumber}
+	_, err = api.EstimateGas(ctx, &args, nil, overrides, blockOverrides)
+	require.NoError(t, err)
+	accessList, err = api.CreateAccessList(ctx, args, nil, nil, overrides, blockOverrides)
+	require.NoError(t, err)
+	require.Empty(t, accessList.Error)
+}
+
 func TestEthCallNonCanonical(t *testing.T) {
 	m, _, _ := rpcdaemontest.CreateTestSentry(t)
 	agg := m.HistoryV3Components()
@@ -78,7 +131,7 @@ func TestEthCallToPrunedBlock(t *testing.T) {
 	m, bankAddress, contractAddress := chainWithDeployedContract(t)
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
 
-	prune(t, m.DB, pruneTo)
+	pruneHistory(t, m.DB, pruneTo)
 
 	agg := m.HistoryV3Components()
 
@@ -373,7 +426,7 @@ func chainWithDeployedContract(t *testing.T) (*stages.MockSentry, libcommon.Addr
 	return m, bankAddress, contractAddr
 }
 
-func prune(t *testing.T, db kv.RwDB, pruneTo uint64) {
+func pruneHistory(t *testing.T, db kv.RwDB, pruneTo uint64) {
 	ctx := context.Background()
 	tx, err := db.BeginRw(ctx)
 	assert.NoError(t, err)
@@ -422,6 +475,40 @@ func TestGetProof(t *testing.T) {
 	//assert.Nil(t, t, res.Verify(res.Root)) // TODO: If this goes through, then client has different root
 }
 
+func TestGetProofHistorical(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
+	api := NewRollupEthAPI(base, m.DB, nil, nil, nil, 5000000, 100_000, EthRollupOptions{MaxGetProofRewindBlockCount: 100_000, Dirs: m.Dirs})
+	ctx := context.Background()
+
+	var addr = libcommon.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	head := rawdb.ReadCurrentHeader(tx).Number.Uint64()
+
+	for blockNum := uint64(1); blockNum < head; blockNum++ {
+		res, err := api.GetProof(ctx, addr, nil, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum)))
+		require.NoError(t, err)
+		// The first node of the account proof is the state root of the block
+		header := rawdb.ReadHeaderByNumber(tx, blockNum)
+		require.Equal(t, header.Root, crypto.Keccak256Hash(common.FromHex(res.AccountProof[0])))
+		balance, err := api.GetBalance(ctx, addr, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum)))
+		require.NoError(t, err)
+		require.Equal(t, balance.ToInt(), res.Balance.ToInt())
+	}
+
+	// Rewinding is limited to maxGetProofRewindBlockCount blocks
+	api = NewRollupEthAPI(base, m.DB, nil, nil, nil, 5000000, 100_000, EthRollupOptions{MaxGetProofRewindBlockCount: 1, Dirs: m.Dirs})
+	_, err = api.GetProof(ctx, addr, nil, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(head-1)))
+	require.NoError(t, err)
+	_, err = api.GetProof(ctx, addr, nil, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(head-2)))
+	require.Error(t, err)
+}
+
 /*
 // Verify an account proof from the getProof RPC. See https://eips.ethereum.org/EIPS/eip-1186
 func (res *AccountResult) Verify(stateRoot common.Hash) error {
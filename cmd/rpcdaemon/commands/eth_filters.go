@@ -18,12 +18,10 @@ func (api *APIImpl) NewPendingTransactionFilter(_ context.Context) (string, erro
 	if api.filters == nil {
 		return "", rpc.ErrNotificationsUnsupported
 	}
-	txsCh, id := api.filters.SubscribePendingTxs(32)
-	go func() {
-		for txs := range txsCh {
-			api.filters.AddPendingTxs(id, txs)
-		}
-	}()
+	id, err := api.filters.NewPendingTxsFilter()
+	if err != nil {
+		return "", err
+	}
 	return "0x" + string(id), nil
 }
 
@@ -32,12 +30,10 @@ func (api *APIImpl) NewBlockFilter(_ context.Context) (string, error) {
 	if api.filters == nil {
 		return "", rpc.ErrNotificationsUnsupported
 	}
-	ch, id := api.filters.SubscribeNewHeads(32)
-	go func() {
-		for block := range ch {
-			api.filters.AddPendingBlock(id, block)
-		}
-	}()
+	id, err := api.filters.NewBlockFilter()
+	if err != nil {
+		return "", err
+	}
 	return "0x" + string(id), nil
 }
 
@@ -46,12 +42,10 @@ func (api *APIImpl) NewFilter(_ context.Context, crit filters.FilterCriteria) (s
 	if api.filters == nil {
 		return "", rpc.ErrNotificationsUnsupported
 	}
-	logs, id := api.filters.SubscribeLogs(256, crit)
-	go func() {
-		for lg := range logs {
-			api.filters.AddLogs(id, lg)
-		}
-	}()
+	id, err := api.filters.NewLogsFilter(crit)
+	if err != nil {
+		return "", err
+	}
 	return "0x" + string(id), nil
 }
 
@@ -84,26 +78,28 @@ func (api *APIImpl) GetFilterChanges(_ context.Context, index string) ([]any, er
 	stub := make([]any, 0)
 	// remove 0x
 	cutIndex := strings.TrimPrefix(index, "0x")
-	if blocks, ok := api.filters.ReadPendingBlocks(rpchelper.HeadsSubID(cutIndex)); ok {
+	kind, err := api.filters.PollFilter(cutIndex)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case rpchelper.BlocksPollingFilter:
+		blocks, _ := api.filters.ReadPendingBlocks(rpchelper.HeadsSubID(cutIndex))
 		for _, v := range blocks {
 			stub = append(stub, v.Hash())
 		}
-		return stub, nil
-	}
-	if txs, ok := api.filters.ReadPendingTxs(rpchelper.PendingTxsSubID(cutIndex)); ok {
-		if len(txs) > 0 {
-			for _, tx := range txs[0] {
+	case rpchelper.PendingTxsPollingFilter:
+		txs, _ := api.filters.ReadPendingTxs(rpchelper.PendingTxsSubID(cutIndex))
+		for _, batch := range txs {
+			for _, tx := range batch {
 				stub = append(stub, tx.Hash())
 			}
-			return stub, nil
 		}
-		return stub, nil
-	}
-	if logs, ok := api.filters.ReadLogs(rpchelper.LogsSubID(cutIndex)); ok {
+	case rpchelper.LogsPollingFilter:
+		logs, _ := api.filters.ReadLogs(rpchelper.LogsSubID(cutIndex))
 		for _, v := range logs {
 			stub = append(stub, v)
 		}
-		return stub, nil
 	}
 	return stub, nil
 }
@@ -116,6 +112,13 @@ func (api *APIImpl) GetFilterLogs(_ context.Context, index string) ([]*types.Log
 		return nil, rpc.ErrNotificationsUnsupported
 	}
 	cutIndex := strings.TrimPrefix(index, "0x")
+	kind, err := api.filters.PollFilter(cutIndex)
+	if err != nil {
+		return nil, err
+	}
+	if kind != rpchelper.LogsPollingFilter {
+		return nil, rpchelper.ErrFilterNotFound
+	}
 	logs, ok := api.filters.ReadLogs(rpchelper.LogsSubID(cutIndex))
 	if len(logs) == 0 || !ok {
 		return []*types.Log{}, nil
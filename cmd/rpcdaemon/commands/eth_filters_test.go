@@ -30,7 +30,7 @@ func TestNewFilters(t *testing.T) {
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
 	mining := txpool.NewMiningClient(conn)
-	ff := rpchelper.New(ctx, nil, nil, mining, func() {})
+	ff := rpchelper.New(ctx, nil, nil, mining, func() {}, rpchelper.DefaultFiltersConfig)
 	api := NewEthAPI(NewBaseApi(ff, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000)
 
 	ptf, err := api.NewPendingTransactionFilter(ctx)
@@ -42,6 +42,16 @@ func TestNewFilters(t *testing.T) {
 	bf, err := api.NewBlockFilter(ctx)
 	assert.Nil(err)
 
+	// Polling filters are polled whether they have buffered anything or not, by the methods of their kind
+	changes, err := api.GetFilterChanges(ctx, bf)
+	assert.Nil(err)
+	assert.Empty(changes)
+	logs, err := api.GetFilterLogs(ctx, nf)
+	assert.Nil(err)
+	assert.Empty(logs)
+	_, err = api.GetFilterLogs(ctx, bf)
+	assert.ErrorIs(err, rpchelper.ErrFilterNotFound)
+
 	ok, err := api.UninstallFilter(ctx, nf)
 	assert.Nil(err)
 	assert.Equal(ok, true)
@@ -53,12 +63,15 @@ func TestNewFilters(t *testing.T) {
 	ok, err = api.UninstallFilter(ctx, ptf)
 	assert.Nil(err)
 	assert.Equal(ok, true)
+
+	_, err = api.GetFilterChanges(ctx, bf)
+	assert.ErrorIs(err, rpchelper.ErrFilterNotFound)
 }
 
 func TestLogsSubscribeAndUnsubscribe_WithoutConcurrentMapIssue(t *testing.T) {
 	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
 	mining := txpool.NewMiningClient(conn)
-	ff := rpchelper.New(ctx, nil, nil, mining, func() {})
+	ff := rpchelper.New(ctx, nil, nil, mining, func() {}, rpchelper.DefaultFiltersConfig)
 
 	// generate some random topics
 	topics := make([][]libcommon.Hash, 0)
@@ -1,9 +1,7 @@
 package commands
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
 	"fmt"
 	"math/big"
 
@@ -11,7 +9,6 @@ import (
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/chain"
 	"github.com/ledgerwatch/erigon-lib/common"
-	"github.com/ledgerwatch/erigon-lib/common/hexutility"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/bitmapdb"
 	"github.com/ledgerwatch/erigon-lib/kv/iter"
@@ -29,7 +26,6 @@ import (
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
 	"github.com/ledgerwatch/erigon/eth/filters"
-	"github.com/ledgerwatch/erigon/ethdb/cbor"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/services"
@@ -37,8 +33,33 @@ import (
 )
 
 func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *chain.Config, block *types.Block, senders []common.Address) (types.Receipts, error) {
-	if cached := rawdb.ReadReceipts(tx, block, senders); cached != nil {
-		return cached, nil
+	receipts, err := api.readReceipts(ctx, tx, chainConfig, block, senders)
+	if err != nil {
+		return nil, err
+	}
+	if optimism := chainConfig.Optimism; optimism != nil {
+		if err := receipts.DeriveL1CostFields(block.Transactions(), optimism.IsRegolith(block.Time())); err != nil {
+			return nil, err
+		}
+	}
+	return receipts, nil
+}
+
+// readReceipts returns the receipts of block from the db or the snapshots, or re-executes it
+// if they are pruned or lack contract addresses.
+func (api *BaseAPI) readReceipts(ctx context.Context, tx kv.Tx, chainConfig *chain.Config, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	stored := rawdb.ReadReceipts(tx, block, senders)
+	if stored == nil {
+		retired, err := api.snapshotReceipts(ctx, block, senders)
+		if err != nil {
+			return nil, err
+		}
+		stored = retired
+	}
+	// Contracts created by deposits before Regolith are addressed by the sender's nonce in the
+	// state, only re-executing the block gives them
+	if stored != nil && stored.CanDeriveContractAddresses(block.Transactions()) {
+		return stored, nil
 	}
 	engine := api.engine()
 
@@ -133,6 +154,9 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (t
 		}
 		end = latest
 	}
+	if err := api.checkLogsRange(begin, end); err != nil {
+		return nil, err
+	}
 
 	if api.historyV3(tx) {
 		return api.getLogsV3(ctx, tx.(kv.TemporalTx), begin, end, crit)
@@ -143,6 +167,9 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (t
 	if err := applyFilters(blockNumbers, tx, begin, end, crit); err != nil {
 		return logs, err
 	}
+	if err := api.applyPrunedFilters(ctx, blockNumbers, tx, begin, end, crit); err != nil {
+		return logs, err
+	}
 	if blockNumbers.IsEmpty() {
 		return logs, nil
 	}
@@ -157,37 +184,9 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (t
 		}
 
 		blockNumber := uint64(iter.Next())
-		var logIndex uint
-		var txIndex uint
-		var blockLogs []*types.Log
-
-		it, err := tx.Prefix(kv.Log, hexutility.EncodeTs(blockNumber))
+		blockLogs, err := api.blockLogs(ctx, tx, blockNumber, addrMap, crit.Topics)
 		if err != nil {
-			return nil, err
-		}
-		for it.HasNext() {
-			k, v, err := it.Next()
-			if err != nil {
-				return logs, err
-			}
-
-			var logs types.Logs
-			if err := cbor.Unmarshal(&logs, bytes.NewReader(v)); err != nil {
-				return logs, fmt.Errorf("receipt unmarshal failed:  %w", err)
-			}
-			for _, log := range logs {
-				log.Index = logIndex
-				logIndex++
-			}
-			filtered := logs.Filter(addrMap, crit.Topics)
-			if len(filtered) == 0 {
-				continue
-			}
-			txIndex = uint(binary.BigEndian.Uint32(k[8:]))
-			for _, log := range filtered {
-				log.TxIndex = txIndex
-			}
-			blockLogs = append(blockLogs, filtered...)
+			return logs, err
 		}
 		if len(blockLogs) == 0 {
 			continue
@@ -216,6 +215,9 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (t
 			}
 		}
 		logs = append(logs, blockLogs...)
+		if maxResults := api.logsLimits.MaxResults; maxResults > 0 && len(logs) > maxResults {
+			return nil, logsResultsError(maxResults, logs[maxResults].BlockNumber, logs[maxResults].Index)
+		}
 	}
 
 	return logs, nil
@@ -449,6 +451,15 @@ func (api *APIImpl) getLogsV3(ctx context.Context, tx kv.TemporalTx, begin, end
 			log.TxHash = txn.Hash()
 		}
 		logs = append(logs, filtered...)
+		if maxResults := api.logsLimits.MaxResults; maxResults > 0 && len(logs) > maxResults {
+			// The first log beyond the limit is in this transaction, its index is within it
+			logIndex := logs[maxResults].Index
+			before, err := api.countLogsV3(ctx, tx, exec, blockNum, txNum, txIndex)
+			if err != nil {
+				return nil, err
+			}
+			return nil, logsResultsError(maxResults, blockNum, before+logIndex)
+		}
 	}
 
 	//stats := api._agg.GetAndResetStats()
@@ -456,6 +467,28 @@ func (api *APIImpl) getLogsV3(ctx context.Context, tx kv.TemporalTx, begin, end
 	return logs, nil
 }
 
+// countLogsV3 returns the number of logs of the transactions of the block before txIndex,
+// txNum being the number of the transaction at txIndex. The history v3 does not store the
+// log indexes within the block, they are counted by executing the transactions.
+func (api *APIImpl) countLogsV3(ctx context.Context, tx kv.TemporalTx, exec *intraBlockExec, blockNum, txNum uint64, txIndex int) (uint, error) {
+	var count uint
+	for i := 0; i < txIndex; i++ {
+		txn, err := api._txnReader.TxnByIdxInBlock(ctx, tx, blockNum, i)
+		if err != nil {
+			return 0, err
+		}
+		if txn == nil {
+			continue
+		}
+		rawLogs, _, err := exec.execTx(txNum-uint64(txIndex-i), i, txn)
+		if err != nil {
+			return 0, err
+		}
+		count += uint(len(rawLogs))
+	}
+	return count, nil
+}
+
 type intraBlockExec struct {
 	ibs         *state.IntraBlockState
 	stateReader *state.HistoryReaderV3
@@ -817,6 +850,17 @@ func marshalReceipt(receipt *types.Receipt, txn types.Transaction, chainConfig *
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
+	// Regolith deposit receipts record the nonce the deposit was executed with
+	if receipt.DepositNonce != nil {
+		fields["depositNonce"] = hexutil.Uint64(*receipt.DepositNonce)
+	}
+	// Rollup receipts of non-deposit transactions carry the L1 data fee
+	if receipt.L1Fee != nil {
+		fields["l1GasPrice"] = (*hexutil.Big)(receipt.L1GasPrice)
+		fields["l1GasUsed"] = (*hexutil.Big)(receipt.L1GasUsed)
+		fields["l1Fee"] = (*hexutil.Big)(receipt.L1Fee)
+		fields["l1FeeScalar"] = receipt.FeeScalar.String()
+	}
 	return fields
 }
 
@@ -0,0 +1,395 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/misc"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+const maxSimulatedBlocks = 256
+
+var (
+	// transferLogAddress is the address of the synthetic logs of ETH transfers, it follows the
+	// convention of tokens standing in for ETH
+	transferLogAddress = libcommon.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+	transferTopic      = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+// simulateVMErrorCode is the error code of the simulated calls failing for other reasons than a revert
+const simulateVMErrorCode = -32015
+
+// SimulateOpts are the parameters of eth_simulateV1.
+type SimulateOpts struct {
+	BlockStateCalls []SimulatedBlock `json:"blockStateCalls"`
+	TraceTransfers  bool             `json:"traceTransfers"` // Add a synthetic log for every ETH transfer
+	Validation      bool             `json:"validation"`     // Check nonces, balances and base fees as block production does
+}
+
+// SimulatedBlock is a block of eth_simulateV1. Its state overrides are applied on top of the
+// state left by the previous simulated block, before its calls are executed.
+type SimulatedBlock struct {
+	BlockOverrides *ethapi.BlockOverrides `json:"blockOverrides"`
+	StateOverrides *ethapi.StateOverrides `json:"stateOverrides"`
+	Calls          []ethapi.CallArgs      `json:"calls"`
+}
+
+type SimulatedBlockResult struct {
+	Number        hexutil.Uint64         `json:"number"`
+	Hash          libcommon.Hash         `json:"hash"`
+	ParentHash    libcommon.Hash         `json:"parentHash"`
+	Timestamp     hexutil.Uint64         `json:"timestamp"`
+	GasLimit      hexutil.Uint64         `json:"gasLimit"`
+	GasUsed       hexutil.Uint64         `json:"gasUsed"`
+	Miner         libcommon.Address      `json:"miner"`
+	BaseFeePerGas *hexutil.Big           `json:"baseFeePerGas,omitempty"`
+	Calls         []*SimulatedCallResult `json:"calls"`
+}
+
+type SimulatedCallResult struct {
+	ReturnData hexutil.Bytes       `json:"returnData"`
+	Logs       []*types.Log        `json:"logs"`
+	GasUsed    hexutil.Uint64      `json:"gasUsed"`
+	Status     hexutil.Uint64      `json:"status"`
+	Error      *SimulatedCallError `json:"error,omitempty"`
+}
+
+type SimulatedCallError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// SimulateV1 implements eth_simulateV1. Executes a sequence of blocks of calls on top of the block
+// blockNrOrHash, each block seeing the state left by the previous ones, without creating any
+// transaction on the block chain.
+func (api *APIImpl) SimulateV1(ctx context.Context, opts SimulateOpts, blockNrOrHashOrNil *rpc.BlockNumberOrHash) ([]*SimulatedBlockResult, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, errors.New("empty blockStateCalls")
+	}
+	if len(opts.BlockStateCalls) > maxSimulatedBlocks {
+		return nil, fmt.Errorf("too many blocks: %d, limit is %d", len(opts.BlockStateCalls), maxSimulatedBlocks)
+	}
+	blockNrOrHash := latestNumOrHash
+	if blockNrOrHashOrNil != nil {
+		blockNrOrHash = *blockNrOrHashOrNil
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(start time.Time) { log.Trace("Executing EVM simulateV1 finished", "runtime", time.Since(start)) }(time.Now())
+
+	blockNum, hash, _, err := rpchelper.GetCanonicalBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	if preBedrock, err := api.isPreBedrock(tx, blockNum); err != nil {
+		return nil, err
+	} else if preBedrock {
+		return nil, fmt.Errorf("simulation on top of pre-Bedrock block %d is not supported", blockNum)
+	}
+	parent, err := api._blockReader.Header(ctx, tx, hash, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNum, hash)
+	}
+
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, 0, api.filters, api.stateCache, api.historyV3(tx), chainConfig.ChainName)
+	if err != nil {
+		return nil, err
+	}
+	ibs := state.New(stateReader)
+
+	// Simulated blocks can refer to the hashes of the simulated blocks before them
+	simulatedHashes := make(map[uint64]libcommon.Hash, len(opts.BlockStateCalls))
+	getHash := func(i uint64) libcommon.Hash {
+		if hash, ok := simulatedHashes[i]; ok {
+			return hash
+		}
+		if i > blockNum {
+			return libcommon.Hash{}
+		}
+		hash, err := rawdb.ReadCanonicalHash(tx, i)
+		if err != nil {
+			log.Debug("Can't get block hash by number", "number", i, "only-canonical", true)
+		}
+		return hash
+	}
+
+	var tracer *transferTracer
+	vmConfig := vm.Config{NoBaseFee: !opts.Validation}
+	if opts.TraceTransfers {
+		tracer = &transferTracer{}
+		vmConfig.Debug, vmConfig.Tracer = true, tracer
+	}
+	evm := vm.NewEVM(evmtypes.BlockContext{}, evmtypes.TxContext{}, ibs, chainConfig, vmConfig)
+
+	var cancel context.CancelFunc
+	if api.evmCallTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, api.evmCallTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+
+	results := make([]*SimulatedBlockResult, 0, len(opts.BlockStateCalls))
+	for blockIdx, block := range opts.BlockStateCalls {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			Coinbase:   parent.Coinbase,
+			Difficulty: new(big.Int).Set(parent.Difficulty),
+			Number:     new(big.Int).Add(parent.Number, libcommon.Big1),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + 1,
+		}
+		// Base fees are only charged when validating, as eth_call does not charge them either
+		if opts.Validation && chainConfig.IsLondon(header.Number.Uint64()) {
+			header.BaseFee = misc.CalcBaseFee(chainConfig, parent)
+		} else if parent.BaseFee != nil {
+			header.BaseFee = new(big.Int)
+		}
+		blockCtx := core.NewEVMBlockContext(header, getHash, api.engine(), &header.Coinbase)
+		if block.BlockOverrides != nil {
+			block.BlockOverrides.Override(&blockCtx)
+		}
+		if blockCtx.BlockNumber <= parent.Number.Uint64() {
+			return nil, fmt.Errorf("block %d: number %d is not greater than the number of its parent %d", blockIdx, blockCtx.BlockNumber, parent.Number.Uint64())
+		}
+		if blockCtx.Time <= parent.Time {
+			return nil, fmt.Errorf("block %d: timestamp %d is not greater than the timestamp of its parent %d", blockIdx, blockCtx.Time, parent.Time)
+		}
+		header.Number.SetUint64(blockCtx.BlockNumber)
+		header.Time = blockCtx.Time
+		header.Coinbase = blockCtx.Coinbase
+		header.GasLimit = blockCtx.GasLimit
+		header.Difficulty.Set(blockCtx.Difficulty)
+		if blockCtx.BaseFee != nil && header.BaseFee != nil {
+			header.BaseFee = blockCtx.BaseFee.ToBig()
+		}
+
+		if block.StateOverrides != nil {
+			if err := block.StateOverrides.Override(ibs); err != nil {
+				return nil, err
+			}
+		}
+
+		rules := chainConfig.Rules(blockCtx.BlockNumber, blockCtx.Time)
+		evm.ResetBetweenBlocks(blockCtx, evmtypes.TxContext{}, ibs, vmConfig, rules)
+		gp := new(core.GasPool).AddGas(blockCtx.GasLimit)
+		calls := make([]*SimulatedCallResult, 0, len(block.Calls))
+		txHashes := make([]libcommon.Hash, 0, len(block.Calls))
+		for callIdx, args := range block.Calls {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("execution aborted (timeout = %v)", api.evmCallTimeout)
+			}
+			if args.Gas == nil {
+				gas := hexutil.Uint64(gp.Gas())
+				args.Gas = &gas
+			}
+			msg, err := args.ToMessage(api.GasCap, blockCtx.BaseFee)
+			if err != nil {
+				return nil, fmt.Errorf("block %d, call %d: %w", blockIdx, callIdx, err)
+			}
+			nonce := ibs.GetNonce(msg.From())
+			if args.Nonce != nil {
+				nonce = uint64(*args.Nonce)
+			}
+			msg = types.NewMessage(msg.From(), msg.To(), nonce, msg.Value(), msg.Gas(), msg.GasPrice(), msg.FeeCap(), msg.Tip(), msg.Data(), msg.AccessList(), opts.Validation /* checkNonce */, false /* isFree */)
+
+			// The calls are keyed by the hash of their unsigned transaction
+			var txn types.Transaction
+			if msg.To() == nil {
+				txn = types.NewContractCreation(nonce, msg.Value(), msg.Gas(), msg.GasPrice(), msg.Data())
+			} else {
+				txn = types.NewTransaction(nonce, *msg.To(), msg.Value(), msg.Gas(), msg.GasPrice(), msg.Data())
+			}
+			txHash := txn.Hash()
+			txHashes = append(txHashes, txHash)
+			// Unlike the transactions of a block, the calls of different simulated blocks
+			// can share a hash, so their logs are collected under a unique key
+			logsKey := libcommon.BigToHash(big.NewInt(int64(blockIdx<<32 | callIdx)))
+			ibs.Prepare(logsKey, libcommon.Hash{}, callIdx)
+
+			evm.Reset(core.NewEVMTxContext(msg), ibs)
+			result, err := core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+			if err != nil {
+				return nil, fmt.Errorf("block %d, call %d: %w", blockIdx, callIdx, err)
+			}
+			if evm.Cancelled() {
+				return nil, fmt.Errorf("execution aborted (timeout = %v)", api.evmCallTimeout)
+			}
+			if err = ibs.FinalizeTx(rules, state.NewNoopWriter()); err != nil {
+				return nil, err
+			}
+
+			logs := ibs.GetLogs(logsKey)
+			if tracer != nil {
+				logs = tracer.logs(logs)
+			}
+			call := &SimulatedCallResult{
+				ReturnData: result.Return(),
+				Logs:       make([]*types.Log, 0, len(logs)),
+				GasUsed:    hexutil.Uint64(result.UsedGas),
+				Status:     hexutil.Uint64(types.ReceiptStatusSuccessful),
+			}
+			for _, l := range logs {
+				l := *l
+				call.Logs = append(call.Logs, &l)
+			}
+			if result.Err != nil {
+				call.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+				if errors.Is(result.Err, vm.ErrExecutionReverted) {
+					revertErr := ethapi.NewRevertError(result)
+					call.Error = &SimulatedCallError{Code: revertErr.ErrorCode(), Message: revertErr.Error(), Data: revertErr.ErrorData().(string)}
+				} else {
+					call.Error = &SimulatedCallError{Code: simulateVMErrorCode, Message: result.Err.Error()}
+				}
+			}
+			calls = append(calls, call)
+		}
+
+		header.GasUsed = blockCtx.GasLimit - gp.Gas()
+		blockHash := header.Hash()
+		simulatedHashes[header.Number.Uint64()] = blockHash
+		var logIndex uint
+		for i, call := range calls {
+			for _, l := range call.Logs {
+				l.BlockNumber = header.Number.Uint64()
+				l.BlockHash = blockHash
+				l.TxHash = txHashes[i]
+				l.TxIndex = uint(i)
+				l.Index = logIndex
+				logIndex++
+			}
+		}
+		res := &SimulatedBlockResult{
+			Number:     hexutil.Uint64(header.Number.Uint64()),
+			Hash:       blockHash,
+			ParentHash: header.ParentHash,
+			Timestamp:  hexutil.Uint64(header.Time),
+			GasLimit:   hexutil.Uint64(header.GasLimit),
+			GasUsed:    hexutil.Uint64(header.GasUsed),
+			Miner:      header.Coinbase,
+			Calls:      calls,
+		}
+		if header.BaseFee != nil {
+			res.BaseFeePerGas = (*hexutil.Big)(header.BaseFee)
+		}
+		results = append(results, res)
+		parent = header
+	}
+	return results, nil
+}
+
+// transferTracer records the ETH transfers of a call as synthetic logs, ordered among the logs
+// emitted by the call. The transfers of reverted frames are dropped with their logs.
+type transferTracer struct {
+	frames [][]*types.Log // Logs of the open call frames, nil stands for a log emitted by the EVM
+}
+
+// logs returns the transfer logs of the last call merged with emitted, the logs the call emitted.
+func (t *transferTracer) logs(emitted []*types.Log) []*types.Log {
+	if len(t.frames) == 0 {
+		return emitted
+	}
+	logs := make([]*types.Log, 0, len(t.frames[0]))
+	for _, l := range t.frames[0] {
+		if l == nil {
+			if len(emitted) == 0 {
+				continue
+			}
+			l, emitted = emitted[0], emitted[1:]
+		}
+		logs = append(logs, l)
+	}
+	return logs
+}
+
+func (t *transferTracer) captureTransfer(from, to libcommon.Address, value *uint256.Int) {
+	if value == nil || value.IsZero() {
+		return
+	}
+	data := value.Bytes32()
+	top := len(t.frames) - 1
+	t.frames[top] = append(t.frames[top], &types.Log{
+		Address: transferLogAddress,
+		Topics:  []libcommon.Hash{transferTopic, libcommon.BytesToHash(from.Bytes()), libcommon.BytesToHash(to.Bytes())},
+		Data:    data[:],
+	})
+}
+
+func (t *transferTracer) CaptureTxStart(gasLimit uint64) {
+	t.frames = nil
+}
+
+func (t *transferTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *transferTracer) CaptureStart(env vm.VMInterface, from libcommon.Address, to libcommon.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.frames = [][]*types.Log{nil}
+	t.captureTransfer(from, to, value)
+}
+
+func (t *transferTracer) CaptureEnd(output []byte, usedGas uint64, err error) {
+	if err != nil {
+		t.frames[0] = nil
+	}
+}
+
+func (t *transferTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.frames = append(t.frames, nil)
+	// Delegate calls only pass on the value of their caller
+	if typ != vm.DELEGATECALL {
+		t.captureTransfer(from, to, value)
+	}
+}
+
+func (t *transferTracer) CaptureExit(output []byte, usedGas uint64, err error) {
+	top := len(t.frames) - 1
+	if err == nil {
+		t.frames[top-1] = append(t.frames[top-1], t.frames[top]...)
+	}
+	t.frames = t.frames[:top]
+}
+
+func (t *transferTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if op >= vm.LOG0 && op <= vm.LOG4 && err == nil {
+		top := len(t.frames) - 1
+		t.frames[top] = append(t.frames[top], nil)
+	}
+}
+
+func (t *transferTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
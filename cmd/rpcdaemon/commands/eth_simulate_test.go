@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+)
+
+func TestSimulateV1(t *testing.T) {
+	// Base fees are only charged by validating simulations of London blocks
+	config := *params.TestChainConfig
+	config.LondonBlock = libcommon.Big0
+	key, _ := crypto.GenerateKey()
+	m := stages.MockWithGenesis(t, &core.Genesis{Config: &config}, key, false)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
+	mining := txpool.NewMiningClient(conn)
+	ff := rpchelper.New(ctx, nil, nil, mining, func() {}, rpchelper.DefaultFiltersConfig)
+	api := NewEthAPI(NewBaseApi(ff, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000)
+
+	var (
+		from     = libcommon.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+		logger   = libcommon.HexToAddress("0x0d3ab14bbad3d99f4203bd7a11acb94882050e7e")
+		store    = libcommon.HexToAddress("0x00000000000000000000000000000000000000aa")
+		reverter = libcommon.HexToAddress("0x00000000000000000000000000000000000000bb")
+		balance  = (*hexutil.Big)(big.NewInt(params.Ether))
+		value    = (*hexutil.Big)(big.NewInt(100))
+	)
+	code := func(code string) *hexutil.Bytes {
+		c := hexutil.Bytes(common.FromHex(code))
+		return &c
+	}
+	blocks := []SimulatedBlock{
+		{
+			StateOverrides: &ethapi.StateOverrides{
+				from:     ethapi.Account{Balance: &balance},
+				logger:   ethapi.Account{Code: code("0x60006000a000")}, // PUSH1 0 PUSH1 0 LOG0 STOP
+				store:    ethapi.Account{Code: code("0x600160005500")}, // PUSH1 1 PUSH1 0 SSTORE STOP
+				reverter: ethapi.Account{Code: code("0x60006000fd")},   // PUSH1 0 PUSH1 0 REVERT
+			},
+			Calls: []ethapi.CallArgs{{From: &from, To: &logger, Value: value}, {From: &from, To: &store}},
+		},
+		{
+			StateOverrides: &ethapi.StateOverrides{
+				// PUSH1 0 SLOAD PUSH1 11 JUMPI PUSH1 0 PUSH1 0 REVERT JUMPDEST STOP: reverts until slot 0 is set
+				store: ethapi.Account{Code: code("0x600054600b5760006000fd5b00")},
+			},
+			Calls: []ethapi.CallArgs{{From: &from, To: &store}, {From: &from, To: &reverter, Value: value}},
+		},
+	}
+
+	results, err := api.SimulateV1(ctx, SimulateOpts{BlockStateCalls: blocks, TraceTransfers: true}, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, results[0].Number+1, results[1].Number)
+	require.Equal(t, results[0].Hash, results[1].ParentHash)
+
+	// The transfer is logged before the log emitted by the callee
+	logs := results[0].Calls[0].Logs
+	require.Len(t, logs, 2)
+	require.Equal(t, transferLogAddress, logs[0].Address)
+	require.Equal(t, []libcommon.Hash{transferTopic, libcommon.BytesToHash(from.Bytes()), libcommon.BytesToHash(logger.Bytes())}, logs[0].Topics)
+	data := uint256.NewInt(100).Bytes32()
+	require.Equal(t, data[:], logs[0].Data)
+	require.Equal(t, logger, logs[1].Address)
+	for i, l := range logs {
+		require.Equal(t, uint(i), l.Index)
+		require.Equal(t, results[0].Hash, l.BlockHash)
+		require.Equal(t, uint64(results[0].Number), l.BlockNumber)
+	}
+	require.Equal(t, hexutil.Uint64(types.ReceiptStatusSuccessful), results[0].Calls[1].Status)
+
+	// The second block sees the storage written by the first one, reverted transfers are not logged
+	require.Equal(t, hexutil.Uint64(types.ReceiptStatusSuccessful), results[1].Calls[0].Status)
+	reverted := results[1].Calls[1]
+	require.Equal(t, hexutil.Uint64(types.ReceiptStatusFailed), reverted.Status)
+	require.Equal(t, 3, reverted.Error.Code)
+	require.Empty(t, reverted.Logs)
+	require.Equal(t, results[1].GasUsed, results[1].Calls[0].GasUsed+reverted.GasUsed)
+
+	// Without tracing transfers only the emitted logs are returned
+	results, err = api.SimulateV1(ctx, SimulateOpts{BlockStateCalls: blocks}, nil)
+	require.NoError(t, err)
+	require.Len(t, results[0].Calls[0].Logs, 1)
+	require.Equal(t, logger, results[0].Calls[0].Logs[0].Address)
+
+	// Validation charges the base fee and checks the nonces
+	_, err = api.SimulateV1(ctx, SimulateOpts{BlockStateCalls: blocks, Validation: true}, nil)
+	require.ErrorIs(t, err, core.ErrFeeCapTooLow)
+	feeCap := (*hexutil.Big)(big.NewInt(100 * params.GWei))
+	nonce := hexutil.Uint64(5)
+	validated := []SimulatedBlock{{
+		StateOverrides: blocks[0].StateOverrides,
+		Calls:          []ethapi.CallArgs{{From: &from, To: &logger, MaxFeePerGas: feeCap, Nonce: &nonce}},
+	}}
+	_, err = api.SimulateV1(ctx, SimulateOpts{BlockStateCalls: validated, Validation: true}, nil)
+	require.ErrorIs(t, err, core.ErrNonceTooHigh)
+	validated[0].Calls[0].Nonce = nil
+	results, err = api.SimulateV1(ctx, SimulateOpts{BlockStateCalls: validated, Validation: true}, nil)
+	require.NoError(t, err)
+	require.NotZero(t, results[0].BaseFeePerGas.ToInt().Sign())
+}
@@ -4,14 +4,19 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/holiman/uint256"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/direct"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
 	"github.com/stretchr/testify/require"
 
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcservices"
+	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/rlp"
@@ -47,7 +52,7 @@ func TestEthSubscribe(t *testing.T) {
 	backendServer := privateapi.NewEthBackendServer(ctx, nil, m.DB, m.Notifications.Events, br, nil, nil, nil, false)
 	backendClient := direct.NewEthBackendClientDirect(backendServer)
 	backend := rpcservices.NewRemoteBackend(backendClient, m.DB, br)
-	ff := rpchelper.New(ctx, backend, nil, nil, func() {})
+	ff := rpchelper.New(ctx, backend, nil, nil, func() {}, rpchelper.DefaultFiltersConfig)
 
 	newHeads, id := ff.SubscribeNewHeads(16)
 	defer ff.UnsubscribeHeads(id)
@@ -64,3 +69,102 @@ func TestEthSubscribe(t *testing.T) {
 		require.Equal(i, header.Number.Uint64())
 	}
 }
+
+func TestEthSubscribeReorg(t *testing.T) {
+	m, require := stages.Mock(t), require.New(t)
+	if m.HistoryV3 {
+		t.Skip()
+	}
+	signer := types.LatestSignerForChainID(nil)
+	topicA, topicB := libcommon.HexToHash("0xaa"), libcommon.HexToHash("0xbb")
+	// Both branches share block 1, then each block emits a log with the topic of its branch
+	generate := func(n int, coinbase libcommon.Address, topic libcommon.Hash) *core.ChainPack {
+		chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, n, func(i int, b *core.BlockGen) {
+			if i == 0 {
+				return
+			}
+			b.SetCoinbase(coinbase)
+			// PUSH32 topic PUSH1 0 PUSH1 0 LOG1 STOP
+			code := append(append([]byte{0x7f}, topic[:]...), common.FromHex("0x60006000a100")...)
+			tx, err := types.SignTx(types.NewContractCreation(b.TxNonce(m.Address), new(uint256.Int), 1e6, new(uint256.Int), code), *signer, m.Key)
+			require.NoError(err)
+			b.AddTx(tx)
+		}, false /* intermediateHashes */)
+		require.NoError(err)
+		return chain
+	}
+	chainA := generate(4, libcommon.Address{1}, topicA)
+	chainB := generate(5, libcommon.Address{2}, topicB)
+	require.Equal(chainA.Blocks[0].Hash(), chainB.Blocks[0].Hash())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	backendServer := privateapi.NewEthBackendServer(ctx, nil, m.DB, m.Notifications.Events, br, nil, nil, nil, false)
+	backendClient := direct.NewEthBackendClientDirect(backendServer)
+	backend := rpcservices.NewRemoteBackend(backendClient, m.DB, br)
+	ff := rpchelper.New(ctx, backend, nil, nil, func() {}, rpchelper.DefaultFiltersConfig)
+
+	newHeads, headsID := ff.SubscribeNewHeads(16)
+	defer ff.UnsubscribeHeads(headsID)
+	// The filters connect to the node asynchronously, subscribe until the node has seen the logs subscription
+	logs, logsID := ff.SubscribeLogs(16, filters.FilterCriteria{})
+	require.Eventually(func() bool {
+		if m.Notifications.Events.HasLogSubsriptions() {
+			return true
+		}
+		ff.UnsubscribeLogs(logsID)
+		logs, logsID = ff.SubscribeLogs(16, filters.FilterCriteria{})
+		return false
+	}, 10*time.Second, 50*time.Millisecond)
+	defer func() { ff.UnsubscribeLogs(logsID) }()
+
+	nextHeader := func() *types.Header {
+		select {
+		case header := <-newHeads:
+			return header
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for header")
+			return nil
+		}
+	}
+	nextLog := func() *types.Log {
+		select {
+		case l := <-logs:
+			return l
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for log")
+			return nil
+		}
+	}
+
+	require.NoError(m.InsertChain(chainA))
+	for _, block := range chainA.Blocks {
+		require.Equal(block.Hash(), nextHeader().Hash())
+	}
+	for _, block := range chainA.Blocks[1:] {
+		l := nextLog()
+		require.Equal(block.Hash(), l.BlockHash)
+		require.False(l.Removed)
+	}
+
+	// Reorg to the longer branch B: the logs of branch A are removed before the logs of branch B arrive
+	require.NoError(m.InsertChain(chainB))
+	for _, block := range chainA.Blocks[1:] {
+		l := nextLog()
+		require.Equal(block.Hash(), l.BlockHash)
+		require.Equal(block.Transactions()[0].Hash(), l.TxHash)
+		require.Equal([]libcommon.Hash{topicA}, l.Topics)
+		require.True(l.Removed)
+	}
+	for _, block := range chainB.Blocks[1:] {
+		l := nextLog()
+		require.Equal(block.Hash(), l.BlockHash)
+		require.Equal([]libcommon.Hash{topicB}, l.Topics)
+		require.False(l.Removed)
+	}
+	// The headers of the new canonical branch are sent again from the fork point
+	for _, block := range chainB.Blocks[1:] {
+		require.Equal(block.Hash(), nextHeader().Hash())
+	}
+}
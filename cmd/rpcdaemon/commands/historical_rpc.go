@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+
+	jsoniter "github.com/json-iterator/go"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// isPreBedrock returns whether blockNum is a legacy block of a rollup chain. The legacy blocks are
+// imported without their state, so requests needing their state are relayed to the historical RPC.
+func (api *BaseAPI) isPreBedrock(tx kv.Tx, blockNum uint64) (bool, error) {
+	cc, err := api.chainConfig(tx)
+	if err != nil {
+		return false, err
+	}
+	optimism := cc.Optimism
+	return optimism != nil && optimism.BedrockBlock != nil && !optimism.IsBedrock(blockNum), nil
+}
+
+// relayToHistoricalBackend calls method of the historical RPC. Its errors are returned as they are,
+// so that clients get the same error codes and messages as from the legacy node.
+func (api *BaseAPI) relayToHistoricalBackend(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if api.historicalRPCService == nil {
+		return rpc.ErrNoHistoricalFallback
+	}
+	return api.historicalRPCService.CallContext(ctx, result, method, args...)
+}
+
+// relayStreamToHistoricalBackend is relayToHistoricalBackend for streaming methods, it writes the
+// result of the historical RPC to stream verbatim.
+func (api *BaseAPI) relayStreamToHistoricalBackend(ctx context.Context, stream *jsoniter.Stream, method string, args ...interface{}) error {
+	var result json.RawMessage
+	if err := api.relayToHistoricalBackend(ctx, &result, method, args...); err != nil {
+		stream.WriteNil()
+		return err
+	}
+	stream.WriteRaw(string(result))
+	return nil
+}
+
+// preBedrockBlock returns the number of the block found by blockNum and whether it is pre-Bedrock.
+// A block blockNum doesn't find is not pre-Bedrock.
+func (api *BaseAPI) preBedrockBlock(ctx context.Context, db kv.RoDB, blockNum func(tx kv.Tx) (uint64, bool, error)) (uint64, bool, error) {
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+	number, ok, err := blockNum(tx)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	preBedrock, err := api.isPreBedrock(tx, number)
+	return number, preBedrock, err
+}
+
+func (api *BaseAPI) blockNumberOf(blockNrOrHash rpc.BlockNumberOrHash) func(tx kv.Tx) (uint64, bool, error) {
+	return func(tx kv.Tx) (uint64, bool, error) {
+		number, _, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
+		return number, err == nil, err
+	}
+}
+
+func (api *BaseAPI) txnBlockNumberOf(ctx context.Context, txHash libcommon.Hash) func(tx kv.Tx) (uint64, bool, error) {
+	return func(tx kv.Tx) (uint64, bool, error) {
+		return api.txnLookup(ctx, tx, txHash)
+	}
+}
+
+// relayRawToHistoricalBackend is relayToHistoricalBackend returning the result of the historical
+// RPC verbatim, so that the fields of the legacy node's response Erigon's types don't model reach
+// the client as they are.
+func (api *BaseAPI) relayRawToHistoricalBackend(ctx context.Context, method string, args ...interface{}) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := api.relayToHistoricalBackend(ctx, &result, method, args...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// historicalEthAPI is the eth namespace served over RPC when there is a historical RPC: the
+// methods relaying pre-Bedrock requests return the result of the legacy node verbatim, instead of
+// the result decoded into the types of APIImpl.
+type historicalEthAPI struct {
+	*APIImpl
+}
+
+func (api *historicalEthAPI) GetProof(ctx context.Context, address libcommon.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (interface{}, error) {
+	if blockNum, preBedrock, err := api.preBedrockBlock(ctx, api.db, api.blockNumberOf(blockNrOrHash)); err != nil {
+		return nil, err
+	} else if preBedrock {
+		return api.relayRawToHistoricalBackend(ctx, "eth_getProof", address, storageKeys, hexutil.EncodeUint64(blockNum))
+	}
+	return api.APIImpl.GetProof(ctx, address, storageKeys, blockNrOrHash)
+}
+
+// historicalTraceAPI is historicalEthAPI for the trace namespace.
+type historicalTraceAPI struct {
+	*TraceAPIImpl
+}
+
+func (api *historicalTraceAPI) ReplayTransaction(ctx context.Context, txHash libcommon.Hash, traceTypes []string) (interface{}, error) {
+	if _, preBedrock, err := api.preBedrockBlock(ctx, api.kv, api.txnBlockNumberOf(ctx, txHash)); err != nil {
+		return nil, err
+	} else if preBedrock {
+		return api.relayRawToHistoricalBackend(ctx, "trace_replayTransaction", txHash, traceTypes)
+	}
+	return api.TraceAPIImpl.ReplayTransaction(ctx, txHash, traceTypes)
+}
+
+func (api *historicalTraceAPI) ReplayBlockTransactions(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, traceTypes []string) (interface{}, error) {
+	if blockNum, preBedrock, err := api.preBedrockBlock(ctx, api.kv, api.blockNumberOf(blockNrOrHash)); err != nil {
+		return nil, err
+	} else if preBedrock {
+		return api.relayRawToHistoricalBackend(ctx, "trace_replayBlockTransactions", hexutil.EncodeUint64(blockNum), traceTypes)
+	}
+	return api.TraceAPIImpl.ReplayBlockTransactions(ctx, blockNrOrHash, traceTypes)
+}
+
+func (api *historicalTraceAPI) Call(ctx context.Context, args TraceCallParam, traceTypes []string, blockNrOrHash *rpc.BlockNumberOrHash) (interface{}, error) {
+	at := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		at = *blockNrOrHash
+	}
+	if blockNum, preBedrock, err := api.preBedrockBlock(ctx, api.kv, api.blockNumberOf(at)); err != nil {
+		return nil, err
+	} else if preBedrock {
+		return api.relayRawToHistoricalBackend(ctx, "trace_call", args, traceTypes, hexutil.EncodeUint64(blockNum))
+	}
+	return api.TraceAPIImpl.Call(ctx, args, traceTypes, blockNrOrHash)
+}
+
+func (api *historicalTraceAPI) CallMany(ctx context.Context, calls json.RawMessage, parentNrOrHash *rpc.BlockNumberOrHash) (interface{}, error) {
+	at := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if parentNrOrHash != nil {
+		at = *parentNrOrHash
+	}
+	if blockNum, preBedrock, err := api.preBedrockBlock(ctx, api.kv, api.blockNumberOf(at)); err != nil {
+		return nil, err
+	} else if preBedrock {
+		return api.relayRawToHistoricalBackend(ctx, "trace_callMany", calls, hexutil.EncodeUint64(blockNum))
+	}
+	return api.TraceAPIImpl.CallMany(ctx, calls, parentNrOrHash)
+}
+
+func (api *historicalTraceAPI) Transaction(ctx context.Context, txHash libcommon.Hash) (interface{}, error) {
+	if _, preBedrock, err := api.preBedrockBlock(ctx, api.kv, api.txnBlockNumberOf(ctx, txHash)); err != nil {
+		return nil, err
+	} else if preBedrock {
+		return api.relayRawToHistoricalBackend(ctx, "trace_transaction", txHash)
+	}
+	return api.TraceAPIImpl.Transaction(ctx, txHash)
+}
+
+func (api *historicalTraceAPI) Get(ctx context.Context, txHash libcommon.Hash, indicies []hexutil.Uint64) (interface{}, error) {
+	if _, preBedrock, err := api.preBedrockBlock(ctx, api.kv, api.txnBlockNumberOf(ctx, txHash)); err != nil {
+		return nil, err
+	} else if preBedrock {
+		return api.relayRawToHistoricalBackend(ctx, "trace_get", txHash, indicies)
+	}
+	return api.TraceAPIImpl.Get(ctx, txHash, indicies)
+}
+
+func (api *historicalTraceAPI) Block(ctx context.Context, blockNr rpc.BlockNumber) (interface{}, error) {
+	blockNum := func(tx kv.Tx) (uint64, bool, error) {
+		number, ok, err := api.blockNumberOf(rpc.BlockNumberOrHashWithNumber(blockNr))(tx)
+		// The genesis block has no traces, see TraceAPIImpl.Block
+		return number, ok && number > 0, err
+	}
+	if number, preBedrock, err := api.preBedrockBlock(ctx, api.kv, blockNum); err != nil {
+		return nil, err
+	} else if preBedrock {
+		return api.relayRawToHistoricalBackend(ctx, "trace_block", hexutil.EncodeUint64(number))
+	}
+	return api.TraceAPIImpl.Block(ctx, blockNr)
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/chain"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+)
+
+// legacyEthService stands in for the eth namespace of the legacy node.
+type legacyEthService struct{}
+
+func (s *legacyEthService) Call(_ context.Context, _ ethapi.CallArgs, blockNr string, _ *ethapi.StateOverrides) (hexutil.Bytes, error) {
+	return hexutil.Bytes("legacy call at " + blockNr), nil
+}
+
+func (s *legacyEthService) EstimateGas(_ context.Context, _ *ethapi.CallArgs, _ string) (hexutil.Uint64, error) {
+	return 42, nil
+}
+
+func (s *legacyEthService) ChainId(_ context.Context) (hexutil.Uint64, error) {
+	return 288, nil
+}
+
+// legacyTraceService stands in for the trace namespace of the legacy node, whose traces carry a
+// field Erigon's traces don't have.
+type legacyTraceService struct{}
+
+func (s *legacyTraceService) Block(_ context.Context, blockNr string) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{{"type": "call", "legacyBlock": blockNr}}, nil
+}
+
+func TestHistoricalRPC(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	config := *params.TestChainConfig
+	config.ChainID = big.NewInt(1_000_904)
+	config.Optimism = &chain.OptimismConfig{BedrockBlock: big.NewInt(3)}
+	params.SetOptimismDefaults(&config)
+	gspec := &core.Genesis{
+		Config:   &config,
+		Alloc:    core.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(params.Ether)}},
+		GasLimit: 10_000_000,
+	}
+	m := stages.MockWithGenesis(t, gspec, key, false)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 4, func(int, *core.BlockGen) {}, false /* intermediateHashes */)
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(chain))
+
+	srv := rpc.NewServer(50, false, true)
+	require.NoError(t, srv.RegisterName("eth", &legacyEthService{}))
+	require.NoError(t, srv.RegisterName("trace", &legacyTraceService{}))
+	httpSrv := httptest.NewServer(srv)
+	defer httpSrv.Close()
+	historicalRPCService, err := rpc.DialHTTP(httpSrv.URL)
+	require.NoError(t, err)
+	defer historicalRPCService.Close()
+
+	newBase := func(historicalRPCService *rpc.Client) *BaseAPI {
+		stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+		br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+		return NewRollupBaseApi(nil, stateCache, br, m.HistoryV3Components(), false, rpccfg.DefaultEvmCallTimeout, m.Engine, RollupOptions{HistoricalRPCService: historicalRPCService})
+	}
+	newAPI := func(historicalRPCService *rpc.Client) *APIImpl {
+		return NewEthAPI(newBase(historicalRPCService), m.DB, nil, nil, nil, 5000000, 100_000)
+	}
+	api := newAPI(historicalRPCService)
+	ctx := context.Background()
+	to := libcommon.HexToAddress("0x0d3ab14bbad3d99f4203bd7a11acb94882050e7e")
+	args := ethapi.CallArgs{To: &to}
+	at := func(blockNum uint64) rpc.BlockNumberOrHash {
+		return rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum))
+	}
+
+	// Pre-Bedrock blocks are served by the legacy node
+	result, err := api.Call(ctx, args, at(2), nil)
+	require.NoError(t, err)
+	require.Equal(t, "legacy call at 0x2", string(result))
+	blockNrOrHash := at(1)
+	gas, err := api.EstimateGas(ctx, &args, &blockNrOrHash, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(42), gas)
+
+	// Bedrock blocks are executed locally
+	result, err = api.Call(ctx, args, at(3), nil)
+	require.NoError(t, err)
+	require.Empty(t, result)
+
+	_, err = newAPI(nil).Call(ctx, args, at(2), nil)
+	require.ErrorIs(t, err, rpc.ErrNoHistoricalFallback)
+
+	// Results of the legacy node are decoded into Erigon's types, but relayed to the RPC clients
+	// verbatim, fields Erigon doesn't model included
+	traceAPI := NewTraceAPI(newBase(historicalRPCService), m.DB, &httpcfg.HttpCfg{})
+	traces, err := traceAPI.Block(ctx, rpc.BlockNumber(2))
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	require.Equal(t, "call", traces[0].Type)
+	relayed, err := (&historicalTraceAPI{traceAPI}).Block(ctx, rpc.BlockNumber(2))
+	require.NoError(t, err)
+	encoded, err := json.Marshal(relayed)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"type":"call","legacyBlock":"0x2"}]`, string(encoded))
+	relayed, err = (&historicalTraceAPI{traceAPI}).Block(ctx, rpc.BlockNumber(3))
+	require.NoError(t, err)
+	require.IsType(t, ParityTraces{}, relayed)
+}
+
+func TestDialRollupServices(t *testing.T) {
+	srv := rpc.NewServer(50, false, true)
+	require.NoError(t, srv.RegisterName("eth", &legacyEthService{}))
+	httpSrv := httptest.NewServer(srv)
+	defer httpSrv.Close()
+	ctx := context.Background()
+
+	rollup, err := DialRollupServices(ctx, httpcfg.HttpCfg{})
+	require.NoError(t, err)
+	require.Nil(t, rollup.Sequencer)
+	require.Nil(t, rollup.Historical)
+
+	rollup, err = DialRollupServices(ctx, httpcfg.HttpCfg{RollupSequencerHTTP: httpSrv.URL, RollupHistoricalRPC: httpSrv.URL, RollupHistoricalRPCTimeout: time.Second})
+	require.NoError(t, err)
+	require.NotNil(t, rollup.Sequencer)
+	require.NotNil(t, rollup.Historical)
+	rollup.Close()
+
+	// Unreachable endpoints are reported when dialing, not on the first relayed request
+	down := httptest.NewServer(srv)
+	down.Close()
+	_, err = DialRollupServices(ctx, httpcfg.HttpCfg{RollupSequencerHTTP: httpSrv.URL, RollupHistoricalRPC: down.URL, RollupHistoricalRPCTimeout: time.Second})
+	require.ErrorContains(t, err, "historical RPC")
+}
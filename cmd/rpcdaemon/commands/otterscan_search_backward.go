@@ -123,6 +123,6 @@ func NewBackwardBlockProvider(chunkLocator ChunkLocator, maxBlock uint64) BlockP
 }
 
 func NewCallCursorBackwardBlockProvider(cursor kv.Cursor, addr common.Address, maxBlock uint64) BlockProvider {
-	chunkLocator := newCallChunkLocator(cursor, addr, false)
+	chunkLocator := newCallChunkLocator(cursor, addr.Bytes(), false)
 	return NewBackwardBlockProvider(chunkLocator, maxBlock)
 }
@@ -102,6 +102,6 @@ func NewForwardBlockProvider(chunkLocator ChunkLocator, minBlock uint64) BlockPr
 }
 
 func NewCallCursorForwardBlockProvider(cursor kv.Cursor, addr common.Address, minBlock uint64) BlockProvider {
-	chunkLocator := newCallChunkLocator(cursor, addr, true)
+	chunkLocator := newCallChunkLocator(cursor, addr.Bytes(), true)
 	return NewForwardBlockProvider(chunkLocator, minBlock)
 }
@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ledgerwatch/erigon-lib/chain"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/calltracer"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+)
+
+type InternalTransfer struct {
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	TransactionHash common.Hash    `json:"transactionHash"`
+	*InternalOperation
+}
+
+type InternalTransfers struct {
+	Transfers []*InternalTransfer `json:"transfers"`
+	FirstPage bool                `json:"firstPage"`
+	LastPage  bool                `json:"lastPage"`
+}
+
+// Search the internal value transfers sent or received by a certain address, i.e. the calls,
+// creations and self-destructs of contracts with a non-zero value.
+//
+// It searches back a certain block (excluding) with the call criteria index; the results are
+// sorted descending. As for SearchTransactionsBefore, it may return a little more than pageSize
+// transfers to include all the transfers of the last found block. The blocks executed before
+// the index was introduced are not searched.
+func (api *OtterscanAPIImpl) SearchInternalTransfersBefore(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*InternalTransfers, error) {
+	dbtx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	if api.historyV3(dbtx) {
+		return nil, errors.New("the call criteria are not indexed with the history v3")
+	}
+
+	criteriaCursor, err := dbtx.Cursor(kv.CallCriteriaIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer criteriaCursor.Close()
+
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return nil, err
+	}
+
+	isFirstPage := false
+	if blockNum == 0 {
+		isFirstPage = true
+	} else {
+		// Internal search code considers blockNum [including], so adjust the value
+		blockNum--
+	}
+
+	chunkLocator := newCallChunkLocator(criteriaCursor, calltracer.TransferCriterion(addr), false)
+	blockProvider := NewBackwardBlockProvider(chunkLocator, blockNum)
+
+	transfers := make([]*InternalTransfer, 0, pageSize)
+	hasMore := true
+	for len(transfers) < int(pageSize) && hasMore {
+		var nextBlock uint64
+		nextBlock, hasMore, err = blockProvider()
+		if err != nil {
+			return nil, err
+		}
+		if !hasMore && nextBlock == 0 {
+			break
+		}
+
+		blockTransfers, err := api.traceBlockTransfers(ctx, dbtx, nextBlock, addr, chainConfig)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(blockTransfers) - 1; i >= 0; i-- {
+			transfers = append(transfers, blockTransfers[i])
+		}
+	}
+
+	return &InternalTransfers{transfers, isFirstPage, !hasMore}, nil
+}
+
+// traceBlockTransfers returns the internal value transfers of addr in the block, in order.
+func (api *OtterscanAPIImpl) traceBlockTransfers(ctx context.Context, dbtx kv.Tx, blockNum uint64, addr common.Address, chainConfig *chain.Config) ([]*InternalTransfer, error) {
+	blockHash, err := rawdb.ReadCanonicalHash(dbtx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	block, _, err := api._blockReader.BlockWithSenders(ctx, dbtx, blockHash, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, nil
+	}
+
+	reader, err := rpchelper.CreateHistoryStateReader(dbtx, blockNum, 0, api.historyV3(dbtx), chainConfig.ChainName)
+	if err != nil {
+		return nil, err
+	}
+	stateCache := shards.NewStateCache(32, 0 /* no limit */)
+	cachedReader := state.NewCachedReader(reader, stateCache)
+	noop := state.NewNoopWriter()
+	cachedWriter := state.NewCachedWriter(noop, stateCache)
+
+	ibs := state.New(cachedReader)
+	signer := types.MakeSigner(chainConfig, blockNum)
+
+	getHeader := func(hash common.Hash, number uint64) *types.Header {
+		h, e := api._blockReader.Header(ctx, dbtx, hash, number)
+		if e != nil {
+			log.Error("getHeader error", "number", number, "hash", hash, "err", e)
+		}
+		return h
+	}
+	engine := api.engine()
+
+	header := block.Header()
+	rules := chainConfig.Rules(block.NumberU64(), header.Time)
+	var transfers []*InternalTransfer
+	for idx, tx := range block.Transactions() {
+		ibs.Prepare(tx.Hash(), block.Hash(), idx)
+
+		msg, _ := tx.AsMessage(*signer, header.BaseFee, rules)
+
+		tracer := NewOperationsTracer(ctx)
+		BlockContext := core.NewEVMBlockContext(header, core.GetHashFn(header, getHeader), engine, nil)
+		TxContext := core.NewEVMTxContext(msg)
+
+		vmenv := vm.NewEVM(BlockContext, TxContext, ibs, chainConfig, vm.Config{Debug: true, Tracer: tracer})
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.GetGas()), true /* refunds */, false /* gasBailout */); err != nil {
+			return nil, err
+		}
+		_ = ibs.FinalizeTx(rules, cachedWriter)
+
+		for _, op := range tracer.Results {
+			if op.Value.ToInt().Sign() == 0 || (op.From != addr && op.To != addr) {
+				continue
+			}
+			transfers = append(transfers, &InternalTransfer{hexutil.Uint64(blockNum), tx.Hash(), op})
+		}
+	}
+	return transfers, nil
+}
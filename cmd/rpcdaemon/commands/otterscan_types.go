@@ -4,8 +4,6 @@ import (
 	"bytes"
 	"encoding/binary"
 
-	"github.com/ledgerwatch/erigon-lib/common"
-	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/kv"
 )
 
@@ -26,23 +24,24 @@ type ChunkProvider func() (chunk []byte, ok bool, err error)
 
 type BlockProvider func() (nextBlock uint64, hasMore bool, err error)
 
-// Standard key format for call from/to indexes [address + block]
-func callIndexKey(addr common.Address, block uint64) []byte {
-	key := make([]byte, length.Addr+8)
-	copy(key[:length.Addr], addr.Bytes())
-	binary.BigEndian.PutUint64(key[length.Addr:], block)
-	return key
+// Standard key format for call from/to indexes [address + block], and for the call
+// criteria index [criterion + block]
+func callIndexKey(key []byte, block uint64) []byte {
+	indexKey := make([]byte, len(key)+8)
+	copy(indexKey[:len(key)], key)
+	binary.BigEndian.PutUint64(indexKey[len(key):], block)
+	return indexKey
 }
 
 const MaxBlockNum = ^uint64(0)
 
-// This ChunkLocator searches over a cursor with a key format of [common.Address, block uint64],
-// where block is the first block number contained in the chunk value.
+// This ChunkLocator searches over a cursor with a key format of [common.Address, block uint64]
+// or [criterion, block uint64], where block is the first block number contained in the chunk value.
 //
 // It positions the cursor on the chunk that contains the first block >= minBlock.
-func newCallChunkLocator(cursor kv.Cursor, addr common.Address, navigateForward bool) ChunkLocator {
+func newCallChunkLocator(cursor kv.Cursor, key []byte, navigateForward bool) ChunkLocator {
 	return func(minBlock uint64) (ChunkProvider, bool, error) {
-		searchKey := callIndexKey(addr, minBlock)
+		searchKey := callIndexKey(key, minBlock)
 		k, _, err := cursor.Seek(searchKey)
 		if k == nil {
 			return nil, false, nil
@@ -51,13 +50,13 @@ func newCallChunkLocator(cursor kv.Cursor, addr common.Address, navigateForward
 			return nil, false, err
 		}
 
-		return newCallChunkProvider(cursor, addr, navigateForward), true, nil
+		return newCallChunkProvider(cursor, key, navigateForward), true, nil
 	}
 }
 
 // This ChunkProvider is built by NewForwardChunkLocator and advances the cursor forward until
-// there is no more chunks for the desired addr.
-func newCallChunkProvider(cursor kv.Cursor, addr common.Address, navigateForward bool) ChunkProvider {
+// there is no more chunks for the desired key.
+func newCallChunkProvider(cursor kv.Cursor, key []byte, navigateForward bool) ChunkProvider {
 	first := true
 	var err error
 	// TODO: is this flag really used?
@@ -86,7 +85,7 @@ func newCallChunkProvider(cursor kv.Cursor, addr common.Address, navigateForward
 			eof = true
 			return nil, false, err
 		}
-		if !bytes.HasPrefix(k, addr.Bytes()) {
+		if !bytes.HasPrefix(k, key) {
 			eof = true
 			return nil, false, nil
 		}
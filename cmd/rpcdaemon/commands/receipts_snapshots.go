@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/turbo/services"
+)
+
+// receiptsReader returns the reader of the snapshots of receipts, nil without them.
+func (api *BaseAPI) receiptsReader() services.ReceiptsReader {
+	reader, ok := api._blockReader.(services.ReceiptsReader)
+	if !ok || reader.ReceiptsTo() == 0 {
+		return nil
+	}
+	return reader
+}
+
+// snapshotReceipts returns the receipts of a block from the snapshots of receipts, nil when the
+// block is not in the snapshots.
+func (api *BaseAPI) snapshotReceipts(ctx context.Context, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	reader := api.receiptsReader()
+	if reader == nil {
+		return nil, nil
+	}
+	receipts, err := reader.RawReceipts(ctx, block.NumberU64())
+	if err != nil || receipts == nil {
+		return nil, err
+	}
+	if len(senders) > 0 {
+		block.SendersToTxs(senders)
+	}
+	if err := receipts.DeriveFields(block.Hash(), block.NumberU64(), block.Transactions(), senders); err != nil {
+		return nil, fmt.Errorf("receipts of block %d: %w", block.NumberU64(), err)
+	}
+	return receipts, nil
+}
+
+// blockLogs returns the logs of the block matching the addresses and topics, with their index
+// in the block and transaction. The logs pruned from the db are read from the snapshots of receipts.
+func (api *BaseAPI) blockLogs(ctx context.Context, tx kv.Tx, blockNumber uint64, addrMap map[common.Address]struct{}, topics [][]common.Hash) ([]*types.Log, error) {
+	var logIndex uint
+	var blockLogs []*types.Log
+	if reader := api.receiptsReader(); reader != nil {
+		inDB, err := tx.Has(kv.Receipts, hexutility.EncodeTs(blockNumber))
+		if err != nil {
+			return nil, err
+		}
+		if !inDB {
+			receipts, err := reader.RawReceipts(ctx, blockNumber)
+			if err != nil {
+				return nil, err
+			}
+			for txIndex, receipt := range receipts {
+				for _, log := range receipt.Logs {
+					log.Index = logIndex
+					logIndex++
+				}
+				filtered := receipt.Logs.Filter(addrMap, topics)
+				for _, log := range filtered {
+					log.TxIndex = uint(txIndex)
+				}
+				blockLogs = append(blockLogs, filtered...)
+			}
+			if receipts != nil {
+				return blockLogs, nil
+			}
+		}
+	}
+
+	it, err := tx.Prefix(kv.Log, hexutility.EncodeTs(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+	for it.HasNext() {
+		k, v, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		var logs types.Logs
+		if err := cbor.Unmarshal(&logs, bytes.NewReader(v)); err != nil {
+			return nil, fmt.Errorf("receipt unmarshal failed:  %w", err)
+		}
+		for _, log := range logs {
+			log.Index = logIndex
+			logIndex++
+		}
+		filtered := logs.Filter(addrMap, topics)
+		if len(filtered) == 0 {
+			continue
+		}
+		txIndex := uint(binary.BigEndian.Uint32(k[8:]))
+		for _, log := range filtered {
+			log.TxIndex = txIndex
+		}
+		blockLogs = append(blockLogs, filtered...)
+	}
+	return blockLogs, nil
+}
+
+// maxPrunedLogsBlocks is the maximum number of pruned blocks whose headers are scanned by a logs
+// query, whatever its maximum block range.
+var maxPrunedLogsBlocks uint64 = 10_000
+
+// applyPrunedFilters adds the blocks from begin to end whose log indices are pruned to out, when
+// their logs bloom matches the filter, so that their logs are read from the snapshots of receipts.
+// The headers of the pruned blocks are read one by one, at most maxPrunedLogsBlocks of them.
+func (api *BaseAPI) applyPrunedFilters(ctx context.Context, out *roaring.Bitmap, tx kv.Tx, begin, end uint64, crit filters.FilterCriteria) error {
+	if api.receiptsReader() == nil {
+		return nil
+	}
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return err
+	}
+	if !pm.Receipts.Enabled() {
+		return nil
+	}
+	progress, err := stages.GetStagePruneProgress(tx, stages.LogIndex)
+	if err != nil {
+		return err
+	}
+	pruneTo := pm.Receipts.PruneTo(progress)
+	if begin >= pruneTo {
+		return nil
+	}
+	to := cmp.Min(end, pruneTo-1)
+	if err := api.checkLogsRange(begin, to); err != nil {
+		return err
+	}
+	if to-begin >= maxPrunedLogsBlocks {
+		return &LogsLimitError{
+			Message: fmt.Sprintf("pruned block range %d-%d exceeds the limit of %d blocks", begin, to, maxPrunedLogsBlocks),
+			Cursor:  LogsCursor{BlockNumber: hexutil.Uint64(begin + maxPrunedLogsBlocks)},
+		}
+	}
+	for blockNumber := begin; blockNumber <= to; blockNumber++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNumber)
+		if err != nil {
+			return err
+		}
+		if header != nil && bloomFilter(header.Bloom, crit.Addresses, crit.Topics) {
+			out.Add(uint32(blockNumber))
+		}
+	}
+	return nil
+}
+
+// bloomFilter returns false when the logs of a bloom don't match the addresses and topics.
+func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if types.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, sub := range topics {
+		included := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if types.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
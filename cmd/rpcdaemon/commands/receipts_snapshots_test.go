@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+)
+
+func TestReceiptsSnapshots(t *testing.T) {
+	require := require.New(t)
+	if ethconfig.EnableHistoryV3InTest {
+		t.Skip("the receipts are not retired with the history v3")
+	}
+
+	// Blocks 1 and 500 create contracts emitting two logs, in a chain beyond a receipts segment
+	signer := types.LatestSignerForChainID(nil)
+	// PUSH1 0 PUSH1 0 LOG0 PUSH1 0 PUSH1 0 LOG0
+	code := common.FromHex("0x60006000a060006000a0")
+	var txHashes []libcommon.Hash
+	m := mockWithGenerator(t, 1_010, func(i int, block *core.BlockGen) {
+		if i != 0 && i != 499 {
+			return
+		}
+		tx, _ := types.SignTx(types.NewContractCreation(block.TxNonce(testAddr), uint256.NewInt(0), 100_000, nil, code), *signer, testKey)
+		block.AddTx(tx)
+		txHashes = append(txHashes, tx.Hash())
+	})
+	ctx, dirs := m.Ctx, datadir.New(t.TempDir())
+	snapshots := snapshotsync.NewRoSnapshots(ethconfig.Snapshot{Enabled: true, Receipts: true}, t.TempDir())
+	defer snapshots.Close()
+	br := snapshotsync.NewBlockReaderWithSnapshots(snapshots)
+	api := NewEthAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, m.HistoryV3Components(), false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000)
+
+	contracts := common.Addresses{crypto.CreateAddress(testAddr, 0), crypto.CreateAddress(testAddr, 1)}
+	crit := filters.FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(1_010), Addresses: contracts}
+	expectedLogs, err := api.GetLogs(ctx, crit)
+	require.NoError(err)
+	require.Len(expectedLogs, 4)
+	var expectedReceipts []map[string]interface{}
+	for _, txHash := range txHashes {
+		receipt, err := api.GetTransactionReceipt(ctx, txHash)
+		require.NoError(err)
+		require.NotNil(receipt)
+		expectedReceipts = append(expectedReceipts, receipt)
+	}
+
+	// The blocks and their receipts are retired, then the receipts are pruned from the db
+	retire := snapshotsync.NewBlockRetire(1, t.TempDir(), snapshots, m.DB, nil, nil)
+	err = retire.RetireBlocks(ctx, 0, 1_000, log.LvlDebug)
+	require.NoError(err)
+	require.Equal(uint64(1_000), snapshots.ReceiptsTo())
+
+	pm := prune.DefaultMode
+	pm.Receipts = prune.Distance(5)
+	err = m.DB.Update(ctx, func(tx kv.RwTx) error {
+		if err := prune.Override(tx, pm); err != nil {
+			return err
+		}
+		// The log indices are pruned first, from the logs in the db. The last chunks of the
+		// addresses are kept by the prune, they are dropped to find the blocks by their blooms.
+		logIndexCfg := stagedsync.StageLogIndexCfg(m.DB, pm, t.TempDir())
+		if err := stagedsync.PruneLogIndex(&stagedsync.PruneState{ID: stages.LogIndex, ForwardProgress: 1_010}, tx, logIndexCfg, ctx); err != nil {
+			return err
+		}
+		if err := tx.ClearBucket(kv.LogAddressIndex); err != nil {
+			return err
+		}
+		if err := tx.ClearBucket(kv.LogTopicIndex); err != nil {
+			return err
+		}
+		execCfg := stagedsync.StageExecuteBlocksCfg(m.DB, pm, 0, nil, m.ChainConfig, m.Engine, nil, nil, nil, false, false, false, dirs, br, nil, nil, ethconfig.Defaults.Sync, nil)
+		return stagedsync.PruneExecutionStage(&stagedsync.PruneState{ID: stages.Execution, ForwardProgress: 1_010}, tx, execCfg, ctx, false)
+	})
+	require.NoError(err)
+	err = m.DB.View(ctx, func(tx kv.Tx) error {
+		// The receipts beyond the prune distance are pruned down to the end of their snapshots
+		for blockNum, inDB := range map[uint64]bool{1: false, 999: false, 1_000: true} {
+			has, err := tx.Has(kv.Receipts, hexutility.EncodeTs(blockNum))
+			require.NoError(err)
+			require.Equal(inDB, has, blockNum)
+		}
+		return nil
+	})
+	require.NoError(err)
+
+	// The rpc reads them back from the snapshots
+	logs, err := api.GetLogs(ctx, crit)
+	require.NoError(err)
+	requireSameJSON(t, expectedLogs, logs)
+	for i, txHash := range txHashes {
+		receipt, err := api.GetTransactionReceipt(ctx, txHash)
+		require.NoError(err)
+		requireSameJSON(t, expectedReceipts[i], receipt)
+	}
+
+	// The pruned blocks scanned at once are limited, also without a maximum block range
+	defer func(limit uint64) { maxPrunedLogsBlocks = limit }(maxPrunedLogsBlocks)
+	maxPrunedLogsBlocks = 600
+	_, err = api.GetLogs(ctx, crit)
+	var limitErr *LogsLimitError
+	require.ErrorAs(err, &limitErr)
+	require.Equal(LogsCursor{BlockNumber: 600}, limitErr.Cursor)
+
+	erigonAPI := NewErigonAPI(api.BaseAPI, m.DB, nil)
+	page, err := erigonAPI.GetLogsPaginated(ctx, crit, nil, nil)
+	require.NoError(err)
+	require.Len(page.Logs, 4)
+	require.Equal(&LogsCursor{BlockNumber: 600}, page.Next)
+	page, err = erigonAPI.GetLogsPaginated(ctx, crit, page.Next, nil)
+	require.NoError(err)
+	require.Empty(page.Logs)
+	require.Nil(page.Next)
+}
+
+// requireSameJSON requires the rpc results to be the same once encoded, the empty and nil slices
+// decoded from the snapshots and the db are not distinguished.
+func requireSameJSON(t *testing.T, expected, actual interface{}) {
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(actual)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedJSON), string(actualJSON))
+}
@@ -21,11 +21,15 @@ import (
 )
 
 // SendRawTransaction implements eth_sendRawTransaction. Creates new message call transaction or a contract creation for previously-signed transactions.
+// On replicas of a rollup the transactions are forwarded to the sequencer, which is the only node building blocks.
 func (api *APIImpl) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
 	txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(encodedTx), uint64(len(encodedTx))))
 	if err != nil {
 		return common.Hash{}, err
 	}
+	if txn.Type() == types.DepositTxType {
+		return common.Hash{}, errors.New("deposit transactions are not allowed over RPC")
+	}
 
 	// If the transaction fee cap is already specified, ensure the
 	// fee of the given transaction is _reasonable_.
@@ -36,6 +40,15 @@ func (api *APIImpl) SendRawTransaction(ctx context.Context, encodedTx hexutil.By
 		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
 	}
 	hash := txn.Hash()
+	if api.seqRPCService != nil {
+		if err := api.seqRPCService.CallContext(ctx, nil, "eth_sendRawTransaction", encodedTx); err != nil {
+			return common.Hash{}, err
+		}
+		if !api.keepLocalTxs {
+			log.Info("Forwarded transaction to the sequencer", "hash", hash.Hex())
+			return hash, nil
+		}
+	}
 	res, err := api.txPool.Add(ctx, &txPoolProto.AddRequest{RlpTxs: [][]byte{encodedTx}})
 	if err != nil {
 		return common.Hash{}, err
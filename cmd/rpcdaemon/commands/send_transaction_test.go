@@ -2,8 +2,10 @@ package commands_test
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"math/big"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/holiman/uint256"
@@ -15,12 +17,14 @@ import (
 
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/commands"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/common/u256"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
@@ -71,7 +75,7 @@ func TestSendRawTransaction(t *testing.T) {
 
 	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, m)
 	txPool := txpool.NewTxpoolClient(conn)
-	ff := rpchelper.New(ctx, nil, txPool, txpool.NewMiningClient(conn), func() {})
+	ff := rpchelper.New(ctx, nil, txPool, txpool.NewMiningClient(conn), func() {}, rpchelper.DefaultFiltersConfig)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
 	api := commands.NewEthAPI(commands.NewBaseApi(ff, stateCache, br, nil, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, txPool, nil, 5000000, 100_000)
@@ -101,6 +105,62 @@ func TestSendRawTransaction(t *testing.T) {
 	//require.Equal(eth.ToProto[m.MultiClient.Protocol()][eth.NewPooledTransactionHashesMsg], sent.Id)
 }
 
+// sequencerService records the raw transactions forwarded to it.
+type sequencerService struct {
+	received []hexutil.Bytes
+}
+
+func (s *sequencerService) SendRawTransaction(_ context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+	s.received = append(s.received, encodedTx)
+	return common.Hash{}, nil
+}
+
+func TestSendRawTransactionForwarded(t *testing.T) {
+	m, require := stages.Mock(t), require.New(t)
+
+	sequencer := &sequencerService{}
+	srv := rpc.NewServer(50, false, true)
+	require.NoError(srv.RegisterName("eth", sequencer))
+	httpSrv := httptest.NewServer(srv)
+	defer httpSrv.Close()
+	seqRPCService, err := rpc.DialHTTP(httpSrv.URL)
+	require.NoError(err)
+	defer seqRPCService.Close()
+
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	// Without a txpool client transactions can only go to the sequencer
+	api := commands.NewRollupEthAPI(commands.NewBaseApi(nil, stateCache, br, nil, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000, commands.EthRollupOptions{SeqRPCService: seqRPCService})
+
+	txn, err := types.SignTx(types.NewTransaction(0, common.Address{1}, uint256.NewInt(1234), params.TxGas, uint256.NewInt(10*params.GWei), nil), *types.LatestSignerForChainID(m.ChainConfig.ChainID), m.Key)
+	require.NoError(err)
+	buf := bytes.NewBuffer(nil)
+	require.NoError(txn.MarshalBinary(buf))
+
+	hash, err := api.SendRawTransaction(context.Background(), buf.Bytes())
+	require.NoError(err)
+	require.Equal(txn.Hash(), hash)
+	require.Len(sequencer.received, 1)
+	require.Equal(buf.Bytes(), []byte(sequencer.received[0]))
+
+	// Deposits are only derived from L1, they are never accepted over RPC
+	from := common.Address{2}
+	deposit := &types.DepositTransaction{
+		SourceHash: &common.Hash{},
+		Nonce:      types.DepositsNonce,
+		From:       &from,
+		To:         &common.Address{1},
+		Mint:       uint256.NewInt(0),
+		Value:      uint256.NewInt(0),
+		GasLimit:   params.TxGas,
+	}
+	buf.Reset()
+	require.NoError(deposit.MarshalBinary(buf))
+	_, err = api.SendRawTransaction(context.Background(), buf.Bytes())
+	require.Error(err)
+	require.Len(sequencer.received, 1)
+}
+
 func transaction(nonce uint64, gaslimit uint64, key *ecdsa.PrivateKey) types.Transaction {
 	return pricedTransaction(nonce, gaslimit, u256.Num1, key)
 }
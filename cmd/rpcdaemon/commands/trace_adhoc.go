@@ -729,6 +729,13 @@ func (api *TraceAPIImpl) ReplayTransaction(ctx context.Context, txHash libcommon
 		}
 		blockNum = *blockNumPtr
 	}
+	if preBedrock, err := api.isPreBedrock(tx, blockNum); err != nil {
+		return nil, err
+	} else if preBedrock {
+		var result *TraceCallResult
+		err = api.relayToHistoricalBackend(ctx, &result, "trace_replayTransaction", txHash, traceTypes)
+		return result, err
+	}
 	block, err := api.blockByNumberWithSenders(tx, blockNum)
 	if err != nil {
 		return nil, err
@@ -807,6 +814,13 @@ func (api *TraceAPIImpl) ReplayBlockTransactions(ctx context.Context, blockNrOrH
 	if err != nil {
 		return nil, err
 	}
+	if preBedrock, err := api.isPreBedrock(tx, blockNumber); err != nil {
+		return nil, err
+	} else if preBedrock {
+		var result []*TraceCallResult
+		err = api.relayToHistoricalBackend(ctx, &result, "trace_replayBlockTransactions", hexutil.EncodeUint64(blockNumber), traceTypes)
+		return result, err
+	}
 
 	parentNr := blockNumber
 	if parentNr > 0 {
@@ -886,6 +900,13 @@ func (api *TraceAPIImpl) Call(ctx context.Context, args TraceCallParam, traceTyp
 	if err != nil {
 		return nil, err
 	}
+	if preBedrock, err := api.isPreBedrock(tx, blockNumber); err != nil {
+		return nil, err
+	} else if preBedrock {
+		var result *TraceCallResult
+		err = api.relayToHistoricalBackend(ctx, &result, "trace_call", args, traceTypes, hexutil.EncodeUint64(blockNumber))
+		return result, err
+	}
 
 	stateReader, err := rpchelper.CreateStateReader(ctx, tx, *blockNrOrHash, 0, api.filters, api.stateCache, api.historyV3(tx), chainConfig.ChainName)
 	if err != nil {
@@ -1054,6 +1075,13 @@ func (api *TraceAPIImpl) CallMany(ctx context.Context, calls json.RawMessage, pa
 	if err != nil {
 		return nil, err
 	}
+	if preBedrock, err := api.isPreBedrock(dbtx, blockNumber); err != nil {
+		return nil, err
+	} else if preBedrock {
+		var result []*TraceCallResult
+		err = api.relayToHistoricalBackend(ctx, &result, "trace_callMany", calls, hexutil.EncodeUint64(blockNumber))
+		return result, err
+	}
 
 	// TODO: can read here only parent header
 	parentBlock, err := api.blockWithSenders(dbtx, hash, blockNumber)
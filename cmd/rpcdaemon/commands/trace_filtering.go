@@ -60,6 +60,13 @@ func (api *TraceAPIImpl) Transaction(ctx context.Context, txHash common.Hash) (P
 		}
 		blockNumber = *blockNumPtr
 	}
+	if preBedrock, err := api.isPreBedrock(tx, blockNumber); err != nil {
+		return nil, err
+	} else if preBedrock {
+		var result ParityTraces
+		err = api.relayToHistoricalBackend(ctx, &result, "trace_transaction", txHash)
+		return result, err
+	}
 	block, err := api.blockByNumberWithSenders(tx, blockNumber)
 	if err != nil {
 		return nil, err
@@ -153,6 +160,13 @@ func (api *TraceAPIImpl) Block(ctx context.Context, blockNr rpc.BlockNumber) (Pa
 	if blockNum == 0 {
 		return []ParityTrace{}, nil
 	}
+	if preBedrock, err := api.isPreBedrock(tx, blockNum); err != nil {
+		return nil, err
+	} else if preBedrock {
+		var result ParityTraces
+		err = api.relayToHistoricalBackend(ctx, &result, "trace_block", hexutil.EncodeUint64(blockNum))
+		return result, err
+	}
 	bn := hexutil.Uint64(blockNum)
 
 	// Extract transactions from block
@@ -363,15 +377,36 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 	if fromBlock > toBlock {
 		return fmt.Errorf("invalid parameters: fromBlock cannot be greater than toBlock")
 	}
+	criteria, err := newTraceFilterCriteria(req)
+	if err != nil {
+		return fmt.Errorf("invalid parameters: %w", err)
+	}
+	if preBedrock, err := api.isPreBedrock(dbtx, toBlock); err != nil {
+		return err
+	} else if preBedrock {
+		return api.relayStreamToHistoricalBackend(ctx, stream, "trace_filter", req)
+	}
+	if preBedrock, err := api.isPreBedrock(dbtx, fromBlock); err != nil {
+		return err
+	} else if preBedrock {
+		return fmt.Errorf("invalid parameters: blocks %d-%d span the Bedrock fork, pre-Bedrock blocks are filtered separately", fromBlock, toBlock)
+	}
 
 	if api.historyV3(dbtx) {
-		return api.filterV3(ctx, dbtx.(kv.TemporalTx), fromBlock, toBlock, req, stream)
+		return api.filterV3(ctx, dbtx.(kv.TemporalTx), fromBlock, toBlock, req, criteria, stream)
 	}
 	toBlock++ //+1 because internally Erigon using semantic [from, to), but some RPC have different semantic
 	fromAddresses, toAddresses, allBlocks, err := traceFilterBitmaps(dbtx, req, fromBlock, toBlock)
 	if err != nil {
 		return err
 	}
+	if criteria.active() {
+		criteriaBlocks, err := criteria.blocks(dbtx, fromBlock, toBlock)
+		if err != nil {
+			return err
+		}
+		allBlocks.And(criteriaBlocks)
+	}
 
 	chainConfig, err := api.chainConfig(dbtx)
 	if err != nil {
@@ -461,7 +496,7 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 			txHash := txs[i].Hash()
 			// Check if transaction concerns any of the addresses we wanted
 			for _, pt := range trace.Trace {
-				if includeAll || filter_trace(pt, fromAddresses, toAddresses) {
+				if (includeAll || filter_trace(pt, fromAddresses, toAddresses)) && criteria.match(pt) {
 					nSeen++
 					pt.BlockHash = &blockHash
 					pt.BlockNumber = &blockNumber
@@ -499,7 +534,7 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 		}
 
 		minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, block.Header(), block.Uncles())
-		if _, ok := toAddresses[block.Coinbase()]; ok || includeAll {
+		if _, ok := toAddresses[block.Coinbase()]; (ok || includeAll) && !criteria.active() {
 			nSeen++
 			var tr ParityTrace
 			var rewardAction = &RewardTraceAction{}
@@ -536,7 +571,7 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 			}
 		}
 		for i, uncle := range block.Uncles() {
-			if _, ok := toAddresses[uncle.Coinbase]; ok || includeAll {
+			if _, ok := toAddresses[uncle.Coinbase]; (ok || includeAll) && !criteria.active() {
 				if i < len(uncleRewards) {
 					nSeen++
 					var tr ParityTrace
@@ -580,7 +615,9 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 	return stream.Flush()
 }
 
-func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromBlock, toBlock uint64, req TraceFilterRequest, stream *jsoniter.Stream) error {
+// filterV3 implements trace_filter with the history v3, which has no call criteria index,
+// so the criteria are only matched against the traces.
+func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromBlock, toBlock uint64, req TraceFilterRequest, criteria *traceFilterCriteria, stream *jsoniter.Stream) error {
 	var fromTxNum, toTxNum uint64
 	var err error
 	if fromBlock > 0 {
@@ -690,7 +727,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 			}
 			// Block reward section, handle specially
 			minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, lastHeader, body.Uncles)
-			if _, ok := toAddresses[lastHeader.Coinbase]; ok || includeAll {
+			if _, ok := toAddresses[lastHeader.Coinbase]; (ok || includeAll) && !criteria.active() {
 				nSeen++
 				var tr ParityTrace
 				var rewardAction = &RewardTraceAction{}
@@ -727,7 +764,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 				}
 			}
 			for i, uncle := range body.Uncles {
-				if _, ok := toAddresses[uncle.Coinbase]; ok || includeAll {
+				if _, ok := toAddresses[uncle.Coinbase]; (ok || includeAll) && !criteria.active() {
 					if i < len(uncleRewards) {
 						nSeen++
 						var tr ParityTrace
@@ -859,7 +896,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 			continue
 		}
 		for _, pt := range traceResult.Trace {
-			if includeAll || filter_trace(pt, fromAddresses, toAddresses) {
+			if (includeAll || filter_trace(pt, fromAddresses, toAddresses)) && criteria.match(pt) {
 				nSeen++
 				pt.BlockHash = &lastBlockHash
 				pt.BlockNumber = &blockNum
@@ -962,6 +999,10 @@ type TraceFilterRequest struct {
 	Mode        TraceFilterMode   `json:"mode"`
 	After       *uint64           `json:"after"`
 	Count       *uint64           `json:"count"`
+	// Criteria of the calls, indexed by the CallTraces stage
+	Selectors      []hexutil.Bytes `json:"selectors"`      // Method selectors, the first 4 bytes of the call inputs
+	CallTypes      []string        `json:"callTypes"`      // call, callcode, delegatecall, staticcall, create or selfdestruct
+	ValueTransfers bool            `json:"valueTransfers"` // Only the internal calls transferring value
 }
 
 type TraceFilterMode string
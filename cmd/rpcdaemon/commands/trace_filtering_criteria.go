@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/bitmapdb"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/calltracer"
+	"github.com/ledgerwatch/erigon/ethdb"
+)
+
+// traceFilterCallTypes are the call types accepted by trace_filter, named as in the traces.
+var traceFilterCallTypes = map[string]vm.OpCode{
+	CALL:           vm.CALL,
+	CALLCODE:       vm.CALLCODE,
+	DELEGATECALL:   vm.DELEGATECALL,
+	STATICCALL:     vm.STATICCALL,
+	"create":       vm.CREATE,
+	"selfdestruct": vm.SELFDESTRUCT,
+}
+
+// traceFilterCriteria are the criteria of trace_filter other than the addresses. A trace
+// matches when it matches each of the given criteria, that is one of the selectors, one
+// of the call types and the value transfers.
+type traceFilterCriteria struct {
+	selectors      map[[calltracer.SelectorLength]byte]struct{}
+	callTypes      map[vm.OpCode]struct{}
+	valueTransfers bool
+}
+
+func newTraceFilterCriteria(req TraceFilterRequest) (*traceFilterCriteria, error) {
+	c := &traceFilterCriteria{
+		selectors:      make(map[[calltracer.SelectorLength]byte]struct{}, len(req.Selectors)),
+		callTypes:      make(map[vm.OpCode]struct{}, len(req.CallTypes)),
+		valueTransfers: req.ValueTransfers,
+	}
+	for _, selector := range req.Selectors {
+		if len(selector) != calltracer.SelectorLength {
+			return nil, fmt.Errorf("invalid selector %s: must be %d bytes", selector, calltracer.SelectorLength)
+		}
+		var s [calltracer.SelectorLength]byte
+		copy(s[:], selector)
+		c.selectors[s] = struct{}{}
+	}
+	for _, name := range req.CallTypes {
+		op, ok := traceFilterCallTypes[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("invalid call type %q", name)
+		}
+		c.callTypes[op] = struct{}{}
+	}
+	return c, nil
+}
+
+func (c *traceFilterCriteria) active() bool {
+	return len(c.selectors) > 0 || len(c.callTypes) > 0 || c.valueTransfers
+}
+
+// blocks returns the blocks between from (included) and to (excluded) which may have
+// traces matching the criteria, according to the call criteria index. The blocks
+// executed before the index was introduced are all returned, to be scanned.
+func (c *traceFilterCriteria) blocks(tx kv.Tx, from, to uint64) (*roaring64.Bitmap, error) {
+	start, err := rawdb.ReadCallCriteriaStart(tx)
+	if err != nil {
+		return nil, err
+	}
+	unindexed := roaring64.New()
+	if from < start {
+		unindexed.AddRange(from, cmp.Min(start, to))
+		from = cmp.Min(start, to)
+	}
+	blocks := roaring64.New()
+	blocks.AddRange(from, to)
+	var groups [][][]byte
+	if len(c.selectors) > 0 {
+		group := make([][]byte, 0, len(c.selectors))
+		for selector := range c.selectors {
+			group = append(group, calltracer.SelectorCriterion(selector[:]))
+		}
+		groups = append(groups, group)
+	}
+	if len(c.callTypes) > 0 {
+		group := make([][]byte, 0, len(c.callTypes))
+		for op := range c.callTypes {
+			group = append(group, calltracer.CallTypeCriterion(op))
+		}
+		groups = append(groups, group)
+	}
+	if c.valueTransfers {
+		groups = append(groups, [][]byte{calltracer.AnyTransferCriterion()})
+	}
+	for _, group := range groups {
+		groupBlocks := roaring64.New()
+		for _, criterion := range group {
+			b, err := bitmapdb.Get64(tx, kv.CallCriteriaIndex, criterion, from, to)
+			if err != nil {
+				if errors.Is(err, ethdb.ErrKeyNotFound) {
+					continue
+				}
+				return nil, err
+			}
+			groupBlocks.Or(b)
+		}
+		blocks.And(groupBlocks)
+	}
+	blocks.Or(unindexed)
+	return blocks, nil
+}
+
+// match returns whether the trace matches the criteria.
+func (c *traceFilterCriteria) match(pt *ParityTrace) bool {
+	if len(c.selectors) > 0 {
+		action, ok := pt.Action.(*CallTraceAction)
+		if !ok || len(action.Input) < calltracer.SelectorLength {
+			return false
+		}
+		var s [calltracer.SelectorLength]byte
+		copy(s[:], action.Input)
+		if _, ok := c.selectors[s]; !ok {
+			return false
+		}
+	}
+	if len(c.callTypes) > 0 {
+		var op vm.OpCode
+		switch action := pt.Action.(type) {
+		case *CallTraceAction:
+			op = traceFilterCallTypes[action.CallType]
+		case *CreateTraceAction:
+			op = vm.CREATE
+		case *SuicideTraceAction:
+			op = vm.SELFDESTRUCT
+		default:
+			return false
+		}
+		if _, ok := c.callTypes[op]; !ok {
+			return false
+		}
+	}
+	if c.valueTransfers {
+		// The top call transfers the value of the transaction, which is not internal
+		if len(pt.TraceAddress) == 0 {
+			return false
+		}
+		switch action := pt.Action.(type) {
+		case *CallTraceAction:
+			if action.CallType != CALL || action.Value.ToInt().Sign() == 0 {
+				return false
+			}
+		case *CreateTraceAction:
+			if action.Value.ToInt().Sign() == 0 {
+				return false
+			}
+		case *SuicideTraceAction:
+			if action.Balance.ToInt().Sign() == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
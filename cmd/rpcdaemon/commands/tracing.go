@@ -8,6 +8,7 @@ import (
 
 	"github.com/holiman/uint256"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/chain"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/log/v3"
 
@@ -70,6 +71,12 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 		}
 		return fmt.Errorf("invalid arguments; block with hash %x not found", hash)
 	}
+	if preBedrock, err := api.isPreBedrock(tx, block.NumberU64()); err != nil {
+		stream.WriteNil()
+		return err
+	} else if preBedrock {
+		return api.relayStreamToHistoricalBackend(ctx, stream, "debug_traceBlockByNumber", hexutil.EncodeUint64(block.NumberU64()), config)
+	}
 
 	chainConfig, err := api.chainConfig(tx)
 	if err != nil {
@@ -84,6 +91,10 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 		return err
 	}
 
+	if config != nil && config.Aggregate != nil && *config.Aggregate {
+		return api.traceBlockAggregate(ctx, block, blockCtx, ibs, config, chainConfig, stream)
+	}
+
 	signer := types.MakeSigner(chainConfig, block.NumberU64())
 	rules := chainConfig.Rules(block.NumberU64(), block.Time())
 	stream.WriteArrayStart()
@@ -112,7 +123,8 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 			GasPrice: msg.GasPrice(),
 		}
 
-		err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
+		tracerCtx := &tracers.Context{BlockHash: block.Hash(), BlockNumber: block.Number(), TxIndex: idx, TxHash: txn.Hash()}
+		err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, tracerCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
 		if err == nil {
 			err = ibs.FinalizeTx(rules, state.NewNoopWriter())
 		}
@@ -138,6 +150,66 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 	return nil
 }
 
+// traceBlockAggregate traces all the transactions of block with the same tracer,
+// and writes its result aggregated over the whole block. Only the profilerTracer
+// aggregates over several transactions, other tracers would report the last one.
+// The timeout of the config applies to the whole block.
+func (api *PrivateDebugAPIImpl) traceBlockAggregate(ctx context.Context, block *types.Block, blockCtx evmtypes.BlockContext, ibs *state.IntraBlockState, config *tracers.TraceConfig, chainConfig *chain.Config, stream *jsoniter.Stream) error {
+	if config.Tracer == nil || *config.Tracer != "profilerTracer" {
+		stream.WriteNil()
+		return fmt.Errorf("aggregating the traces of a block requires the profilerTracer")
+	}
+	tracerCtx := &tracers.Context{BlockHash: block.Hash(), BlockNumber: block.Number()}
+	tracer, _, cancel, err := transactions.AssembleTracer(ctx, config, tracerCtx, stream, api.evmCallTimeout)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+	defer cancel()
+
+	engine := api.engine()
+	signer := types.MakeSigner(chainConfig, block.NumberU64())
+	rules := chainConfig.Rules(block.NumberU64(), block.Time())
+	for idx, txn := range block.Transactions() {
+		select {
+		default:
+		case <-ctx.Done():
+			stream.WriteNil()
+			return ctx.Err()
+		}
+		ibs.Prepare(txn.Hash(), block.Hash(), idx)
+		msg, _ := txn.AsMessage(*signer, block.BaseFee(), rules)
+
+		if msg.FeeCap().IsZero() && engine != nil {
+			syscall := func(contract common.Address, data []byte) ([]byte, error) {
+				return core.SysCallContract(contract, data, *chainConfig, ibs, block.Header(), engine, true /* constCall */)
+			}
+			msg.SetIsFree(engine.IsServiceTransaction(msg.From(), syscall))
+		}
+
+		txCtx := evmtypes.TxContext{
+			TxHash:   txn.Hash(),
+			Origin:   msg.From(),
+			GasPrice: msg.GasPrice(),
+		}
+		if _, err = transactions.ExecuteTraceTx(blockCtx, txCtx, ibs, config, chainConfig, stream, tracer, false, msg); err != nil {
+			return err
+		}
+		if err = ibs.FinalizeTx(rules, state.NewNoopWriter()); err != nil {
+			stream.WriteNil()
+			return err
+		}
+	}
+	r, err := tracer.(tracers.Tracer).GetResult()
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+	stream.Write(r)
+	stream.Flush()
+	return nil
+}
+
 // TraceTransaction implements debug_traceTransaction. Returns Geth style transaction traces.
 func (api *PrivateDebugAPIImpl) TraceTransaction(ctx context.Context, hash common.Hash, config *tracers.TraceConfig, stream *jsoniter.Stream) error {
 	tx, err := api.db.BeginRo(ctx)
@@ -174,6 +246,12 @@ func (api *PrivateDebugAPIImpl) TraceTransaction(ctx context.Context, hash commo
 		}
 		blockNum = *blockNumPtr
 	}
+	if preBedrock, err := api.isPreBedrock(tx, blockNum); err != nil {
+		stream.WriteNil()
+		return err
+	} else if preBedrock {
+		return api.relayStreamToHistoricalBackend(ctx, stream, "debug_traceTransaction", hash, config)
+	}
 	block, err := api.blockByNumberWithSenders(tx, blockNum)
 	if err != nil {
 		stream.WriteNil()
@@ -215,7 +293,8 @@ func (api *PrivateDebugAPIImpl) TraceTransaction(ctx context.Context, hash commo
 		return err
 	}
 	// Trace the transaction and return
-	return transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
+	tracerCtx := &tracers.Context{BlockHash: block.Hash(), BlockNumber: block.Number(), TxIndex: int(txnIndex), TxHash: hash}
+	return transactions.TraceTx(ctx, msg, blockCtx, txCtx, tracerCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
 }
 
 func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *tracers.TraceConfig, stream *jsoniter.Stream) error {
@@ -235,6 +314,11 @@ func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallA
 	if err != nil {
 		return fmt.Errorf("get block number: %v", err)
 	}
+	if preBedrock, err := api.isPreBedrock(dbtx, blockNumber); err != nil {
+		return err
+	} else if preBedrock {
+		return api.relayStreamToHistoricalBackend(ctx, stream, "debug_traceCall", args, hexutil.EncodeUint64(blockNumber), config)
+	}
 
 	stateReader, err := rpchelper.CreateStateReader(ctx, dbtx, blockNrOrHash, 0, api.filters, api.stateCache, api.historyV3(dbtx), chainConfig.ChainName)
 	if err != nil {
@@ -271,7 +355,7 @@ func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallA
 	blockCtx := transactions.NewEVMBlockContext(engine, header, blockNrOrHash.RequireCanonical, dbtx, api._blockReader)
 	txCtx := core.NewEVMTxContext(msg)
 	// Trace the transaction and return
-	return transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
+	return transactions.TraceTx(ctx, msg, blockCtx, txCtx, nil, ibs, config, chainConfig, stream, api.evmCallTimeout)
 }
 
 func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bundle, simulateContext StateContext, config *tracers.TraceConfig, stream *jsoniter.Stream) error {
@@ -281,11 +365,9 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 		evm                *vm.EVM
 		blockCtx           evmtypes.BlockContext
 		txCtx              evmtypes.TxContext
-		overrideBlockHash  map[uint64]common.Hash
 		baseFee            uint256.Int
 	)
 
-	overrideBlockHash = make(map[uint64]common.Hash)
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		stream.WriteNil()
@@ -357,9 +439,6 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 	}
 
 	getHash := func(i uint64) common.Hash {
-		if hash, ok := overrideBlockHash[i]; ok {
-			return hash
-		}
 		hash, err := rawdb.ReadCanonicalHash(tx, i)
 		if err != nil {
 			log.Debug("Can't get block hash by number", "number", i, "only-canonical", true)
@@ -423,7 +502,7 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 	for bundle_index, bundle := range bundles {
 		stream.WriteArrayStart()
 		// first change blockContext
-		blockHeaderOverride(&blockCtx, bundle.BlockOverride, overrideBlockHash)
+		bundle.BlockOverride.Override(&blockCtx)
 		for txn_index, txn := range bundle.Transactions {
 			if txn.Gas == nil || *(txn.Gas) == 0 {
 				txn.Gas = (*hexutil.Uint64)(&api.GasCap)
@@ -436,7 +515,7 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 			txCtx = core.NewEVMTxContext(msg)
 			ibs := evm.IntraBlockState().(*state.IntraBlockState)
 			ibs.Prepare(common.Hash{}, parent.Hash(), txn_index)
-			err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, evm.IntraBlockState(), config, chainConfig, stream, api.evmCallTimeout)
+			err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, nil, evm.IntraBlockState(), config, chainConfig, stream, api.evmCallTimeout)
 
 			if err != nil {
 				stream.WriteNil()
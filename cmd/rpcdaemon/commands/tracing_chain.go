@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common/debug"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// TraceChainResult is the notification of debug_traceChain for a block.
+type TraceChainResult struct {
+	Block  hexutil.Uint64  `json:"block"`
+	Hash   common.Hash     `json:"hash"`
+	Traces json.RawMessage `json:"traces,omitempty"` // As returned by debug_traceBlockByHash
+	Error  string          `json:"error,omitempty"`
+}
+
+// traceChainJob is a block to trace, whose result is sent to the subscriber once
+// the results of the previous blocks are sent.
+type traceChainJob struct {
+	number uint64
+	result chan *TraceChainResult
+}
+
+// TraceChain implements debug_subscribe("traceChain", start, end, config). Notifies the traces
+// of the blocks from start to end included, in order. The blocks are traced in parallel, by at
+// most --rpc.tracechain.concurrency workers for all the subscriptions, and the workers wait when
+// the notifications are too far behind. The subscription ends after the end block or the first
+// failure, a disconnected subscriber resumes by subscribing again from the block following the
+// last one it received.
+func (api *PrivateDebugAPIImpl) TraceChain(ctx context.Context, start, end rpc.BlockNumber, config *tracers.TraceConfig) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	from, to, err := api.traceChainRange(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	// The subscription outlives the request context
+	traceCtx, cancel := context.WithCancel(context.Background())
+	jobs := make(chan traceChainJob)
+	pending := make(chan traceChainJob, 2*api.traceChainWorkers) // Bounds how far the tracing runs ahead of the notifications
+
+	go func() {
+		defer debug.LogPanic()
+		defer close(pending)
+		defer close(jobs)
+		for number := from; number <= to; number++ {
+			job := traceChainJob{number: number, result: make(chan *TraceChainResult, 1)}
+			select {
+			case pending <- job:
+			case <-traceCtx.Done():
+				return
+			}
+			select {
+			case jobs <- job:
+			case <-traceCtx.Done():
+				return
+			}
+		}
+	}()
+	for i := 0; i < api.traceChainWorkers; i++ {
+		go func() {
+			defer debug.LogPanic()
+			for job := range jobs {
+				job.result <- api.traceChainBlock(traceCtx, job.number, config)
+			}
+		}()
+	}
+	go func() {
+		defer debug.LogPanic()
+		defer cancel()
+		for job := range pending {
+			var result *TraceChainResult
+			select {
+			case result = <-job.result:
+			case <-rpcSub.Err():
+				return
+			}
+			if err := notifier.Notify(rpcSub.ID, result); err != nil {
+				log.Warn("error while notifying subscription", "err", err)
+				return
+			}
+			if result.Error != "" {
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// traceChainRange returns the numbers of the start and end blocks of debug_traceChain.
+func (api *PrivateDebugAPIImpl) traceChainRange(ctx context.Context, start, end rpc.BlockNumber) (uint64, uint64, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+	from, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(start), tx, api.filters)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(end), tx, api.filters)
+	if err != nil {
+		return 0, 0, err
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("start block %d is after end block %d", from, to)
+	}
+	return from, to, nil
+}
+
+// traceChainBlock traces the canonical block number as debug_traceBlockByHash does.
+func (api *PrivateDebugAPIImpl) traceChainBlock(ctx context.Context, number uint64, config *tracers.TraceConfig) *TraceChainResult {
+	result := &TraceChainResult{Block: hexutil.Uint64(number)}
+	if err := api.traceChainSem.Acquire(ctx, 1); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer api.traceChainSem.Release(1)
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Hash, err = rawdb.ReadCanonicalHash(tx, number)
+	tx.Rollback()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if result.Hash == (common.Hash{}) {
+		result.Error = fmt.Sprintf("block %d not found", number)
+		return result
+	}
+
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	if err = api.traceBlock(ctx, rpc.BlockNumberOrHashWithHash(result.Hash, true), config, stream); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if err = stream.Flush(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Traces = buf.Bytes()
+	return result
+}
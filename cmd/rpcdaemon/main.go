@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/ledgerwatch/erigon-lib/common"
@@ -30,7 +31,15 @@ func main() {
 
 		// TODO: Replace with correct consensus Engine
 		engine := ethash.NewFaker()
-		apiList := commands.APIList(db, borDb, backend, txPool, mining, ff, stateCache, blockReader, agg, *cfg, engine, nil)
+		rollupServices, err := commands.DialRollupServices(ctx, *cfg)
+		if err != nil {
+			return fmt.Errorf("could not connect to the rollup services: %w", err)
+		}
+		defer rollupServices.Close()
+		apiList, err := commands.APIList(db, borDb, backend, txPool, mining, ff, stateCache, blockReader, agg, *cfg, engine, nil, rollupServices)
+		if err != nil {
+			return err
+		}
 		if err := cli.StartRpcServer(ctx, *cfg, apiList, nil); err != nil {
 			log.Error(err.Error())
 			return nil
@@ -197,6 +197,18 @@ func (back *RemoteBackend) CanonicalHash(ctx context.Context, tx kv.Getter, bloc
 func (back *RemoteBackend) TxnByIdxInBlock(ctx context.Context, tx kv.Getter, blockNum uint64, i int) (types.Transaction, error) {
 	return back.blockReader.TxnByIdxInBlock(ctx, tx, blockNum, i)
 }
+func (back *RemoteBackend) RawReceipts(ctx context.Context, blockHeight uint64) (types.Receipts, error) {
+	if reader, ok := back.blockReader.(services.ReceiptsReader); ok {
+		return reader.RawReceipts(ctx, blockHeight)
+	}
+	return nil, nil
+}
+func (back *RemoteBackend) ReceiptsTo() uint64 {
+	if reader, ok := back.blockReader.(services.ReceiptsReader); ok {
+		return reader.ReceiptsTo()
+	}
+	return 0
+}
 
 func (back *RemoteBackend) EngineNewPayload(ctx context.Context, payload *types2.ExecutionPayload) (res *remote.EnginePayloadStatus, err error) {
 	return back.remoteEthBackend.EngineNewPayload(ctx, payload)
@@ -26,6 +26,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/chain"
@@ -57,6 +58,8 @@ import (
 	"github.com/ledgerwatch/erigon/p2p/netutil"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/params/networkname"
+	"github.com/ledgerwatch/erigon/turbo/proofs"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
 // These are all the command line flags we support.
@@ -258,6 +261,24 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ethconfig.Defaults.RPCTxFeeCap,
 	}
+	ProofsAddressesFlag = cli.StringFlag{
+		Name:  "proofs.addresses",
+		Usage: "Comma separated accounts to retain eth_getProof proofs of at past blocks (default: the L2ToL1MessagePasser on rollup chains)",
+	}
+	ProofsStorageKeysFlag = cli.StringFlag{
+		Name:  "proofs.storagekeys",
+		Usage: "Comma separated storage slots to retain proofs of, for each of --proofs.addresses",
+	}
+	ProofsIntervalFlag = cli.Uint64Flag{
+		Name:  "proofs.interval",
+		Usage: "Retain proofs of every N-th block (0 = disable)",
+		Value: ethconfig.Defaults.Proofs.Interval,
+	}
+	ProofsRetentionFlag = cli.Uint64Flag{
+		Name:  "proofs.retention",
+		Usage: "Number of blocks behind the head to keep retained proofs for (0 = keep forever)",
+		Value: ethconfig.Defaults.Proofs.Retention,
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -342,6 +363,11 @@ var (
 		Usage: "Does limit amount of goroutines to process 1 batch request. Means 1 bach request can't overload server. 1 batch still can have unlimited amount of request",
 		Value: 2,
 	}
+	RpcTraceChainConcurrencyFlag = cli.UintFlag{
+		Name:  "rpc.tracechain.concurrency",
+		Usage: "Maximum number of blocks traced at the same time by all the debug_traceChain subscriptions",
+		Value: 4,
+	}
 	RpcStreamingDisableFlag = cli.BoolFlag{
 		Name:  "rpc.streaming.disable",
 		Usage: "Erigon has enalbed json streaming for some heavy endpoints (like trace_*). It's treadoff: greatly reduce amount of RAM (in some cases from 30GB to 30mb), but it produce invalid json format if error happened in the middle of streaming (because json is not streaming-friendly format)",
@@ -356,6 +382,68 @@ var (
 		Usage: "Maximum number of bytes returned from eth_call or similar invocations",
 		Value: 100_000,
 	}
+	RpcMaxGetProofRewindBlockCount = cli.IntFlag{
+		Name:  "rpc.maxgetproofrewindblockcount.limit",
+		Usage: "Maximum number of blocks eth_getProof rewinds the state trie to build proofs at past blocks",
+		Value: 100_000,
+	}
+	RpcGetLogsMaxBlockRangeFlag = cli.Uint64Flag{
+		Name:  "rpc.getlogs.maxrange",
+		Usage: "Maximum number of blocks queried at once by eth_getLogs and erigon_getLogs (0 = no limit)",
+		Value: 0,
+	}
+	RpcGetLogsMaxResultsFlag = cli.IntFlag{
+		Name:  "rpc.getlogs.maxresults",
+		Usage: "Maximum number of logs returned at once by eth_getLogs and erigon_getLogs (0 = no limit)",
+		Value: 0,
+	}
+	RpcFiltersTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.filters.timeout",
+		Usage: "Filters installed by eth_newFilter, eth_newBlockFilter and eth_newPendingTransactionFilter are uninstalled when not polled for this long (0 = never)",
+		Value: rpchelper.DefaultFiltersConfig.Timeout,
+	}
+	RpcFiltersMaxFlag = cli.IntFlag{
+		Name:  "rpc.filters.max",
+		Usage: "Maximum number of installed polling filters (0 = no limit)",
+		Value: rpchelper.DefaultFiltersConfig.MaxFilters,
+	}
+	RpcFiltersMaxLogsFlag = cli.IntFlag{
+		Name:  "rpc.filters.maxlogs",
+		Usage: "Maximum number of logs a polling filter buffers between polls, older logs are dropped (0 = no limit)",
+		Value: rpchelper.DefaultFiltersConfig.MaxLogs,
+	}
+	RpcFiltersMaxHeadersFlag = cli.IntFlag{
+		Name:  "rpc.filters.maxheaders",
+		Usage: "Maximum number of block hashes a polling filter buffers between polls, older ones are dropped (0 = no limit)",
+		Value: rpchelper.DefaultFiltersConfig.MaxHeaders,
+	}
+	RpcFiltersMaxTxsFlag = cli.IntFlag{
+		Name:  "rpc.filters.maxtxs",
+		Usage: "Maximum number of transaction hashes a polling filter buffers between polls, older ones are dropped (0 = no limit)",
+		Value: rpchelper.DefaultFiltersConfig.MaxTxs,
+	}
+	RollupSequencerHTTPFlag = cli.StringFlag{
+		Name:  "rollup.sequencerhttp",
+		Usage: "HTTP endpoint of the sequencer, raw transactions are forwarded to it instead of the local txpool",
+	}
+	RollupSequencerHTTPTimeoutFlag = cli.DurationFlag{
+		Name:  "rollup.sequencerhttptimeout",
+		Usage: "Timeout of the requests forwarded to the sequencer",
+		Value: 5 * time.Second,
+	}
+	RollupKeepLocalTxsFlag = cli.BoolFlag{
+		Name:  "rollup.keeplocaltxs",
+		Usage: "Also add the transactions forwarded to the sequencer to the local txpool",
+	}
+	RollupHistoricalRPCFlag = cli.StringFlag{
+		Name:  "rollup.historicalrpc",
+		Usage: "RPC endpoint of the legacy node, requests about pre-Bedrock blocks are relayed to it",
+	}
+	RollupHistoricalRPCTimeoutFlag = cli.DurationFlag{
+		Name:  "rollup.historicalrpctimeout",
+		Usage: "Timeout of the requests relayed to the legacy node",
+		Value: 5 * time.Second,
+	}
 	HTTPTraceFlag = cli.BoolFlag{
 		Name:  "http.trace",
 		Usage: "Trace HTTP requests with INFO level",
@@ -369,6 +457,18 @@ var (
 		Name:  "rpc.accessList",
 		Usage: "Specify granular (method-by-method) API allowlist",
 	}
+	RpcLimitsFlag = cli.StringFlag{
+		Name:  "rpc.limits",
+		Usage: "Specify a JSON file of the rate limits, the response size limits and the timeouts of the methods for each client",
+	}
+	RpcLimitsJWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.limits.jwtsecret",
+		Usage: "Path to the hex encoded secret of the JWTs identifying the clients of --rpc.limits, which must differ from the Engine API secret",
+	}
+	RpcLimitsTrustedProxiesFlag = cli.StringFlag{
+		Name:  "rpc.limits.trustedproxies",
+		Usage: "Comma separated list of the IP addresses or CIDR ranges of the proxies trusted to forward the IP address of the clients of --rpc.limits in X-Forwarded-For or X-Real-IP",
+	}
 
 	RpcGasCapFlag = cli.UintFlag{
 		Name:  "rpc.gascap",
@@ -632,6 +732,19 @@ var (
 		Name:  ethconfig.FlagSnapStop,
 		Usage: "Workaround to stop producing new snapshots, if you meet some snapshots-related critical bug. It will stop move historical data from DB to new immutable snapshots. DB will grow and may slightly slow-down - and removing this flag in future will not fix this effect (db size will not greatly reduce).",
 	}
+	SnapPreverifiedFlag = cli.StringFlag{
+		Name:  ethconfig.FlagSnapPreverified,
+		Usage: "Local TOML file, or directory of <chain>.toml and history/<chain>.toml files, of the preverified snapshots of a custom chain (names and info-hashes, as generated by `downloader preverified`)",
+		Value: "",
+	}
+	SnapReceiptsFlag = cli.BoolFlag{
+		Name:  ethconfig.FlagSnapReceipts,
+		Usage: "Retire receipts and logs to snapshots with the blocks, and read them from the snapshots once pruned by --prune=r",
+	}
+	SnapScrubEveryFlag = cli.DurationFlag{
+		Name:  ethconfig.FlagSnapScrubEvery,
+		Usage: "Verify the snapshots this often in background (info-hashes of the preverified segments, segments against their indices), and rebuild the corrupted indices (0 = never)",
+	}
 	TorrentVerbosityFlag = cli.IntFlag{
 		Name:  "torrent.verbosity",
 		Value: 2,
@@ -1138,6 +1251,8 @@ func DataDirForNetwork(datadir string, network string) string {
 		return networkDataDirCheckingLegacy(datadir, "gnosis")
 	case networkname.ChiadoChainName:
 		return networkDataDirCheckingLegacy(datadir, "chiado")
+	case networkname.BobaDevnetChainName:
+		return networkDataDirCheckingLegacy(datadir, "boba-devnet")
 
 	default:
 		return datadir
@@ -1429,6 +1544,30 @@ func setWhitelist(ctx *cli.Context, cfg *ethconfig.Config) {
 	}
 }
 
+func setProofs(ctx *cli.Context, cfg *proofs.Config) {
+	if ctx.IsSet(ProofsAddressesFlag.Name) {
+		cfg.Addresses = nil
+		for _, account := range SplitAndTrim(ctx.String(ProofsAddressesFlag.Name)) {
+			if !libcommon.IsHexAddress(account) {
+				Fatalf("Invalid account in --%s: %s", ProofsAddressesFlag.Name, account)
+			}
+			cfg.Addresses = append(cfg.Addresses, libcommon.HexToAddress(account))
+		}
+	}
+	if ctx.IsSet(ProofsStorageKeysFlag.Name) {
+		cfg.StorageKeys = nil
+		for _, key := range SplitAndTrim(ctx.String(ProofsStorageKeysFlag.Name)) {
+			var hash libcommon.Hash
+			if err := hash.UnmarshalText([]byte(key)); err != nil {
+				Fatalf("Invalid storage slot in --%s: %s", ProofsStorageKeysFlag.Name, key)
+			}
+			cfg.StorageKeys = append(cfg.StorageKeys, hash)
+		}
+	}
+	cfg.Interval = ctx.Uint64(ProofsIntervalFlag.Name)
+	cfg.Retention = ctx.Uint64(ProofsRetentionFlag.Name)
+}
+
 // CheckExclusive verifies that only a single instance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -1479,6 +1618,10 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	cfg.SentinelPort = ctx.Uint64(SentinelPortFlag.Name)
 
 	cfg.Sync.UseSnapshots = ethconfig.UseSnapshotsByChainName(ctx.String(ChainFlag.Name))
+	cfg.Snapshot.Preverified = strings.TrimSpace(ctx.String(SnapPreverifiedFlag.Name))
+	if cfg.Snapshot.Preverified != "" { // custom chain with its own snapshots
+		cfg.Sync.UseSnapshots = true
+	}
 	if ctx.IsSet(SnapshotFlag.Name) { //force override default by cli
 		cfg.Sync.UseSnapshots = ctx.Bool(SnapshotFlag.Name)
 	}
@@ -1486,6 +1629,8 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	cfg.Dirs = nodeConfig.Dirs
 	cfg.Snapshot.KeepBlocks = ctx.Bool(SnapKeepBlocksFlag.Name)
 	cfg.Snapshot.Produce = !ctx.Bool(SnapStopFlag.Name)
+	cfg.Snapshot.Receipts = ctx.Bool(SnapReceiptsFlag.Name)
+	cfg.Snapshot.ScrubEvery = ctx.Duration(SnapScrubEveryFlag.Name)
 	cfg.Snapshot.NoDownloader = ctx.Bool(NoDownloaderFlag.Name)
 	cfg.Snapshot.Verify = ctx.Bool(DownloaderVerifyFlag.Name)
 	cfg.Snapshot.DownloaderAddr = strings.TrimSpace(ctx.String(DownloaderAddrFlag.Name))
@@ -1532,6 +1677,7 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	setMiner(ctx, &cfg.Miner)
 	setWhitelist(ctx, cfg)
 	setBorConfig(ctx, cfg)
+	setProofs(ctx, &cfg.Proofs)
 
 	cfg.Ethstats = ctx.String(EthStatsURLFlag.Name)
 	cfg.P2PEnabled = len(nodeConfig.P2P.SentryAddr) == 0
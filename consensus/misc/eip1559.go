@@ -32,13 +32,16 @@ import (
 // - gas limit check
 // - basefee check
 func VerifyEip1559Header(config *chain.Config, parent, header *types.Header) error {
-	// Verify that the gas limit remains within allowed bounds
-	parentGasLimit := parent.GasLimit
-	if !config.IsLondon(parent.Number.Uint64()) {
-		parentGasLimit = parent.GasLimit * params.ElasticityMultiplier
-	}
-	if err := VerifyGaslimit(parentGasLimit, header.GasLimit); err != nil {
-		return err
+	// Verify that the gas limit remains within allowed bounds.
+	// On rollup chains the gas limit is dictated by the rollup node and may change arbitrarily.
+	if config.Optimism == nil {
+		parentGasLimit := parent.GasLimit
+		if !config.IsLondon(parent.Number.Uint64()) {
+			parentGasLimit = parent.GasLimit * ElasticityMultiplier(config)
+		}
+		if err := VerifyGaslimit(parentGasLimit, header.GasLimit); err != nil {
+			return err
+		}
 	}
 	// Verify the header is not malformed
 	if header.BaseFee == nil {
@@ -61,9 +64,9 @@ func CalcBaseFee(config *chain.Config, parent *types.Header) *big.Int {
 	}
 
 	var (
-		parentGasTarget          = parent.GasLimit / params.ElasticityMultiplier
+		parentGasTarget          = parent.GasLimit / ElasticityMultiplier(config)
 		parentGasTargetBig       = new(big.Int).SetUint64(parentGasTarget)
-		baseFeeChangeDenominator = new(big.Int).SetUint64(getBaseFeeChangeDenominator(config.Bor, parent.Number.Uint64()))
+		baseFeeChangeDenominator = new(big.Int).SetUint64(getBaseFeeChangeDenominator(config, parent.Number.Uint64()))
 	)
 	// If the parent gasUsed is the same as the target, the baseFee remains unchanged.
 	if parent.GasUsed == parentGasTarget {
@@ -94,9 +97,23 @@ func CalcBaseFee(config *chain.Config, parent *types.Header) *big.Int {
 	}
 }
 
-func getBaseFeeChangeDenominator(borConfig *chain.BorConfig, number uint64) uint64 {
+// ElasticityMultiplier returns the bound on the maximum gas limit an EIP-1559 block may have,
+// which rollup chains may configure.
+func ElasticityMultiplier(config *chain.Config) uint64 {
+	if optimism := config.Optimism; optimism != nil {
+		return optimism.EIP1559Elasticity
+	}
+	return params.ElasticityMultiplier
+}
+
+func getBaseFeeChangeDenominator(config *chain.Config, number uint64) uint64 {
+	// Rollup chains configure their own denominator
+	if optimism := config.Optimism; optimism != nil {
+		return optimism.EIP1559Denominator
+	}
+
 	// If we're running bor based chain post delhi hardfork, return the new value
-	if borConfig != nil && borConfig.IsDelhi(number) {
+	if borConfig := config.Bor; borConfig != nil && borConfig.IsDelhi(number) {
 		return params.BaseFeeChangeDenominatorPostDelhi
 	}
 
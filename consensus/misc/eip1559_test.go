@@ -149,3 +149,64 @@ func TestCalcBaseFee(t *testing.T) {
 		}
 	}
 }
+
+func optimismConfig() *chain.Config {
+	config := config()
+	config.ChainID = big.NewInt(901_901)
+	config.Optimism = &chain.OptimismConfig{
+		EIP1559Elasticity:  6,
+		EIP1559Denominator: 50,
+		BedrockBlock:       common.Big0,
+	}
+	params.SetOptimismDefaults(config)
+	return config
+}
+
+// TestBlockGasLimitsOptimism checks that the rollup node may change the gas limit arbitrarily
+func TestBlockGasLimitsOptimism(t *testing.T) {
+	initial := new(big.Int).SetUint64(params.InitialBaseFee)
+	parent := &types.Header{
+		GasUsed:  15000000,
+		GasLimit: 30000000,
+		BaseFee:  initial,
+		Number:   big.NewInt(5),
+	}
+	header := &types.Header{
+		GasUsed:  0,
+		GasLimit: 15000000,
+		BaseFee:  CalcBaseFee(optimismConfig(), parent),
+		Number:   big.NewInt(6),
+	}
+	if err := VerifyEip1559Header(optimismConfig(), parent, header); err != nil {
+		t.Errorf("Expected valid header: %s", err)
+	}
+	header.BaseFee = initial
+	if err := VerifyEip1559Header(config(), parent, header); err == nil {
+		t.Errorf("Expected invalid header")
+	}
+}
+
+// TestCalcBaseFeeOptimism checks the configured elasticity and denominator are used
+func TestCalcBaseFeeOptimism(t *testing.T) {
+	tests := []struct {
+		parentBaseFee   int64
+		parentGasLimit  uint64
+		parentGasUsed   uint64
+		expectedBaseFee int64
+	}{
+		{params.InitialBaseFee, 30000000, 5000000, params.InitialBaseFee}, // usage == target
+		{params.InitialBaseFee, 30000000, 4000000, 996000000},             // usage below target
+		{params.InitialBaseFee, 30000000, 6000000, 1004000000},            // usage above target
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   common.Big32,
+			GasLimit: test.parentGasLimit,
+			GasUsed:  test.parentGasUsed,
+			BaseFee:  big.NewInt(test.parentBaseFee),
+		}
+		if have, want := CalcBaseFee(optimismConfig(), parent), big.NewInt(test.expectedBaseFee); have.Cmp(want) != 0 {
+			t.Errorf("test %d: have %d  want %d, ", i, have, want)
+		}
+	}
+}
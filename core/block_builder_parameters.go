@@ -17,4 +17,5 @@ type BlockBuilderParameters struct {
 	PayloadId             uint64
 	Deposits              [][]byte
 	NoTxPool              bool
+	GasLimit              *uint64 // Set by the rollup node, overrides the configured mining gas limit
 }
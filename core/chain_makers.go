@@ -465,7 +465,7 @@ func MakeEmptyHeader(parent *types.Header, chainConfig *chain.Config, timestamp
 	if chainConfig.IsLondon(header.Number.Uint64()) {
 		header.BaseFee = misc.CalcBaseFee(chainConfig, parent)
 		if !chainConfig.IsLondon(parent.Number.Uint64()) {
-			parentGasLimit = parent.GasLimit * params.ElasticityMultiplier
+			parentGasLimit = parent.GasLimit * misc.ElasticityMultiplier(chainConfig)
 		}
 	}
 	if targetGasLimit != nil {
@@ -95,4 +95,8 @@ var (
 	// ErrSenderNoEOA is returned if the sender of a transaction is a contract.
 	// See EIP-3607: Reject transactions from senders with deployed code.
 	ErrSenderNoEOA = errors.New("sender not an eoa")
+
+	// ErrSystemTxNotSupported is returned for any deposit that is marked as a
+	// system transaction after the Regolith fork.
+	ErrSystemTxNotSupported = errors.New("system tx not supported")
 )
@@ -763,6 +763,18 @@ func DefaultChiadoGenesisBlock() *Genesis {
 	}
 }
 
+// DefaultBobaDevnetGenesisBlock returns the genesis block of a local Boba rollup devnet,
+// funding the well-known development accounts.
+func DefaultBobaDevnetGenesisBlock() *Genesis {
+	return &Genesis{
+		Config:     params.BobaDevnetChainConfig,
+		Timestamp:  0,
+		GasLimit:   30_000_000,
+		Difficulty: big.NewInt(0),
+		Alloc:      readPrealloc("allocs/boba-devnet.json"),
+	}
+}
+
 // Pre-calculated version of:
 //
 //	DevnetSignPrivateKey = crypto.HexToECDSA(sha256.Sum256([]byte("erigon devnet key")))
@@ -829,6 +841,8 @@ func DefaultGenesisBlockByChainName(chain string) *Genesis {
 		return DefaultGnosisGenesisBlock()
 	case networkname.ChiadoChainName:
 		return DefaultChiadoGenesisBlock()
+	case networkname.BobaDevnetChainName:
+		return DefaultBobaDevnetGenesisBlock()
 	default:
 		return nil
 	}
@@ -21,7 +21,9 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/big"
 	"time"
@@ -943,8 +945,8 @@ func ReadRawReceipts(db kv.Tx, blockNum uint64) types.Receipts {
 	if len(data) == 0 {
 		return nil
 	}
-	var receipts types.Receipts
-	if err := cbor.Unmarshal(&receipts, bytes.NewReader(data)); err != nil {
+	receipts, err := unmarshalReceipts(data)
+	if err != nil {
 		log.Error("receipt unmarshal failed", "err", err)
 		return nil
 	}
@@ -1030,6 +1032,51 @@ func ReadReceiptsByHash(db kv.Tx, hash libcommon.Hash) (types.Receipts, error) {
 	return receipts, nil
 }
 
+// marshalReceipts encodes receipts the way they are stored. The deposit nonces of rollup deposit
+// receipts aren't part of their codec, they follow the receipts if any of them has one.
+func marshalReceipts(w io.Writer, receipts types.Receipts) error {
+	if err := cbor.Marshal(w, receipts); err != nil {
+		return err
+	}
+	var depositNonces []*uint64
+	for i, r := range receipts {
+		if r.DepositNonce == nil {
+			continue
+		}
+		if depositNonces == nil {
+			depositNonces = make([]*uint64, len(receipts))
+		}
+		depositNonces[i] = r.DepositNonce
+	}
+	if depositNonces == nil {
+		return nil
+	}
+	return cbor.Marshal(w, depositNonces)
+}
+
+// unmarshalReceipts decodes receipts encoded by marshalReceipts.
+func unmarshalReceipts(data []byte) (types.Receipts, error) {
+	d := cbor.DecoderBytes(data)
+	var receipts types.Receipts
+	if err := d.Decode(&receipts); err != nil {
+		return nil, err
+	}
+	var depositNonces []*uint64
+	if err := d.Decode(&depositNonces); err != nil {
+		if errors.Is(err, io.EOF) {
+			return receipts, nil
+		}
+		return nil, fmt.Errorf("deposit nonces: %w", err)
+	}
+	if len(depositNonces) != len(receipts) {
+		return nil, fmt.Errorf("%d deposit nonces for %d receipts", len(depositNonces), len(receipts))
+	}
+	for i, nonce := range depositNonces {
+		receipts[i].DepositNonce = nonce
+	}
+	return receipts, nil
+}
+
 // WriteReceipts stores all the transaction receipts belonging to a block.
 func WriteReceipts(tx kv.Putter, number uint64, receipts types.Receipts) error {
 	buf := bytes.NewBuffer(make([]byte, 0, 1024))
@@ -1050,7 +1097,7 @@ func WriteReceipts(tx kv.Putter, number uint64, receipts types.Receipts) error {
 	}
 
 	buf.Reset()
-	err := cbor.Marshal(buf, receipts)
+	err := marshalReceipts(buf, receipts)
 	if err != nil {
 		return fmt.Errorf("encode block receipts for block %d: %w", number, err)
 	}
@@ -1082,7 +1129,7 @@ func AppendReceipts(tx kv.StatelessWriteTx, blockNumber uint64, receipts types.R
 	}
 
 	buf.Reset()
-	err := cbor.Marshal(buf, receipts)
+	err := marshalReceipts(buf, receipts)
 	if err != nil {
 		return fmt.Errorf("encode block receipts for block %d: %w", blockNumber, err)
 	}
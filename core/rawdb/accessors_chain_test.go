@@ -32,6 +32,7 @@ import (
 	"github.com/ledgerwatch/erigon/common/u256"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rlp"
 )
@@ -435,6 +436,69 @@ func TestBlockReceiptStorage(t *testing.T) {
 	}
 }
 
+// Tests that the deposit nonce of rollup deposit receipts survives storage.
+func TestDepositReceiptStorage(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require := require.New(t)
+
+	nonce := uint64(0)
+	receipts := types.Receipts{
+		// Pre-Regolith deposits have no deposit nonce
+		{Type: types.DepositTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 1},
+		{Type: types.DepositTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 2, DepositNonce: &nonce},
+		{Type: types.DynamicFeeTxType, Status: types.ReceiptStatusFailed, CumulativeGasUsed: 3},
+	}
+	require.NoError(WriteReceipts(tx, 1, receipts))
+
+	stored := ReadRawReceipts(tx, 1)
+	require.Len(stored, len(receipts))
+	for i, r := range stored {
+		require.Equal(receipts[i].Type, r.Type)
+		require.Equal(receipts[i].CumulativeGasUsed, r.CumulativeGasUsed)
+		require.Equal(receipts[i].DepositNonce, r.DepositNonce)
+	}
+}
+
+// Tests the cbor round trip of receipts with and without deposit nonces.
+func TestReceiptsCborRoundTrip(t *testing.T) {
+	nonce := uint64(7)
+	for _, tt := range []struct {
+		name     string
+		receipts types.Receipts
+	}{
+		{"no deposit nonce", types.Receipts{
+			{Type: types.DepositTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 1},
+			{Type: types.DynamicFeeTxType, Status: types.ReceiptStatusFailed, CumulativeGasUsed: 2},
+		}},
+		{"deposit nonce", types.Receipts{
+			{Type: types.DepositTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 1, DepositNonce: &nonce},
+			{Type: types.DynamicFeeTxType, Status: types.ReceiptStatusFailed, CumulativeGasUsed: 2},
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, marshalReceipts(&buf, tt.receipts))
+
+			// Receipts keep their encoding, deposit nonces only follow them if there are any
+			var plain bytes.Buffer
+			require.NoError(t, cbor.Marshal(&plain, tt.receipts))
+			require.True(t, bytes.HasPrefix(buf.Bytes(), plain.Bytes()))
+			if tt.receipts[0].DepositNonce == nil {
+				require.Equal(t, plain.Bytes(), buf.Bytes())
+			}
+
+			decoded, err := unmarshalReceipts(buf.Bytes())
+			require.NoError(t, err)
+			require.Len(t, decoded, len(tt.receipts))
+			for i, r := range decoded {
+				require.Equal(t, tt.receipts[i].Status, r.Status)
+				require.Equal(t, tt.receipts[i].CumulativeGasUsed, r.CumulativeGasUsed)
+				require.Equal(t, tt.receipts[i].DepositNonce, r.DepositNonce)
+			}
+		})
+	}
+}
+
 // Tests block storage and retrieval operations with withdrawals.
 func TestBlockWithdrawalsStorage(t *testing.T) {
 	_, tx := memdb.NewTestTx(t)
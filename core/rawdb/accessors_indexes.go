@@ -17,9 +17,11 @@
 package rawdb
 
 import (
+	"encoding/binary"
 	"math/big"
 
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/log/v3"
 
@@ -64,6 +66,28 @@ func DeleteTxLookupEntry(db kv.Deleter, hash libcommon.Hash) error {
 	return db.Delete(kv.TxLookup, hash.Bytes())
 }
 
+// CallCriteriaStartKey is the key of the first block of the call criteria index. The
+// blocks executed before the index was introduced have no criteria.
+var CallCriteriaStartKey = []byte("call_criteria_start")
+
+// ReadCallCriteriaStart retrieves the first block of the call criteria index, all the
+// blocks are indexed when it is not stored.
+func ReadCallCriteriaStart(db kv.Getter) (uint64, error) {
+	data, err := db.GetOne(kv.DatabaseInfo, CallCriteriaStartKey)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// WriteCallCriteriaStart stores the first block of the call criteria index.
+func WriteCallCriteriaStart(db kv.Putter, number uint64) error {
+	return db.Put(kv.DatabaseInfo, CallCriteriaStartKey, hexutility.EncodeTs(number))
+}
+
 // ReadTransactionByHash retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransactionByHash(db kv.Tx, hash libcommon.Hash) (types.Transaction, libcommon.Hash, uint64, uint64, error) {
@@ -95,6 +95,7 @@ func ResetBlocks(tx kv.RwTx, db kv.RoDB, snapshots *snapshotsync.RoSnapshots, ag
 		kv.NonCanonicalTxs,
 		kv.EthTx,
 		kv.MaxTxNum,
+		kv.SideForkBlocks,
 	); err != nil {
 		return err
 	}
@@ -160,6 +161,10 @@ func ResetExec(ctx context.Context, db kv.RwDB, chain string, tmpDir string) (er
 		if err := clearTables(ctx, db, tx, stateHistoryBuckets...); err != nil {
 			return nil
 		}
+		// All the blocks are indexed once executed again
+		if err := tx.Delete(kv.DatabaseInfo, rawdb.CallCriteriaStartKey); err != nil {
+			return err
+		}
 		if !historyV3 {
 			genesis := core.DefaultGenesisBlockByChainName(chain)
 			if _, _, err := genesis.WriteGenesisState(tx, tmpDir); err != nil {
@@ -187,7 +192,7 @@ func ResetTxLookup(tx kv.RwTx) error {
 var Tables = map[stages.SyncStage][]string{
 	stages.HashState:           {kv.HashedAccounts, kv.HashedStorage, kv.ContractCode},
 	stages.IntermediateHashes:  {kv.TrieOfAccounts, kv.TrieOfStorage},
-	stages.CallTraces:          {kv.CallFromIndex, kv.CallToIndex},
+	stages.CallTraces:          {kv.CallFromIndex, kv.CallToIndex, kv.CallCriteriaIndex},
 	stages.LogIndex:            {kv.LogAddressIndex, kv.LogTopicIndex},
 	stages.AccountHistoryIndex: {kv.AccountsHistory},
 	stages.StorageHistoryIndex: {kv.StorageHistory},
@@ -204,6 +209,7 @@ var stateHistoryBuckets = []string{
 	kv.Receipts,
 	kv.Log,
 	kv.CallTraceSet,
+	kv.CallCriteriaSet,
 }
 var stateHistoryV3Buckets = []string{
 	kv.AccountHistoryKeys, kv.AccountIdx, kv.AccountHistoryVals, kv.AccountSettings,
@@ -56,6 +56,12 @@ func applyTransaction(config *chain.Config, engine consensus.EngineReader, gp *G
 	// Update the evm with the new transaction context.
 	evm.Reset(txContext, ibs)
 
+	// Deposits carry no nonce, a contract they create is addressed by the sender's nonce in the state
+	nonce := tx.GetNonce()
+	if msg.IsDepositTx() {
+		nonce = ibs.GetNonce(msg.From())
+	}
+
 	result, err := ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
 	if err != nil {
 		return nil, nil, err
@@ -79,9 +85,14 @@ func applyTransaction(config *chain.Config, engine consensus.EngineReader, gp *G
 		}
 		receipt.TxHash = tx.Hash()
 		receipt.GasUsed = result.UsedGas
+		if msg.IsDepositTx() {
+			if optimism := config.Optimism; optimism != nil && optimism.IsRegolith(header.Time) {
+				receipt.DepositNonce = &nonce
+			}
+		}
 		// if the transaction created a contract, store the creation address in the receipt.
 		if msg.To() == nil {
-			receipt.ContractAddress = crypto.CreateAddress(evm.TxContext().Origin, tx.GetNonce())
+			receipt.ContractAddress = crypto.CreateAddress(evm.TxContext().Origin, nonce)
 		}
 		// Set the receipt logs and create a bloom for filtering
 		receipt.Logs = ibs.GetLogs(tx.Hash())
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/txpool"
 	types2 "github.com/ledgerwatch/erigon-lib/types"
@@ -33,7 +34,6 @@ import (
 	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/params"
-	"github.com/ledgerwatch/log/v3"
 )
 
 var emptyCodeHash = crypto.Keccak256Hash(nil)
@@ -65,6 +65,8 @@ type StateTransition struct {
 	gasFeeCap  *uint256.Int
 	tip        *uint256.Int
 	initialGas uint64
+	l1Cost     *uint256.Int // L1 data fee charged on rollup chains, nil otherwise
+	optimism   *chain.OptimismConfig
 	value      *uint256.Int
 	data       []byte
 	state      evmtypes.IntraBlockState
@@ -96,7 +98,9 @@ type Message interface {
 
 	IsFree() bool
 	IsSystemTx() bool
+	IsDepositTx() bool
 	Mint() *uint256.Int
+	RollupDataGas() uint64
 }
 
 // ExecutionResult includes all output after executing given evm
@@ -172,9 +176,20 @@ func NewStateTransition(evm vm.VMInterface, msg Message, gp *GasPool) *StateTran
 
 		isParlia: isParlia,
 		isBor:    isBor,
+		optimism: evm.ChainConfig().Optimism,
 	}
 }
 
+// isBedrock returns whether the message is executed in a post-Bedrock block of a rollup chain.
+func (st *StateTransition) isBedrock() bool {
+	return st.optimism != nil && st.optimism.IsBedrock(st.evm.Context().BlockNumber)
+}
+
+// isRegolith returns whether the message is executed in a post-Regolith block of a rollup chain.
+func (st *StateTransition) isRegolith() bool {
+	return st.optimism != nil && st.optimism.IsRegolith(st.evm.Context().Time)
+}
+
 // ApplyMessage computes the new state by applying the given message
 // against the old state within the environment.
 //
@@ -204,6 +219,15 @@ func (st *StateTransition) buyGas(gasBailout bool) error {
 	if overflow {
 		return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
 	}
+	st.l1Cost = nil
+	if st.isBedrock() && !st.msg.IsDepositTx() {
+		st.l1Cost = types.L1CostFromState(st.state, st.optimism.L1BlockAddress, st.msg.RollupDataGas(), st.isRegolith())
+	}
+	if st.l1Cost != nil {
+		if mgval, overflow = mgval.AddOverflow(mgval, st.l1Cost); overflow {
+			return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
+		}
+	}
 	balanceCheck := mgval
 	if st.gasFeeCap != nil {
 		balanceCheck = st.sharedBuyGasBalance.SetUint64(st.msg.Gas())
@@ -215,12 +239,19 @@ func (st *StateTransition) buyGas(gasBailout bool) error {
 		if overflow {
 			return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
 		}
+		if st.l1Cost != nil {
+			if balanceCheck, overflow = balanceCheck.AddOverflow(balanceCheck, st.l1Cost); overflow {
+				return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
+			}
+		}
 	}
 	var subBalance = false
 	if have, want := st.state.GetBalance(st.msg.From()), balanceCheck; have.Cmp(want) < 0 {
 		if !gasBailout {
 			return fmt.Errorf("%w: address %v have %v want %v", ErrInsufficientFunds, st.msg.From().Hex(), have, want)
 		}
+		// Nothing is debited under gas bailout, so there is no L1 fee to collect either
+		st.l1Cost = nil
 	} else {
 		subBalance = true
 	}
@@ -252,21 +283,20 @@ func CheckEip1559TxGasFeeCap(from libcommon.Address, gasFeeCap, tip, baseFee *ui
 
 // DESCRIBED: docs/programmers_guide/guide.md#nonce
 func (st *StateTransition) preCheck(gasBailout bool) error {
-       if st.msg.Nonce() == 0xffff_ffff_ffff_fffd { //types.DepositsNonce
-       		log.Debug("MMDBG preCheck for Deposit txn")
-	
-		// Following section copied from Optimism patchset
-		
-	       // No fee fields to check, no nonce to check, and no need to check if EOA (L1 already verified it for us)
-	       // Gas is free, but no refunds!
-	       st.initialGas = st.msg.Gas()
-	       st.gas += st.msg.Gas() // Add gas here in order to be able to execute calls.
-	       // Don't touch the gas pool for system transactions
-	       if true { // FIXME st.msg.IsSystemTx() {
-		       return nil
-	       }
-	       return st.gp.SubGas(st.msg.Gas()) // gas used by deposits may not be used by other txs
-       }
+	if st.msg.IsDepositTx() {
+		// No fee fields to check, no nonce to check, and no need to check if EOA (L1 already verified it for us)
+		// Gas is free, but no refunds!
+		st.initialGas = st.msg.Gas()
+		st.gas += st.msg.Gas() // Add gas here in order to be able to execute calls.
+		// Don't touch the gas pool for system transactions
+		if st.msg.IsSystemTx() {
+			if st.isRegolith() {
+				return fmt.Errorf("%w: address %v", ErrSystemTxNotSupported, st.msg.From().Hex())
+			}
+			return nil
+		}
+		return st.gp.SubGas(st.msg.Gas()) // gas used by deposits may not be used by other txs
+	}
 
 	// Make sure this transaction's nonce is correct.
 	if st.msg.CheckNonce() {
@@ -305,36 +335,36 @@ func (st *StateTransition) preCheck(gasBailout bool) error {
 }
 
 func (st *StateTransition) TransitionDb(refunds bool, gasBailout bool) (*ExecutionResult, error) {
-       if mint := st.msg.Mint(); mint != nil {
-	       st.state.AddBalance(st.msg.From(), mint)
-       }
-       snap := st.state.Snapshot()
-
-       result, err := st.innerTransitionDb(refunds, gasBailout)
-       // Failed deposits must still be included. Unless we cannot produce the block at all due to the gas limit.
-       // On deposit failure, we rewind any state changes from after the minting, and increment the nonce.
-       if err != nil && err != ErrGasLimitReached && st.msg.Nonce() == types.DepositsNonce {
-	       st.state.RevertToSnapshot(snap)
-	       // Even though we revert the state changes, always increment the nonce for the next deposit transaction
-	       st.state.SetNonce(st.msg.From(), st.state.GetNonce(st.msg.From())+1)
-	       // Record deposits as using all their gas (matches the gas pool)
-	       // System Transactions are special & are not recorded as using any gas (anywhere)
-	       gasUsed := st.msg.Gas()
-	       if st.msg.IsSystemTx() {
-		       gasUsed = 0
-	       }
-	       result = &ExecutionResult{
-		       UsedGas:    gasUsed,
-		       Err:	   fmt.Errorf("failed deposit: %w", err),
-		       ReturnData: nil,
-	       }
-	       err = nil
-       }
-       return result, err
+	if mint := st.msg.Mint(); mint != nil {
+		st.state.AddBalance(st.msg.From(), mint)
+	}
+	snap := st.state.Snapshot()
+
+	result, err := st.innerTransitionDb(refunds, gasBailout)
+	// Failed deposits must still be included. Unless we cannot produce the block at all due to the gas limit.
+	// On deposit failure, we rewind any state changes from after the minting, and increment the nonce.
+	if err != nil && err != ErrGasLimitReached && st.msg.IsDepositTx() {
+		st.state.RevertToSnapshot(snap)
+		// Even though we revert the state changes, always increment the nonce for the next deposit transaction
+		st.state.SetNonce(st.msg.From(), st.state.GetNonce(st.msg.From())+1)
+		// Record deposits as using all their gas (matches the gas pool)
+		// System Transactions are special & are not recorded as using any gas (anywhere)
+		// Regolith changes this behaviour so the actual gas used is reported.
+		// In this case the actual gas used is the full gas limit, as the deposit failed.
+		gasUsed := st.msg.Gas()
+		if st.msg.IsSystemTx() && !st.isRegolith() {
+			gasUsed = 0
+		}
+		result = &ExecutionResult{
+			UsedGas:    gasUsed,
+			Err:        fmt.Errorf("failed deposit: %w", err),
+			ReturnData: nil,
+		}
+		err = nil
+	}
+	return result, err
 }
 
-
-
 // TransitionDb will transition the state by applying the current message and
 // returning the evm execution result with following fields.
 //
@@ -448,6 +478,22 @@ func (st *StateTransition) innerTransitionDb(refunds bool, gasBailout bool) (*Ex
 		st.state.SetNonce(msg.From(), st.state.GetNonce(sender.Address())+1)
 		ret, st.gas, vmerr = st.evm.Call(sender, st.to(), st.data, st.gas, st.value, bailout)
 	}
+	// Deposits skip refunds and fee payments. Before Regolith they are also
+	// recorded as using all their gas, to match what was taken from the gas pool.
+	if msg.IsDepositTx() && !st.isRegolith() {
+		// System Transactions are special & are not recorded as using any gas (anywhere)
+		gasUsed := msg.Gas()
+		if msg.IsSystemTx() {
+			gasUsed = 0
+		}
+		return &ExecutionResult{
+			UsedGas:    gasUsed,
+			Err:        vmerr,
+			ReturnData: ret,
+		}, nil
+	}
+	// No ETH is refunded for the unused gas of a deposit, its gas price is always zero,
+	// but refundGas still returns the unused gas to the block gas pool.
 	if refunds {
 		if rules.IsLondon {
 			// After EIP-3529: refunds are capped to gasUsed / 5
@@ -457,6 +503,14 @@ func (st *StateTransition) innerTransitionDb(refunds bool, gasBailout bool) (*Ex
 			st.refundGas(params.RefundQuotient)
 		}
 	}
+	if msg.IsDepositTx() {
+		// Since Regolith deposits report the gas actually used, but still don't pay any fees
+		return &ExecutionResult{
+			UsedGas:    st.gasUsed(),
+			Err:        vmerr,
+			ReturnData: ret,
+		}, nil
+	}
 	effectiveTip := st.gasPrice
 	if rules.IsLondon {
 		if st.gasFeeCap.Gt(st.evm.Context().BaseFee) {
@@ -477,6 +531,17 @@ func (st *StateTransition) innerTransitionDb(refunds bool, gasBailout bool) (*Ex
 		burnAmount := new(uint256.Int).Mul(new(uint256.Int).SetUint64(st.gasUsed()), st.evm.Context().BaseFee)
 		st.state.AddBalance(burntContractAddress, burnAmount)
 	}
+	if st.isBedrock() && !msg.IsDepositTx() {
+		// The base fee is collected by the rollup instead of being burnt
+		if rules.IsLondon && !msg.IsFree() {
+			baseFeeAmount := new(uint256.Int).Mul(new(uint256.Int).SetUint64(st.gasUsed()), st.evm.Context().BaseFee)
+			st.state.AddBalance(st.optimism.BaseFeeRecipient, baseFeeAmount)
+		}
+		// The L1 data fee was debited together with the gas in buyGas and is not refundable
+		if st.l1Cost != nil {
+			st.state.AddBalance(st.optimism.L1FeeRecipient, st.l1Cost)
+		}
+	}
 	if st.isBor {
 		// Deprecating transfer log and will be removed in future fork. PLEASE DO NOT USE this transfer log going forward. Parameters won't get updated as expected going forward with EIP1559
 		// add transfer log
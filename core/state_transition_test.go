@@ -0,0 +1,273 @@
+package core_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+const (
+	depositTestRegolithTime = 10
+	depositTestGasLimit     = 100_000
+	depositTestPoolGas      = 30_000_000
+)
+
+var (
+	depositTestSender   = libcommon.HexToAddress("0x3c44cdddb6a900fa2b585dd299e03d12fa4293bc")
+	depositTestCoinbase = libcommon.HexToAddress("0x4200000000000000000000000000000000000011")
+	depositTestContract = libcommon.HexToAddress("0x70997970c51812dc3a010c7d01b50e0d17dc79c8")
+)
+
+func depositTestConfig() *chain.Config {
+	config := &chain.Config{
+		ChainID:               big.NewInt(1_000_903),
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		BerlinBlock:           big.NewInt(0),
+		LondonBlock:           big.NewInt(0),
+	}
+	config.Optimism = &chain.OptimismConfig{
+		BedrockBlock: big.NewInt(0),
+		RegolithTime: big.NewInt(depositTestRegolithTime),
+	}
+	params.SetOptimismDefaults(config)
+	return config
+}
+
+// applyDeposit executes a deposit calling into code at the given block time.
+func applyDeposit(t *testing.T, code []byte, isSystemTx bool, time uint64) (*core.ExecutionResult, *core.GasPool, *state.IntraBlockState) {
+	_, tx := memdb.NewTestTx(t)
+	ibs := state.New(state.NewPlainStateReader(tx))
+	ibs.SetCode(depositTestContract, code)
+
+	config := depositTestConfig()
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		Time:       time,
+		Difficulty: new(big.Int),
+		GasLimit:   depositTestPoolGas,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+	}
+	deposit := &types.DepositTransaction{
+		SourceHash: &libcommon.Hash{},
+		Nonce:      types.DepositsNonce,
+		From:       &depositTestSender,
+		To:         &depositTestContract,
+		Mint:       uint256.NewInt(1_000_000),
+		Value:      uint256.NewInt(0),
+		GasLimit:   depositTestGasLimit,
+		IsSystemTx: isSystemTx,
+	}
+	msg, err := deposit.AsMessage(*types.LatestSignerForChainID(nil), nil, nil)
+	require.NoError(t, err)
+
+	blockContext := core.NewEVMBlockContext(header, func(n uint64) libcommon.Hash { return libcommon.Hash{} }, nil, &depositTestCoinbase)
+	evm := vm.NewEVM(blockContext, core.NewEVMTxContext(msg), ibs, config, vm.Config{})
+	gp := new(core.GasPool).AddGas(depositTestPoolGas)
+	result, err := core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+	require.NoError(t, err)
+	return result, gp, ibs
+}
+
+// storedValue returns the first storage slot of the test contract.
+func storedValue(ibs *state.IntraBlockState) uint64 {
+	var value uint256.Int
+	ibs.GetState(depositTestContract, &libcommon.Hash{}, &value)
+	return value.Uint64()
+}
+
+func TestDepositGasAccounting(t *testing.T) {
+	var (
+		// PUSH1 1 PUSH1 0 SSTORE STOP: 21000 + 3 + 3 + 22100 (cold SSTORE from zero)
+		storeCode = common.FromHex("0x600160005500")
+		storeGas  = uint64(43_106)
+		// PUSH1 0 PUSH1 0 REVERT: 21000 + 3 + 3
+		revertCode = common.FromHex("0x60006000fd")
+		revertGas  = uint64(21_006)
+		// JUMPDEST PUSH1 0 JUMP: loops until out of gas
+		loopCode = common.FromHex("0x5b600056")
+	)
+	for _, tt := range []struct {
+		name    string
+		code    []byte
+		time    uint64
+		vmErr   error
+		usedGas uint64
+	}{
+		// Before Regolith deposits always use their full gas limit
+		{"success", storeCode, depositTestRegolithTime - 1, nil, depositTestGasLimit},
+		{"reverted", revertCode, depositTestRegolithTime - 1, vm.ErrExecutionReverted, depositTestGasLimit},
+		{"out of gas", loopCode, depositTestRegolithTime - 1, vm.ErrOutOfGas, depositTestGasLimit},
+		// Since Regolith the gas actually used is reported
+		{"regolith success", storeCode, depositTestRegolithTime, nil, storeGas},
+		{"regolith reverted", revertCode, depositTestRegolithTime, vm.ErrExecutionReverted, revertGas},
+		{"regolith out of gas", loopCode, depositTestRegolithTime, vm.ErrOutOfGas, depositTestGasLimit},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			result, gp, ibs := applyDeposit(t, tt.code, false, tt.time)
+			require.Equal(t, tt.usedGas, result.UsedGas)
+			if tt.vmErr == nil {
+				require.NoError(t, result.Err)
+				require.Equal(t, uint64(1), storedValue(ibs))
+			} else {
+				require.ErrorIs(t, result.Err, tt.vmErr)
+			}
+			// Deposits take their gas from the block and don't pay fees
+			require.Equal(t, uint64(depositTestPoolGas)-tt.usedGas, gp.Gas())
+			require.Equal(t, uint64(1), ibs.GetNonce(depositTestSender))
+			require.Equal(t, uint256.NewInt(1_000_000), ibs.GetBalance(depositTestSender))
+			require.True(t, ibs.GetBalance(depositTestCoinbase).IsZero())
+			require.True(t, ibs.GetBalance(params.OptimismBaseFeeRecipient).IsZero())
+		})
+	}
+}
+
+func TestDepositSystemTx(t *testing.T) {
+	storeCode := common.FromHex("0x600160005500")
+
+	// Before Regolith system deposits are free and don't touch the gas pool
+	result, gp, ibs := applyDeposit(t, storeCode, true, depositTestRegolithTime-1)
+	require.NoError(t, result.Err)
+	require.Equal(t, uint64(0), result.UsedGas)
+	require.Equal(t, uint64(depositTestPoolGas), gp.Gas())
+	require.Equal(t, uint64(1), storedValue(ibs))
+
+	// Since Regolith they are included as failed deposits using their full gas limit
+	result, _, ibs = applyDeposit(t, storeCode, true, depositTestRegolithTime)
+	require.ErrorIs(t, result.Err, core.ErrSystemTxNotSupported)
+	require.Equal(t, uint64(depositTestGasLimit), result.UsedGas)
+	require.Equal(t, uint64(1), ibs.GetNonce(depositTestSender))
+	require.Equal(t, uint256.NewInt(1_000_000), ibs.GetBalance(depositTestSender))
+	require.Equal(t, uint64(0), storedValue(ibs))
+}
+
+// applyUserTx executes a plain transfer of a signed legacy transaction at the given block time,
+// with the L1 fee parameters set in the L1Block predeploy.
+func applyUserTx(t *testing.T, time uint64) (types.Transaction, libcommon.Address, *core.ExecutionResult, *state.IntraBlockState) {
+	_, tx := memdb.NewTestTx(t)
+	ibs := state.New(state.NewPlainStateReader(tx))
+
+	config := depositTestConfig()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	ibs.SetBalance(sender, uint256.NewInt(params.Ether))
+	ibs.SetState(params.OptimismL1BlockAddress, &types.L1BaseFeeSlot, *uint256.NewInt(1_000))
+	ibs.SetState(params.OptimismL1BlockAddress, &types.OverheadSlot, *uint256.NewInt(2_100))
+	ibs.SetState(params.OptimismL1BlockAddress, &types.ScalarSlot, *uint256.NewInt(1_500_000))
+
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		Time:       time,
+		Difficulty: new(big.Int),
+		GasLimit:   depositTestPoolGas,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+	}
+	signer := types.LatestSignerForChainID(config.ChainID)
+	signed, err := types.SignTx(types.NewTransaction(0, depositTestContract, uint256.NewInt(1), params.TxGas+4+16, uint256.NewInt(2*params.InitialBaseFee), []byte{0x00, 0x01}), *signer, key)
+	require.NoError(t, err)
+	msg, err := signed.AsMessage(*signer, header.BaseFee, config.Rules(1, time))
+	require.NoError(t, err)
+
+	blockContext := core.NewEVMBlockContext(header, func(n uint64) libcommon.Hash { return libcommon.Hash{} }, nil, &depositTestCoinbase)
+	evm := vm.NewEVM(blockContext, core.NewEVMTxContext(msg), ibs, config, vm.Config{})
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(depositTestPoolGas), true /* refunds */, false /* gasBailout */)
+	require.NoError(t, err)
+	return signed, sender, result, ibs
+}
+
+func TestL1CostAccounting(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		time    uint64
+		padding uint64
+	}{
+		// Before Regolith the data gas is padded for the signature and RLP overhead
+		{"bedrock", depositTestRegolithTime - 1, 68 * 16},
+		{"regolith", depositTestRegolithTime, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, sender, result, ibs := applyUserTx(t, tt.time)
+			require.NoError(t, result.Err)
+			require.Equal(t, params.TxGas+4+16, result.UsedGas)
+
+			// (data gas + overhead) * l1BaseFee * scalar / 1e6
+			l1Cost := (types.RollupDataGas(tx) + tt.padding + 2_100) * 1_000 * 3 / 2
+			require.Equal(t, uint256.NewInt(l1Cost), ibs.GetBalance(params.OptimismL1FeeRecipient))
+
+			debit := result.UsedGas*2*params.InitialBaseFee + l1Cost + 1
+			require.Equal(t, uint256.NewInt(params.Ether-debit), ibs.GetBalance(sender))
+			require.Equal(t, uint256.NewInt(result.UsedGas*params.InitialBaseFee), ibs.GetBalance(params.OptimismBaseFeeRecipient))
+		})
+	}
+}
+
+func TestDepositContractCreation(t *testing.T) {
+	// PUSH1 0 PUSH1 0 RETURN: deploys empty code
+	initCode := common.FromHex("0x60006000f3")
+	for _, tt := range []struct {
+		name         string
+		time         uint64
+		depositNonce bool
+	}{
+		{"bedrock", depositTestRegolithTime - 1, false},
+		{"regolith", depositTestRegolithTime, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, tx := memdb.NewTestTx(t)
+			ibs := state.New(state.NewPlainStateReader(tx))
+			ibs.SetNonce(depositTestSender, 5)
+
+			header := &types.Header{
+				Number:     big.NewInt(1),
+				Time:       tt.time,
+				Difficulty: new(big.Int),
+				GasLimit:   depositTestPoolGas,
+				BaseFee:    big.NewInt(params.InitialBaseFee),
+			}
+			deposit := &types.DepositTransaction{
+				SourceHash: &libcommon.Hash{},
+				Nonce:      types.DepositsNonce,
+				From:       &depositTestSender,
+				Value:      uint256.NewInt(0),
+				GasLimit:   depositTestGasLimit,
+				Data:       initCode,
+			}
+			usedGas := new(uint64)
+			receipt, _, err := core.ApplyTransaction(depositTestConfig(), func(n uint64) libcommon.Hash { return libcommon.Hash{} }, nil, &depositTestCoinbase,
+				new(core.GasPool).AddGas(depositTestPoolGas), ibs, state.NewNoopWriter(), header, deposit, usedGas, vm.Config{})
+			require.NoError(t, err)
+			require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+
+			// The contract is addressed by the sender's nonce in the state, before and since Regolith
+			contract := crypto.CreateAddress(depositTestSender, 5)
+			require.Equal(t, contract, receipt.ContractAddress)
+			require.Equal(t, uint64(1), ibs.GetNonce(contract))
+			require.Equal(t, uint64(6), ibs.GetNonce(depositTestSender))
+			if tt.depositNonce {
+				require.Equal(t, uint64(5), *receipt.DepositNonce)
+			} else {
+				require.Nil(t, receipt.DepositNonce)
+			}
+		})
+	}
+}
@@ -467,6 +467,8 @@ func (tx AccessListTx) AsMessage(s Signer, _ *big.Int, rules *chain.Rules) (Mess
 		return msg, errors.New("eip-2930 transactions require Berlin")
 	}
 
+	msg.rollupMarshal = tx.MarshalBinary
+
 	var err error
 	msg.from, err = tx.Sender(s)
 	return msg, err
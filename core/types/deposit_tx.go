@@ -23,37 +23,34 @@
 package types
 
 import (
-	//"encoding/binary"
 	"fmt"
 	"io"
 	"math/big"
-	//"math/bits"
+	"math/bits"
 
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/chain"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
+
 	"github.com/ledgerwatch/erigon/common"
-	//"github.com/ledgerwatch/erigon/common/u256"
-	"bytes"
-	rlp2 "github.com/ethereum/go-ethereum/rlp" // Use this one to avoid a bunch of BS with the ledgerwatch/erigon/rlp version
 	"github.com/ledgerwatch/erigon/rlp"
-	"github.com/ledgerwatch/log/v3"
-	types2 "github.com/ledgerwatch/erigon-lib/types"
 )
 
-// DepositTransaction is the transaction data of regular Ethereum transactions.
+// DepositTransaction is an L1->L2 deposit, derived by the rollup node from L1 and included
+// at the start of L2 blocks. It is not signed: the sender is given explicitly.
 type DepositTransaction struct {
 	TransactionMisc
 
-	SourceHash *libcommon.Hash
-	Nonce      uint64
-	From       *libcommon.Address
-	To         *libcommon.Address
-	Mint       *uint256.Int
-	Value      *uint256.Int
-	GasLimit   uint64
-	IsSystemTx bool
-	Data       []byte
+	SourceHash *libcommon.Hash    // Uniquely identifies the source of the deposit
+	Nonce      uint64             // Always DepositsNonce, deposits are not nonce-ordered
+	From       *libcommon.Address // Exposed through the L1 deposit event, not recovered from a signature
+	To         *libcommon.Address `rlp:"nil"` // nil means contract creation
+	Mint       *uint256.Int       // ETH minted on L2 before execution, nil is the same as zero
+	Value      *uint256.Int       // wei amount transferred from From
+	GasLimit   uint64             // gas limit, bought on L1
+	IsSystemTx bool               // System deposits are exempt from the L2 gas limit
+	Data       []byte             // contract invocation input data
 }
 
 func (tx DepositTransaction) GetGas() uint64          { return tx.GasLimit }
@@ -61,111 +58,239 @@ func (tx DepositTransaction) GetPrice() *uint256.Int  { return uint256.NewInt(0)
 func (tx DepositTransaction) GetTip() *uint256.Int    { return uint256.NewInt(0) }
 func (tx DepositTransaction) GetFeeCap() *uint256.Int { return uint256.NewInt(0) }
 func (tx DepositTransaction) GetNonce() uint64        { return tx.Nonce }
+
+// GetEffectiveGasTip is zero: the gas of a deposit is paid for on L1.
 func (tx DepositTransaction) GetEffectiveGasTip(baseFee *uint256.Int) *uint256.Int {
-	/*
-		if baseFee == nil {
-			return tx.GetTip()
-		}
-		gasFeeCap := tx.GetFeeCap()
-		// return 0 because effectiveFee cant be < 0
-		if gasFeeCap.Lt(baseFee) {
-			return uint256.NewInt(0)
-		}
-		effectiveFee := new(uint256.Int).Sub(gasFeeCap, baseFee)
-		if tx.GetTip().Lt(effectiveFee) {
-			return tx.GetTip()
-		} else {
-			return effectiveFee
-		}
-	*/
 	return uint256.NewInt(0)
 }
 
+// Cost is the value transferred, the gas price of a deposit is zero.
 func (tx DepositTransaction) Cost() *uint256.Int {
-	log.Warn("MMDBG dtX Cost")
-	total := new(uint256.Int).SetUint64(0) // FIXME
-	//total.Mul(total, tx.GasPrice)
-	//total.Add(total, tx.Value)
-	return total
+	return new(uint256.Int).Set(tx.value())
 }
 
 func (tx DepositTransaction) GetAccessList() types2.AccessList {
 	return types2.AccessList{}
 }
+
 func (tx DepositTransaction) GetData() []byte {
 	return tx.Data
 }
 
+// Protected is true: the source hash makes a deposit unique, so it can't be replayed on another chain.
 func (tx DepositTransaction) Protected() bool {
-	return true // FIXME
+	return true
 }
 
-func (tx DepositTransaction) EncodingSize() int {
-	// FIXME - inefficient
-	var bb bytes.Buffer
-	tx.EncodeRLP(&bb)
-	log.Debug("MMDBG tx.EncodingSize", "tx", tx, "len", bb.Len())
-	
-	return bb.Len()
+// mint returns the minted amount, treating nil as zero.
+func (tx DepositTransaction) mint() *uint256.Int {
+	if tx.Mint == nil {
+		return new(uint256.Int)
+	}
+	return tx.Mint
+}
+
+// value returns the transferred amount, treating nil as zero.
+func (tx DepositTransaction) value() *uint256.Int {
+	if tx.Value == nil {
+		return new(uint256.Int)
+	}
+	return tx.Value
 }
 
 // copy creates a deep copy of the transaction data and initializes all fields.
 func (tx DepositTransaction) copy() *DepositTransaction {
 	cpy := &DepositTransaction{
-		SourceHash: tx.SourceHash,
+		TransactionMisc: TransactionMisc{
+			time: tx.time,
+		},
 		Nonce:      tx.Nonce,
-		From:       tx.From,
-		To:         tx.To,
-		Mint:       tx.Mint,
-		Value:      tx.Value,
 		GasLimit:   tx.GasLimit,
 		IsSystemTx: tx.IsSystemTx,
 		Data:       common.CopyBytes(tx.Data),
+		// These are copied below.
+		Value: new(uint256.Int),
+	}
+	if tx.SourceHash != nil {
+		sourceHash := *tx.SourceHash
+		cpy.SourceHash = &sourceHash
+	}
+	if tx.From != nil {
+		from := *tx.From
+		cpy.From = &from
+	}
+	if tx.To != nil {
+		to := *tx.To
+		cpy.To = &to
+	}
+	if tx.Mint != nil {
+		cpy.Mint = new(uint256.Int).Set(tx.Mint)
+	}
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
 	}
-
 	return cpy
 }
 
-/*
-func (tx *DepositTransaction) Size() common.StorageSize {
-	log.Warn("MMDBG dtX Size")
-	if size := tx.size.Load(); size != nil {
-		return size.(common.StorageSize)
+func (tx DepositTransaction) EncodingSize() int {
+	payloadSize := tx.payloadSize()
+	envelopeSize := payloadSize
+	// Add envelope size and type size
+	if payloadSize >= 56 {
+		envelopeSize += (bits.Len(uint(payloadSize)) + 7) / 8
+	}
+	envelopeSize += 2
+	return envelopeSize
+}
+
+func (tx DepositTransaction) payloadSize() (payloadSize int) {
+	// size of SourceHash
+	payloadSize += 33
+	// size of From
+	payloadSize += 21
+	// size of To
+	payloadSize++
+	if tx.To != nil {
+		payloadSize += 20
+	}
+	// size of Mint
+	payloadSize++
+	payloadSize += rlp.Uint256LenExcludingHead(tx.mint())
+	// size of Value
+	payloadSize++
+	payloadSize += rlp.Uint256LenExcludingHead(tx.value())
+	// size of GasLimit
+	payloadSize++
+	payloadSize += rlp.IntLenExcludingHead(tx.GasLimit)
+	// size of IsSystemTx
+	payloadSize++
+	// size of Data
+	payloadSize++
+	switch len(tx.Data) {
+	case 0:
+	case 1:
+		if tx.Data[0] >= 128 {
+			payloadSize++
+		}
+	default:
+		if len(tx.Data) >= 56 {
+			payloadSize += (bits.Len(uint(len(tx.Data))) + 7) / 8
+		}
+		payloadSize += len(tx.Data)
 	}
-	c := 42 // FIXME
-	tx.size.Store(common.StorageSize(c))
-	return common.StorageSize(c)
+	return payloadSize
 }
-*/
 
 // MarshalBinary returns the canonical encoding of the transaction.
 // For legacy transactions, it returns the RLP encoding. For EIP-2718 typed
 // transactions, it returns the type and payload.
 func (tx DepositTransaction) MarshalBinary(w io.Writer) error {
-	//log.Debug("MMDBG MarshalBinary calling EncodeRLP")
-	return tx.EncodeRLP(w)
+	payloadSize := tx.payloadSize()
+	var b [33]byte
+	// encode TxType
+	b[0] = DepositTxType
+	if _, err := w.Write(b[:1]); err != nil {
+		return err
+	}
+	if err := tx.encodePayload(w, b[:], payloadSize); err != nil {
+		return err
+	}
+	return nil
 }
 
-// EncodeRLP implements rlp.Encoder
-func (tx DepositTransaction) EncodeRLP(w io.Writer) error {
-
-	var bb bytes.Buffer
-	buf := rlp2.NewEncoderBuffer(&bb)
-	buf.WriteUint64(DepositTxType)
-	idx1 := buf.List()
-	buf.WriteBytes(tx.SourceHash.Bytes())
-	buf.WriteBytes(tx.From.Bytes())
-	buf.WriteBytes(tx.To.Bytes())
-	buf.WriteBytes(tx.Mint.Bytes())
-	buf.WriteBytes(tx.Value.Bytes())
-	buf.WriteUint64(tx.GasLimit)
-	buf.WriteBool(tx.IsSystemTx)
-	buf.WriteBytes(tx.Data)
-	buf.ListEnd(idx1)
-
-	//log.Debug("MMDBG EncodeRLP", "bufBytes", buf.ToBytes())
-	w.Write(buf.ToBytes())
+func (tx DepositTransaction) encodePayload(w io.Writer, b []byte, payloadSize int) error {
+	// prefix
+	if err := EncodeStructSizePrefix(payloadSize, w, b); err != nil {
+		return err
+	}
+	// encode SourceHash
+	var sourceHash libcommon.Hash
+	if tx.SourceHash != nil {
+		sourceHash = *tx.SourceHash
+	}
+	b[0] = 128 + 32
+	if _, err := w.Write(b[:1]); err != nil {
+		return err
+	}
+	if _, err := w.Write(sourceHash[:]); err != nil {
+		return err
+	}
+	// encode From
+	var from libcommon.Address
+	if tx.From != nil {
+		from = *tx.From
+	}
+	b[0] = 128 + 20
+	if _, err := w.Write(b[:1]); err != nil {
+		return err
+	}
+	if _, err := w.Write(from[:]); err != nil {
+		return err
+	}
+	// encode To
+	if tx.To == nil {
+		b[0] = 128
+	} else {
+		b[0] = 128 + 20
+	}
+	if _, err := w.Write(b[:1]); err != nil {
+		return err
+	}
+	if tx.To != nil {
+		if _, err := w.Write(tx.To.Bytes()); err != nil {
+			return err
+		}
+	}
+	// encode Mint
+	if err := tx.mint().EncodeRLP(w); err != nil {
+		return err
+	}
+	// encode Value
+	if err := tx.value().EncodeRLP(w); err != nil {
+		return err
+	}
+	// encode GasLimit
+	if err := rlp.EncodeInt(tx.GasLimit, w, b); err != nil {
+		return err
+	}
+	// encode IsSystemTx
+	if tx.IsSystemTx {
+		b[0] = 0x01
+	} else {
+		b[0] = 128
+	}
+	if _, err := w.Write(b[:1]); err != nil {
+		return err
+	}
+	// encode Data
+	if err := rlp.EncodeString(tx.Data, w, b); err != nil {
+		return err
+	}
+	return nil
+}
 
+// EncodeRLP implements rlp.Encoder, wrapping the typed transaction into an RLP string.
+func (tx DepositTransaction) EncodeRLP(w io.Writer) error {
+	payloadSize := tx.payloadSize()
+	envelopeSize := payloadSize
+	if payloadSize >= 56 {
+		envelopeSize += (bits.Len(uint(payloadSize)) + 7) / 8
+	}
+	// size of struct prefix and TxType
+	envelopeSize += 2
+	var b [33]byte
+	// envelope
+	if err := rlp.EncodeStringSizePrefix(envelopeSize, w, b[:]); err != nil {
+		return err
+	}
+	// encode TxType
+	b[0] = DepositTxType
+	if _, err := w.Write(b[:1]); err != nil {
+		return err
+	}
+	if err := tx.encodePayload(w, b[:], payloadSize); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -178,13 +303,16 @@ func (tx *DepositTransaction) DecodeRLP(s *rlp.Stream) error {
 		return fmt.Errorf("list header: %w", err)
 	}
 
-	tx.Nonce = 0xffff_ffff_ffff_fffd // DepositsNonce from Optimism
+	tx.Nonce = DepositsNonce
 
 	if b, err = s.Bytes(); err != nil {
 		return fmt.Errorf("read SourceHash: %w", err)
 	}
+	if len(b) != 32 {
+		return fmt.Errorf("wrong size for SourceHash: %d", len(b))
+	}
 	tx.SourceHash = new(libcommon.Hash)
-	tx.SourceHash.SetBytes(b)
+	copy((*tx.SourceHash)[:], b)
 
 	if b, err = s.Bytes(); err != nil {
 		return fmt.Errorf("read From: %w", err)
@@ -198,11 +326,13 @@ func (tx *DepositTransaction) DecodeRLP(s *rlp.Stream) error {
 	if b, err = s.Bytes(); err != nil {
 		return fmt.Errorf("read To: %w", err)
 	}
-	if len(b) != 20 {
+	if len(b) > 0 && len(b) != 20 {
 		return fmt.Errorf("wrong size for To: %d", len(b))
 	}
-	tx.To = &libcommon.Address{}
-	copy((*tx.To)[:], b)
+	if len(b) > 0 {
+		tx.To = &libcommon.Address{}
+		copy((*tx.To)[:], b)
+	}
 
 	if b, err = s.Uint256Bytes(); err != nil {
 		return fmt.Errorf("read Mint: %w", err)
@@ -229,134 +359,97 @@ func (tx *DepositTransaction) DecodeRLP(s *rlp.Stream) error {
 	if err = s.ListEnd(); err != nil {
 		return fmt.Errorf("close tx struct: %w", err)
 	}
-
-	log.Debug("MMDBG DecodeRLP successful", "tx", tx)
 	return nil
 }
 
 // AsMessage returns the transaction as a core.Message.
 func (tx DepositTransaction) AsMessage(s Signer, _ *big.Int, _ *chain.Rules) (Message, error) {
-	//log.Warn("MMDBG dtX AsMessage")
 	msg := Message{
 		sourceHash: tx.SourceHash,
 		nonce:      tx.Nonce,
 		from:       *tx.From,
 		gasLimit:   tx.GasLimit,
 		to:         tx.To,
-		mint:       *tx.Mint,
-		amount:     *tx.Value,
+		mint:       *tx.mint(),
+		amount:     *tx.value(),
 		isSystemTx: tx.IsSystemTx,
+		isDeposit:  true,
 		data:       tx.Data,
 		accessList: nil,
 		checkNonce: true,
 	}
-
-	var err error
-	//msg.from, err = tx.Sender(s)
-	log.Debug("MMDBG dtX AsMessage", "msg", msg)
-	return msg, err
+	return msg, nil
 }
 
+// WithSignature returns a copy of the transaction: deposits are not signed.
 func (tx *DepositTransaction) WithSignature(signer Signer, sig []byte) (Transaction, error) {
-	log.Warn("MMDBG dtX WithSignature")
-	cpy := tx.copy()
-	/*
-		r, s, v, err := signer.SignatureValues(tx, sig)
-		if err != nil {
-			return nil, err
-		}
-		cpy.R.Set(r)
-		cpy.S.Set(s)
-		cpy.V.Set(v)
-	*/
-	return cpy, nil
+	return tx.copy(), nil
 }
 
+// FakeSign returns a copy of the transaction: deposits are not signed.
 func (tx *DepositTransaction) FakeSign(address libcommon.Address) (Transaction, error) {
-	log.Warn("MMDBG dtX FakeSign")
-	cpy := tx.copy()
-	//	cpy.R.Set(u256.Num1)
-	//	cpy.S.Set(u256.Num1)
-	//	cpy.V.Set(u256.Num4)
-	//	cpy.from.Store(address)
-	return cpy, nil
+	return tx.copy(), nil
 }
 
 // Hash computes the hash (but not for signatures!)
 func (tx *DepositTransaction) Hash() libcommon.Hash {
-	//log.Warn("MMDBG dtX Hash")
-
 	if hash := tx.hash.Load(); hash != nil {
 		return *hash.(*libcommon.Hash)
 	}
-	hash := rlpHash([]interface{}{
-		tx.SourceHash,
-		tx.From,
+	var sourceHash libcommon.Hash
+	if tx.SourceHash != nil {
+		sourceHash = *tx.SourceHash
+	}
+	var from libcommon.Address
+	if tx.From != nil {
+		from = *tx.From
+	}
+	hash := prefixedRlpHash(DepositTxType, []interface{}{
+		sourceHash,
+		from,
 		tx.To,
-		tx.Mint,
-		tx.Value,
+		tx.mint(),
+		tx.value(),
 		tx.GasLimit,
 		tx.IsSystemTx,
 		tx.Data,
 	})
 	tx.hash.Store(&hash)
 	return hash
-
 }
 
+// SigningHash is empty: deposits are not signed.
 func (tx DepositTransaction) SigningHash(chainID *big.Int) libcommon.Hash {
-	return libcommon.Hash{} // FIXME
+	return libcommon.Hash{}
 }
 
-/*
-func (tx DepositTransaction) SigningHash(chainID *big.Int) common.Hash {
-	if chainID != nil && chainID.Sign() != 0 {
-		return rlpHash([]interface{}{
-			tx.Nonce,
-			tx.GasPrice,
-			tx.Gas,
-			tx.To,
-			tx.Value,
-			tx.Data,
-			chainID, uint(0), uint(0),
-		})
-	}
-	return rlpHash([]interface{}{
-		tx.Nonce,
-		tx.GasPrice,
-		tx.Gas,
-		tx.To,
-		tx.Value,
-		tx.Data,
-	})
-}
-*/
-
 func (tx DepositTransaction) Type() byte { return DepositTxType }
 
 func (tx DepositTransaction) RawSignatureValues() (*uint256.Int, *uint256.Int, *uint256.Int) {
-	log.Warn("MMDBG dtX RawSignatureValues")
 	return uint256.NewInt(0), uint256.NewInt(0), uint256.NewInt(0)
 }
 
+// GetChainID is zero: deposits are not signed for a particular chain.
 func (tx DepositTransaction) GetChainID() *uint256.Int {
-	log.Warn("MMDBG dtX GetChainID")
-	return new(uint256.Int).SetUint64(901) // FIXME
+	return new(uint256.Int)
 }
+
 func (tx DepositTransaction) GetSender() (libcommon.Address, bool) {
 	return *tx.From, true
 }
+
 func (tx DepositTransaction) GetTo() *libcommon.Address {
 	return tx.To
 }
 
 func (tx DepositTransaction) GetValue() *uint256.Int {
-	return tx.Value
+	return tx.value()
 }
 
 func (tx DepositTransaction) IsContractDeploy() bool {
-	return false
+	return tx.To == nil
 }
+
 func (tx DepositTransaction) IsStarkNet() bool {
 	return false
 }
@@ -364,7 +457,7 @@ func (tx DepositTransaction) IsStarkNet() bool {
 func (tx *DepositTransaction) Sender(signer Signer) (libcommon.Address, error) {
 	return *tx.From, nil
 }
+
+// SetSender is a no-op, the sender of a deposit is part of the transaction.
 func (tx *DepositTransaction) SetSender(addr libcommon.Address) {
-	log.Warn("MMDBG dtX SetSender")
-	// NOP - FIXME? ct.from.Store(addr)
 }
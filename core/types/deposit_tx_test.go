@@ -0,0 +1,179 @@
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// depositTestVectors are deposits encoded as specified at
+// https://github.com/ethereum-optimism/optimism/blob/develop/specs/deposits.md#the-deposited-transaction-type:
+// 0x7E || rlp([sourceHash, from, to, mint, value, gas, isSystemTx, data]). The first one is the deposit
+// of the op-geth tracer tests (eth/tracers/internal/tracetest/testdata/call_tracer/deposit.json).
+// The hashes are the keccak256 of the encodings, computed independently of this package.
+func depositTestVectors() []struct {
+	name string
+	tx   *DepositTransaction
+	enc  string
+	hash libcommon.Hash
+} {
+	sourceHash := libcommon.HexToHash("0xb4f9f798a5fe956d1b79c3eff355febf9e1039a7440948845536982cb62aa031")
+	from := libcommon.HexToAddress("0xbc339e628e6fe32c39e84392d087567b2743ea35")
+	creationSourceHash := libcommon.HexToHash("0x01")
+	creator := libcommon.HexToAddress("0x3c44cdddb6a900fa2b585dd299e03d12fa4293bc")
+	depositor := libcommon.HexToAddress("0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001")
+	l1Block := libcommon.HexToAddress("0x4200000000000000000000000000000000000015")
+	return []struct {
+		name string
+		tx   *DepositTransaction
+		enc  string
+		hash libcommon.Hash
+	}{
+		{
+			name: "op-geth deposit",
+			tx: &DepositTransaction{
+				SourceHash: &sourceHash,
+				Nonce:      DepositsNonce,
+				From:       &from,
+				To:         &from,
+				Mint:       uint256.NewInt(0),
+				Value:      uint256.NewInt(0x1aa535d3d0c000),
+				GasLimit:   200_000,
+				Data:       []byte{0x00},
+			},
+			enc:  "7ef85aa0b4f9f798a5fe956d1b79c3eff355febf9e1039a7440948845536982cb62aa03194bc339e628e6fe32c39e84392d087567b2743ea3594bc339e628e6fe32c39e84392d087567b2743ea3580871aa535d3d0c00083030d408000",
+			hash: libcommon.HexToHash("0xa47277b3d92384e70906bed705723a15c91d9f0fff2fc602824d14ea201801c1"),
+		},
+		{
+			name: "contract creation",
+			tx: &DepositTransaction{
+				SourceHash: &creationSourceHash,
+				Nonce:      DepositsNonce,
+				From:       &creator,
+				Mint:       uint256.NewInt(1_000_000_000_000_000_000),
+				Value:      uint256.NewInt(7),
+				GasLimit:   500_000,
+				Data:       []byte{0x60, 0x00},
+			},
+			enc:  "7ef849a00000000000000000000000000000000000000000000000000000000000000001943c44cdddb6a900fa2b585dd299e03d12fa4293bc80880de0b6b3a7640000078307a12080826000",
+			hash: libcommon.HexToHash("0x669de6177ec3dd5e03ae4c0c3e9068f7c50aa1db834cc874f8872f2940626a3b"),
+		},
+		{
+			name: "system deposit",
+			tx: &DepositTransaction{
+				SourceHash: &creationSourceHash,
+				Nonce:      DepositsNonce,
+				From:       &depositor,
+				To:         &l1Block,
+				Mint:       uint256.NewInt(0),
+				Value:      uint256.NewInt(0),
+				GasLimit:   1_000_000,
+				IsSystemTx: true,
+				Data:       []byte{0x01, 0x5d, 0x8e, 0xb9},
+			},
+			enc:  "7ef857a0000000000000000000000000000000000000000000000000000000000000000194deaddeaddeaddeaddeaddeaddeaddeaddead00019442000000000000000000000000000000000000158080830f42400184015d8eb9",
+			hash: libcommon.HexToHash("0xa7d84b541269850741af614d896aa6f53712af662d1e41473f9f8b9ae36e19cd"),
+		},
+	}
+}
+
+func TestDepositTxEncoding(t *testing.T) {
+	for _, tt := range depositTestVectors() {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, tt.tx.MarshalBinary(&buf))
+			require.Equal(t, tt.enc, common.Bytes2Hex(buf.Bytes()))
+			require.Equal(t, tt.tx.EncodingSize(), buf.Len())
+			require.Equal(t, tt.hash, tt.tx.Hash())
+
+			decoded, err := UnmarshalTransactionFromBinary(common.FromHex(tt.enc))
+			require.NoError(t, err)
+			require.Equal(t, tt.hash, decoded.Hash())
+			require.Equal(t, tt.tx, decoded)
+
+			// Inside blocks typed transactions are wrapped into an RLP string
+			buf.Reset()
+			require.NoError(t, tt.tx.EncodeRLP(&buf))
+			decoded, err = DecodeTransaction(rlp.NewStream(bytes.NewReader(buf.Bytes()), uint64(buf.Len())))
+			require.NoError(t, err)
+			require.Equal(t, tt.hash, decoded.Hash())
+		})
+	}
+}
+
+func TestDepositTxNilAmounts(t *testing.T) {
+	from := libcommon.HexToAddress("0xbc339e628e6fe32c39e84392d087567b2743ea35")
+	zero := &DepositTransaction{
+		SourceHash: &libcommon.Hash{},
+		Nonce:      DepositsNonce,
+		From:       &from,
+		To:         &from,
+		Mint:       uint256.NewInt(0),
+		Value:      uint256.NewInt(0),
+		GasLimit:   200_000,
+	}
+	// Nil mint and value are the same as zero
+	nilAmounts := zero.copy()
+	nilAmounts.Mint, nilAmounts.Value = nil, nil
+
+	require.True(t, nilAmounts.Cost().IsZero())
+	require.True(t, nilAmounts.GetValue().IsZero())
+	require.Equal(t, zero.EncodingSize(), nilAmounts.EncodingSize())
+	require.Equal(t, zero.Hash(), nilAmounts.Hash())
+
+	var want, got bytes.Buffer
+	require.NoError(t, zero.MarshalBinary(&want))
+	require.NoError(t, nilAmounts.MarshalBinary(&got))
+	require.Equal(t, want.Bytes(), got.Bytes())
+
+	msg, err := nilAmounts.AsMessage(*LatestSignerForChainID(nil), nil, nil)
+	require.NoError(t, err)
+	require.True(t, msg.Mint().IsZero())
+	require.True(t, msg.Value().IsZero())
+}
+
+func TestDepositTxContractCreation(t *testing.T) {
+	from := libcommon.HexToAddress("0x3c44cdddb6a900fa2b585dd299e03d12fa4293bc")
+	tx := &DepositTransaction{
+		SourceHash: &libcommon.Hash{},
+		Nonce:      DepositsNonce,
+		From:       &from,
+		Value:      uint256.NewInt(7),
+		GasLimit:   500_000,
+		Data:       []byte{0x60, 0x00},
+	}
+	require.True(t, tx.IsContractDeploy())
+	require.Equal(t, uint256.NewInt(7), tx.Cost())
+
+	msg, err := tx.AsMessage(*LatestSignerForChainID(nil), nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, msg.To())
+	require.True(t, msg.IsDepositTx())
+	require.True(t, msg.Mint().IsZero())
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.MarshalBinary(&buf))
+	decoded, err := UnmarshalTransactionFromBinary(buf.Bytes())
+	require.NoError(t, err)
+	require.Nil(t, decoded.GetTo())
+	require.Equal(t, DepositsNonce, decoded.GetNonce())
+	require.Equal(t, tx.Hash(), decoded.Hash())
+
+	// The contract is addressed by the nonce of the sender in the state, recorded in the receipt
+	// since Regolith: keccak256(rlp([from, 3]))[12:]
+	nonce := uint64(3)
+	receipts := Receipts{{CumulativeGasUsed: 53_000, DepositNonce: &nonce}}
+	require.NoError(t, receipts.DeriveFields(libcommon.Hash{}, 1, Transactions{tx}, []libcommon.Address{from}))
+	require.Equal(t, libcommon.HexToAddress("0xbc9129dc0487fc2e169941c75aabc539f208fb01"), receipts[0].ContractAddress)
+
+	// Before Regolith the nonce is only known by executing the block
+	receipts = Receipts{{CumulativeGasUsed: 53_000}}
+	require.NoError(t, receipts.DeriveFields(libcommon.Hash{}, 1, Transactions{tx}, []libcommon.Address{from}))
+	require.Equal(t, libcommon.Address{}, receipts[0].ContractAddress)
+}
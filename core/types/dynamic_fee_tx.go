@@ -402,6 +402,8 @@ func (tx DynamicFeeTransaction) AsMessage(s Signer, baseFee *big.Int, rules *cha
 		msg.gasPrice.Set(tx.FeeCap)
 	}
 
+	msg.rollupMarshal = tx.MarshalBinary
+
 	var err error
 	msg.from, err = tx.Sender(s)
 	return msg, err
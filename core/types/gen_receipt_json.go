@@ -7,8 +7,7 @@ import (
 	"errors"
 	"math/big"
 
-	libcommon "github.com/ledgerwatch/erigon-lib/common"
-
+	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 )
 
@@ -17,18 +16,23 @@ var _ = (*receiptMarshaling)(nil)
 // MarshalJSON marshals as JSON.
 func (r Receipt) MarshalJSON() ([]byte, error) {
 	type Receipt struct {
-		Type              hexutil.Uint64    `json:"type,omitempty"`
-		PostState         hexutil.Bytes     `json:"root"`
-		Status            hexutil.Uint64    `json:"status"`
-		CumulativeGasUsed hexutil.Uint64    `json:"cumulativeGasUsed" gencodec:"required"`
-		Bloom             Bloom             `json:"logsBloom"         gencodec:"required"`
-		Logs              []*Log            `json:"logs"              gencodec:"required"`
-		TxHash            libcommon.Hash    `json:"transactionHash" gencodec:"required"`
-		ContractAddress   libcommon.Address `json:"contractAddress"`
-		GasUsed           hexutil.Uint64    `json:"gasUsed" gencodec:"required"`
-		BlockHash         libcommon.Hash    `json:"blockHash,omitempty"`
-		BlockNumber       *hexutil.Big      `json:"blockNumber,omitempty"`
-		TransactionIndex  hexutil.Uint      `json:"transactionIndex"`
+		Type              hexutil.Uint64  `json:"type,omitempty"`
+		PostState         hexutil.Bytes   `json:"root" codec:"1"`
+		Status            hexutil.Uint64  `json:"status" codec:"2"`
+		CumulativeGasUsed hexutil.Uint64  `json:"cumulativeGasUsed" gencodec:"required" codec:"3"`
+		Bloom             Bloom           `json:"logsBloom"         gencodec:"required" codec:"-"`
+		Logs              Logs            `json:"logs"              gencodec:"required" codec:"-"`
+		DepositNonce      *hexutil.Uint64 `json:"depositNonce,omitempty" codec:"-"`
+		TxHash            common.Hash     `json:"transactionHash" gencodec:"required" codec:"-"`
+		ContractAddress   common.Address  `json:"contractAddress" codec:"-"`
+		GasUsed           hexutil.Uint64  `json:"gasUsed" gencodec:"required" codec:"-"`
+		BlockHash         common.Hash     `json:"blockHash,omitempty" codec:"-"`
+		BlockNumber       *hexutil.Big    `json:"blockNumber,omitempty" codec:"-"`
+		TransactionIndex  hexutil.Uint    `json:"transactionIndex" codec:"-"`
+		L1GasPrice        *hexutil.Big    `json:"l1GasPrice,omitempty" codec:"-"`
+		L1GasUsed         *hexutil.Big    `json:"l1GasUsed,omitempty" codec:"-"`
+		L1Fee             *hexutil.Big    `json:"l1Fee,omitempty" codec:"-"`
+		FeeScalar         *big.Float      `json:"l1FeeScalar,omitempty" codec:"-"`
 	}
 	var enc Receipt
 	enc.Type = hexutil.Uint64(r.Type)
@@ -37,30 +41,40 @@ func (r Receipt) MarshalJSON() ([]byte, error) {
 	enc.CumulativeGasUsed = hexutil.Uint64(r.CumulativeGasUsed)
 	enc.Bloom = r.Bloom
 	enc.Logs = r.Logs
+	enc.DepositNonce = (*hexutil.Uint64)(r.DepositNonce)
 	enc.TxHash = r.TxHash
 	enc.ContractAddress = r.ContractAddress
 	enc.GasUsed = hexutil.Uint64(r.GasUsed)
 	enc.BlockHash = r.BlockHash
 	enc.BlockNumber = (*hexutil.Big)(r.BlockNumber)
 	enc.TransactionIndex = hexutil.Uint(r.TransactionIndex)
+	enc.L1GasPrice = (*hexutil.Big)(r.L1GasPrice)
+	enc.L1GasUsed = (*hexutil.Big)(r.L1GasUsed)
+	enc.L1Fee = (*hexutil.Big)(r.L1Fee)
+	enc.FeeScalar = r.FeeScalar
 	return json.Marshal(&enc)
 }
 
 // UnmarshalJSON unmarshals from JSON.
 func (r *Receipt) UnmarshalJSON(input []byte) error {
 	type Receipt struct {
-		Type              *hexutil.Uint64    `json:"type,omitempty"`
-		PostState         *hexutil.Bytes     `json:"root"`
-		Status            *hexutil.Uint64    `json:"status"`
-		CumulativeGasUsed *hexutil.Uint64    `json:"cumulativeGasUsed" gencodec:"required"`
-		Bloom             *Bloom             `json:"logsBloom"         gencodec:"required"`
-		Logs              []*Log             `json:"logs"              gencodec:"required"`
-		TxHash            *libcommon.Hash    `json:"transactionHash" gencodec:"required"`
-		ContractAddress   *libcommon.Address `json:"contractAddress"`
-		GasUsed           *hexutil.Uint64    `json:"gasUsed" gencodec:"required"`
-		BlockHash         *libcommon.Hash    `json:"blockHash,omitempty"`
-		BlockNumber       *hexutil.Big       `json:"blockNumber,omitempty"`
-		TransactionIndex  *hexutil.Uint      `json:"transactionIndex"`
+		Type              *hexutil.Uint64 `json:"type,omitempty"`
+		PostState         *hexutil.Bytes  `json:"root" codec:"1"`
+		Status            *hexutil.Uint64 `json:"status" codec:"2"`
+		CumulativeGasUsed *hexutil.Uint64 `json:"cumulativeGasUsed" gencodec:"required" codec:"3"`
+		Bloom             *Bloom          `json:"logsBloom"         gencodec:"required" codec:"-"`
+		Logs              *Logs           `json:"logs"              gencodec:"required" codec:"-"`
+		DepositNonce      *hexutil.Uint64 `json:"depositNonce,omitempty" codec:"-"`
+		TxHash            *common.Hash    `json:"transactionHash" gencodec:"required" codec:"-"`
+		ContractAddress   *common.Address `json:"contractAddress" codec:"-"`
+		GasUsed           *hexutil.Uint64 `json:"gasUsed" gencodec:"required" codec:"-"`
+		BlockHash         *common.Hash    `json:"blockHash,omitempty" codec:"-"`
+		BlockNumber       *hexutil.Big    `json:"blockNumber,omitempty" codec:"-"`
+		TransactionIndex  *hexutil.Uint   `json:"transactionIndex" codec:"-"`
+		L1GasPrice        *hexutil.Big    `json:"l1GasPrice,omitempty" codec:"-"`
+		L1GasUsed         *hexutil.Big    `json:"l1GasUsed,omitempty" codec:"-"`
+		L1Fee             *hexutil.Big    `json:"l1Fee,omitempty" codec:"-"`
+		FeeScalar         *big.Float      `json:"l1FeeScalar,omitempty" codec:"-"`
 	}
 	var dec Receipt
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -86,7 +100,10 @@ func (r *Receipt) UnmarshalJSON(input []byte) error {
 	if dec.Logs == nil {
 		return errors.New("missing required field 'logs' for Receipt")
 	}
-	r.Logs = dec.Logs
+	r.Logs = *dec.Logs
+	if dec.DepositNonce != nil {
+		r.DepositNonce = (*uint64)(dec.DepositNonce)
+	}
 	if dec.TxHash == nil {
 		return errors.New("missing required field 'transactionHash' for Receipt")
 	}
@@ -107,5 +124,17 @@ func (r *Receipt) UnmarshalJSON(input []byte) error {
 	if dec.TransactionIndex != nil {
 		r.TransactionIndex = uint(*dec.TransactionIndex)
 	}
+	if dec.L1GasPrice != nil {
+		r.L1GasPrice = (*big.Int)(dec.L1GasPrice)
+	}
+	if dec.L1GasUsed != nil {
+		r.L1GasUsed = (*big.Int)(dec.L1GasUsed)
+	}
+	if dec.L1Fee != nil {
+		r.L1Fee = (*big.Int)(dec.L1Fee)
+	}
+	if dec.FeeScalar != nil {
+		r.FeeScalar = dec.FeeScalar
+	}
 	return nil
 }
@@ -368,6 +368,8 @@ func (tx LegacyTx) AsMessage(s Signer, _ *big.Int, _ *chain.Rules) (Message, err
 		checkNonce: true,
 	}
 
+	msg.rollupMarshal = tx.MarshalBinary
+
 	var err error
 	msg.from, err = tx.Sender(s)
 	return msg, err
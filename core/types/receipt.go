@@ -28,7 +28,6 @@ import (
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/rlp"
-	"github.com/ledgerwatch/log/v3"
 )
 
 // go:generate gencodec -type Receipt -field-override receiptMarshaling -out gen_receipt_json.go
@@ -58,6 +57,11 @@ type Receipt struct {
 	Bloom             Bloom  `json:"logsBloom"         gencodec:"required" codec:"-"`
 	Logs              Logs   `json:"logs"              gencodec:"required" codec:"-"`
 
+	// DepositNonce is the nonce of the sender of a deposit transaction at the time it was
+	// executed. It was introduced in Regolith and is only set on Regolith deposit receipts.
+	// It isn't part of the codec, so that other receipts keep their stored encoding.
+	DepositNonce *uint64 `json:"depositNonce,omitempty" codec:"-"`
+
 	// Implementation fields: These fields are added by geth when processing a transaction.
 	// They are stored in the chain database.
 	TxHash          libcommon.Hash    `json:"transactionHash" gencodec:"required" codec:"-"`
@@ -69,6 +73,13 @@ type Receipt struct {
 	BlockHash        libcommon.Hash `json:"blockHash,omitempty" codec:"-"`
 	BlockNumber      *big.Int       `json:"blockNumber,omitempty" codec:"-"`
 	TransactionIndex uint           `json:"transactionIndex" codec:"-"`
+
+	// Rollup L1 data fee fields: These fields are derived from the L1 attributes deposit of the
+	// containing block and are not stored in the chain database.
+	L1GasPrice *big.Int   `json:"l1GasPrice,omitempty" codec:"-"`
+	L1GasUsed  *big.Int   `json:"l1GasUsed,omitempty" codec:"-"`
+	L1Fee      *big.Int   `json:"l1Fee,omitempty" codec:"-"`
+	FeeScalar  *big.Float `json:"l1FeeScalar,omitempty" codec:"-"`
 }
 
 type receiptMarshaling struct {
@@ -79,6 +90,10 @@ type receiptMarshaling struct {
 	GasUsed           hexutil.Uint64
 	BlockNumber       *hexutil.Big
 	TransactionIndex  hexutil.Uint
+	DepositNonce      *hexutil.Uint64
+	L1GasPrice        *hexutil.Big
+	L1GasUsed         *hexutil.Big
+	L1Fee             *hexutil.Big
 }
 
 // receiptRLP is the consensus encoding of a receipt.
@@ -89,11 +104,22 @@ type receiptRLP struct {
 	Logs              []*Log
 }
 
+// depositReceiptRLP is the consensus encoding of a deposit receipt.
+type depositReceiptRLP struct {
+	PostStateOrStatus []byte
+	CumulativeGasUsed uint64
+	Bloom             Bloom
+	Logs              []*Log
+	// DepositNonce was introduced in Regolith to store the actual nonce used by deposit transactions.
+	DepositNonce *uint64 `rlp:"optional"`
+}
+
 // storedReceiptRLP is the storage encoding of a receipt.
 type storedReceiptRLP struct {
 	PostStateOrStatus []byte
 	CumulativeGasUsed uint64
 	Logs              []*LogForStorage
+	DepositNonce      *uint64 `rlp:"optional"`
 }
 
 // v4StoredReceiptRLP is the storage encoding of a receipt used in database version 4.
@@ -140,13 +166,21 @@ func (r Receipt) EncodeRLP(w io.Writer) error {
 		return rlp.Encode(w, data)
 	}
 	buf := new(bytes.Buffer)
-	buf.WriteByte(r.Type)
-	if err := rlp.Encode(buf, data); err != nil {
+	if err := r.encodeTyped(data, buf); err != nil {
 		return err
 	}
 	return rlp.Encode(w, buf.Bytes())
 }
 
+// encodeTyped writes the canonical encoding of a typed receipt to w.
+func (r *Receipt) encodeTyped(data *receiptRLP, w *bytes.Buffer) error {
+	w.WriteByte(r.Type)
+	if r.Type == DepositTxType {
+		return rlp.Encode(w, &depositReceiptRLP{data.PostStateOrStatus, data.CumulativeGasUsed, data.Bloom, data.Logs, r.DepositNonce})
+	}
+	return rlp.Encode(w, data)
+}
+
 func (r *Receipt) decodePayload(s *rlp.Stream) error {
 	_, err := s.List()
 	if err != nil {
@@ -215,6 +249,15 @@ func (r *Receipt) decodePayload(s *rlp.Stream) error {
 	if err = s.ListEnd(); err != nil {
 		return fmt.Errorf("close Logs: %w", err)
 	}
+	if r.Type == DepositTxType {
+		// DepositNonce is only present from Regolith on
+		nonce, err := s.Uint()
+		if err == nil {
+			r.DepositNonce = &nonce
+		} else if !errors.Is(err, rlp.EOL) {
+			return fmt.Errorf("read DepositNonce: %w", err)
+		}
+	}
 	if err := s.ListEnd(); err != nil {
 		return fmt.Errorf("close receipt payload: %w", err)
 	}
@@ -247,13 +290,10 @@ func (r *Receipt) DecodeRLP(s *rlp.Stream) error {
 		}
 		r.Type = b[0]
 		switch r.Type {
-		case AccessListTxType, DynamicFeeTxType:
+		case AccessListTxType, DynamicFeeTxType, DepositTxType:
 			if err := r.decodePayload(s); err != nil {
 				return err
 			}
-		case DepositTxType:
-			log.Debug("MMDBG receipt DepositTxType handler")
-			return ErrTxTypeNotSupported
 		default:
 			return ErrTxTypeNotSupported
 		}
@@ -307,6 +347,12 @@ func (r *Receipt) Copy() *Receipt {
 	blockHash := libcommon.BytesToHash(r.BlockHash.Bytes())
 	blockNumber := big.NewInt(0).Set(r.BlockNumber)
 
+	var depositNonce *uint64
+	if r.DepositNonce != nil {
+		nonce := *r.DepositNonce
+		depositNonce = &nonce
+	}
+
 	return &Receipt{
 		Type:              r.Type,
 		PostState:         postState,
@@ -314,12 +360,17 @@ func (r *Receipt) Copy() *Receipt {
 		CumulativeGasUsed: r.CumulativeGasUsed,
 		Bloom:             bloom,
 		Logs:              logs,
+		DepositNonce:      depositNonce,
 		TxHash:            txHash,
 		ContractAddress:   contractAddress,
 		GasUsed:           r.GasUsed,
 		BlockHash:         blockHash,
 		BlockNumber:       blockNumber,
 		TransactionIndex:  r.TransactionIndex,
+		L1GasPrice:        r.L1GasPrice,
+		L1GasUsed:         r.L1GasUsed,
+		L1Fee:             r.L1Fee,
+		FeeScalar:         r.FeeScalar,
 	}
 }
 
@@ -336,6 +387,7 @@ func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
 		PostStateOrStatus: (*Receipt)(r).statusEncoding(),
 		CumulativeGasUsed: r.CumulativeGasUsed,
 		Logs:              make([]*LogForStorage, len(r.Logs)),
+		DepositNonce:      r.DepositNonce,
 	}
 	for i, log := range r.Logs {
 		enc.Logs[i] = (*LogForStorage)(log)
@@ -376,6 +428,7 @@ func decodeStoredReceiptRLP(r *ReceiptForStorage, blob []byte) error {
 	for i, log := range stored.Logs {
 		r.Logs[i] = (*Log)(log)
 	}
+	r.DepositNonce = stored.DepositNonce
 	//r.Bloom = CreateBloom(Receipts{(*Receipt)(r)})
 
 	return nil
@@ -446,6 +499,10 @@ func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 		if err := rlp.Encode(w, data); err != nil {
 			panic(err)
 		}
+	case DepositTxType:
+		if err := r.encodeTyped(data, w); err != nil {
+			panic(err)
+		}
 	default:
 		// For unsupported types, write nothing. Since this is for
 		// DeriveSha, the error will be caught matching the derived hash
@@ -453,6 +510,36 @@ func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 	}
 }
 
+// creationNonce returns the nonce the contract created by tx is addressed by. Deposits carry
+// no nonce, the sender's nonce in the state is used: it is recorded in the receipt since
+// Regolith, before that it is only known by executing the block.
+func (r *Receipt) creationNonce(tx Transaction) (uint64, bool) {
+	if tx.Type() != DepositTxType {
+		return tx.GetNonce(), true
+	}
+	if r.DepositNonce == nil {
+		return 0, false
+	}
+	return *r.DepositNonce, true
+}
+
+// CanDeriveContractAddresses reports whether the addresses of the contracts created by txs can
+// be derived from the receipts, which isn't the case for deposits before Regolith.
+func (r Receipts) CanDeriveContractAddresses(txs Transactions) bool {
+	for i, tx := range txs {
+		if i >= len(r) {
+			break
+		}
+		if tx.GetTo() != nil {
+			continue
+		}
+		if _, ok := r[i].creationNonce(tx); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // DeriveFields fills the receipts with their computed fields based on consensus
 // data and contextual infos like containing block and transactions.
 func (r Receipts) DeriveFields(hash libcommon.Hash, number uint64, txs Transactions, senders []libcommon.Address) error {
@@ -478,7 +565,11 @@ func (r Receipts) DeriveFields(hash libcommon.Hash, number uint64, txs Transacti
 			// If one wants to deploy a contract, one needs to send a transaction that does not have `To` field
 			// and then the address of the contract one is creating this way will depend on the `tx.From`
 			// and the nonce of the creating account (which is `tx.From`).
-			r[i].ContractAddress = crypto.CreateAddress(senders[i], txs[i].GetNonce())
+			if nonce, ok := r[i].creationNonce(txs[i]); ok {
+				r[i].ContractAddress = crypto.CreateAddress(senders[i], nonce)
+			} else {
+				r[i].ContractAddress = libcommon.Address{}
+			}
 		}
 		// The used gas can be calculated based on previous r
 		if i == 0 {
@@ -289,6 +289,87 @@ func TestTypedReceiptEncodingDecoding(t *testing.T) {
 	}
 }
 
+// TestDepositReceiptEncodingDecoding checks that deposit receipts round-trip with and
+// without the Regolith deposit nonce.
+func TestDepositReceiptEncodingDecoding(t *testing.T) {
+	nonce := uint64(7)
+	for _, depositNonce := range []*uint64{nil, &nonce} {
+		want := &Receipt{
+			Type:              DepositTxType,
+			Status:            ReceiptStatusSuccessful,
+			CumulativeGasUsed: 21000,
+			Logs: []*Log{
+				{Address: libcommon.BytesToAddress([]byte{0x11}), Topics: []libcommon.Hash{{0x22}}, Data: []byte{0x33}},
+			},
+			DepositNonce: depositNonce,
+		}
+		want.Bloom = CreateBloom(Receipts{want})
+
+		enc, err := rlp.EncodeToBytes(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Receipt
+		if err := rlp.DecodeBytes(enc, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Type != DepositTxType {
+			t.Fatalf("got type %x, want %x", got.Type, DepositTxType)
+		}
+		if !reflect.DeepEqual(got.DepositNonce, want.DepositNonce) {
+			t.Fatalf("got deposit nonce %v, want %v", got.DepositNonce, want.DepositNonce)
+		}
+		if !reflect.DeepEqual(got.Logs, want.Logs) {
+			t.Fatalf("got logs %v, want %v", got.Logs, want.Logs)
+		}
+
+		// The receipt root commits to the deposit nonce
+		var buf bytes.Buffer
+		Receipts{want}.EncodeIndex(0, &buf)
+		if buf.Bytes()[0] != DepositTxType {
+			t.Fatalf("got type prefix %x, want %x", buf.Bytes()[0], DepositTxType)
+		}
+		if !bytes.Equal(buf.Bytes(), enc[len(enc)-buf.Len():]) {
+			t.Fatalf("consensus encoding mismatch: %x vs %x", buf.Bytes(), enc)
+		}
+	}
+}
+
+func TestDeriveFieldsDepositContractAddress(t *testing.T) {
+	from := libcommon.HexToAddress("0x3c44cdddb6a900fa2b585dd299e03d12fa4293bc")
+	nonce := uint64(7)
+	txs := Transactions{&DepositTransaction{
+		SourceHash: &libcommon.Hash{},
+		Nonce:      DepositsNonce,
+		From:       &from,
+		Value:      u256.Num0,
+		GasLimit:   500_000,
+		Data:       []byte{0x60, 0x00},
+	}}
+	receipts := Receipts{&Receipt{Type: DepositTxType, Status: ReceiptStatusSuccessful, CumulativeGasUsed: 50_000, DepositNonce: &nonce}}
+	if !receipts.CanDeriveContractAddresses(txs) {
+		t.Fatal("contract address of a Regolith deposit can't be derived")
+	}
+	if err := receipts.DeriveFields(libcommon.Hash{}, 1, txs, []libcommon.Address{from}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := receipts[0].ContractAddress, crypto.CreateAddress(from, nonce); got != want {
+		t.Fatalf("got contract address %x, want %x", got, want)
+	}
+
+	// Before Regolith the nonce isn't recorded, the deposit nonce mustn't be used instead
+	receipts[0].DepositNonce = nil
+	if receipts.CanDeriveContractAddresses(txs) {
+		t.Fatal("contract address of a pre-Regolith deposit can be derived")
+	}
+	if err := receipts.DeriveFields(libcommon.Hash{}, 1, txs, []libcommon.Address{from}); err != nil {
+		t.Fatal(err)
+	}
+	if got := receipts[0].ContractAddress; got != (libcommon.Address{}) {
+		t.Fatalf("got contract address %x, want none", got)
+	}
+}
+
 func clearComputedFieldsOnReceipts(t *testing.T, receipts Receipts) {
 	t.Helper()
 
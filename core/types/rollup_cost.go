@@ -0,0 +1,136 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+)
+
+// Storage layout of the L1Block predeploy used to price the L1 data-availability fee,
+// as specified at https://github.com/ethereum-optimism/optimism/blob/develop/specs/exec-engine.md#l1-cost-fees-l1-fee-vault
+var (
+	L1BaseFeeSlot = libcommon.BigToHash(big.NewInt(1))
+	OverheadSlot  = libcommon.BigToHash(big.NewInt(5))
+	ScalarSlot    = libcommon.BigToHash(big.NewInt(6))
+)
+
+// l1InfoFuncSignature is the selector of L1Block.setL1BlockValues, which is called by the
+// L1 attributes deposit transaction at the start of every L2 block.
+var l1InfoFuncSignature = []byte{0x01, 0x5d, 0x8e, 0xb9}
+
+// l1InfoLen is the length of the setL1BlockValues calldata: selector followed by 8 words
+// (number, timestamp, basefee, hash, sequenceNumber, batcherHash, l1FeeOverhead, l1FeeScalar).
+const l1InfoLen = 4 + 8*32
+
+var feeScalarDenominator = uint256.NewInt(1_000_000)
+
+// preRegolithDataGasPadding is added to the rollup data gas of the transactions before Regolith,
+// to account for their signature and RLP overhead: 68 non-zero bytes.
+const preRegolithDataGasPadding = 68 * 16
+
+// L1CostState is the part of the intra-block state needed to price the L1 data fee.
+type L1CostState interface {
+	GetState(address libcommon.Address, slot *libcommon.Hash, outValue *uint256.Int)
+}
+
+// RollupDataGas returns the L1 calldata gas of the canonical encoding of tx:
+// 4 gas per zero byte and 16 gas per non-zero byte. Deposits do not pay an L1 fee.
+// See L1DataGas for the gas actually charged.
+func RollupDataGas(tx Transaction) uint64 {
+	if tx.Type() == DepositTxType {
+		return 0
+	}
+	return rollupDataGas(tx.MarshalBinary)
+}
+
+func rollupDataGas(marshal func(w io.Writer) error) uint64 {
+	var buf bytes.Buffer
+	if err := marshal(&buf); err != nil {
+		return 0
+	}
+	var zeroes, ones uint64
+	for _, b := range buf.Bytes() {
+		if b == 0 {
+			zeroes++
+		} else {
+			ones++
+		}
+	}
+	return zeroes*4 + ones*16
+}
+
+// L1DataGas returns the L1 calldata gas charged for a message of the given rollup data gas,
+// which is padded before Regolith. Messages without rollup data gas are not charged.
+func L1DataGas(rollupDataGas uint64, regolith bool) uint64 {
+	if rollupDataGas == 0 || regolith {
+		return rollupDataGas
+	}
+	return rollupDataGas + preRegolithDataGasPadding
+}
+
+// L1Cost computes the L1 data fee: (rollupDataGas + overhead) * l1BaseFee * scalar / 1e6.
+func L1Cost(rollupDataGas uint64, l1BaseFee, overhead, scalar *uint256.Int) *uint256.Int {
+	l1GasUsed := new(uint256.Int).SetUint64(rollupDataGas)
+	l1GasUsed.Add(l1GasUsed, overhead)
+	l1Cost := l1GasUsed.Mul(l1GasUsed, l1BaseFee)
+	l1Cost.Mul(l1Cost, scalar)
+	return l1Cost.Div(l1Cost, feeScalarDenominator)
+}
+
+// L1CostFromState reads the L1 fee parameters from the L1Block predeploy at l1Block and returns
+// the L1 data fee of a message with the given rollup data gas, or nil if there is nothing to charge.
+func L1CostFromState(ibs L1CostState, l1Block libcommon.Address, rollupDataGas uint64, regolith bool) *uint256.Int {
+	if rollupDataGas == 0 {
+		return nil
+	}
+	var l1BaseFee, overhead, scalar uint256.Int
+	ibs.GetState(l1Block, &L1BaseFeeSlot, &l1BaseFee)
+	ibs.GetState(l1Block, &OverheadSlot, &overhead)
+	ibs.GetState(l1Block, &ScalarSlot, &scalar)
+	return L1Cost(L1DataGas(rollupDataGas, regolith), &l1BaseFee, &overhead, &scalar)
+}
+
+// ExtractL1GasParams decodes the L1 base fee, fee overhead and fee scalar from the calldata
+// of the L1 attributes deposit transaction.
+func ExtractL1GasParams(data []byte) (l1BaseFee, overhead, scalar *uint256.Int, err error) {
+	if len(data) != l1InfoLen {
+		return nil, nil, nil, fmt.Errorf("expected L1 info calldata of %d bytes, got %d", l1InfoLen, len(data))
+	}
+	if !bytes.Equal(data[:4], l1InfoFuncSignature) {
+		return nil, nil, nil, fmt.Errorf("unexpected L1 info function selector %x", data[:4])
+	}
+	word := func(i int) *uint256.Int {
+		offset := 4 + i*32
+		return new(uint256.Int).SetBytes(data[offset : offset+32])
+	}
+	return word(2), word(6), word(7), nil
+}
+
+// DeriveL1CostFields fills the L1 fee fields of non-deposit receipts from the L1 attributes
+// deposit which opens every rollup block. Blocks without such a deposit are left untouched.
+// regolith tells whether the block is past Regolith, before which the data gas is padded.
+func (r Receipts) DeriveL1CostFields(txs Transactions, regolith bool) error {
+	if len(txs) < 2 || len(txs) != len(r) || txs[0].Type() != DepositTxType {
+		return nil
+	}
+	l1BaseFee, overhead, scalar, err := ExtractL1GasParams(txs[0].GetData())
+	if err != nil {
+		return err
+	}
+	feeScalar := new(big.Float).Quo(new(big.Float).SetInt(scalar.ToBig()), new(big.Float).SetInt(feeScalarDenominator.ToBig()))
+	for i := range r {
+		if txs[i].Type() == DepositTxType {
+			continue
+		}
+		dataGas := L1DataGas(RollupDataGas(txs[i]), regolith)
+		r[i].L1GasPrice = l1BaseFee.ToBig()
+		r[i].L1GasUsed = new(uint256.Int).Add(uint256.NewInt(dataGas), overhead).ToBig()
+		r[i].L1Fee = L1Cost(dataGas, l1BaseFee, overhead, scalar).ToBig()
+		r[i].FeeScalar = feeScalar
+	}
+	return nil
+}
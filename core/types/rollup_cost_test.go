@@ -0,0 +1,112 @@
+package types
+
+import (
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common/u256"
+	"github.com/ledgerwatch/erigon/crypto"
+)
+
+func TestL1Cost(t *testing.T) {
+	// (1000 + 2100) * 10 gwei * 1.0
+	cost := L1Cost(1000, uint256.NewInt(10_000_000_000), uint256.NewInt(2100), uint256.NewInt(1_000_000))
+	require.Equal(t, uint64(31_000_000_000_000), cost.Uint64())
+
+	// scalar 0.684
+	cost = L1Cost(1000, uint256.NewInt(1000), uint256.NewInt(0), uint256.NewInt(684_000))
+	require.Equal(t, uint64(684_000), cost.Uint64())
+}
+
+func TestRollupDataGas(t *testing.T) {
+	require.Equal(t, uint64(2*4+2*16), rollupDataGas(func(w io.Writer) error {
+		_, err := w.Write([]byte{0x00, 0x01, 0x00, 0xff})
+		return err
+	}))
+
+	from := libcommon.HexToAddress("0x1")
+	deposit := &DepositTransaction{From: &from, Mint: u256.Num0, Value: u256.Num0, Data: []byte{0x01}}
+	require.Zero(t, RollupDataGas(deposit))
+	msg, err := deposit.AsMessage(*LatestSignerForChainID(nil), nil, nil)
+	require.NoError(t, err)
+	require.Zero(t, msg.RollupDataGas())
+
+	// The messages encode their transaction only when asked for its data gas
+	key, _ := crypto.GenerateKey()
+	userTx, err := SignTx(NewTransaction(1, from, u256.Num1, 21000, u256.Num1, []byte{0x00, 0x01}), *LatestSignerForChainID(nil), key)
+	require.NoError(t, err)
+	msg, err = userTx.AsMessage(*LatestSignerForChainID(nil), nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, RollupDataGas(userTx), msg.RollupDataGas())
+}
+
+func l1InfoData(l1BaseFee, overhead, scalar uint64) []byte {
+	data := make([]byte, l1InfoLen)
+	copy(data, l1InfoFuncSignature)
+	put := func(i int, v uint64) {
+		b := uint256.NewInt(v).Bytes32()
+		copy(data[4+i*32:], b[:])
+	}
+	put(0, 17_000_000) // number
+	put(1, 1_680_000_000)
+	put(2, l1BaseFee)
+	put(4, 3) // sequenceNumber
+	put(6, overhead)
+	put(7, scalar)
+	return data
+}
+
+func TestExtractL1GasParams(t *testing.T) {
+	l1BaseFee, overhead, scalar, err := ExtractL1GasParams(l1InfoData(7, 2100, 1_000_000))
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), l1BaseFee.Uint64())
+	require.Equal(t, uint64(2100), overhead.Uint64())
+	require.Equal(t, uint64(1_000_000), scalar.Uint64())
+
+	_, _, _, err = ExtractL1GasParams([]byte{0x01, 0x5d, 0x8e, 0xb9})
+	require.Error(t, err)
+}
+
+func TestDeriveL1CostFields(t *testing.T) {
+	from := libcommon.HexToAddress("0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001")
+	l1Block := libcommon.HexToAddress("0x4200000000000000000000000000000000000015")
+	l1Info := &DepositTransaction{
+		SourceHash: &libcommon.Hash{},
+		From:       &from,
+		To:         &l1Block,
+		Mint:       u256.Num0,
+		Value:      u256.Num0,
+		GasLimit:   1_000_000,
+		IsSystemTx: true,
+		Data:       l1InfoData(1000, 2100, 1_000_000),
+	}
+	userTx := NewTransaction(1, libcommon.HexToAddress("0x1"), u256.Num1, 21000, u256.Num1, []byte{0x00, 0x01})
+	txs := Transactions{l1Info, userTx}
+	receipts := Receipts{&Receipt{}, &Receipt{}}
+
+	require.NoError(t, receipts.DeriveL1CostFields(txs, true))
+	require.Nil(t, receipts[0].L1Fee)
+
+	dataGas := RollupDataGas(userTx)
+	require.Equal(t, big.NewInt(1000), receipts[1].L1GasPrice)
+	require.Equal(t, new(big.Int).SetUint64(dataGas+2100), receipts[1].L1GasUsed)
+	require.Equal(t, new(big.Int).SetUint64((dataGas+2100)*1000), receipts[1].L1Fee)
+	require.Equal(t, "1", receipts[1].FeeScalar.String())
+
+	// Before Regolith the data gas is padded for the signature and RLP overhead
+	require.NoError(t, receipts.DeriveL1CostFields(txs, false))
+	require.Equal(t, new(big.Int).SetUint64(dataGas+68*16+2100), receipts[1].L1GasUsed)
+	require.Equal(t, new(big.Int).SetUint64((dataGas+68*16+2100)*1000), receipts[1].L1Fee)
+}
+
+func TestL1DataGas(t *testing.T) {
+	require.Equal(t, uint64(1000), L1DataGas(1000, true))
+	require.Equal(t, uint64(1000+68*16), L1DataGas(1000, false))
+	// Messages without data gas, like eth_call's, are never charged
+	require.Zero(t, L1DataGas(0, false))
+}
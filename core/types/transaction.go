@@ -483,6 +483,9 @@ type Message struct {
 	checkNonce bool
 	isFree     bool
 	isSystemTx bool
+	isDeposit  bool
+
+	rollupMarshal func(w io.Writer) error // encodes the originating transaction, to price its L1 data fee
 }
 
 func NewMessage(from libcommon.Address, to *libcommon.Address, nonce uint64, amount *uint256.Int, gasLimit uint64, gasPrice *uint256.Int, feeCap, tip *uint256.Int, data []byte, accessList types2.AccessList, checkNonce bool, isFree bool) Message {
@@ -545,3 +548,15 @@ func (m *Message) ChangeGas(globalGasCap, desiredGas uint64) {
 	m.gasLimit = gas
 }
 func (m Message) IsSystemTx() bool { return m.isSystemTx }
+
+// IsDepositTx is true for messages originating from a rollup deposit transaction.
+func (m Message) IsDepositTx() bool { return m.isDeposit }
+
+// RollupDataGas is the L1 calldata gas of the originating transaction, used to price the L1 data fee.
+// It encodes the transaction, so it is only computed on rollup chains, when the fee is charged.
+func (m Message) RollupDataGas() uint64 {
+	if m.rollupMarshal == nil {
+		return 0
+	}
+	return rollupDataGas(m.rollupMarshal)
+}
@@ -14,15 +14,58 @@ import (
 	"github.com/ledgerwatch/erigon/core/vm"
 )
 
+// Kinds of criteria, the first byte of a criterion. The criteria of the same kind have
+// the same length, so a criterion is never the prefix of another one.
+const (
+	selectorCriterion    byte = iota + 1 // + selector (4 bytes)
+	callTypeCriterion                    // + opcode (1 byte)
+	transferCriterion                    // + address (20 bytes)
+	anyTransferCriterion                 // nothing
+)
+
+// SelectorLength is the length of the method selectors, at the start of the call inputs.
+const SelectorLength = 4
+
+// SelectorCriterion is the criterion of the calls whose input starts with selector.
+func SelectorCriterion(selector []byte) []byte {
+	return append([]byte{selectorCriterion}, selector[:SelectorLength]...)
+}
+
+// CallTypeCriterion is the criterion of the calls made by op, CREATE2 being indexed as CREATE.
+func CallTypeCriterion(op vm.OpCode) []byte {
+	if op == vm.CREATE2 {
+		op = vm.CREATE
+	}
+	return []byte{callTypeCriterion, byte(op)}
+}
+
+// TransferCriterion is the criterion of the internal value transfers sent or received by addr.
+func TransferCriterion(addr libcommon.Address) []byte {
+	return append([]byte{transferCriterion}, addr[:]...)
+}
+
+// AnyTransferCriterion is the criterion of all the internal value transfers.
+func AnyTransferCriterion() []byte {
+	return []byte{anyTransferCriterion}
+}
+
+// IsValueTransfer returns whether a call made by op with a non-zero value moves
+// the value to another account, unlike DELEGATECALL and CALLCODE.
+func IsValueTransfer(op vm.OpCode) bool {
+	return op == vm.CALL || op == vm.CREATE || op == vm.CREATE2 || op == vm.SELFDESTRUCT
+}
+
 type CallTracer struct {
-	froms map[libcommon.Address]struct{}
-	tos   map[libcommon.Address]bool // address -> isCreated
+	froms    map[libcommon.Address]struct{}
+	tos      map[libcommon.Address]bool // address -> isCreated
+	criteria map[string]struct{}
 }
 
 func NewCallTracer() *CallTracer {
 	return &CallTracer{
-		froms: make(map[libcommon.Address]struct{}),
-		tos:   make(map[libcommon.Address]bool),
+		froms:    make(map[libcommon.Address]struct{}),
+		tos:      make(map[libcommon.Address]bool),
+		criteria: make(map[string]struct{}),
 	}
 }
 
@@ -45,11 +88,35 @@ func (ct *CallTracer) captureStartOrEnter(from, to libcommon.Address, create boo
 	}
 }
 
+func (ct *CallTracer) addCriterion(criterion []byte) {
+	ct.criteria[string(criterion)] = struct{}{}
+}
+
+// captureCriteria records the criteria of a call, whose value is transferred internally
+// if the call is not the top call of a transaction.
+func (ct *CallTracer) captureCriteria(typ vm.OpCode, from, to libcommon.Address, input []byte, value *uint256.Int, internal bool) {
+	ct.addCriterion(CallTypeCriterion(typ))
+	if typ != vm.CREATE && typ != vm.CREATE2 && typ != vm.SELFDESTRUCT && len(input) >= SelectorLength {
+		ct.addCriterion(SelectorCriterion(input))
+	}
+	if internal && IsValueTransfer(typ) && value != nil && !value.IsZero() {
+		ct.addCriterion(TransferCriterion(from))
+		ct.addCriterion(TransferCriterion(to))
+		ct.addCriterion(AnyTransferCriterion())
+	}
+}
+
 func (ct *CallTracer) CaptureStart(env vm.VMInterface, from libcommon.Address, to libcommon.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
 	ct.captureStartOrEnter(from, to, create, code)
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	ct.captureCriteria(typ, from, to, input, value, false)
 }
 func (ct *CallTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
 	ct.captureStartOrEnter(from, to, create, code)
+	ct.captureCriteria(typ, from, to, input, value, true)
 }
 func (ct *CallTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
 }
@@ -103,5 +170,25 @@ func (ct *CallTracer) WriteToDb(tx kv.StatelessWriteTx, block *types.Block, vmCo
 		}
 		copy(prev[:], addr[:])
 	}
+	return ct.writeCriteria(tx, blockNumEnc[:])
+}
+
+func (ct *CallTracer) writeCriteria(tx kv.StatelessWriteTx, blockNumEnc []byte) error {
+	criteria := make([]string, 0, len(ct.criteria))
+	for criterion := range ct.criteria {
+		criteria = append(criteria, criterion)
+	}
+	sort.Strings(criteria)
+	for j, criterion := range criteria {
+		if j == 0 {
+			if err := tx.Append(kv.CallCriteriaSet, blockNumEnc, []byte(criterion)); err != nil {
+				return err
+			}
+		} else {
+			if err := tx.AppendDup(kv.CallCriteriaSet, blockNumEnc, []byte(criterion)); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
@@ -40,6 +40,7 @@ import (
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/params/networkname"
+	"github.com/ledgerwatch/erigon/turbo/proofs"
 )
 
 // AggregationStep number of transactions in smallest static file
@@ -75,6 +76,7 @@ var Defaults = Config{
 		ReconWorkerCount:           estimate.ReconstituteState.Workers(),
 		BodyCacheLimit:             256 * 1024 * 1024,
 		BodyDownloadTimeoutSeconds: 30,
+		MaxForkDepth:               32,
 	},
 	Ethash: ethash.Config{
 		CachesInMem:      2,
@@ -94,6 +96,10 @@ var Defaults = Config{
 	RPCGasCap:        50000000,
 	GPO:              FullNodeGPO,
 	RPCTxFeeCap:      1, // 1 ether
+	Proofs: proofs.Config{
+		Interval:  proofs.DefaultInterval,
+		Retention: proofs.DefaultRetention,
+	},
 
 	ImportMode: false,
 	Snapshot: Snapshot{
@@ -136,6 +142,9 @@ type Snapshot struct {
 	NoDownloader   bool // possible to use snapshots without calling Downloader
 	Verify         bool // verify snapshots on startup
 	DownloaderAddr string
+	Preverified    string        // Local file or directory of the preverified snapshots of a custom chain
+	Receipts       bool          // produce snapshots of receipts and logs, read instead of the pruned receipts
+	ScrubEvery     time.Duration // verify the open snapshots this often, and rebuild their corrupted indices (0 = never)
 }
 
 func (s Snapshot) String() string {
@@ -149,12 +158,21 @@ func (s Snapshot) String() string {
 	if !s.Produce {
 		out = append(out, "--"+FlagSnapStop+"=true")
 	}
+	if s.Receipts {
+		out = append(out, "--"+FlagSnapReceipts+"=true")
+	}
+	if s.ScrubEvery > 0 {
+		out = append(out, "--"+FlagSnapScrubEvery+"="+s.ScrubEvery.String())
+	}
 	return strings.Join(out, " ")
 }
 
 var (
-	FlagSnapKeepBlocks = "snap.keepblocks"
-	FlagSnapStop       = "snap.stop"
+	FlagSnapKeepBlocks  = "snap.keepblocks"
+	FlagSnapStop        = "snap.stop"
+	FlagSnapPreverified = "snap.preverified"
+	FlagSnapReceipts    = "snap.receipts"
+	FlagSnapScrubEvery  = "snap.scrub.every"
 )
 
 func NewSnapCfg(enabled, keepBlocks, produce bool) Snapshot {
@@ -222,6 +240,9 @@ type Config struct {
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64 `toml:",omitempty"`
 
+	// Proofs of accounts retained for eth_getProof at past blocks
+	Proofs proofs.Config
+
 	StateStream bool
 
 	//  New DB and Snapshots format of history allows: parallel blocks execution, get state as of given transaction without executing whole block.",
@@ -257,6 +278,8 @@ type Sync struct {
 
 	BodyCacheLimit             datasize.ByteSize
 	BodyDownloadTimeoutSeconds int // TODO: change to duration
+	// MaxForkDepth is the maximum distance of the fork point from the head of the side forks validated by the fork validator
+	MaxForkDepth uint64
 }
 
 // Chains where snapshots are enabled by default
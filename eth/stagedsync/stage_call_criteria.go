@@ -0,0 +1,191 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/c2h5oh/datasize"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/dbg"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// The call criteria (selectors, call types and internal value transfers) are indexed
+// by the CallTraces stage, from the kv.CallCriteriaSet table written during the
+// execution to the kv.CallCriteriaIndex table.
+
+func promoteCallCriteria(logPrefix string, tx kv.RwTx, startBlock, endBlock uint64, bufLimit datasize.ByteSize, flushEvery time.Duration, quit <-chan struct{}, tmpdir string) error {
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+
+	criteria := map[string]*roaring64.Bitmap{}
+	collector := etl.NewCollector(logPrefix, tmpdir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer collector.Close()
+	checkFlushEvery := time.NewTicker(flushEvery)
+	defer checkFlushEvery.Stop()
+
+	criteriaCursor, err := tx.RwCursorDupSort(kv.CallCriteriaSet)
+	if err != nil {
+		return fmt.Errorf("failed to create cursor: %w", err)
+	}
+	defer criteriaCursor.Close()
+
+	var k, v []byte
+	for k, v, err = criteriaCursor.Seek(hexutility.EncodeTs(startBlock)); k != nil; k, v, err = criteriaCursor.Next() {
+		if err != nil {
+			return err
+		}
+		blockNum := binary.BigEndian.Uint64(k)
+		if blockNum > endBlock {
+			break
+		}
+		m, ok := criteria[string(v)]
+		if !ok {
+			m = roaring64.New()
+			criteria[string(v)] = m
+		}
+		m.Add(blockNum)
+		select {
+		default:
+		case <-quit:
+			return libcommon.ErrStopped
+		case <-logEvery.C:
+			var m runtime.MemStats
+			dbg.ReadMemStats(&m)
+			log.Info(fmt.Sprintf("[%s] Progress of call criteria", logPrefix), "number", blockNum,
+				"alloc", libcommon.ByteCount(m.Alloc), "sys", libcommon.ByteCount(m.Sys))
+		case <-checkFlushEvery.C:
+			if needFlush64(criteria, bufLimit) {
+				if err := flushBitmaps64(collector, criteria); err != nil {
+					return err
+				}
+				criteria = map[string]*roaring64.Bitmap{}
+			}
+		}
+	}
+	if err = flushBitmaps64(collector, criteria); err != nil {
+		return err
+	}
+
+	// Clean up before loading the index to reclaim space, as for the call traces
+	for k, _, err = criteriaCursor.First(); k != nil; k, _, err = criteriaCursor.NextNoDup() {
+		if err != nil {
+			return err
+		}
+		blockNum := binary.BigEndian.Uint64(k)
+		if blockNum+params.FullImmutabilityThreshold >= endBlock {
+			break
+		}
+		if err = criteriaCursor.DeleteCurrentDuplicates(); err != nil {
+			return fmt.Errorf("remove call criteria for block %d: %w", blockNum, err)
+		}
+	}
+
+	return collector.Load(tx, kv.CallCriteriaIndex, newCallIndexLoader(), etl.TransformArgs{Quit: quit})
+}
+
+func unwindCallCriteria(logPrefix string, tx kv.RwTx, from, to uint64, ctx context.Context) error {
+	criteriaCursor, err := tx.CursorDupSort(kv.CallCriteriaSet)
+	if err != nil {
+		return fmt.Errorf("create cursor for call criteria: %w", err)
+	}
+	defer criteriaCursor.Close()
+
+	criteria := map[string]struct{}{}
+	var k, v []byte
+	for k, v, err = criteriaCursor.Seek(hexutility.EncodeTs(to + 1)); k != nil; k, v, err = criteriaCursor.Next() {
+		if err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint64(k) >= from {
+			break
+		}
+		criteria[string(v)] = struct{}{}
+		select {
+		case <-ctx.Done():
+			return libcommon.ErrStopped
+		default:
+		}
+	}
+	if err = truncateBitmaps64(tx, kv.CallCriteriaIndex, criteria, to); err != nil {
+		return fmt.Errorf("[%s] %w", logPrefix, err)
+	}
+	return nil
+}
+
+func pruneCallCriteria(tx kv.RwTx, logPrefix string, pruneTo uint64, ctx context.Context, tmpdir string) error {
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+
+	collector := etl.NewCollector(logPrefix, tmpdir, etl.NewOldestEntryBuffer(etl.BufferOptimalSize))
+	defer collector.Close()
+
+	{
+		criteriaCursor, err := tx.CursorDupSort(kv.CallCriteriaSet)
+		if err != nil {
+			return fmt.Errorf("create cursor for call criteria: %w", err)
+		}
+		defer criteriaCursor.Close()
+
+		var k, v []byte
+		for k, v, err = criteriaCursor.First(); k != nil; k, v, err = criteriaCursor.Next() {
+			if err != nil {
+				return err
+			}
+			if binary.BigEndian.Uint64(k) >= pruneTo {
+				break
+			}
+			if err := collector.Collect(v, nil); err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return libcommon.ErrStopped
+			default:
+			}
+		}
+	}
+
+	c, err := tx.RwCursor(kv.CallCriteriaIndex)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	return collector.Load(tx, "", func(criterion, _ []byte, _ etl.CurrentTableReader, _ etl.LoadNextFunc) error {
+		for k, _, err := c.Seek(criterion); k != nil; k, _, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			if !bytes.HasPrefix(k, criterion) {
+				break
+			}
+			blockNum := binary.BigEndian.Uint64(k[len(criterion):])
+			if blockNum >= pruneTo {
+				break
+			}
+			if err = c.DeleteCurrent(); err != nil {
+				return fmt.Errorf("failed delete, block=%d: %w", blockNum, err)
+			}
+		}
+		select {
+		case <-logEvery.C:
+			log.Info(fmt.Sprintf("[%s]", logPrefix), "table", kv.CallCriteriaIndex, "key", hex.EncodeToString(criterion))
+		case <-ctx.Done():
+			return libcommon.ErrStopped
+		default:
+		}
+		return nil
+	}, etl.TransformArgs{})
+}
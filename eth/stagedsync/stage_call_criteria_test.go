@@ -0,0 +1,92 @@
+package stagedsync
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/bitmapdb"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/calltracer"
+)
+
+var testSelector = []byte{0xa9, 0x05, 0x9c, 0xbb}
+
+func genTestCallCriteriaSet(t *testing.T, tx kv.RwTx, from, to uint64) {
+	for i := from; i < to; i++ {
+		err := tx.Put(kv.CallCriteriaSet, hexutility.EncodeTs(i), calltracer.CallTypeCriterion(vm.CALL))
+		require.NoError(t, err)
+		if i%2 == 0 {
+			err = tx.Put(kv.CallCriteriaSet, hexutility.EncodeTs(i), calltracer.SelectorCriterion(testSelector))
+			require.NoError(t, err)
+		}
+		if i%3 == 0 {
+			err = tx.Put(kv.CallCriteriaSet, hexutility.EncodeTs(i), calltracer.AnyTransferCriterion())
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestCallCriteria(t *testing.T) {
+	ctx, assert := context.Background(), assert.New(t)
+	_, tx := memdb.NewTestTx(t)
+	genTestCallCriteriaSet(t, tx, 0, 30)
+	blocks := func(criterion []byte) []uint64 {
+		b, err := bitmapdb.Get64(tx, kv.CallCriteriaIndex, criterion, 0, 30)
+		assert.NoError(err)
+		return b.ToArray()
+	}
+	selectors := func() []uint64 { return blocks(calltracer.SelectorCriterion(testSelector)) }
+	transfers := func() []uint64 { return blocks(calltracer.AnyTransferCriterion()) }
+
+	// forward 0->20
+	err := promoteCallCriteria("test", tx, 0, 20, 0, time.Nanosecond, ctx.Done(), "")
+	assert.NoError(err)
+	assert.Equal([]uint64{0, 2, 4, 6, 8, 10, 12, 14, 16, 18, 20}, selectors())
+	assert.Equal([]uint64{0, 3, 6, 9, 12, 15, 18}, transfers())
+	assert.Len(blocks(calltracer.CallTypeCriterion(vm.CALL)), 21)
+
+	// unwind 20->10
+	err = unwindCallCriteria("test", tx, 20, 10, ctx)
+	assert.NoError(err)
+	assert.Equal([]uint64{0, 2, 4, 6, 8, 10}, selectors())
+	assert.Equal([]uint64{0, 3, 6, 9}, transfers())
+
+	// forward 10->30
+	err = promoteCallCriteria("test", tx, 10, 30, 0, time.Nanosecond, ctx.Done(), "")
+	assert.NoError(err)
+	assert.Equal([]uint64{0, 3, 6, 9, 12, 15, 18, 21, 24, 27}, transfers())
+
+	// forward 30->4000, the criteria of each block take several chunks
+	genTestCallCriteriaSet(t, tx, 30, 4000)
+	err = promoteCallCriteria("test", tx, 30, 4000, 0, time.Nanosecond, ctx.Done(), "")
+	assert.NoError(err)
+	criterion := calltracer.SelectorCriterion(testSelector)
+	c, err := tx.Cursor(kv.CallCriteriaIndex)
+	require.NoError(t, err)
+	defer c.Close()
+	k, _, err := c.Seek(criterion)
+	require.NoError(t, err)
+	firstChunk := common.CopyBytes(k)
+	chunkEnd := binary.BigEndian.Uint64(firstChunk[len(criterion):])
+	require.Less(t, chunkEnd, uint64(4000))
+
+	// prune 0 -> the end of the first chunk, which is removed
+	err = pruneCallCriteria(tx, "test", chunkEnd+1, ctx, "")
+	assert.NoError(err)
+	v, err := tx.GetOne(kv.CallCriteriaIndex, firstChunk)
+	assert.NoError(err)
+	assert.Nil(v)
+	b, err := bitmapdb.Get64(tx, kv.CallCriteriaIndex, criterion, 0, 4000)
+	assert.NoError(err)
+	assert.Equal(chunkEnd+2, b.Minimum())
+	assert.Equal(uint64(3998), b.Maximum())
+}
@@ -73,6 +73,9 @@ func SpawnCallTraces(s *StageState, tx kv.RwTx, cfg CallTracesCfg, ctx context.C
 	if err := promoteCallTraces(logPrefix, tx, s.BlockNumber+1, endBlock, bitmapsBufLimit, bitmapsFlushEvery, quit, cfg.tmpdir); err != nil {
 		return err
 	}
+	if err := promoteCallCriteria(logPrefix, tx, s.BlockNumber+1, endBlock, bitmapsBufLimit, bitmapsFlushEvery, quit, cfg.tmpdir); err != nil {
+		return err
+	}
 
 	if err := s.Update(tx, endBlock); err != nil {
 		return err
@@ -213,11 +216,24 @@ func promoteCallTraces(logPrefix string, tx kv.RwTx, startBlock, endBlock uint64
 }
 
 func finaliseCallTraces(collectorFrom, collectorTo *etl.Collector, logPrefix string, tx kv.RwTx, quit <-chan struct{}) error {
+	loaderFunc := newCallIndexLoader()
+	if err := collectorFrom.Load(tx, kv.CallFromIndex, loaderFunc, etl.TransformArgs{Quit: quit}); err != nil {
+		return err
+	}
+	if err := collectorTo.Load(tx, kv.CallToIndex, loaderFunc, etl.TransformArgs{Quit: quit}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// newCallIndexLoader returns the loader of collected bitmaps into a call index, which
+// merges them with the last chunk of their key in the index.
+func newCallIndexLoader() etl.LoadFunc {
 	var buf = bytes.NewBuffer(nil)
 	lastChunkKey := make([]byte, 128)
 	reader := bytes.NewReader(nil)
 	reader2 := bytes.NewReader(nil)
-	var loaderFunc = func(k []byte, v []byte, table etl.CurrentTableReader, next etl.LoadNextFunc) error {
+	return func(k []byte, v []byte, table etl.CurrentTableReader, next etl.LoadNextFunc) error {
 		reader.Reset(v)
 		currentBitmap := roaring64.New()
 		if _, err := currentBitmap.ReadFrom(reader); err != nil {
@@ -251,13 +267,6 @@ func finaliseCallTraces(collectorFrom, collectorTo *etl.Collector, logPrefix str
 		}
 		return nil
 	}
-	if err := collectorFrom.Load(tx, kv.CallFromIndex, loaderFunc, etl.TransformArgs{Quit: quit}); err != nil {
-		return err
-	}
-	if err := collectorTo.Load(tx, kv.CallToIndex, loaderFunc, etl.TransformArgs{Quit: quit}); err != nil {
-		return err
-	}
-	return nil
 }
 
 func UnwindCallTraces(u *UnwindState, s *StageState, tx kv.RwTx, cfg CallTracesCfg, ctx context.Context) (err error) {
@@ -280,6 +289,9 @@ func UnwindCallTraces(u *UnwindState, s *StageState, tx kv.RwTx, cfg CallTracesC
 	if err := DoUnwindCallTraces(logPrefix, tx, s.BlockNumber, u.UnwindPoint, ctx, cfg.tmpdir); err != nil {
 		return err
 	}
+	if err := unwindCallCriteria(logPrefix, tx, s.BlockNumber, u.UnwindPoint, ctx); err != nil {
+		return err
+	}
 
 	if err := u.Done(tx); err != nil {
 		return err
@@ -380,6 +392,9 @@ func PruneCallTraces(s *PruneState, tx kv.RwTx, cfg CallTracesCfg, ctx context.C
 		if err = pruneCallTraces(tx, logPrefix, cfg.prune.CallTraces.PruneTo(s.ForwardProgress), ctx, cfg.tmpdir); err != nil {
 			return err
 		}
+		if err = pruneCallCriteria(tx, logPrefix, cfg.prune.CallTraces.PruneTo(s.ForwardProgress), ctx, cfg.tmpdir); err != nil {
+			return err
+		}
 	}
 	if err := s.Done(tx); err != nil {
 		return err
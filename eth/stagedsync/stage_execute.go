@@ -64,6 +64,25 @@ type WithSnapshots interface {
 	Snapshots() *snapshotsync.RoSnapshots
 }
 
+// receiptsSnapshots returns the snapshots the receipts are retired to, nil when they are not.
+func receiptsSnapshots(blockReader services.FullBlockReader) *snapshotsync.RoSnapshots {
+	withSnapshots, ok := blockReader.(WithSnapshots)
+	if !ok || withSnapshots.Snapshots() == nil || !withSnapshots.Snapshots().Cfg().Receipts {
+		return nil
+	}
+	return withSnapshots.Snapshots()
+}
+
+// receiptsPruneTo keeps the receipts in the db until they are retired, when they are retired to the
+// snapshots of receipts.
+func receiptsPruneTo(pruneTo uint64, blockReader services.FullBlockReader) uint64 {
+	snapshots := receiptsSnapshots(blockReader)
+	if snapshots == nil {
+		return pruneTo
+	}
+	return cmp.Min(pruneTo, snapshots.ReceiptsTo())
+}
+
 type headerDownloader interface {
 	ReportBadHeaderPoS(badHeader, lastValidAncestor common.Hash)
 }
@@ -79,6 +98,7 @@ type ExecuteBlockCfg struct {
 	badBlockHalt  bool
 	stateStream   bool
 	accumulator   *shards.Accumulator
+	notifier      ChainEventNotifier
 	blockReader   services.FullBlockReader
 	hd            headerDownloader
 
@@ -98,6 +118,7 @@ func StageExecuteBlocksCfg(
 	engine consensus.Engine,
 	vmConfig *vm.Config,
 	accumulator *shards.Accumulator,
+	notifier ChainEventNotifier,
 	stateStream bool,
 	badBlockHalt bool,
 
@@ -119,6 +140,7 @@ func StageExecuteBlocksCfg(
 		vmConfig:      vmConfig,
 		dirs:          dirs,
 		accumulator:   accumulator,
+		notifier:      notifier,
 		stateStream:   stateStream,
 		badBlockHalt:  badBlockHalt,
 		blockReader:   blockReader,
@@ -387,6 +409,8 @@ func SpawnExecuteBlocksStage(s *StageState, u Unwinder, tx kv.RwTx, toBlock uint
 		return err
 	}
 	nextStagesExpectData := nextStageProgress > 0 // Incremental move of next stages depend on fully written ChangeSets, Receipts, CallTraceSet
+	// The receipts retired to the snapshots are written also beyond the prune distance, and pruned once retired
+	retireReceipts := receiptsSnapshots(cfg.blockReader) != nil
 
 	logPrefix := s.LogPrefix()
 	var to = prevStageProgress
@@ -446,7 +470,7 @@ Loop:
 
 		// Incremental move of next stages depend on fully written ChangeSets, Receipts, CallTraceSet
 		writeChangeSets := nextStagesExpectData || blockNum > cfg.prune.History.PruneTo(to)
-		writeReceipts := nextStagesExpectData || blockNum > cfg.prune.Receipts.PruneTo(to)
+		writeReceipts := nextStagesExpectData || retireReceipts || blockNum > cfg.prune.Receipts.PruneTo(to)
 		writeCallTraces := nextStagesExpectData || blockNum > cfg.prune.CallTraces.PruneTo(to)
 		if err = executeBlock(block, tx, batch, cfg, *cfg.vmConfig, writeChangeSets, writeReceipts, writeCallTraces, initialCycle, stateStream); err != nil {
 			if !errors.Is(err, context.Canceled) {
@@ -578,6 +602,44 @@ func UnwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 	return nil
 }
 
+// unwoundBlockHashes returns the hashes of the executed blocks from from to to. The canonical hashes of
+// their numbers may already point at the new branch, so they are found from the parents of the head block
+// of the last completed cycle. Only the blocks executed after it are taken as canonical.
+func unwoundBlockHashes(tx kv.Tx, from, to uint64) ([]common.Hash, error) {
+	if to < from {
+		return nil, nil
+	}
+	hashes := make([]common.Hash, to-from+1)
+	hash := rawdb.ReadHeadBlockHash(tx)
+	headNumber := rawdb.ReadHeaderNumber(tx, hash)
+	if headNumber == nil {
+		return nil, fmt.Errorf("head block %x not found", hash)
+	}
+	number := *headNumber
+	for ; number > to; number-- {
+		header := rawdb.ReadHeader(tx, hash, number)
+		if header == nil {
+			return nil, fmt.Errorf("header %d %x not found", number, hash)
+		}
+		hash = header.ParentHash
+	}
+	for n := to; n > number; n-- {
+		var err error
+		if hashes[n-from], err = rawdb.ReadCanonicalHash(tx, n); err != nil {
+			return nil, err
+		}
+	}
+	for ; number >= from; number-- {
+		hashes[number-from] = hash
+		header := rawdb.ReadHeader(tx, hash, number)
+		if header == nil {
+			return nil, fmt.Errorf("header %d %x not found", number, hash)
+		}
+		hash = header.ParentHash
+	}
+	return hashes, nil
+}
+
 func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context.Context, cfg ExecuteBlockCfg, initialCycle bool) error {
 	logPrefix := s.LogPrefix()
 	stateBucket := kv.PlainState
@@ -596,6 +658,19 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 			return err
 		}
 		accumulator.StartChange(u.UnwindPoint, hash, txs, true)
+
+		// The logs of the unwound blocks are about to be truncated, keep them to notify the log subscribers
+		if cfg.notifier != nil && cfg.notifier.HasLogSubsriptions() {
+			hashes, err := unwoundBlockHashes(tx, u.UnwindPoint+1, s.BlockNumber)
+			if err != nil {
+				return fmt.Errorf("read hashes of unwound blocks: %w", err)
+			}
+			removedLogs, err := ReadBlocksLogs(tx, u.UnwindPoint+1, hashes, true /* removed */)
+			if err != nil {
+				return fmt.Errorf("read logs of unwound blocks: %w", err)
+			}
+			accumulator.RemoveLogs(removedLogs)
+		}
 	}
 
 	if cfg.historyV3 {
@@ -696,9 +771,19 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 		return fmt.Errorf("delete newer epochs: %w", err)
 	}
 
-	// Truncate CallTraceSet
+	// Truncate CallTraceSet and CallCriteriaSet
 	keyStart := hexutility.EncodeTs(u.UnwindPoint + 1)
-	c, err := tx.RwCursorDupSort(kv.CallTraceSet)
+	for _, table := range []string{kv.CallTraceSet, kv.CallCriteriaSet} {
+		if err := truncateDupSortFrom(tx, table, keyStart); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func truncateDupSortFrom(tx kv.RwTx, table string, keyStart []byte) error {
+	c, err := tx.RwCursorDupSort(table)
 	if err != nil {
 		return err
 	}
@@ -712,7 +797,6 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 			return err
 		}
 	}
-
 	return nil
 }
 
@@ -762,14 +846,15 @@ func PruneExecutionStage(s *PruneState, tx kv.RwTx, cfg ExecuteBlockCfg, ctx con
 		}
 
 		if cfg.prune.Receipts.Enabled() {
-			if err = rawdb.PruneTable(tx, kv.Receipts, cfg.prune.Receipts.PruneTo(s.ForwardProgress), ctx, math.MaxInt32); err != nil {
+			pruneTo := receiptsPruneTo(cfg.prune.Receipts.PruneTo(s.ForwardProgress), cfg.blockReader)
+			if err = rawdb.PruneTable(tx, kv.Receipts, pruneTo, ctx, math.MaxInt32); err != nil {
 				return err
 			}
-			if err = rawdb.PruneTable(tx, kv.BorReceipts, cfg.prune.Receipts.PruneTo(s.ForwardProgress), ctx, math.MaxUint32); err != nil {
+			if err = rawdb.PruneTable(tx, kv.BorReceipts, pruneTo, ctx, math.MaxUint32); err != nil {
 				return err
 			}
 			// LogIndex.Prune will read everything what not pruned here
-			if err = rawdb.PruneTable(tx, kv.Log, cfg.prune.Receipts.PruneTo(s.ForwardProgress), ctx, math.MaxInt32); err != nil {
+			if err = rawdb.PruneTable(tx, kv.Log, pruneTo, ctx, math.MaxInt32); err != nil {
 				return err
 			}
 		}
@@ -777,6 +862,9 @@ func PruneExecutionStage(s *PruneState, tx kv.RwTx, cfg ExecuteBlockCfg, ctx con
 			if err = rawdb.PruneTableDupSort(tx, kv.CallTraceSet, logPrefix, cfg.prune.CallTraces.PruneTo(s.ForwardProgress), logEvery, ctx); err != nil {
 				return err
 			}
+			if err = rawdb.PruneTableDupSort(tx, kv.CallCriteriaSet, logPrefix, cfg.prune.CallTraces.PruneTo(s.ForwardProgress), logEvery, ctx); err != nil {
+				return err
+			}
 		}
 	}
 
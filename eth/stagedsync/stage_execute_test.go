@@ -4,16 +4,21 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon-lib/kv/rawdbv3"
 	"github.com/ledgerwatch/erigon-lib/kv/temporal/historyv2"
 	libstate "github.com/ledgerwatch/erigon-lib/state"
 	"github.com/ledgerwatch/erigon/cmd/state/exec22"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
@@ -238,3 +243,39 @@ func TestExec22(t *testing.T) {
 		compareCurrentState(t, tx1, tx2, kv.PlainState, kv.PlainContractCode)
 	})
 }
+
+func TestUnwoundBlockHashes(t *testing.T) {
+	require := require.New(t)
+	_, tx := memdb.NewTestTx(t)
+
+	// Branch A is executed up to block 4, then the canonical hashes of blocks 2 to 5 point at branch B
+	branch := func(from uint64, parent common.Hash, extra byte) []common.Hash {
+		var hashes []common.Hash
+		for number := from; number <= 5; number++ {
+			header := &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: parent, Extra: []byte{extra}}
+			rawdb.WriteHeader(tx, header)
+			parent = header.Hash()
+			hashes = append(hashes, parent)
+		}
+		return hashes
+	}
+	a := branch(0, common.Hash{}, 'a')
+	b := branch(2, a[1], 'b')
+	for i, hash := range append(append([]common.Hash{}, a[:2]...), b...) {
+		require.NoError(rawdb.WriteCanonicalHash(tx, hash, uint64(i)))
+	}
+	rawdb.WriteHeadBlockHash(tx, a[4])
+
+	hashes, err := unwoundBlockHashes(tx, 2, 4)
+	require.NoError(err)
+	require.Equal(a[2:5], hashes)
+	// The blocks executed after the head block are canonical
+	hashes, err = unwoundBlockHashes(tx, 3, 5)
+	require.NoError(err)
+	require.Equal([]common.Hash{a[3], a[4], b[3]}, hashes)
+
+	// The logs of a block whose body is missing are not read
+	require.NoError(tx.Put(kv.Log, dbutils.LogKey(3, 0), nil))
+	_, err = ReadBlocksLogs(tx, 2, hashes, true)
+	require.Error(err)
+}
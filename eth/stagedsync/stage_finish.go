@@ -120,18 +120,23 @@ func PruneFinish(u *PruneState, tx kv.RwTx, cfg FinishCfg, ctx context.Context)
 	return nil
 }
 
-func NotifyNewHeaders(ctx context.Context, finishStageBeforeSync uint64, finishStageAfterSync uint64, unwindTo *uint64, notifier ChainEventNotifier, tx kv.Tx) error {
+// NotifyNewHeaders sends the headers and logs of the new canonical blocks to the RPC daemon. On a reorg
+// removedLogs, the logs of the unwound blocks, are sent first, so that log subscribers drop them before
+// receiving the logs of the new branch.
+func NotifyNewHeaders(ctx context.Context, finishStageBeforeSync uint64, finishStageAfterSync uint64, unwindTo *uint64, removedLogs []*remote.SubscribeLogsReply, notifier ChainEventNotifier, tx kv.Tx) error {
 	t := time.Now()
 	if notifier == nil {
 		log.Trace("RPC Daemon notification channel not set. No headers notifications will be sent")
 		return nil
 	}
+	if len(removedLogs) > 0 && notifier.HasLogSubsriptions() {
+		notifier.OnLogs(removedLogs)
+	}
+
 	// Notify all headers we have (either canonical or not) in a maximum range span of 1024
 	var notifyFrom uint64
-	var isUnwind bool
 	if unwindTo != nil && *unwindTo != 0 && (*unwindTo) < finishStageBeforeSync {
 		notifyFrom = *unwindTo
-		isUnwind = true
 	} else {
 		heightSpan := finishStageAfterSync - finishStageBeforeSync
 		if heightSpan > 1024 {
@@ -171,7 +176,7 @@ func NotifyNewHeaders(ctx context.Context, finishStageBeforeSync uint64, finishS
 
 		t = time.Now()
 		if notifier.HasLogSubsriptions() {
-			logs, err := ReadLogs(tx, notifyFrom, isUnwind)
+			logs, err := ReadLogs(tx, notifyFrom, false /* removed */)
 			if err != nil {
 				return err
 			}
@@ -183,7 +188,25 @@ func NotifyNewHeaders(ctx context.Context, finishStageBeforeSync uint64, finishS
 	return nil
 }
 
-func ReadLogs(tx kv.Tx, from uint64, isUnwind bool) ([]*remote.SubscribeLogsReply, error) {
+// ReadLogs returns the logs of the canonical blocks from block number from, marked as removed or not.
+func ReadLogs(tx kv.Tx, from uint64, removed bool) ([]*remote.SubscribeLogsReply, error) {
+	return readLogs(tx, from, removed, func(blockNum uint64) (libcommon.Hash, error) {
+		return rawdb.ReadCanonicalHash(tx, blockNum)
+	})
+}
+
+// ReadBlocksLogs returns the logs of the blocks of hashes, the block number from and the next ones,
+// marked as removed or not. It reads the logs of blocks that are not canonical anymore.
+func ReadBlocksLogs(tx kv.Tx, from uint64, hashes []libcommon.Hash, removed bool) ([]*remote.SubscribeLogsReply, error) {
+	return readLogs(tx, from, removed, func(blockNum uint64) (libcommon.Hash, error) {
+		if blockNum-from >= uint64(len(hashes)) {
+			return libcommon.Hash{}, fmt.Errorf("logs of block %d beyond the %d blocks from %d", blockNum, len(hashes), from)
+		}
+		return hashes[blockNum-from], nil
+	})
+}
+
+func readLogs(tx kv.Tx, from uint64, removed bool, blockHash func(blockNum uint64) (libcommon.Hash, error)) ([]*remote.SubscribeLogsReply, error) {
 	logs, err := tx.Cursor(kv.Log)
 	if err != nil {
 		return nil, err
@@ -203,11 +226,18 @@ func ReadLogs(tx kv.Tx, from uint64, isUnwind bool) ([]*remote.SubscribeLogsRepl
 		if block == nil || blockNum != prevBlockNum {
 			logIndex = 0
 			prevBlockNum = blockNum
-			if block, err = rawdb.ReadBlockByNumber(tx, blockNum); err != nil {
+			hash, err := blockHash(blockNum)
+			if err != nil {
 				return nil, err
 			}
+			if block = rawdb.ReadBlock(tx, hash, blockNum); block == nil {
+				return nil, fmt.Errorf("block %d %x of the logs not found", blockNum, hash)
+			}
 		}
 		txIndex := uint64(binary.BigEndian.Uint32(k[8:]))
+		if txIndex >= uint64(len(block.Transactions())) {
+			return nil, fmt.Errorf("logs of transaction %d of block %d %x, which has %d transactions", txIndex, blockNum, block.Hash(), len(block.Transactions()))
+		}
 		txHash := block.Transactions()[txIndex].Hash()
 		var ll types.Logs
 		reader.Reset(v)
@@ -224,7 +254,7 @@ func ReadLogs(tx kv.Tx, from uint64, isUnwind bool) ([]*remote.SubscribeLogsRepl
 				Topics:           make([]*types2.H256, 0, len(l.Topics)),
 				TransactionHash:  gointerfaces.ConvertHashToH256(txHash),
 				TransactionIndex: txIndex,
-				Removed:          isUnwind,
+				Removed:          removed,
 			}
 			logIndex++
 			for _, topic := range l.Topics {
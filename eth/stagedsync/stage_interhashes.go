@@ -8,10 +8,12 @@ import (
 	"math/bits"
 
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
 	"github.com/ledgerwatch/erigon-lib/common/hexutility"
 	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/etl"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon-lib/kv/rawdbv3"
 	"github.com/ledgerwatch/erigon-lib/kv/temporal/historyv2"
 	"github.com/ledgerwatch/erigon-lib/state"
@@ -24,6 +26,8 @@ import (
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/turbo/proofs"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
 	"github.com/ledgerwatch/erigon/turbo/trie"
@@ -40,9 +44,11 @@ type TrieCfg struct {
 
 	historyV3 bool
 	agg       *state.AggregatorV3
+
+	proofs *proofs.Store // Proofs taken at the blocks the trie root is verified for, nil takes none
 }
 
-func StageTrieCfg(db kv.RwDB, checkRoot, saveNewHashesToDB, badBlockHalt bool, tmpDir string, blockReader services.FullBlockReader, hd *headerdownload.HeaderDownload, historyV3 bool, agg *state.AggregatorV3) TrieCfg {
+func StageTrieCfg(db kv.RwDB, checkRoot, saveNewHashesToDB, badBlockHalt bool, tmpDir string, blockReader services.FullBlockReader, hd *headerdownload.HeaderDownload, historyV3 bool, agg *state.AggregatorV3, proofStore *proofs.Store) TrieCfg {
 	return TrieCfg{
 		db:                db,
 		checkRoot:         checkRoot,
@@ -54,6 +60,8 @@ func StageTrieCfg(db kv.RwDB, checkRoot, saveNewHashesToDB, badBlockHalt bool, t
 
 		historyV3: historyV3,
 		agg:       agg,
+
+		proofs: proofStore,
 	}
 }
 
@@ -132,8 +140,23 @@ func SpawnIntermediateHashesStage(s *StageState, u Unwinder, tx kv.RwTx, cfg Tri
 			log.Warn("Unwinding due to incorrect root hash", "to", unwindTo)
 			u.UnwindTo(unwindTo, headerHash)
 		}
-	} else if err = s.Update(tx, to); err != nil {
-		return trie.EmptyRoot, err
+	} else {
+		if err = s.Update(tx, to); err != nil {
+			return trie.EmptyRoot, err
+		}
+		// Proofs are built from the trie, which is only at a block between the runs of the stage
+		if cfg.proofs != nil {
+			// The proof db is not part of tx: drop the proofs left after the progress by a run that was not committed
+			if err = cfg.proofs.Unwind(ctx, s.BlockNumber); err != nil {
+				return trie.EmptyRoot, fmt.Errorf("[%s] unwind retained proofs: %w", logPrefix, err)
+			}
+			if err = cfg.proofs.Retain(ctx, tx, to); err != nil {
+				return trie.EmptyRoot, fmt.Errorf("[%s] retain proofs: %w", logPrefix, err)
+			}
+			if err = retainSkippedProofs(ctx, logPrefix, s.BlockNumber, to, tx, cfg); err != nil {
+				return trie.EmptyRoot, fmt.Errorf("[%s] retain proofs: %w", logPrefix, err)
+			}
+		}
 	}
 
 	if !useExternalTx {
@@ -622,9 +645,14 @@ func UnwindIntermediateHashesStage(u *UnwindState, s *StageState, tx kv.RwTx, cf
 	expectedRootHash := syncHeadHeader.Root
 
 	logPrefix := s.LogPrefix()
-	if err := unwindIntermediateHashesStageImpl(logPrefix, u, s, tx, cfg, expectedRootHash, quit); err != nil {
+	if err := unwindIntermediateHashesStageImpl(logPrefix, u, s, tx, cfg, expectedRootHash, false /* memBatch */, quit); err != nil {
 		return err
 	}
+	if cfg.proofs != nil {
+		if err := cfg.proofs.Unwind(ctx, u.UnwindPoint); err != nil {
+			return fmt.Errorf("[%s] unwind retained proofs: %w", logPrefix, err)
+		}
+	}
 	if err := u.Done(tx); err != nil {
 		return err
 	}
@@ -636,7 +664,10 @@ func UnwindIntermediateHashesStage(u *UnwindState, s *StageState, tx kv.RwTx, cf
 	return nil
 }
 
-func unwindIntermediateHashesStageImpl(logPrefix string, u *UnwindState, s *StageState, db kv.RwTx, cfg TrieCfg, expectedRootHash libcommon.Hash, quit <-chan struct{}) error {
+// unwindIntermediateHashesStageImpl unwinds the trie of db. With memBatch, db is a memory batch,
+// which reports no last key for a table whose last key it deleted: etl would take the deletions
+// for appends past the end of the table and skip them, so they are applied with db.Delete.
+func unwindIntermediateHashesStageImpl(logPrefix string, u *UnwindState, s *StageState, db kv.RwTx, cfg TrieCfg, expectedRootHash libcommon.Hash, memBatch bool, quit <-chan struct{}) error {
 	p := NewHashPromoter(db, cfg.tmpDir, quit, logPrefix)
 	rl := trie.NewRetainList(0)
 	if cfg.historyV3 {
@@ -683,15 +714,86 @@ func unwindIntermediateHashesStageImpl(logPrefix string, u *UnwindState, s *Stag
 		return fmt.Errorf("wrong trie root: %x, expected (from header): %x", hash, expectedRootHash)
 	}
 	log.Info(fmt.Sprintf("[%s] Trie root", logPrefix), "hash", hash.Hex())
-	if err := accTrieCollector.Load(db, kv.TrieOfAccounts, etl.IdentityLoadFunc, etl.TransformArgs{Quit: quit}); err != nil {
+	load := func(collector *etl.Collector, table string) error {
+		if !memBatch {
+			return collector.Load(db, table, etl.IdentityLoadFunc, etl.TransformArgs{Quit: quit})
+		}
+		return collector.Load(db, table, func(k, v []byte, _ etl.CurrentTableReader, next etl.LoadNextFunc) error {
+			if len(v) == 0 {
+				return db.Delete(table, k)
+			}
+			return next(k, k, v)
+		}, etl.TransformArgs{Quit: quit})
+	}
+	if err := load(accTrieCollector, kv.TrieOfAccounts); err != nil {
 		return err
 	}
-	if err := stTrieCollector.Load(db, kv.TrieOfStorage, etl.IdentityLoadFunc, etl.TransformArgs{Quit: quit}); err != nil {
+	if err := load(stTrieCollector, kv.TrieOfStorage); err != nil {
 		return err
 	}
 	return nil
 }
 
+// RewindTrie unwinds the hashed state and the trie of tx from head back to blockNum, so that
+// proofs at blockNum can be built from them. tx is meant to be a memory batch on top of a
+// read-only transaction, as nothing of the rewind is to be persisted.
+func RewindTrie(ctx context.Context, logPrefix string, tx kv.RwTx, head, blockNum uint64, dirs datadir.Dirs, blockReader services.FullBlockReader, historyV3 bool, agg *state.AggregatorV3) error {
+	header, err := blockReader.HeaderByNumber(ctx, tx, blockNum)
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		return fmt.Errorf("header not found for block number %d", blockNum)
+	}
+
+	u := &UnwindState{UnwindPoint: blockNum}
+	s := &StageState{BlockNumber: head}
+	if err := unwindHashStateStageImpl(logPrefix, u, s, tx, StageHashStateCfg(nil, dirs, historyV3, agg), ctx); err != nil {
+		return err
+	}
+	cfg := StageTrieCfg(nil, false, false, false, dirs.Tmp, blockReader, nil, historyV3, agg, nil)
+	return unwindIntermediateHashesStageImpl(logPrefix, u, s, tx, cfg, header.Root, true /* memBatch */, ctx.Done())
+}
+
+// maxSkippedProofs bounds the due blocks a run of the stage went past that proofs are taken of,
+// each one costing a rewind of the trie. The older ones of a longer run are not retained.
+var maxSkippedProofs = 32
+
+// retainSkippedProofs takes the proofs of the due blocks a run of the stage went past, from
+// the trie rewound from to down to each of them in a memory batch, which is dropped afterwards.
+func retainSkippedProofs(ctx context.Context, logPrefix string, from, to uint64, tx kv.RwTx, cfg TrieCfg) error {
+	blocks := cfg.proofs.Config().DueBetween(from, to)
+	if len(blocks) == 0 {
+		return nil
+	}
+	if len(blocks) > maxSkippedProofs {
+		log.Warn(fmt.Sprintf("[%s] Proofs not retained for the older blocks of the run", logPrefix), "from", blocks[len(blocks)-1], "to", blocks[maxSkippedProofs])
+		blocks = blocks[:maxSkippedProofs]
+	}
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return err
+	}
+	pruneTo := pm.History.PruneTo(to)
+
+	batch := memdb.NewMemoryBatch(tx, cfg.tmpDir)
+	defer batch.Rollback()
+	head := to
+	for _, blockNum := range blocks {
+		if blockNum < pruneTo {
+			break // The state history to rewind to the block is pruned
+		}
+		if err = RewindTrie(ctx, logPrefix, batch, head, blockNum, datadir.Dirs{Tmp: cfg.tmpDir}, cfg.blockReader, cfg.historyV3, cfg.agg); err != nil {
+			return err
+		}
+		if err = cfg.proofs.RetainAt(ctx, batch, tx, blockNum, cfg.historyV3); err != nil {
+			return err
+		}
+		head = blockNum
+	}
+	return nil
+}
+
 func assertSubset(a, b uint16) {
 	if (a & b) != a { // a & b == a - checks whether a is subset of b
 		panic(fmt.Errorf("invariant 'is subset' failed: %b, %b", a, b))
@@ -3,18 +3,27 @@ package stagedsync
 import (
 	"context"
 	"encoding/binary"
+	"math/big"
 	"testing"
 
+	"github.com/RoaringBitmap/roaring/roaring64"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/hexutility"
 	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/kv/temporal/historyv2"
+	"github.com/ledgerwatch/log/v3"
 
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/proofs"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/trie"
 
@@ -78,7 +87,7 @@ func TestAccountAndStorageTrie(t *testing.T) {
 
 	historyV3 := false
 	blockReader := snapshotsync.NewBlockReader()
-	cfg := StageTrieCfg(db, false, true, false, t.TempDir(), blockReader, nil, historyV3, nil)
+	cfg := StageTrieCfg(db, false, true, false, t.TempDir(), blockReader, nil, historyV3, nil, nil)
 	_, err := RegenerateIntermediateHashes("IH", tx, cfg, libcommon.Hash{} /* expectedRootHash */, ctx)
 	assert.Nil(t, err)
 
@@ -200,7 +209,7 @@ func TestAccountTrieAroundExtensionNode(t *testing.T) {
 	assert.Nil(t, tx.Put(kv.HashedAccounts, hash6[:], encoded))
 
 	blockReader := snapshotsync.NewBlockReader()
-	_, err := RegenerateIntermediateHashes("IH", tx, StageTrieCfg(db, false, true, false, t.TempDir(), blockReader, nil, historyV3, nil), libcommon.Hash{} /* expectedRootHash */, ctx)
+	_, err := RegenerateIntermediateHashes("IH", tx, StageTrieCfg(db, false, true, false, t.TempDir(), blockReader, nil, historyV3, nil, nil), libcommon.Hash{} /* expectedRootHash */, ctx)
 	assert.Nil(t, err)
 
 	accountTrie := make(map[string][]byte)
@@ -263,7 +272,7 @@ func TestStorageDeletion(t *testing.T) {
 	// ----------------------------------------------------------------
 	historyV3 := false
 	blockReader := snapshotsync.NewBlockReader()
-	cfg := StageTrieCfg(db, false, true, false, t.TempDir(), blockReader, nil, historyV3, nil)
+	cfg := StageTrieCfg(db, false, true, false, t.TempDir(), blockReader, nil, historyV3, nil, nil)
 	_, err = RegenerateIntermediateHashes("IH", tx, cfg, libcommon.Hash{} /* expectedRootHash */, ctx)
 	assert.Nil(t, err)
 
@@ -382,7 +391,7 @@ func TestHiveTrieRoot(t *testing.T) {
 
 	historyV3 := false
 	blockReader := snapshotsync.NewBlockReader()
-	cfg := StageTrieCfg(db, false, true, false, t.TempDir(), blockReader, nil, historyV3, nil)
+	cfg := StageTrieCfg(db, false, true, false, t.TempDir(), blockReader, nil, historyV3, nil, nil)
 	_, err := RegenerateIntermediateHashes("IH", tx, cfg, libcommon.Hash{} /* expectedRootHash */, ctx)
 	require.Nil(t, err)
 
@@ -404,3 +413,112 @@ func TestHiveTrieRoot(t *testing.T) {
 
 	assert.Equal(t, regeneratedRoot, incrementalRoot)
 }
+
+func TestIntermediateHashesRetainsProofs(t *testing.T) {
+	db, tx := memdb.NewTestTx(t)
+	ctx := context.Background()
+
+	address := libcommon.HexToAddress("0x4200000000000000000000000000000000000016")
+	addrHash, err := common.HashData(address[:])
+	require.NoError(t, err)
+	encode := func(balance uint64) []byte {
+		acc := accounts.NewAccount()
+		acc.Balance.SetUint64(balance)
+		encoded := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(encoded)
+		return encoded
+	}
+	rootOf := func(encoded []byte) libcommon.Hash {
+		_, tx := memdb.NewTestTx(t)
+		require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], encoded))
+		root, err := trie.CalcRoot("test", tx)
+		require.NoError(t, err)
+		return root
+	}
+	encoded := encode(params.Ether)
+	require.NoError(t, tx.Put(kv.PlainState, address[:], encoded))
+	require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], encoded))
+	root := rootOf(encoded)
+
+	writeHeader := func(number uint64, root libcommon.Hash) {
+		header := &types.Header{Number: new(big.Int).SetUint64(number), Root: root}
+		rawdb.WriteHeader(tx, header)
+		require.NoError(t, rawdb.WriteCanonicalHash(tx, header.Hash(), number))
+	}
+	for _, number := range []uint64{10, 19, 20, 25} {
+		writeHeader(number, root)
+	}
+	writeHeader(30, libcommon.HexToHash("0xbad"))
+
+	proofDB := mdbx.NewMDBX(log.New()).InMem(t.TempDir()).Label(kv.AcctProofDB).
+		WithTableCfg(func(kv.TableCfg) kv.TableCfg { return proofs.TablesCfg }).MustOpen()
+	t.Cleanup(proofDB.Close)
+	store := proofs.NewStore(proofDB, proofs.Config{Addresses: []libcommon.Address{address}, Interval: 10, Retention: 20})
+	cfg := StageTrieCfg(db, true, true, true, t.TempDir(), snapshotsync.NewBlockReader(), nil, false, nil, store)
+
+	// Proofs are taken at the due blocks the trie root is verified for
+	spawn := func(to uint64) error {
+		require.NoError(t, stages.SaveStageProgress(tx, stages.Execution, to))
+		progress, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+		require.NoError(t, err)
+		_, err = SpawnIntermediateHashesStage(&StageState{ID: stages.IntermediateHashes, BlockNumber: progress}, nil, tx, cfg, ctx, true)
+		return err
+	}
+	require.NoError(t, spawn(20))
+	accProof, err := store.Read(ctx, address, nil, 20)
+	require.NoError(t, err)
+	require.Equal(t, uint64(params.Ether), accProof.Balance.ToInt().Uint64())
+	require.NotEmpty(t, accProof.AccountProof)
+
+	require.NoError(t, spawn(25))
+	_, err = store.Read(ctx, address, nil, 25)
+	require.ErrorIs(t, err, proofs.ErrNotRetained)
+
+	require.Error(t, spawn(30))
+	_, err = store.Read(ctx, address, nil, 30)
+	require.ErrorIs(t, err, proofs.ErrNotRetained)
+
+	// Unwinding the trie drops the proofs of the unwound blocks
+	u := &UnwindState{ID: stages.IntermediateHashes, UnwindPoint: 19}
+	require.NoError(t, UnwindIntermediateHashesStage(u, &StageState{ID: stages.IntermediateHashes, BlockNumber: 25}, tx, cfg, ctx))
+	_, err = store.Read(ctx, address, nil, 20)
+	require.ErrorIs(t, err, proofs.ErrNotRetained)
+
+	// A run over several blocks takes the proofs of the due blocks it went past within the
+	// retention window, from the trie rewound to them. The balance changes at block 33.
+	writeHeader(30, root)
+	changed := encode(2 * params.Ether)
+	require.NoError(t, tx.Put(kv.PlainState, address[:], changed))
+	require.NoError(t, tx.Put(kv.HashedAccounts, addrHash[:], changed))
+	require.NoError(t, tx.Put(kv.AccountChangeSet, hexutility.EncodeTs(33), append(address.Bytes(), encoded...)))
+	index, err := roaring64.BitmapOf(33).ToBytes()
+	require.NoError(t, err)
+	require.NoError(t, tx.Put(kv.AccountsHistory, historyv2.AccountIndexChunkKey(address[:], ^uint64(0)), index))
+	for _, number := range []uint64{40, 45} {
+		writeHeader(number, rootOf(changed))
+	}
+	require.NoError(t, spawn(45))
+	for blockNum, balance := range map[uint64]uint64{30: params.Ether, 40: 2 * params.Ether} {
+		accProof, err := store.Read(ctx, address, nil, blockNum)
+		require.NoError(t, err, blockNum)
+		require.Equal(t, balance, accProof.Balance.ToInt().Uint64(), blockNum)
+	}
+	_, err = store.Read(ctx, address, nil, 20)
+	require.ErrorIs(t, err, proofs.ErrNotRetained)
+
+	// The proofs left after the progress by a run that was not committed are dropped by the next run,
+	// which only takes the proofs of the latest due blocks it went past
+	require.NoError(t, store.Retain(ctx, tx, 70))
+	defer func(limit int) { maxSkippedProofs = limit }(maxSkippedProofs)
+	maxSkippedProofs = 1
+	for _, number := range []uint64{50, 60, 65} {
+		writeHeader(number, rootOf(changed))
+	}
+	require.NoError(t, spawn(65))
+	_, err = store.Read(ctx, address, nil, 60)
+	require.NoError(t, err)
+	for _, blockNum := range []uint64{50, 70} {
+		_, err = store.Read(ctx, address, nil, blockNum)
+		require.ErrorIs(t, err, proofs.ErrNotRetained, blockNum)
+	}
+}
@@ -174,12 +174,12 @@ func SpawnMiningCreateBlockStage(s *StageState, tx kv.RwTx, cfg MiningCreateBloc
 		timestamp = cfg.blockBuilderParameters.Timestamp
 	}
 
-	//header := core.MakeEmptyHeader(parent, &cfg.chainConfig, timestamp, &cfg.miner.MiningConfig.GasLimit)
-	var newGas uint64
-	newGas = 15_000_000	// FIXME - extract from 1st Deposit tx
-	log.Debug("MMDBG Override gas limit", "old", cfg.miner.MiningConfig.GasLimit, "new", newGas)
-	header := core.MakeEmptyHeader(parent, &cfg.chainConfig, timestamp, &newGas)
-	
+	header := core.MakeEmptyHeader(parent, &cfg.chainConfig, timestamp, &cfg.miner.MiningConfig.GasLimit)
+	if cfg.blockBuilderParameters != nil && cfg.blockBuilderParameters.GasLimit != nil {
+		// The rollup node dictates the gas limit, it is not subject to the usual adjustment bounds
+		header.GasLimit = *cfg.blockBuilderParameters.GasLimit
+	}
+
 	header.Coinbase = coinbase
 	header.Extra = cfg.miner.MiningConfig.ExtraData
 
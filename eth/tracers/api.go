@@ -16,4 +16,5 @@ type TraceConfig struct {
 	Reexec         *uint64
 	NoRefunds      *bool // Turns off gas refunds when tracing
 	StateOverrides *ethapi.StateOverrides
+	Aggregate      *bool // Traces all the transactions of a block with the profilerTracer, returning its only result. Timeout covers the whole block
 }
@@ -0,0 +1,9 @@
+package tracetest
+
+import (
+	"testing"
+)
+
+func TestErc20TransferTracerNative(t *testing.T) {
+	testJSONTracer("erc20TransferTracer", "erc20_transfer_tracer", t)
+}
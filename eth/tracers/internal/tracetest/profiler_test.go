@@ -0,0 +1,135 @@
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/tests"
+)
+
+type profileStats struct {
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+type profile struct {
+	Transactions       uint64                             `json:"transactions"`
+	Steps              uint64                             `json:"steps"`
+	Gas                uint64                             `json:"gas"`
+	MemoryExpansionGas uint64                             `json:"memoryExpansionGas"`
+	Opcodes            map[string]profileStats            `json:"opcodes"`
+	Contracts          map[libcommon.Address]profileStats `json:"contracts"`
+	Depths             []profileStats                     `json:"depths"`
+	Precompiles        map[libcommon.Address]profileStats `json:"precompiles"`
+}
+
+// TestProfilerTracer profiles a contract writing to memory and giving it to the
+// identity precompile. The gas given to the precompile is not accounted to STATICCALL.
+func TestProfilerTracer(t *testing.T) {
+	var to = libcommon.HexToAddress("0x00000000000000000000000000000000deadbeef")
+	privkey, err := crypto.HexToECDSA("0000000000000000deadbeef00000000000000000000000000000000deadbeef")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	signer := types.LatestSigner(params.MainnetChainConfig)
+	tx, err := types.SignNewTx(privkey, *signer, &types.LegacyTx{
+		GasPrice: uint256.NewInt(0),
+		CommonTx: types.CommonTx{
+			Gas: 50000,
+			To:  &to,
+		},
+	})
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	origin, _ := signer.Sender(tx)
+	txContext := evmtypes.TxContext{
+		Origin:   origin,
+		GasPrice: uint256.NewInt(1),
+	}
+	context := evmtypes.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Coinbase:    libcommon.Address{},
+		BlockNumber: 8000000,
+		Time:        5,
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+	}
+	identity := libcommon.BytesToAddress([]byte{0x4})
+	var code = []byte{
+		byte(vm.PUSH1), 0x0, byte(vm.PUSH1), 0x0, byte(vm.MSTORE), // expand memory to one word
+		byte(vm.PUSH1), 0x0, byte(vm.PUSH1), 0x0, byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x0, // outs zero, ins the first word
+		byte(vm.PUSH1), 0x4, byte(vm.GAS), byte(vm.STATICCALL), // address=identity, gas=GAS
+		byte(vm.POP), byte(vm.STOP),
+	}
+	var alloc = core.GenesisAlloc{
+		to: core.GenesisAccount{
+			Nonce: 1,
+			Code:  code,
+		},
+		origin: core.GenesisAccount{
+			Nonce:   0,
+			Balance: big.NewInt(500000000000000),
+		},
+	}
+	rules := params.MainnetChainConfig.Rules(context.BlockNumber, context.Time)
+	_, dbTx := memdb.NewTestTx(t)
+	statedb, _ := tests.MakePreState(rules, dbTx, alloc, context.BlockNumber)
+	// Create the tracer, the EVM environment and run it
+	tracer, err := tracers.New("profilerTracer", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create profiler tracer: %v", err)
+	}
+	evm := vm.NewEVM(context, txContext, statedb, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	msg, err := tx.AsMessage(*signer, nil, rules)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.GetGas()))
+	if _, err = st.TransitionDb(true /* refunds */, false /* gasBailout */); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var have profile
+	if err := json.Unmarshal(res, &have); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	// STATICCALL costs 700 before Berlin, the identity precompile 15 + 3 per word
+	want := profile{
+		Transactions:       1,
+		Steps:              12,
+		Gas:                749,
+		MemoryExpansionGas: 3,
+		Opcodes: map[string]profileStats{
+			"PUSH1":      {Count: 7, Gas: 21},
+			"MSTORE":     {Count: 1, Gas: 6},
+			"GAS":        {Count: 1, Gas: 2},
+			"STATICCALL": {Count: 1, Gas: 700},
+			"POP":        {Count: 1, Gas: 2},
+			"STOP":       {Count: 1, Gas: 0},
+		},
+		Contracts:   map[libcommon.Address]profileStats{to: {Count: 12, Gas: 731}},
+		Depths:      []profileStats{{Count: 12, Gas: 731}},
+		Precompiles: map[libcommon.Address]profileStats{identity: {Count: 1, Gas: 18}},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("profile mismatch\n have: %v\n want: %+v\n", string(res), want)
+	}
+}
@@ -0,0 +1,317 @@
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	register("flatCallTracer", newFlatCallTracer)
+}
+
+var parityErrorMapping = map[string]string{
+	"contract creation code storage out of gas": "Out of gas",
+	"out of gas":                      "Out of gas",
+	"gas uint64 overflow":             "Out of gas",
+	"max code size exceeded":          "Out of gas",
+	"invalid jump destination":        "Bad jump destination",
+	"execution reverted":              "Reverted",
+	"return data out of bounds":       "Out of bounds",
+	"stack limit reached 1024 (1023)": "Out of stack",
+	"precompiled failed":              "Built-in failed",
+	"invalid input length":            "Built-in failed",
+}
+
+var parityErrorMappingStartingWith = map[string]string{
+	"invalid opcode:": "Bad instruction",
+	"stack underflow": "Stack underflow",
+}
+
+// flatCallFrame is a Parity-style trace of a call, as returned by trace_transaction.
+type flatCallFrame struct {
+	Action              flatCallAction  `json:"action"`
+	BlockHash           *libcommon.Hash `json:"blockHash"`
+	BlockNumber         uint64          `json:"blockNumber"`
+	Error               string          `json:"error,omitempty"`
+	Result              *flatCallResult `json:"result,omitempty"`
+	Subtraces           int             `json:"subtraces"`
+	TraceAddress        []int           `json:"traceAddress"`
+	TransactionHash     *libcommon.Hash `json:"transactionHash"`
+	TransactionPosition uint64          `json:"transactionPosition"`
+	Type                string          `json:"type"`
+}
+
+type flatCallAction struct {
+	SelfDestructed *libcommon.Address `json:"address,omitempty"`
+	Balance        *hexutil.Big       `json:"balance,omitempty"`
+	CallType       string             `json:"callType,omitempty"`
+	CreationMethod string             `json:"creationMethod,omitempty"`
+	From           *libcommon.Address `json:"from,omitempty"`
+	Gas            *hexutil.Uint64    `json:"gas,omitempty"`
+	Init           *hexutil.Bytes     `json:"init,omitempty"`
+	Input          *hexutil.Bytes     `json:"input,omitempty"`
+	RefundAddress  *libcommon.Address `json:"refundAddress,omitempty"`
+	To             *libcommon.Address `json:"to,omitempty"`
+	Value          *hexutil.Big       `json:"value,omitempty"`
+}
+
+type flatCallResult struct {
+	Address *libcommon.Address `json:"address,omitempty"`
+	Code    *hexutil.Bytes     `json:"code,omitempty"`
+	GasUsed *hexutil.Uint64    `json:"gasUsed,omitempty"`
+	Output  *hexutil.Bytes     `json:"output,omitempty"`
+}
+
+// flatCallTracer reports the call frames of a transaction as the flat list of Parity-style
+// traces returned by trace_transaction. It is built on top of callTracer.
+type flatCallTracer struct {
+	tracer      *callTracer
+	config      flatCallTracerConfig
+	ctx         *tracers.Context
+	started     bool   // Whether the top call started, it does not for deposits failing before execution
+	precompiles []bool // Whether the open child calls are calls to precompiles
+}
+
+type flatCallTracerConfig struct {
+	ConvertParityErrors bool `json:"convertParityErrors"` // If true, errors are converted to the Parity format
+	IncludePrecompiles  bool `json:"includePrecompiles"`  // If true, calls to precompiles are included
+}
+
+// newFlatCallTracer returns a native go tracer which tracks call frames of a tx in the
+// Parity format, and implements vm.EVMLogger.
+func newFlatCallTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config flatCallTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	// The inner call tracer uses the default configuration, Parity traces have no logs
+	tracer, err := newCallTracer(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &flatCallTracer{tracer: tracer.(*callTracer), ctx: ctx, config: config}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *flatCallTracer) CaptureStart(env vm.VMInterface, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.started = true
+	t.tracer.CaptureStart(env, from, to, precompile, create, input, gas, value, code)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *flatCallTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.tracer.CaptureEnd(output, gasUsed, err)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *flatCallTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	t.tracer.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *flatCallTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	t.tracer.CaptureFault(pc, op, gas, cost, scope, depth, err)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *flatCallTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.tracer.CaptureEnter(typ, from, to, precompile, create, input, gas, value, code)
+	t.precompiles = append(t.precompiles, precompile)
+
+	// Child calls must have a value, even if it's zero. Practically speaking,
+	// only STATICCALL and DELEGATECALL have nil value.
+	if call := &t.tracer.callstack[len(t.tracer.callstack)-1]; call.Value == nil {
+		call.Value = new(big.Int)
+	}
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *flatCallTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.tracer.CaptureExit(output, gasUsed, err)
+	precompile := t.precompiles[len(t.precompiles)-1]
+	t.precompiles = t.precompiles[:len(t.precompiles)-1]
+
+	// Parity traces don't include CALL/STATICCALLs to precompiles
+	if t.config.IncludePrecompiles || !precompile {
+		return
+	}
+	parent := &t.tracer.callstack[len(t.tracer.callstack)-1]
+	if len(parent.Calls) == 0 {
+		return
+	}
+	if typ := parent.Calls[len(parent.Calls)-1].Type; typ == vm.CALL || typ == vm.STATICCALL {
+		parent.Calls = parent.Calls[:len(parent.Calls)-1]
+	}
+}
+
+func (t *flatCallTracer) CaptureTxStart(gasLimit uint64) {
+	t.tracer.CaptureTxStart(gasLimit)
+}
+
+func (t *flatCallTracer) CaptureTxEnd(restGas uint64) {
+	t.tracer.CaptureTxEnd(restGas)
+}
+
+// GetResult returns the json-encoded list of flat traces, and any error arising
+// from the encoding or forceful termination (via `Stop`). Deposits failing before
+// their execution, like system transactions after Regolith, have no traces.
+func (t *flatCallTracer) GetResult() (json.RawMessage, error) {
+	if len(t.tracer.callstack) != 1 {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+	flat := []flatCallFrame{}
+	if t.started {
+		var err error
+		if flat, err = flatFromNested(&t.tracer.callstack[0], []int{}, t.config.ConvertParityErrors, t.ctx); err != nil {
+			return nil, err
+		}
+	}
+	res, err := json.Marshal(flat)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.tracer.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *flatCallTracer) Stop(err error) {
+	t.tracer.Stop(err)
+}
+
+// flatFromNested appends the traces of input and of its children, in depth-first order.
+func flatFromNested(input *callFrame, traceAddress []int, convertErrs bool, ctx *tracers.Context) ([]flatCallFrame, error) {
+	var frame *flatCallFrame
+	switch input.Type {
+	case vm.CREATE, vm.CREATE2:
+		frame = newFlatCreate(input)
+	case vm.SELFDESTRUCT:
+		frame = newFlatSelfdestruct(input)
+	case vm.CALL, vm.STATICCALL, vm.CALLCODE, vm.DELEGATECALL:
+		frame = newFlatCall(input)
+	default:
+		return nil, fmt.Errorf("unrecognized call frame type: %s", input.Type)
+	}
+
+	frame.Error = input.Error
+	if convertErrs {
+		convertErrorToParity(frame)
+	}
+	// The output of reverted calls holds the revert reason, it is discarded for other failures
+	if input.Error != "" && input.Error != vm.ErrExecutionReverted.Error() {
+		frame.Result = nil
+	}
+	frame.TraceAddress = traceAddress
+	frame.Subtraces = len(input.Calls)
+	if ctx != nil {
+		if ctx.BlockHash != (libcommon.Hash{}) {
+			frame.BlockHash = &ctx.BlockHash
+		}
+		if ctx.BlockNumber != nil {
+			frame.BlockNumber = ctx.BlockNumber.Uint64()
+		}
+		if ctx.TxHash != (libcommon.Hash{}) {
+			frame.TransactionHash = &ctx.TxHash
+			frame.TransactionPosition = uint64(ctx.TxIndex)
+		}
+	}
+
+	output := []flatCallFrame{*frame}
+	for i := range input.Calls {
+		childAddress := make([]int, len(traceAddress), len(traceAddress)+1)
+		copy(childAddress, traceAddress)
+		flat, err := flatFromNested(&input.Calls[i], append(childAddress, i), convertErrs, ctx)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, flat...)
+	}
+	return output, nil
+}
+
+func newFlatCreate(input *callFrame) *flatCallFrame {
+	var (
+		gas     = hexutil.Uint64(input.Gas)
+		gasUsed = hexutil.Uint64(input.GasUsed)
+		init    = hexutil.Bytes(input.Input)
+		code    = hexutil.Bytes(input.Output)
+	)
+	return &flatCallFrame{
+		Type: strings.ToLower(vm.CREATE.String()),
+		Action: flatCallAction{
+			CreationMethod: strings.ToLower(input.Type.String()),
+			From:           &input.From,
+			Gas:            &gas,
+			Value:          (*hexutil.Big)(input.Value),
+			Init:           &init,
+		},
+		Result: &flatCallResult{
+			GasUsed: &gasUsed,
+			Address: &input.To,
+			Code:    &code,
+		},
+	}
+}
+
+func newFlatCall(input *callFrame) *flatCallFrame {
+	var (
+		gas     = hexutil.Uint64(input.Gas)
+		gasUsed = hexutil.Uint64(input.GasUsed)
+		data    = hexutil.Bytes(input.Input)
+		output  = hexutil.Bytes(input.Output)
+	)
+	return &flatCallFrame{
+		Type: strings.ToLower(vm.CALL.String()),
+		Action: flatCallAction{
+			From:     &input.From,
+			To:       &input.To,
+			Gas:      &gas,
+			Value:    (*hexutil.Big)(input.Value),
+			CallType: strings.ToLower(input.Type.String()),
+			Input:    &data,
+		},
+		Result: &flatCallResult{
+			GasUsed: &gasUsed,
+			Output:  &output,
+		},
+	}
+}
+
+func newFlatSelfdestruct(input *callFrame) *flatCallFrame {
+	return &flatCallFrame{
+		Type: "suicide",
+		Action: flatCallAction{
+			SelfDestructed: &input.From,
+			Balance:        (*hexutil.Big)(input.Value),
+			RefundAddress:  &input.To,
+		},
+	}
+}
+
+func convertErrorToParity(call *flatCallFrame) {
+	if call.Error == "" {
+		return
+	}
+	if parityError, ok := parityErrorMapping[call.Error]; ok {
+		call.Error = parityError
+		return
+	}
+	for gethError, parityError := range parityErrorMappingStartingWith {
+		if strings.HasPrefix(call.Error, gethError) {
+			call.Error = parityError
+			return
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	register("erc20TransferTracer", newErc20TransferTracer)
+}
+
+var (
+	erc20TransferTopic = libcommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef") // Transfer(address,address,uint256)
+	erc20ApprovalTopic = libcommon.HexToHash("0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925") // Approval(address,address,uint256)
+
+	erc20TransferSelector     = []byte{0xa9, 0x05, 0x9c, 0xbb} // transfer(address,uint256)
+	erc20TransferFromSelector = []byte{0x23, 0xb8, 0x72, 0xdd} // transferFrom(address,address,uint256)
+	erc20ApproveSelector      = []byte{0x09, 0x5e, 0xa7, 0xb3} // approve(address,uint256)
+)
+
+// Sources of the reported transfers and approvals
+const (
+	erc20SourceLog  = "log"
+	erc20SourceCall = "call"
+)
+
+type erc20Transfer struct {
+	Token  libcommon.Address `json:"token"`
+	From   libcommon.Address `json:"from"`
+	To     libcommon.Address `json:"to"`
+	Value  *hexutil.Big      `json:"value"`
+	Source string            `json:"source"`
+}
+
+type erc20Approval struct {
+	Token   libcommon.Address `json:"token"`
+	Owner   libcommon.Address `json:"owner"`
+	Spender libcommon.Address `json:"spender"`
+	Value   *hexutil.Big      `json:"value"`
+	Source  string            `json:"source"`
+}
+
+// erc20Event is either a transfer or an approval.
+type erc20Event struct {
+	transfer *erc20Transfer
+	approval *erc20Approval
+}
+
+func (e erc20Event) token() libcommon.Address {
+	if e.transfer != nil {
+		return e.transfer.Token
+	}
+	return e.approval.Token
+}
+
+func (e erc20Event) source() string {
+	if e.transfer != nil {
+		return e.transfer.Source
+	}
+	return e.approval.Source
+}
+
+type erc20Frame struct {
+	call   *erc20Event  // Transfer or approval requested by the input of the call, if any
+	events []erc20Event // Events of the call and of its successful subcalls, in execution order
+}
+
+// erc20TransferTracer reports the ERC-20 token transfers and approvals of a transaction,
+// decoded from the Transfer and Approval logs. Calls to transfer, transferFrom and approve
+// of tokens not logging the matching event are reported too. Events of the reverted calls
+// are dropped.
+//
+// Example:
+//
+//	> debug.traceTransaction( "0x214e597e35da083692f5386141e69f47e973b2c56e7a8073b1ea08fd7571e9de", {tracer: "erc20TransferTracer"})
+//	{
+//	  transfers: [{token: "0x...", from: "0x...", to: "0x...", value: "0x64", source: "log"}],
+//	  approvals: []
+//	}
+type erc20TransferTracer struct {
+	noopTracer
+	frames    []erc20Frame
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newErc20TransferTracer returns a native go tracer which collects the token transfers and
+// approvals of a tx, and implements vm.EVMLogger.
+func newErc20TransferTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &erc20TransferTracer{}, nil
+}
+
+// decodeErc20Call returns the transfer or approval requested from token by caller with input.
+func decodeErc20Call(token, caller libcommon.Address, input []byte) *erc20Event {
+	if len(input) < 4 {
+		return nil
+	}
+	word := func(i int) []byte { return input[4+32*i : 4+32*(i+1)] }
+	address := func(i int) libcommon.Address { return libcommon.BytesToAddress(word(i)) }
+	value := func(i int) *hexutil.Big { return (*hexutil.Big)(new(big.Int).SetBytes(word(i))) }
+	switch {
+	case bytes.Equal(input[:4], erc20TransferSelector) && len(input) >= 4+32*2:
+		return &erc20Event{transfer: &erc20Transfer{Token: token, From: caller, To: address(0), Value: value(1), Source: erc20SourceCall}}
+	case bytes.Equal(input[:4], erc20TransferFromSelector) && len(input) >= 4+32*3:
+		return &erc20Event{transfer: &erc20Transfer{Token: token, From: address(0), To: address(1), Value: value(2), Source: erc20SourceCall}}
+	case bytes.Equal(input[:4], erc20ApproveSelector) && len(input) >= 4+32*2:
+		return &erc20Event{approval: &erc20Approval{Token: token, Owner: caller, Spender: address(0), Value: value(1), Source: erc20SourceCall}}
+	}
+	return nil
+}
+
+// decodeErc20Log returns the transfer or approval logged by token, ERC-721 transfers and
+// approvals have their token id as a fourth topic and are skipped.
+func decodeErc20Log(token libcommon.Address, topics []libcommon.Hash, data []byte) *erc20Event {
+	if len(topics) != 3 || len(data) != 32 {
+		return nil
+	}
+	value := (*hexutil.Big)(new(big.Int).SetBytes(data))
+	switch topics[0] {
+	case erc20TransferTopic:
+		return &erc20Event{transfer: &erc20Transfer{Token: token, From: libcommon.BytesToAddress(topics[1][:]), To: libcommon.BytesToAddress(topics[2][:]), Value: value, Source: erc20SourceLog}}
+	case erc20ApprovalTopic:
+		return &erc20Event{approval: &erc20Approval{Token: token, Owner: libcommon.BytesToAddress(topics[1][:]), Spender: libcommon.BytesToAddress(topics[2][:]), Value: value, Source: erc20SourceLog}}
+	}
+	return nil
+}
+
+func (t *erc20TransferTracer) enter(typ vm.OpCode, from, to libcommon.Address, input []byte) {
+	var frame erc20Frame
+	// Delegated calls run in the context of their caller, which was called with the same input
+	if typ == vm.CALL {
+		frame.call = decodeErc20Call(to, from, input)
+	}
+	t.frames = append(t.frames, frame)
+}
+
+func (t *erc20TransferTracer) exit(err error) {
+	size := len(t.frames)
+	frame := t.frames[size-1]
+	t.frames = t.frames[:size-1]
+	if err != nil {
+		return
+	}
+	if frame.call != nil {
+		logged := false
+		for _, e := range frame.events {
+			if e.source() == erc20SourceLog && e.token() == frame.call.token() && (e.transfer != nil) == (frame.call.transfer != nil) {
+				logged = true
+				break
+			}
+		}
+		if !logged {
+			frame.events = append(frame.events, *frame.call)
+		}
+	}
+	if size > 1 {
+		t.frames[size-2].events = append(t.frames[size-2].events, frame.events...)
+	} else {
+		// Keep the events of the top call for GetResult
+		t.frames = append(t.frames, erc20Frame{events: frame.events})
+	}
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *erc20TransferTracer) CaptureStart(env vm.VMInterface, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	t.frames = nil
+	t.enter(typ, from, to, input)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *erc20TransferTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit(err)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *erc20TransferTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if op != vm.LOG3 || err != nil || atomic.LoadUint32(&t.interrupt) > 0 {
+		return
+	}
+	stackData := scope.Stack.Data
+	mStart := stackData[len(stackData)-1]
+	mSize := stackData[len(stackData)-2]
+	if !mSize.IsUint64() || mSize.Uint64() != 32 {
+		return
+	}
+	topics := make([]libcommon.Hash, 3)
+	for i := range topics {
+		topics[i] = libcommon.Hash(stackData[len(stackData)-2-(i+1)].Bytes32())
+	}
+	data := scope.Memory.GetCopy(int64(mStart.Uint64()), 32)
+	if event := decodeErc20Log(scope.Contract.Address(), topics, data); event != nil {
+		t.frames[len(t.frames)-1].events = append(t.frames[len(t.frames)-1].events, *event)
+	}
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *erc20TransferTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.enter(typ, from, to, input)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *erc20TransferTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.exit(err)
+}
+
+// GetResult returns the json-encoded transfers and approvals, and any error arising
+// from the encoding or forceful termination (via `Stop`).
+func (t *erc20TransferTracer) GetResult() (json.RawMessage, error) {
+	result := struct {
+		Transfers []*erc20Transfer `json:"transfers"`
+		Approvals []*erc20Approval `json:"approvals"`
+	}{
+		Transfers: []*erc20Transfer{},
+		Approvals: []*erc20Approval{},
+	}
+	if len(t.frames) == 1 {
+		for _, e := range t.frames[0].events {
+			if e.transfer != nil {
+				result.Transfers = append(result.Transfers, e.transfer)
+			} else {
+				result.Approvals = append(result.Approvals, e.approval)
+			}
+		}
+	}
+	res, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *erc20TransferTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
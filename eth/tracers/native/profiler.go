@@ -0,0 +1,237 @@
+package native
+
+import (
+	"encoding/json"
+	"sync/atomic"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+func init() {
+	register("profilerTracer", newProfilerTracer)
+}
+
+// profileStats aggregates the executions and the gas of a set of opcodes or calls.
+type profileStats struct {
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+func (s *profileStats) add(gas uint64) {
+	s.Count++
+	s.Gas += gas
+}
+
+type profile struct {
+	Transactions       uint64                              `json:"transactions"`
+	Steps              uint64                              `json:"steps"`
+	Gas                uint64                              `json:"gas"` // Gas of the executed opcodes and precompiles
+	MemoryExpansionGas uint64                              `json:"memoryExpansionGas"`
+	Opcodes            map[string]*profileStats            `json:"opcodes"`
+	Contracts          map[libcommon.Address]*profileStats `json:"contracts"`
+	Depths             []*profileStats                     `json:"depths"` // Indexed by call depth, starting at 0 for the top call
+	Precompiles        map[libcommon.Address]*profileStats `json:"precompiles"`
+}
+
+// profilerStep is a call opcode whose gas is known only once the call returned.
+type profilerStep struct {
+	op       vm.OpCode
+	contract libcommon.Address
+	gas      uint64 // Gas available before the opcode
+	cost     uint64 // Cost of the opcode, including the gas given to the callee
+}
+
+type profilerFrame struct {
+	address    libcommon.Address
+	precompile bool
+	memorySize uint64        // Size of the memory after the last opcode
+	pending    *profilerStep // Call opcode waiting for the next step of the frame
+	childGas   uint64        // Gas used by the callee of the pending opcode
+}
+
+// profilerTracer aggregates the gas and the executions of opcodes per opcode, per
+// contract and per call depth, with the usage of precompiles and the gas spent
+// expanding memory. The gas of the call opcodes excludes the gas used by the callee,
+// which is accounted to the opcodes of the callee or to the precompile. The tracer
+// keeps aggregating when reused for several transactions.
+//
+// Example:
+//
+//	> debug.traceTransaction( "0x214e597e35da083692f5386141e69f47e973b2c56e7a8073b1ea08fd7571e9de", {tracer: "profilerTracer"})
+//	{
+//	  transactions: 1,
+//	  steps: 24,
+//	  gas: 2617,
+//	  memoryExpansionGas: 9,
+//	  opcodes: {PUSH1: {count: 10, gas: 30}, ...},
+//	  contracts: {0x...: {count: 20, gas: 2557}, ...},
+//	  depths: [{count: 20, gas: 2557}, {count: 4, gas: 60}],
+//	  precompiles: {}
+//	}
+type profilerTracer struct {
+	noopTracer
+	profile   profile
+	frames    []*profilerFrame
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newProfilerTracer returns a native go tracer which profiles the opcodes of
+// transactions, and implements vm.EVMLogger.
+func newProfilerTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &profilerTracer{
+		profile: profile{
+			Opcodes:     make(map[string]*profileStats),
+			Contracts:   make(map[libcommon.Address]*profileStats),
+			Depths:      []*profileStats{},
+			Precompiles: make(map[libcommon.Address]*profileStats),
+		},
+	}, nil
+}
+
+// memoryGas returns the gas cost of a memory of the given size.
+func memoryGas(size uint64) uint64 {
+	words := (size + 31) / 32
+	return words*params.MemoryGas + words*words/params.QuadCoeffDiv
+}
+
+// addressStats returns the stats of address in m, adding them if missing.
+func addressStats(m map[libcommon.Address]*profileStats, address libcommon.Address) *profileStats {
+	if m[address] == nil {
+		m[address] = &profileStats{}
+	}
+	return m[address]
+}
+
+// isCallOp returns whether the cost of op includes the gas given to the callee.
+func isCallOp(op vm.OpCode) bool {
+	return op == vm.CALL || op == vm.CALLCODE || op == vm.DELEGATECALL || op == vm.STATICCALL
+}
+
+func (t *profilerTracer) record(op vm.OpCode, contract libcommon.Address, depth int, gas uint64) {
+	name := op.String()
+	if t.profile.Opcodes[name] == nil {
+		t.profile.Opcodes[name] = &profileStats{}
+	}
+	t.profile.Opcodes[name].add(gas)
+	addressStats(t.profile.Contracts, contract).add(gas)
+	for len(t.profile.Depths) <= depth {
+		t.profile.Depths = append(t.profile.Depths, &profileStats{})
+	}
+	t.profile.Depths[depth].add(gas)
+	t.profile.Steps++
+	t.profile.Gas += gas
+}
+
+// settle records the pending call opcode of the top frame, with the given gas
+// available after it.
+func (t *profilerTracer) settle(gasAfter uint64) {
+	frame := t.frames[len(t.frames)-1]
+	step := frame.pending
+	if step == nil {
+		return
+	}
+	gas := step.gas - gasAfter
+	if gas >= frame.childGas {
+		gas -= frame.childGas
+	} else {
+		gas = 0
+	}
+	t.record(step.op, step.contract, len(t.frames)-1, gas)
+	frame.pending, frame.childGas = nil, 0
+}
+
+func (t *profilerTracer) enter(to libcommon.Address, precompile bool) {
+	t.frames = append(t.frames, &profilerFrame{address: to, precompile: precompile})
+}
+
+func (t *profilerTracer) exit(gasUsed uint64) {
+	frame := t.frames[len(t.frames)-1]
+	// The call opcode failed, so there is no next step to settle it with
+	if step := frame.pending; step != nil {
+		t.record(step.op, step.contract, len(t.frames)-1, step.cost)
+	}
+	t.frames = t.frames[:len(t.frames)-1]
+	if frame.precompile {
+		addressStats(t.profile.Precompiles, frame.address).add(gasUsed)
+		t.profile.Gas += gasUsed
+	}
+	// Only the gas given by call opcodes is included in their cost
+	if len(t.frames) > 0 && t.frames[len(t.frames)-1].pending != nil {
+		t.frames[len(t.frames)-1].childGas += gasUsed
+	}
+}
+
+// CaptureTxStart implements the EVMLogger interface to count the profiled transactions.
+func (t *profilerTracer) CaptureTxStart(gasLimit uint64) {
+	t.profile.Transactions++
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *profilerTracer) CaptureStart(env vm.VMInterface, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.frames = nil
+	t.enter(to, precompile)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *profilerTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit(gasUsed)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *profilerTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		return
+	}
+	t.settle(gas)
+	if err != nil {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	contract := scope.Contract.Address()
+	if scope.Contract.CodeAddr != nil {
+		contract = *scope.Contract.CodeAddr
+	}
+	// The memory is already expanded for the current opcode
+	if size := uint64(scope.Memory.Len()); size > frame.memorySize {
+		t.profile.MemoryExpansionGas += memoryGas(size) - memoryGas(frame.memorySize)
+		frame.memorySize = size
+	}
+	if isCallOp(op) {
+		frame.pending = &profilerStep{op: op, contract: contract, gas: gas, cost: cost}
+		return
+	}
+	t.record(op, contract, len(t.frames)-1, cost)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *profilerTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.enter(to, precompile)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *profilerTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.exit(gasUsed)
+}
+
+// GetResult returns the json-encoded profile, and any error arising from the
+// encoding or forceful termination (via `Stop`).
+func (t *profilerTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.profile)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *profilerTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
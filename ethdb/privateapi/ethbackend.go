@@ -295,6 +295,20 @@ func (s *EthBackendServer) checkWithdrawalsPresence(time uint64, withdrawals []*
 	return nil
 }
 
+// checkGasLimitPresence makes sure the rollup node sets the gas limit of the blocks it asks for.
+func (s *EthBackendServer) checkGasLimitPresence(gasLimit *uint64) error {
+	if s.config.Optimism == nil {
+		return nil
+	}
+	if gasLimit == nil {
+		return &rpc.InvalidParamsError{Message: "missing gasLimit"}
+	}
+	if *gasLimit == 0 {
+		return &rpc.InvalidParamsError{Message: "gasLimit must be greater than zero"}
+	}
+	return nil
+}
+
 func (s *EthBackendServer) EngineGetBlobsBundleV1(ctx context.Context, in *remote.EngineGetBlobsBundleRequest) (*types2.BlobsBundleV1, error) {
 	return nil, fmt.Errorf("EngineGetBlobsBundleV1: not implemented yet")
 }
@@ -684,6 +698,10 @@ func (s *EthBackendServer) EngineForkChoiceUpdated(ctx context.Context, req *rem
 	if err := s.checkWithdrawalsPresence(payloadAttributes.Timestamp, param.Withdrawals); err != nil {
 		return nil, err
 	}
+	if err := s.checkGasLimitPresence(payloadAttributes.GasLimit); err != nil {
+		return nil, err
+	}
+	param.GasLimit = payloadAttributes.GasLimit
 
 	// Initiate payload building
 	log.Debug("MMDBG ethbackend.go Initiate payload building", "len", len(payloadAttributes.Transactions), "depositTx", payloadAttributes.Transactions)
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+// callCriteriaStart records the first block of the call criteria index. The criteria are
+// written during the execution, so the blocks already executed have none and are not in
+// the index: trace_filter scans them instead.
+var callCriteriaStart = Migration{
+	Name: "call_criteria_start",
+	Up: func(db kv.RwDB, dirs datadir.Dirs, progress []byte, BeforeCommit Callback) (err error) {
+		tx, err := db.BeginRw(context.Background())
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		execProgress, err := stages.GetStageProgress(tx, stages.Execution)
+		if err != nil {
+			return err
+		}
+		if execProgress > 0 {
+			if err := rawdb.WriteCallCriteriaStart(tx, execProgress+1); err != nil {
+				return err
+			}
+		}
+		if err := BeforeCommit(tx, nil, true); err != nil {
+			return err
+		}
+		return tx.Commit()
+	},
+}
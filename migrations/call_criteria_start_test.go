@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+func TestCallCriteriaStart(t *testing.T) {
+	require, tmpDir := require.New(t), t.TempDir()
+	start := func(db kv.RwDB) (start uint64) {
+		err := db.View(context.Background(), func(tx kv.Tx) (err error) {
+			start, err = rawdb.ReadCallCriteriaStart(tx)
+			return err
+		})
+		require.NoError(err)
+		return start
+	}
+
+	// The blocks of a new node are all indexed
+	db := memdb.NewTestDB(t)
+	migrator := NewMigrator(kv.ChainDB)
+	migrator.Migrations = []Migration{callCriteriaStart}
+	err := migrator.Apply(db, tmpDir)
+	require.NoError(err)
+	require.Zero(start(db))
+
+	// The blocks executed before the migration are not
+	db = memdb.NewTestDB(t)
+	err = db.Update(context.Background(), func(tx kv.RwTx) error {
+		return stages.SaveStageProgress(tx, stages.Execution, 100)
+	})
+	require.NoError(err)
+	err = migrator.Apply(db, tmpDir)
+	require.NoError(err)
+	require.Equal(uint64(101), start(db))
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/migrations"
 	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/turbo/proofs"
 	"github.com/ledgerwatch/log/v3"
 )
 
@@ -339,13 +340,7 @@ func OpenDatabase(config *nodecfg.Config, logger log.Logger, label kv.Label) (kv
 			opts = opts.GrowthStep(16 * datasize.MB)
 		}
 		if label == kv.AcctProofDB {
-			proofCfg :=  func(defaultBuckets kv.TableCfg) kv.TableCfg {
-				return kv.TableCfg {
-					"AccountProof": {},
-					"DbInfo": {},
-				}
-			}
-			opts = opts.WithTableCfg(proofCfg)
+			opts = opts.WithTableCfg(func(defaultBuckets kv.TableCfg) kv.TableCfg { return proofs.TablesCfg })
 		}
 		return opts.Open()
 	}
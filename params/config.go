@@ -35,17 +35,15 @@ import (
 var chainspecs embed.FS
 
 func readChainSpec(filename string) *chain.Config {
-	f, err := chainspecs.Open(filename)
+	data, err := chainspecs.ReadFile(filename)
 	if err != nil {
 		panic(fmt.Sprintf("Could not open chainspec for %s: %v", filename, err))
 	}
-	defer f.Close()
-	decoder := json.NewDecoder(f)
 	spec := &chain.Config{}
-	err = decoder.Decode(&spec)
-	if err != nil {
+	if err = json.Unmarshal(data, spec); err != nil {
 		panic(fmt.Sprintf("Could not parse chainspec for %s: %v", filename, err))
 	}
+	SetOptimismDefaults(spec)
 	return spec
 }
 
@@ -64,6 +62,7 @@ var (
 	BorDevnetGenesisHash  = libcommon.HexToHash("0x5a06b25b0c6530708ea0b98a3409290e39dce6be7f558493aeb6e4b99a172a87")
 	GnosisGenesisHash     = libcommon.HexToHash("0x4f1dd23188aab3a76b463e4af801b52b1248ef073c648cbdc4c9333d3da79756")
 	ChiadoGenesisHash     = libcommon.HexToHash("0xada44fd8d2ecab8b08f256af07ad3e777f17fb434f8f8e678b312f576212ba9a")
+	BobaDevnetGenesisHash = libcommon.HexToHash("0x8de627b1fbe8252433c410b6f41bd56b99f2d5dc0ee82bbd22080d37b92493c9")
 )
 
 var (
@@ -150,6 +149,9 @@ var (
 
 	ChiadoChainConfig = readChainSpec("chainspecs/chiado.json")
 
+	// BobaDevnetChainConfig contains the chain parameters to run a node on a local Boba rollup devnet.
+	BobaDevnetChainConfig = readChainSpec("chainspecs/boba-devnet.json")
+
 	CliqueSnapshot = NewSnapshotConfig(10, 1024, 16384, true, "")
 
 	TestChainConfig = &chain.Config{
@@ -239,6 +241,8 @@ func ChainConfigByChainName(chain string) *chain.Config {
 		return GnosisChainConfig
 	case networkname.ChiadoChainName:
 		return ChiadoChainConfig
+	case networkname.BobaDevnetChainName:
+		return BobaDevnetChainConfig
 	default:
 		return nil
 	}
@@ -272,6 +276,8 @@ func GenesisHashByChainName(chain string) *libcommon.Hash {
 		return &GnosisGenesisHash
 	case networkname.ChiadoChainName:
 		return &ChiadoGenesisHash
+	case networkname.BobaDevnetChainName:
+		return &BobaDevnetGenesisHash
 	default:
 		return nil
 	}
@@ -305,6 +311,8 @@ func ChainConfigByGenesisHash(genesisHash libcommon.Hash) *chain.Config {
 		return GnosisChainConfig
 	case genesisHash == ChiadoGenesisHash:
 		return ChiadoChainConfig
+	case genesisHash == BobaDevnetGenesisHash:
+		return BobaDevnetChainConfig
 	default:
 		return nil
 	}
@@ -15,6 +15,7 @@ const (
 	BorDevnetChainName  = "bor-devnet"
 	GnosisChainName     = "gnosis"
 	ChiadoChainName     = "chiado"
+	BobaDevnetChainName = "boba-devnet"
 )
 
 var All = []string{
@@ -31,4 +32,5 @@ var All = []string{
 	BorDevnetChainName,
 	GnosisChainName,
 	ChiadoChainName,
+	BobaDevnetChainName,
 }
@@ -0,0 +1,41 @@
+package params
+
+import (
+	"github.com/ledgerwatch/erigon-lib/chain"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+)
+
+// Rollup predeploys, as specified at https://github.com/ethereum-optimism/optimism/blob/develop/specs/predeploys.md
+var (
+	OptimismL1BlockAddress             = libcommon.HexToAddress("0x4200000000000000000000000000000000000015")
+	OptimismL2ToL1MessagePasserAddress = libcommon.HexToAddress("0x4200000000000000000000000000000000000016")
+	OptimismBaseFeeRecipient           = libcommon.HexToAddress("0x4200000000000000000000000000000000000019")
+	OptimismL1FeeRecipient             = libcommon.HexToAddress("0x420000000000000000000000000000000000001A")
+)
+
+// SetOptimismDefaults fills in the protocol constants left out of the rollup section of config,
+// if any. It is applied to the configs decoded from chainspecs, genesis files and the database.
+func SetOptimismDefaults(config *chain.Config) {
+	if config == nil || config.Optimism == nil {
+		return
+	}
+	c := config.Optimism
+	if c.EIP1559Elasticity == 0 {
+		c.EIP1559Elasticity = ElasticityMultiplier
+	}
+	if c.EIP1559Denominator == 0 {
+		c.EIP1559Denominator = BaseFeeChangeDenominator
+	}
+	if c.BaseFeeRecipient == (libcommon.Address{}) {
+		c.BaseFeeRecipient = OptimismBaseFeeRecipient
+	}
+	if c.L1FeeRecipient == (libcommon.Address{}) {
+		c.L1FeeRecipient = OptimismL1FeeRecipient
+	}
+	if c.L1BlockAddress == (libcommon.Address{}) {
+		c.L1BlockAddress = OptimismL1BlockAddress
+	}
+	if c.L2ToL1MessagePasserAddress == (libcommon.Address{}) {
+		c.L2ToL1MessagePasserAddress = OptimismL2ToL1MessagePasserAddress
+	}
+}
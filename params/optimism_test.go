@@ -0,0 +1,48 @@
+package params
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/chain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBobaDevnetOptimismConfig(t *testing.T) {
+	optimism := BobaDevnetChainConfig.Optimism
+	require.NotNil(t, optimism)
+	require.Equal(t, uint64(10), optimism.EIP1559Elasticity)
+	require.Equal(t, uint64(50), optimism.EIP1559Denominator)
+	require.Equal(t, OptimismL2ToL1MessagePasserAddress, optimism.L2ToL1MessagePasserAddress)
+	require.True(t, optimism.IsBedrock(0))
+	require.True(t, optimism.IsRegolith(0))
+
+	require.Nil(t, MainnetChainConfig.Optimism)
+}
+
+func TestOptimismConfigRoundTrip(t *testing.T) {
+	config := &chain.Config{}
+	require.NoError(t, json.Unmarshal([]byte(`{"chainId":1000901,"optimism":{"bedrockBlock":100,"regolithTime":5000}}`), config))
+	SetOptimismDefaults(config)
+
+	optimism := config.Optimism
+	require.NotNil(t, optimism)
+	// Left-out fields fall back to the protocol defaults
+	require.Equal(t, uint64(ElasticityMultiplier), optimism.EIP1559Elasticity)
+	require.Equal(t, OptimismL1FeeRecipient, optimism.L1FeeRecipient)
+	require.False(t, optimism.IsBedrock(99))
+	require.True(t, optimism.IsBedrock(100))
+	require.False(t, optimism.IsRegolith(4999))
+
+	data, err := json.Marshal(config)
+	require.NoError(t, err)
+
+	decoded := &chain.Config{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	require.Equal(t, optimism, decoded.Optimism)
+
+	// Configs don't share their rollup sections, even with the same chain ID
+	other := &chain.Config{}
+	require.NoError(t, json.Unmarshal([]byte(`{"chainId":1000901}`), other))
+	require.Nil(t, other.Optimism)
+}
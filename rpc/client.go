@@ -78,6 +78,8 @@ type Client struct {
 	isHTTP          bool
 	services        *serviceRegistry
 	methodAllowList AllowList
+	limiter         *limiter // limits of the server methods, for server connections
+	limiterClient   string
 
 	idCounter uint32
 
@@ -113,6 +115,7 @@ type clientConn struct {
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
 	handler := newHandler(ctx, conn, c.idgen, c.services, c.methodAllowList, 50, false /* traceRequests */)
+	handler.limiter, handler.client = c.limiter, c.limiterClient
 	return &clientConn{conn, handler}
 }
 
@@ -196,27 +199,29 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), nil, "")
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, limiter *limiter, limiterClient string) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
-		idgen:       idgen,
-		isHTTP:      isHTTP,
-		services:    services,
-		writeConn:   conn,
-		close:       make(chan struct{}),
-		closing:     make(chan struct{}),
-		didClose:    make(chan struct{}),
-		reconnected: make(chan ServerCodec),
-		readOp:      make(chan readOp),
-		readErr:     make(chan error),
-		reqInit:     make(chan *requestOp),
-		reqSent:     make(chan error, 1),
-		reqTimeout:  make(chan *requestOp),
+		idgen:         idgen,
+		isHTTP:        isHTTP,
+		services:      services,
+		limiter:       limiter,
+		limiterClient: limiterClient,
+		writeConn:     conn,
+		close:         make(chan struct{}),
+		closing:       make(chan struct{}),
+		didClose:      make(chan struct{}),
+		reconnected:   make(chan ServerCodec),
+		readOp:        make(chan readOp),
+		readErr:       make(chan error),
+		reqInit:       make(chan *requestOp),
+		reqSent:       make(chan error, 1),
+		reqTimeout:    make(chan *requestOp),
 	}
 	if !isHTTP {
 		go c.dispatch(conn)
@@ -26,6 +26,10 @@ var (
 	_ Error = new(invalidMessageError)
 	_ Error = new(InvalidParamsError)
 	_ Error = new(CustomError)
+	_ Error = new(NoHistoricalFallbackError)
+	_ Error = new(rateLimitError)
+	_ Error = new(responseTooLargeError)
+	_ Error = new(timeoutError)
 )
 
 const defaultErrorCode = -32000
@@ -82,3 +86,45 @@ type CustomError struct {
 func (e *CustomError) ErrorCode() int { return e.Code }
 
 func (e *CustomError) Error() string { return e.Message }
+
+// the client exceeded the rate limit of the method
+type rateLimitError struct{ method string }
+
+func (e *rateLimitError) ErrorCode() int { return -32005 }
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s", e.method)
+}
+
+// the result is larger than the maximum response size of the method
+type responseTooLargeError struct {
+	method string
+	limit  int
+}
+
+func (e *responseTooLargeError) ErrorCode() int { return -32008 }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("response of %s exceeds the limit of %d bytes", e.method, e.limit)
+}
+
+// the method ran longer than its timeout
+type timeoutError struct{ method string }
+
+func (e *timeoutError) ErrorCode() int { return -32002 }
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("request timed out: %s", e.method)
+}
+
+// ErrNoHistoricalFallback is returned for requests about pre-Bedrock blocks of a rollup chain
+// when no historical RPC is configured to relay them to.
+var ErrNoHistoricalFallback = NoHistoricalFallbackError{}
+
+type NoHistoricalFallbackError struct{}
+
+func (e NoHistoricalFallbackError) ErrorCode() int { return -32801 }
+
+func (e NoHistoricalFallbackError) Error() string {
+	return "no historical RPC is available for this historical (pre-bedrock) execution request"
+}
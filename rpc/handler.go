@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -66,6 +67,8 @@ type handler struct {
 
 	allowList     AllowList // a list of explicitly allowed methods, if empty -- everything is allowed
 	forbiddenList ForbiddenList
+	limiter       *limiter // limits of the methods, nil if there are none
+	client        string   // the client of the connection, for the limiter
 
 	subLock             sync.Mutex
 	serverSubs          map[ID]*Subscription
@@ -424,6 +427,11 @@ func (h *handler) isMethodAllowedByGranularControl(method string) bool {
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage, stream *jsoniter.Stream) *jsonrpcMessage {
+	limits, limited := h.limiter.methodLimits(msg.Method)
+	if limited && !h.limiter.allow(msg.Method, h.client, limits) {
+		throttledRequestGauge.Inc()
+		return msg.errorResponse(&rateLimitError{method: msg.Method})
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg, stream)
 	}
@@ -441,7 +449,12 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage, stream *jsoniter
 		return msg.errorResponse(&InvalidParamsError{err.Error()})
 	}
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args, stream)
+	var answer *jsonrpcMessage
+	if limited {
+		answer = h.runLimitedMethod(cp.ctx, msg, callb, args, stream, limits)
+	} else {
+		answer = h.runMethod(cp.ctx, msg, callb, args, stream)
+	}
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -493,7 +506,7 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 	if !callb.streamable {
 		result, err := callb.call(ctx, msg.Method, args, stream)
 		if err != nil {
-			return msg.errorResponse(err)
+			return msg.errorResponse(methodError(ctx, msg.Method, err))
 		}
 		return msg.response(result)
 	}
@@ -512,13 +525,22 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 	if err != nil {
 		stream.WriteNil()
 		stream.WriteMore()
-		HandleError(err, stream)
+		HandleError(methodError(ctx, msg.Method, err), stream)
 	}
 	stream.WriteObjectEnd()
 	stream.Flush()
 	return nil
 }
 
+// methodError returns the error of a method, which is a timeout error if the method
+// failed after its timeout.
+func methodError(ctx context.Context, method string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &timeoutError{method: method}
+	}
+	return err
+}
+
 // unsubscribe is the callback function for all *_unsubscribe calls.
 func (h *handler) unsubscribe(ctx context.Context, id ID) (bool, error) {
 	h.subLock.Lock()
@@ -254,7 +254,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if !s.disableStreaming {
 		stream = jsoniter.NewStream(jsoniter.ConfigDefault, w, 4096)
 	}
-	s.serveSingleRequest(ctx, codec, stream)
+	s.serveSingleRequest(ctx, codec, stream, s.limiter.requestClient(r))
 }
 
 // validateRequest returns a non-zero response code and error message if the
@@ -296,6 +296,15 @@ func CheckJwtSecret(w http.ResponseWriter, r *http.Request, jwtSecret []byte) bo
 		return false
 	}
 
+	if _, err := verifyJwt(tokenStr, jwtSecret); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// verifyJwt returns the claims of the token if it is signed with jwtSecret and recent.
+func verifyJwt(tokenStr string, jwtSecret []byte) (*jwt.RegisteredClaims, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
 	}
@@ -309,20 +318,17 @@ func CheckJwtSecret(w http.ResponseWriter, r *http.Request, jwtSecret []byte) bo
 
 	switch {
 	case err != nil:
-		http.Error(w, err.Error(), http.StatusForbidden)
+		return nil, err
 	case !token.Valid:
-		http.Error(w, "invalid token", http.StatusForbidden)
+		return nil, errors.New("invalid token")
 	case !claims.VerifyExpiresAt(time.Now(), false): // optional
-		http.Error(w, "token is expired", http.StatusForbidden)
+		return nil, errors.New("token is expired")
 	case claims.IssuedAt == nil:
-		http.Error(w, "missing issued-at", http.StatusForbidden)
+		return nil, errors.New("missing issued-at")
 	case time.Since(claims.IssuedAt.Time) > jwtTokenExpiry:
-		http.Error(w, "stale token", http.StatusForbidden)
+		return nil, errors.New("stale token")
 	case time.Until(claims.IssuedAt.Time) > jwtTokenExpiry:
-		http.Error(w, "future token", http.StatusForbidden)
-	default:
-		return true
+		return nil, errors.New("future token")
 	}
-
-	return false
+	return &claims, nil
 }
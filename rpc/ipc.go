@@ -34,6 +34,6 @@ func (s *Server) ServeListener(l net.Listener) error {
 			return err
 		}
 		log.Trace("Accepted RPC connection", "conn", conn.RemoteAddr())
-		go s.ServeCodec(NewCodec(conn), 0)
+		go s.serveCodec(NewCodec(conn), connClient(conn))
 	}
 }
@@ -0,0 +1,261 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/time/rate"
+)
+
+// DefaultLimitsMethod is the method whose limits apply to the methods without limits.
+const DefaultLimitsMethod = "*"
+
+// MethodLimits are the limits of the calls of an RPC method by each client.
+type MethodLimits struct {
+	Rate            float64       // Calls per second, 0 for no rate limit
+	Burst           int           // Calls made at once, defaults to the rate
+	MaxResponseSize int           // Maximum size of the results in bytes, 0 for no limit
+	Timeout         time.Duration // Maximum execution time, 0 for no limit
+}
+
+// Limits are the limits of the RPC methods by method name.
+type Limits map[string]MethodLimits
+
+// limiterIdleTimeout is the time after which the rate limit of an idle client is forgotten.
+const limiterIdleTimeout = 10 * time.Minute
+
+type limiterKey struct {
+	method string
+	client string
+}
+
+type limiterBucket struct {
+	*rate.Limiter
+	lastUsed time.Time
+}
+
+// limiter applies the limits of the methods to the clients of a server, with a token
+// bucket per method and client. The clients are identified by the subject of their
+// JWT when it is signed with jwtSecret, otherwise by their IP address, which is the
+// one forwarded by the proxy when they connect through one of trustedProxies.
+type limiter struct {
+	limits         Limits
+	jwtSecret      []byte
+	trustedProxies []*net.IPNet
+
+	mu          sync.Mutex
+	buckets     map[limiterKey]*limiterBucket
+	lastCleanup time.Time
+}
+
+func newLimiter(limits Limits, jwtSecret []byte, trustedProxies []*net.IPNet) *limiter {
+	if len(limits) == 0 {
+		return nil
+	}
+	return &limiter{
+		limits:         limits,
+		jwtSecret:      jwtSecret,
+		trustedProxies: trustedProxies,
+		buckets:        make(map[limiterKey]*limiterBucket),
+		lastCleanup:    time.Now(),
+	}
+}
+
+// ParseTrustedProxies parses the IP addresses and CIDR ranges of the trusted proxies.
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		if proxy == "" {
+			continue
+		}
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// methodLimits returns the limits of method, false if it has none.
+func (l *limiter) methodLimits(method string) (MethodLimits, bool) {
+	if l == nil {
+		return MethodLimits{}, false
+	}
+	limits, ok := l.limits[method]
+	if !ok {
+		limits, ok = l.limits[DefaultLimitsMethod]
+	}
+	return limits, ok
+}
+
+// allow takes a token from the bucket of the client for method, and returns false if
+// the bucket is empty.
+func (l *limiter) allow(method, client string, limits MethodLimits) bool {
+	if limits.Rate <= 0 {
+		return true
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastCleanup) > limiterIdleTimeout {
+		for key, bucket := range l.buckets {
+			if now.Sub(bucket.lastUsed) > limiterIdleTimeout {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastCleanup = now
+	}
+	key := limiterKey{method: method, client: client}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		burst := limits.Burst
+		if burst <= 0 {
+			burst = int(math.Ceil(limits.Rate))
+		}
+		bucket = &limiterBucket{Limiter: rate.NewLimiter(rate.Limit(limits.Rate), burst)}
+		l.buckets[key] = bucket
+	}
+	bucket.lastUsed = now
+	return bucket.AllowN(now, 1)
+}
+
+// requestClient returns the client making an HTTP request or a WebSocket handshake.
+func (l *limiter) requestClient(r *http.Request) string {
+	if l != nil && l.jwtSecret != nil {
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			claims, err := verifyJwt(strings.TrimPrefix(auth, "Bearer "), l.jwtSecret)
+			if err == nil && claims.Subject != "" {
+				return "jwt:" + claims.Subject
+			}
+		}
+	}
+	client := addrClient(r.RemoteAddr)
+	if l == nil || !l.trustedProxy(client) {
+		return client
+	}
+	// Each proxy appends the address it got the request from to X-Forwarded-For, the
+	// client is the last address not appended by a trusted proxy. The addresses before
+	// it are set by the client itself.
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		addrs := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			client = strings.TrimSpace(addrs[i])
+			if !l.trustedProxy(client) {
+				break
+			}
+		}
+		return client
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	return client
+}
+
+// trustedProxy returns whether addr is the IP address of a trusted proxy.
+func (l *limiter) trustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range l.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// addrClient returns the client connecting from addr, its IP address.
+func addrClient(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// connClient returns the client of a connection, its IP address if any.
+func connClient(conn net.Conn) string {
+	if addr := conn.RemoteAddr(); addr != nil {
+		return addrClient(addr.String())
+	}
+	return ""
+}
+
+// runLimitedMethod runs the Go callback for an RPC method within its timeout, and
+// replaces the results larger than its maximum size by an error. The results of the
+// streamable methods are buffered up to the maximum size, and the method is aborted
+// as soon as its result is larger.
+func (h *handler) runLimitedMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value, stream *jsoniter.Stream, limits MethodLimits) *jsonrpcMessage {
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+	if limits.MaxResponseSize <= 0 {
+		return h.runMethod(ctx, msg, callb, args, stream)
+	}
+	if !callb.streamable {
+		answer := h.runMethod(ctx, msg, callb, args, stream)
+		if len(answer.Result) > limits.MaxResponseSize {
+			return msg.errorResponse(&responseTooLargeError{method: msg.Method, limit: limits.MaxResponseSize})
+		}
+		return answer
+	}
+	ctx, abort := context.WithCancel(ctx)
+	defer abort()
+	result := &limitedBuffer{limit: limits.MaxResponseSize, abort: abort}
+	buffered := jsoniter.NewStream(jsoniter.ConfigDefault, result, 4096)
+	if answer := h.runMethod(ctx, msg, callb, args, buffered); answer != nil {
+		return answer
+	}
+	if result.exceeded {
+		return msg.errorResponse(&responseTooLargeError{method: msg.Method, limit: limits.MaxResponseSize})
+	}
+	stream.Write(result.buf)
+	stream.Flush()
+	return nil
+}
+
+var errResponseTooLarge = errors.New("response too large")
+
+// limitedBuffer is the writer of the results of the streamable methods, counting the
+// bytes the methods flush. Once the result is larger than limit, it is dropped and the
+// method is aborted.
+type limitedBuffer struct {
+	buf      []byte
+	limit    int
+	abort    context.CancelFunc
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errResponseTooLarge
+	}
+	if len(b.buf)+len(p) > b.limit {
+		b.buf, b.exceeded = nil, true
+		b.abort()
+		return 0, errResponseTooLarge
+	}
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
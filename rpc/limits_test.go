@@ -0,0 +1,157 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func errorCode(t *testing.T, err error) int {
+	t.Helper()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	rpcErr, ok := err.(Error)
+	if !ok {
+		t.Fatalf("unexpected error type %T: %v", err, err)
+	}
+	return rpcErr.ErrorCode()
+}
+
+func TestLimitsRate(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetLimits(Limits{"test_echo": {Rate: 0.001, Burst: 2}}, nil, nil)
+	client := DialInProc(server)
+	defer client.Close()
+
+	var resp echoResult
+	for i := 0; i < 2; i++ {
+		if err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"})
+	if code := errorCode(t, err); code != -32005 {
+		t.Errorf("wrong error code %d, want -32005", code)
+	}
+
+	// The methods without limits are not throttled
+	for i := 0; i < 3; i++ {
+		if err := client.Call(nil, "test_noArgsRets"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLimitsTimeout(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetLimits(Limits{"test_block": {Timeout: 50 * time.Millisecond}}, nil, nil)
+	client := DialInProc(server)
+	defer client.Close()
+
+	err := client.Call(nil, "test_block")
+	if code := errorCode(t, err); code != -32002 {
+		t.Errorf("wrong error code %d, want -32002", code)
+	}
+}
+
+func TestLimitsResponseSize(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetLimits(Limits{DefaultLimitsMethod: {MaxResponseSize: 64}}, nil, nil)
+	client := DialInProc(server)
+	defer client.Close()
+
+	var resp echoResult
+	if err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	err := client.Call(&resp, "test_echo", strings.Repeat("hello", 20), 10, &echoArgs{"world"})
+	if code := errorCode(t, err); code != -32008 {
+		t.Errorf("wrong error code %d, want -32008", code)
+	}
+}
+
+type streamService struct{}
+
+// Items streams n items, or items until it is aborted if n is negative.
+func (s *streamService) Items(ctx context.Context, n int, stream *jsoniter.Stream) error {
+	stream.WriteArrayStart()
+	for i := 0; n < 0 || i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i > 0 {
+			stream.WriteMore()
+		}
+		stream.WriteInt(i)
+		stream.Flush()
+	}
+	stream.WriteArrayEnd()
+	return nil
+}
+
+func TestLimitsStreamedResponseSize(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	if err := server.RegisterName("stream", new(streamService)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetLimits(Limits{DefaultLimitsMethod: {MaxResponseSize: 64}}, nil, nil)
+	client := DialInProc(server)
+	defer client.Close()
+
+	var items []int
+	if err := client.Call(&items, "stream_items", 3); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Errorf("got %d items, want 3", len(items))
+	}
+	// The method streaming without end is aborted once its result is too large
+	err := client.Call(&items, "stream_items", -1)
+	if code := errorCode(t, err); code != -32008 {
+		t.Errorf("wrong error code %d, want -32008", code)
+	}
+}
+
+func TestLimitsTrustedProxies(t *testing.T) {
+	trustedProxies, err := ParseTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseTrustedProxies([]string{"proxy"}); err == nil {
+		t.Error("expected an error for an invalid proxy")
+	}
+	l := newLimiter(Limits{DefaultLimitsMethod: {Rate: 1}}, nil, trustedProxies)
+
+	tests := []struct {
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"1.2.3.4:5", nil, "1.2.3.4"},
+		// Untrusted peers can't pick their client
+		{"1.2.3.4:5", map[string]string{"X-Forwarded-For": "5.6.7.8", "X-Real-IP": "5.6.7.8"}, "1.2.3.4"},
+		{"10.0.0.1:5", map[string]string{"X-Forwarded-For": "5.6.7.8"}, "5.6.7.8"},
+		{"10.0.0.1:5", map[string]string{"X-Real-IP": "5.6.7.8"}, "5.6.7.8"},
+		{"10.0.0.1:5", nil, "10.0.0.1"},
+		// The addresses set by the client are skipped, the trusted proxies too
+		{"10.0.0.1:5", map[string]string{"X-Forwarded-For": "9.9.9.9, 5.6.7.8, 192.168.1.1"}, "5.6.7.8"},
+	}
+	for _, test := range tests {
+		r := &http.Request{RemoteAddr: test.remoteAddr, Header: http.Header{}}
+		for name, value := range test.headers {
+			r.Header.Set(name, value)
+		}
+		if client := l.requestClient(r); client != test.want {
+			t.Errorf("client of %s %v is %q, want %q", test.remoteAddr, test.headers, client, test.want)
+		}
+	}
+}
@@ -23,8 +23,9 @@ import (
 )
 
 var (
-	rpcRequestGauge    = metrics.GetOrCreateCounter("rpc_total")
-	failedReqeustGauge = metrics.GetOrCreateCounter("rpc_failure")
+	rpcRequestGauge       = metrics.GetOrCreateCounter("rpc_total")
+	failedReqeustGauge    = metrics.GetOrCreateCounter("rpc_failure")
+	throttledRequestGauge = metrics.GetOrCreateCounter("rpc_throttled")
 )
 
 func newRPCServingTimerMS(method string, valid bool) *metrics.Summary {
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"sync/atomic"
 
 	mapset "github.com/deckarep/golang-set"
@@ -46,6 +47,7 @@ const (
 type Server struct {
 	services        serviceRegistry
 	methodAllowList AllowList
+	limiter         *limiter
 	idgen           func() ID
 	run             int32
 	codecs          mapset.Set
@@ -71,6 +73,14 @@ func (s *Server) SetAllowList(allowList AllowList) {
 	s.methodAllowList = allowList
 }
 
+// SetLimits sets the rate limits, the response size limits and the timeouts of the methods
+// for each client. The clients are identified by the subject of their JWT when it is signed
+// with jwtSecret, otherwise by their IP address. The IP address of the clients connecting
+// through one of trustedProxies is the one the proxy forwards in X-Forwarded-For or X-Real-IP.
+func (s *Server) SetLimits(limits Limits, jwtSecret []byte, trustedProxies []*net.IPNet) {
+	s.limiter = newLimiter(limits, jwtSecret, trustedProxies)
+}
+
 // SetBatchLimit sets limit of number of requests in a batch
 func (s *Server) SetBatchLimit(limit int) {
 	s.batchLimit = limit
@@ -90,6 +100,11 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(codec, addrClient(codec.remoteAddr()))
+}
+
+// serveCodec serves the codec of a connection of the client.
+func (s *Server) serveCodec(codec ServerCodec, client string) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -101,7 +116,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, s.limiter, client)
 	<-codec.closed()
 	c.Close()
 }
@@ -109,7 +124,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 // serveSingleRequest reads and processes a single RPC request from the given codec. This
 // is used to serve HTTP connections. Subscriptions and reverse calls are not allowed in
 // this mode.
-func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, stream *jsoniter.Stream) {
+func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, stream *jsoniter.Stream, client string) {
 	// Don't serve if server is stopped.
 	if atomic.LoadInt32(&s.run) == 0 {
 		return
@@ -117,6 +132,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, stre
 
 	h := newHandler(ctx, codec, s.idgen, &s.services, s.methodAllowList, s.batchConcurrency, s.traceRequests)
 	h.allowSubscribe = false
+	h.limiter, h.client = s.limiter, client
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
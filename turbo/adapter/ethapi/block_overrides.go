@@ -0,0 +1,57 @@
+package ethapi
+
+import (
+	"math/big"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
+)
+
+type BlockOverrides struct {
+	BlockNumber *hexutil.Uint64
+	Coinbase    *libcommon.Address
+	Timestamp   *hexutil.Uint64
+	GasLimit    *hexutil.Uint
+	Difficulty  *hexutil.Uint
+	BaseFee     *uint256.Int
+	BlockHash   *map[uint64]libcommon.Hash
+}
+
+// Override replaces the fields of blockCtx. Overridden block hashes are returned by
+// blockCtx.GetHash, before falling back to the hashes it returned so far.
+func (overrides *BlockOverrides) Override(blockCtx *evmtypes.BlockContext) {
+	if overrides.BlockNumber != nil {
+		blockCtx.BlockNumber = uint64(*overrides.BlockNumber)
+	}
+	if overrides.BaseFee != nil {
+		blockCtx.BaseFee = overrides.BaseFee
+	}
+	if overrides.Coinbase != nil {
+		blockCtx.Coinbase = *overrides.Coinbase
+	}
+	if overrides.Difficulty != nil {
+		blockCtx.Difficulty = big.NewInt(int64(*overrides.Difficulty))
+	}
+	if overrides.Timestamp != nil {
+		blockCtx.Time = uint64(*overrides.Timestamp)
+	}
+	if overrides.GasLimit != nil {
+		blockCtx.GasLimit = uint64(*overrides.GasLimit)
+	}
+	if overrides.BlockHash != nil && len(*overrides.BlockHash) > 0 {
+		overrideBlockHash := make(map[uint64]libcommon.Hash, len(*overrides.BlockHash))
+		for blockNum, hash := range *overrides.BlockHash {
+			overrideBlockHash[blockNum] = hash
+		}
+		getHash := blockCtx.GetHash
+		blockCtx.GetHash = func(blockNum uint64) libcommon.Hash {
+			if hash, ok := overrideBlockHash[blockNum]; ok {
+				return hash
+			}
+			return getHash(blockNum)
+		}
+	}
+}
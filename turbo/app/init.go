@@ -11,6 +11,7 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/node"
+	"github.com/ledgerwatch/erigon/params"
 )
 
 var initCommand = cli.Command{
@@ -49,6 +50,7 @@ func initGenesis(ctx *cli.Context) error {
 	if err := json.NewDecoder(file).Decode(genesis); err != nil {
 		utils.Fatalf("invalid genesis file: %v", err)
 	}
+	params.SetOptimismDefaults(genesis.Config)
 
 	// Open and initialise both full and light databases
 	stack := MakeConfigNodeDefault(ctx)
@@ -46,6 +46,7 @@ var DefaultFlags = []cli.Flag{
 	&TLSCACertFlag,
 	&StateStreamDisableFlag,
 	&SyncLoopThrottleFlag,
+	&SyncMaxForkDepthFlag,
 	&BadBlockFlag,
 
 	&utils.HTTPEnabledFlag,
@@ -64,13 +65,30 @@ var DefaultFlags = []cli.Flag{
 	&utils.HTTPTraceFlag,
 	&utils.StateCacheFlag,
 	&utils.RpcBatchConcurrencyFlag,
+	&utils.RpcTraceChainConcurrencyFlag,
 	&utils.RpcStreamingDisableFlag,
 	&utils.DBReadConcurrencyFlag,
 	&utils.RpcAccessListFlag,
+	&utils.RpcLimitsFlag,
+	&utils.RpcLimitsJWTSecretFlag,
+	&utils.RpcLimitsTrustedProxiesFlag,
 	&utils.RpcTraceCompatFlag,
 	&utils.RpcGasCapFlag,
 	&utils.RpcBatchLimit,
 	&utils.RpcReturnDataLimit,
+	&utils.RpcMaxGetProofRewindBlockCount,
+	&utils.RpcGetLogsMaxBlockRangeFlag,
+	&utils.RpcGetLogsMaxResultsFlag,
+	&utils.RpcFiltersTimeoutFlag,
+	&utils.RpcFiltersMaxFlag,
+	&utils.RpcFiltersMaxLogsFlag,
+	&utils.RpcFiltersMaxHeadersFlag,
+	&utils.RpcFiltersMaxTxsFlag,
+	&utils.RollupSequencerHTTPFlag,
+	&utils.RollupSequencerHTTPTimeoutFlag,
+	&utils.RollupKeepLocalTxsFlag,
+	&utils.RollupHistoricalRPCFlag,
+	&utils.RollupHistoricalRPCTimeoutFlag,
 	&utils.TxpoolApiAddrFlag,
 	&utils.TraceMaxtracesFlag,
 	&HTTPReadTimeoutFlag,
@@ -83,6 +101,9 @@ var DefaultFlags = []cli.Flag{
 
 	&utils.SnapKeepBlocksFlag,
 	&utils.SnapStopFlag,
+	&utils.SnapPreverifiedFlag,
+	&utils.SnapReceiptsFlag,
+	&utils.SnapScrubEveryFlag,
 	&utils.DbPageSizeFlag,
 	&utils.TorrentPortFlag,
 	&utils.TorrentMaxPeersFlag,
@@ -117,6 +138,10 @@ var DefaultFlags = []cli.Flag{
 	&utils.MetricsHTTPFlag,
 	&utils.MetricsPortFlag,
 	&utils.HistoryV3Flag,
+	&utils.ProofsAddressesFlag,
+	&utils.ProofsStorageKeysFlag,
+	&utils.ProofsIntervalFlag,
+	&utils.ProofsRetentionFlag,
 	&utils.IdentityFlag,
 	&utils.CliqueSnapshotCheckpointIntervalFlag,
 	&utils.CliqueSnapshotInmemorySnapshotsFlag,
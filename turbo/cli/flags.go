@@ -23,6 +23,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/node/nodecfg"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
 var (
@@ -146,6 +147,12 @@ var (
 		Value: "",
 	}
 
+	SyncMaxForkDepthFlag = cli.Uint64Flag{
+		Name:  "sync.fork.depth",
+		Usage: "Maximum distance of the fork point from the head of the side forks fully validated by engine_newPayload, deeper side forks are only accepted. Their state history must not be pruned (see --prune.h)",
+		Value: ethconfig.Defaults.Sync.MaxForkDepth,
+	}
+
 	BadBlockFlag = cli.StringFlag{
 		Name:  "bad.block",
 		Usage: "Marks block with given hex string as bad and forces initial reorg before normal staged sync",
@@ -246,6 +253,7 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		}
 		cfg.Sync.LoopThrottle = syncLoopThrottle
 	}
+	cfg.Sync.MaxForkDepth = ctx.Uint64(SyncMaxForkDepthFlag.Name)
 
 	if ctx.String(BadBlockFlag.Name) != "" {
 		bytes, err := hexutil.Decode(ctx.String(BadBlockFlag.Name))
@@ -378,16 +386,40 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 		},
 		EvmCallTimeout: ctx.Duration(EvmCallTimeoutFlag.Name),
 
-		WebsocketEnabled:     ctx.IsSet(utils.WSEnabledFlag.Name),
-		RpcBatchConcurrency:  ctx.Uint(utils.RpcBatchConcurrencyFlag.Name),
-		RpcStreamingDisable:  ctx.Bool(utils.RpcStreamingDisableFlag.Name),
-		DBReadConcurrency:    ctx.Int(utils.DBReadConcurrencyFlag.Name),
-		RpcAllowListFilePath: ctx.String(utils.RpcAccessListFlag.Name),
-		Gascap:               ctx.Uint64(utils.RpcGasCapFlag.Name),
-		MaxTraces:            ctx.Uint64(utils.TraceMaxtracesFlag.Name),
-		TraceCompatibility:   ctx.Bool(utils.RpcTraceCompatFlag.Name),
-		BatchLimit:           ctx.Int(utils.RpcBatchLimit.Name),
-		ReturnDataLimit:      ctx.Int(utils.RpcReturnDataLimit.Name),
+		WebsocketEnabled:        ctx.IsSet(utils.WSEnabledFlag.Name),
+		RpcBatchConcurrency:     ctx.Uint(utils.RpcBatchConcurrencyFlag.Name),
+		RpcStreamingDisable:     ctx.Bool(utils.RpcStreamingDisableFlag.Name),
+		TraceChainConcurrency:   ctx.Uint(utils.RpcTraceChainConcurrencyFlag.Name),
+		DBReadConcurrency:       ctx.Int(utils.DBReadConcurrencyFlag.Name),
+		RpcAllowListFilePath:    ctx.String(utils.RpcAccessListFlag.Name),
+		RpcLimitsFilePath:       ctx.String(utils.RpcLimitsFlag.Name),
+		RpcLimitsJWTSecretPath:  ctx.String(utils.RpcLimitsJWTSecretFlag.Name),
+		RpcLimitsTrustedProxies: utils.SplitAndTrim(ctx.String(utils.RpcLimitsTrustedProxiesFlag.Name)),
+		Gascap:                  ctx.Uint64(utils.RpcGasCapFlag.Name),
+		MaxTraces:               ctx.Uint64(utils.TraceMaxtracesFlag.Name),
+		TraceCompatibility:      ctx.Bool(utils.RpcTraceCompatFlag.Name),
+		BatchLimit:              ctx.Int(utils.RpcBatchLimit.Name),
+		ReturnDataLimit:         ctx.Int(utils.RpcReturnDataLimit.Name),
+
+		MaxGetProofRewindBlockCount: ctx.Int(utils.RpcMaxGetProofRewindBlockCount.Name),
+
+		GetLogsMaxBlockRange: ctx.Uint64(utils.RpcGetLogsMaxBlockRangeFlag.Name),
+		GetLogsMaxResults:    ctx.Int(utils.RpcGetLogsMaxResultsFlag.Name),
+
+		RpcFiltersConfig: rpchelper.FiltersConfig{
+			Timeout:    ctx.Duration(utils.RpcFiltersTimeoutFlag.Name),
+			MaxFilters: ctx.Int(utils.RpcFiltersMaxFlag.Name),
+			MaxLogs:    ctx.Int(utils.RpcFiltersMaxLogsFlag.Name),
+			MaxHeaders: ctx.Int(utils.RpcFiltersMaxHeadersFlag.Name),
+			MaxTxs:     ctx.Int(utils.RpcFiltersMaxTxsFlag.Name),
+		},
+
+		RollupSequencerHTTP:        ctx.String(utils.RollupSequencerHTTPFlag.Name),
+		RollupSequencerHTTPTimeout: ctx.Duration(utils.RollupSequencerHTTPTimeoutFlag.Name),
+		RollupKeepLocalTxs:         ctx.Bool(utils.RollupKeepLocalTxsFlag.Name),
+
+		RollupHistoricalRPC:        ctx.String(utils.RollupHistoricalRPCFlag.Name),
+		RollupHistoricalRPCTimeout: ctx.Duration(utils.RollupHistoricalRPCTimeoutFlag.Name),
 
 		TxPoolApiAddr: ctx.String(utils.TxpoolApiAddrFlag.Name),
 
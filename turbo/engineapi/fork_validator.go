@@ -15,9 +15,11 @@ package engineapi
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"sync"
 
+	"github.com/VictoriaMetrics/metrics"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -28,19 +30,36 @@ import (
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/shards"
 )
 
-// the maximum point from the current head, past which side forks are not validated anymore.
-const maxForkDepth = 32 // 32 slots is the duration of an epoch thus there cannot be side forks in PoS deeper than 32 blocks from head.
+// the default maximum distance of the fork point from the current head, past which side forks are not validated anymore.
+const DefaultMaxForkDepth = 32 // 32 slots is the duration of an epoch thus there cannot be side forks in PoS deeper than 32 blocks from head.
+
+// payloadValidations counts the outcomes of the payloads validation. The payloads are only accepted
+// when their side fork can't be validated: the fork point is too deep or its state history is pruned,
+// a block of the side fork is missing, or its body is already in the db.
+var payloadValidations = map[string]*metrics.Counter{
+	"valid":          metrics.GetOrCreateCounter(`fork_validator_payloads{outcome="valid"}`),
+	"invalid":        metrics.GetOrCreateCounter(`fork_validator_payloads{outcome="invalid"}`),
+	"too_deep":       metrics.GetOrCreateCounter(`fork_validator_payloads{outcome="too_deep"}`),
+	"pruned_history": metrics.GetOrCreateCounter(`fork_validator_payloads{outcome="pruned_history"}`),
+	"missing_parent": metrics.GetOrCreateCounter(`fork_validator_payloads{outcome="missing_parent"}`),
+	"body_in_db":     metrics.GetOrCreateCounter(`fork_validator_payloads{outcome="body_in_db"}`),
+}
 
 type validatePayloadFunc func(kv.RwTx, *types.Header, *types.RawBody, uint64, []*types.Header, []*types.RawBody, *shards.Notifications) error
 
 type ForkValidator struct {
 	// Hash => side fork block, any block saved into this map is considered valid.
 	// blocks saved are required to have at most distance maxForkDepth from the head.
-	// if we miss a segment, we only accept the block and give up on full validation.
+	// the blocks off the canonical chain are also saved into the kv.SideForkBlocks table, with the blocks we could
+	// not validate yet: if we miss a segment, we accept the block and validate its side fork once complete.
 	sideForksBlock map[libcommon.Hash]types.RawBlock
+	// the maximum distance of the fork point from the head, past which side forks are not validated anymore.
+	maxForkDepth uint64
 	// current memory batch containing chain head that extend canonical fork.
 	extendingFork *memdb.MemoryMutation
 	// notifications accumulated for the extending fork
@@ -59,13 +78,15 @@ type ForkValidator struct {
 func NewForkValidatorMock(currentHeight uint64) *ForkValidator {
 	return &ForkValidator{
 		sideForksBlock: make(map[libcommon.Hash]types.RawBlock),
+		maxForkDepth:   DefaultMaxForkDepth,
 		currentHeight:  currentHeight,
 	}
 }
 
-func NewForkValidator(currentHeight uint64, validatePayload validatePayloadFunc, tmpDir string) *ForkValidator {
+func NewForkValidator(currentHeight uint64, validatePayload validatePayloadFunc, tmpDir string, maxForkDepth uint64) *ForkValidator {
 	return &ForkValidator{
 		sideForksBlock:  make(map[libcommon.Hash]types.RawBlock),
+		maxForkDepth:    maxForkDepth,
 		validatePayload: validatePayload,
 		currentHeight:   currentHeight,
 		tmpDir:          tmpDir,
@@ -138,7 +159,8 @@ func (fv *ForkValidator) FlushExtendingFork(tx kv.RwTx, accumulator *shards.Accu
 // ValidatePayload returns whether a payload is valid or invalid, or if cannot be determined, it will be accepted.
 // if the payload extend the canonical chain, then we stack it in extendingFork without any unwind.
 // if the payload is a fork then we unwind to the point where the fork meet the canonical chain and we check if it is valid or not from there.
-// if for any reasons none of the action above can be performed due to lack of information, we accept the payload and avoid validation.
+// if for any reasons none of the action above can be performed due to lack of information, we accept the payload and store it,
+// so that its side fork is validated once complete.
 func (fv *ForkValidator) ValidatePayload(tx kv.RwTx, header *types.Header, body *types.RawBody, extendCanonical bool) (status remote.EngineStatus, latestValidHash libcommon.Hash, validationError error, criticalError error) {
 	fv.lock.Lock()
 	defer fv.lock.Unlock()
@@ -147,9 +169,16 @@ func (fv *ForkValidator) ValidatePayload(tx kv.RwTx, header *types.Header, body
 		return
 	}
 	defer fv.clean()
+	if criticalError = fv.pruneSideForks(tx); criticalError != nil {
+		return
+	}
 
-	// If the block is stored within the side fork it means it was already validated.
-	if _, ok := fv.sideForksBlock[header.Hash()]; ok {
+	// If the block is stored as valid within the side fork it means it was already validated.
+	var valid bool
+	if _, valid, criticalError = fv.sideForkBlock(tx, header.Hash(), header.Number.Uint64()); criticalError != nil {
+		return
+	}
+	if valid {
 		status = remote.EngineStatus_VALID
 		latestValidHash = header.Hash()
 		return
@@ -168,14 +197,9 @@ func (fv *ForkValidator) ValidatePayload(tx kv.RwTx, header *types.Header, body
 		}
 		// Update fork head hash.
 		fv.extendingForkHeadHash = header.Hash()
-		return fv.validateAndStorePayload(fv.extendingFork, header, body, 0, nil, nil, fv.extendingForkNotifications)
+		return fv.validateAndStorePayload(tx, fv.extendingFork, header, body, 0, nil, nil, fv.extendingForkNotifications)
 	}
 
-	// if the block is not in range of maxForkDepth from head then we do not validate it.
-	if math.AbsoluteDifference(fv.currentHeight, header.Number.Uint64()) > maxForkDepth {
-		status = remote.EngineStatus_ACCEPTED
-		return
-	}
 	// Let's assemble the side fork backwards
 	var foundCanonical bool
 	currentHash := header.ParentHash
@@ -187,12 +211,22 @@ func (fv *ForkValidator) ValidatePayload(tx kv.RwTx, header *types.Header, body
 	var bodiesChain []*types.RawBody
 	var headersChain []*types.Header
 	unwindPoint := header.Number.Uint64() - 1
-	for !foundCanonical {
-		var sb types.RawBlock
-		var ok bool
-		if sb, ok = fv.sideForksBlock[currentHash]; !ok {
+	for {
+		// if the fork point is not in range of maxForkDepth from head then we do not validate the side fork.
+		if math.AbsoluteDifference(fv.currentHeight, unwindPoint) > fv.maxForkDepth {
+			status, criticalError = fv.accept(tx, header, body, "too_deep")
+			return
+		}
+		if foundCanonical {
+			break
+		}
+		var sb *types.RawBlock
+		if sb, _, criticalError = fv.sideForkBlock(tx, currentHash, unwindPoint); criticalError != nil {
+			return
+		}
+		if sb == nil {
 			// We miss some components so we did not check validity.
-			status = remote.EngineStatus_ACCEPTED
+			status, criticalError = fv.accept(tx, header, body, "missing_parent")
 			return
 		}
 		headersChain = append([]*types.Header{sb.Header}, headersChain...)
@@ -204,7 +238,7 @@ func (fv *ForkValidator) ValidatePayload(tx kv.RwTx, header *types.Header, body
 		}
 		// MakesBodyCanonical do not support PoS.
 		if has {
-			status = remote.EngineStatus_ACCEPTED
+			status, criticalError = fv.accept(tx, header, body, "body_in_db")
 			return
 		}
 		currentHash = sb.Header.ParentHash
@@ -214,6 +248,15 @@ func (fv *ForkValidator) ValidatePayload(tx kv.RwTx, header *types.Header, body
 		}
 		unwindPoint = sb.Header.Number.Uint64() - 1
 	}
+	// The side fork is executed against the state of the fork point, unwound from the head with the state history.
+	var historyAvailable bool
+	if historyAvailable, criticalError = fv.stateHistoryAvailable(tx, unwindPoint); criticalError != nil {
+		return
+	}
+	if !historyAvailable {
+		status, criticalError = fv.accept(tx, header, body, "pruned_history")
+		return
+	}
 	// Do not set an unwind point if we are already there.
 	if unwindPoint == fv.currentHeight {
 		unwindPoint = 0
@@ -224,7 +267,98 @@ func (fv *ForkValidator) ValidatePayload(tx kv.RwTx, header *types.Header, body
 		Events:      shards.NewEvents(),
 		Accumulator: shards.NewAccumulator(),
 	}
-	return fv.validateAndStorePayload(batch, header, body, unwindPoint, headersChain, bodiesChain, notifications)
+	return fv.validateAndStorePayload(tx, batch, header, body, unwindPoint, headersChain, bodiesChain, notifications)
+}
+
+// accept stores a side fork block which could not be validated, so that its side fork is validated
+// once complete.
+func (fv *ForkValidator) accept(tx kv.RwTx, header *types.Header, body *types.RawBody, outcome string) (remote.EngineStatus, error) {
+	payloadValidations[outcome].Inc()
+	log.Debug("[fork validator] Payload accepted without validation", "number", header.Number.Uint64(), "hash", header.Hash(), "reason", outcome)
+	if body == nil {
+		return remote.EngineStatus_ACCEPTED, nil
+	}
+	return remote.EngineStatus_ACCEPTED, writeSideForkBlock(tx, header, body, false)
+}
+
+// stateHistoryAvailable returns whether the state history of the db reaches back to the unwind point.
+func (fv *ForkValidator) stateHistoryAvailable(tx kv.Tx, unwindPoint uint64) (bool, error) {
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return false, err
+	}
+	if !pm.History.Enabled() {
+		return true, nil
+	}
+	return unwindPoint >= pm.History.PruneTo(fv.currentHeight), nil
+}
+
+// sideForkBlock returns a block of the side forks, and whether it was validated.
+func (fv *ForkValidator) sideForkBlock(tx kv.Tx, hash libcommon.Hash, number uint64) (*types.RawBlock, bool, error) {
+	if sb, ok := fv.sideForksBlock[hash]; ok {
+		return &sb, true, nil
+	}
+	return readSideForkBlock(tx, hash, number)
+}
+
+// storeValidBlock saves a validated block into the side forks, and into the db if persist is set.
+func (fv *ForkValidator) storeValidBlock(tx kv.RwTx, header *types.Header, body *types.RawBody, persist bool) error {
+	fv.sideForksBlock[header.Hash()] = types.RawBlock{Header: header, Body: body}
+	if !persist {
+		return nil
+	}
+	return writeSideForkBlock(tx, header, body, true)
+}
+
+// pruneSideForks deletes the side fork blocks of the db whose distance exceed the height of the head.
+func (fv *ForkValidator) pruneSideForks(tx kv.RwTx) error {
+	if fv.currentHeight <= fv.maxForkDepth {
+		return nil
+	}
+	c, err := tx.RwCursor(kv.SideForkBlocks)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint64(k) >= fv.currentHeight-fv.maxForkDepth {
+			break
+		}
+		if err := c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSideForkBlock reads a block of the kv.SideForkBlocks table, and whether it was validated.
+// key - block number (8 bytes) + block hash (32 bytes)
+// value - validated (1 byte) + rlp(types.RawBlock)
+func readSideForkBlock(tx kv.Getter, hash libcommon.Hash, number uint64) (*types.RawBlock, bool, error) {
+	v, err := tx.GetOne(kv.SideForkBlocks, dbutils.BlockBodyKey(number, hash))
+	if err != nil || len(v) == 0 {
+		return nil, false, err
+	}
+	block := &types.RawBlock{}
+	if err := rlp.DecodeBytes(v[1:], block); err != nil {
+		return nil, false, fmt.Errorf("side fork block %d %x: %w", number, hash, err)
+	}
+	return block, v[0] == 1, nil
+}
+
+func writeSideForkBlock(tx kv.Putter, header *types.Header, body *types.RawBody, valid bool) error {
+	v, err := rlp.EncodeToBytes(&types.RawBlock{Header: header, Body: body})
+	if err != nil {
+		return err
+	}
+	var validated byte
+	if valid {
+		validated = 1
+	}
+	return tx.Put(kv.SideForkBlocks, dbutils.BlockBodyKey(header.Number.Uint64(), header.Hash()), append([]byte{validated}, v...))
 }
 
 // Clear wipes out current extending fork data, this method is called after fcu is called,
@@ -265,11 +399,13 @@ func (fv *ForkValidator) ClearWithUnwind(tx kv.RwTx, accumulator *shards.Accumul
 }
 
 // validateAndStorePayload validate and store a payload fork chain if such chain results valid.
-func (fv *ForkValidator) validateAndStorePayload(tx kv.RwTx, header *types.Header, body *types.RawBody, unwindPoint uint64, headersChain []*types.Header, bodiesChain []*types.RawBody,
+// the payload is validated in batch, and the validated blocks are stored in tx.
+func (fv *ForkValidator) validateAndStorePayload(tx kv.RwTx, batch kv.RwTx, header *types.Header, body *types.RawBody, unwindPoint uint64, headersChain []*types.Header, bodiesChain []*types.RawBody,
 	notifications *shards.Notifications) (status remote.EngineStatus, latestValidHash libcommon.Hash, validationError error, criticalError error) {
-	validationError = fv.validatePayload(tx, header, body, unwindPoint, headersChain, bodiesChain, notifications)
+	validationError = fv.validatePayload(batch, header, body, unwindPoint, headersChain, bodiesChain, notifications)
 	latestValidHash = header.Hash()
 	if validationError != nil {
+		payloadValidations["invalid"].Inc()
 		latestValidHash = header.ParentHash
 		status = remote.EngineStatus_INVALID
 		if fv.extendingFork != nil {
@@ -279,10 +415,11 @@ func (fv *ForkValidator) validateAndStorePayload(tx kv.RwTx, header *types.Heade
 		fv.extendingForkHeadHash = libcommon.Hash{}
 		return
 	}
+	payloadValidations["valid"].Inc()
 	// If we do not have the body we can recover it from the batch.
 	if body == nil {
 		var bodyFromDb *types.Body
-		bodyFromDb, criticalError = rawdb.ReadBodyWithTransactions(tx, header.Hash(), header.Number.Uint64())
+		bodyFromDb, criticalError = rawdb.ReadBodyWithTransactions(batch, header.Hash(), header.Number.Uint64())
 		if criticalError != nil {
 			return
 		}
@@ -290,9 +427,18 @@ func (fv *ForkValidator) validateAndStorePayload(tx kv.RwTx, header *types.Heade
 			criticalError = fmt.Errorf("ForkValidator failed to recover block body: %d, %x", header.Number.Uint64(), header.Hash())
 			return
 		}
-		fv.sideForksBlock[header.Hash()] = types.RawBlock{Header: header, Body: bodyFromDb.RawBody()}
-	} else {
-		fv.sideForksBlock[header.Hash()] = types.RawBlock{Header: header, Body: body}
+		body = bodyFromDb.RawBody()
+	}
+	// The blocks of the side fork were validated with the payload. A payload extending the head is not
+	// persisted, its block is read from the canonical tables once the forkchoice makes it canonical.
+	for i := range headersChain {
+		if criticalError = fv.storeValidBlock(tx, headersChain[i], bodiesChain[i], true); criticalError != nil {
+			return
+		}
+	}
+	offCanonical := len(headersChain) > 0 || header.Number.Uint64() <= fv.currentHeight
+	if criticalError = fv.storeValidBlock(tx, header, body, offCanonical); criticalError != nil {
+		return
 	}
 	status = remote.EngineStatus_VALID
 	return
@@ -301,7 +447,7 @@ func (fv *ForkValidator) validateAndStorePayload(tx kv.RwTx, header *types.Heade
 // clean wipes out all outdated side forks whose distance exceed the height of the head.
 func (fv *ForkValidator) clean() {
 	for hash, sb := range fv.sideForksBlock {
-		if math.AbsoluteDifference(fv.currentHeight, sb.Header.Number.Uint64()) > maxForkDepth {
+		if math.AbsoluteDifference(fv.currentHeight, sb.Header.Number.Uint64()) > fv.maxForkDepth {
 			delete(fv.sideForksBlock, hash)
 		}
 	}
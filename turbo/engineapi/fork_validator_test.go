@@ -0,0 +1,99 @@
+package engineapi
+
+import (
+	"math/big"
+	"testing"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+)
+
+func TestForkValidatorSideForks(t *testing.T) {
+	require := require.New(t)
+	_, tx := memdb.NewTestTx(t)
+	dir := t.TempDir()
+
+	// The canonical chain is at block 10, the side fork forks at block 9
+	var parent libcommon.Hash
+	for number := uint64(0); number <= 10; number++ {
+		h := &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: parent, Difficulty: big.NewInt(1)}
+		rawdb.WriteHeader(tx, h)
+		require.NoError(rawdb.WriteCanonicalHash(tx, h.Hash(), number))
+		parent = h.Hash()
+	}
+	forkPoint, err := rawdb.ReadCanonicalHash(tx, 9)
+	require.NoError(err)
+	side10 := &types.Header{Number: big.NewInt(10), ParentHash: forkPoint, Difficulty: big.NewInt(2)}
+	side11 := &types.Header{Number: big.NewInt(11), ParentHash: side10.Hash(), Difficulty: big.NewInt(2)}
+
+	var validated [][]libcommon.Hash
+	var unwindPoints []uint64
+	validate := func(_ kv.RwTx, header *types.Header, _ *types.RawBody, unwindPoint uint64, headersChain []*types.Header, _ []*types.RawBody, _ *shards.Notifications) error {
+		var hashes []libcommon.Hash
+		for _, h := range append(headersChain, header) {
+			hashes = append(hashes, h.Hash())
+		}
+		validated = append(validated, hashes)
+		unwindPoints = append(unwindPoints, unwindPoint)
+		return nil
+	}
+	validatePayload := func(fv *ForkValidator, header *types.Header) remote.EngineStatus {
+		status, _, validationErr, criticalErr := fv.ValidatePayload(tx, header, &types.RawBody{}, false)
+		require.NoError(validationErr)
+		require.NoError(criticalErr)
+		return status
+	}
+
+	// A side fork block whose parent is missing is accepted and stored
+	require.Equal(remote.EngineStatus_ACCEPTED, validatePayload(NewForkValidator(10, validate, dir, DefaultMaxForkDepth), side11))
+	require.Empty(validated)
+	sb, valid, err := readSideForkBlock(tx, side11.Hash(), 11)
+	require.NoError(err)
+	require.NotNil(sb)
+	require.False(valid)
+
+	// The side fork is validated once complete, also after a restart
+	require.Equal(remote.EngineStatus_VALID, validatePayload(NewForkValidator(10, validate, dir, DefaultMaxForkDepth), side10))
+	require.Equal(remote.EngineStatus_VALID, validatePayload(NewForkValidator(10, validate, dir, DefaultMaxForkDepth), side11))
+	require.Equal([][]libcommon.Hash{{side10.Hash()}, {side10.Hash(), side11.Hash()}}, validated)
+	require.Equal([]uint64{9, 9}, unwindPoints)
+
+	// The validated blocks are not validated again
+	require.Equal(remote.EngineStatus_VALID, validatePayload(NewForkValidator(10, validate, dir, DefaultMaxForkDepth), side11))
+	require.Len(validated, 2)
+
+	// A payload extending the head is validated but not persisted
+	head, err := rawdb.ReadCanonicalHash(tx, 10)
+	require.NoError(err)
+	next := &types.Header{Number: big.NewInt(11), ParentHash: head, Difficulty: big.NewInt(1)}
+	status, _, validationErr, criticalErr := NewForkValidator(10, validate, dir, DefaultMaxForkDepth).ValidatePayload(tx, next, &types.RawBody{}, true)
+	require.NoError(validationErr)
+	require.NoError(criticalErr)
+	require.Equal(remote.EngineStatus_VALID, status)
+	require.Len(validated, 3)
+	sb, _, err = readSideForkBlock(tx, next.Hash(), 11)
+	require.NoError(err)
+	require.Nil(sb)
+
+	// The side forks deeper than the maximum fork depth are only accepted
+	side10b := &types.Header{Number: big.NewInt(10), ParentHash: forkPoint, Difficulty: big.NewInt(3)}
+	require.Equal(remote.EngineStatus_ACCEPTED, validatePayload(NewForkValidator(10, validate, dir, 0), side10b))
+	require.Len(validated, 3)
+
+	// The side fork blocks are pruned once too far from the head
+	fv := NewForkValidator(10, validate, dir, DefaultMaxForkDepth)
+	fv.NotifyCurrentHeight(100)
+	require.NoError(fv.pruneSideForks(tx))
+	for _, h := range []*types.Header{side10, side11, side10b} {
+		sb, _, err := readSideForkBlock(tx, h.Hash(), h.Number.Uint64())
+		require.NoError(err)
+		require.Nil(sb)
+	}
+}
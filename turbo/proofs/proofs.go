@@ -0,0 +1,322 @@
+// Package proofs retains the Merkle proofs of selected accounts at past blocks, so that
+// eth_getProof can be served for them once the state trie has moved past those blocks.
+//
+// Proofs are taken by the IntermediateHashes stage once it verified the trie root of a block
+// that is a multiple of Interval, for the configured addresses and storage slots. When the stage
+// catches up over several blocks at once, the trie is rewound to the latest due blocks it went
+// past. Proofs are kept in their own database for Retention blocks behind the head and are dropped
+// again when their blocks are unwound, or when the run of the stage that took them is not committed.
+package proofs
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// Proof table of the proof database: block number (8 bytes) + address -> JSON of accounts.AccProofResult
+const Proof = "StateProof"
+
+// TablesCfg are the tables of the proof database.
+var TablesCfg = kv.TableCfg{
+	Proof:           {},
+	kv.DatabaseInfo: {},
+}
+
+const (
+	DefaultInterval  = 20
+	DefaultRetention = 86_400 // two days of 2 second blocks
+)
+
+// ErrNotRetained is returned for proofs that are not kept by the proof database.
+var ErrNotRetained = errors.New("proof not retained")
+
+// Config selects the proofs to retain.
+type Config struct {
+	Addresses   []libcommon.Address // Accounts to take proofs of
+	StorageKeys []libcommon.Hash    // Storage slots proven together with each account
+	Interval    uint64              // Take proofs of every Interval-th block
+	Retention   uint64              // Number of blocks behind the head to keep proofs for, 0 keeps them forever
+}
+
+// Enabled returns whether any proofs are to be retained.
+func (c Config) Enabled() bool {
+	return len(c.Addresses) > 0 && c.Interval > 0
+}
+
+// Due returns whether proofs are taken at blockNum.
+func (c Config) Due(blockNum uint64) bool {
+	return c.Enabled() && blockNum%c.Interval == 0
+}
+
+// DueBetween returns the blocks after from and before to that proofs are taken at and that are
+// within Retention blocks of to, the newest first.
+func (c Config) DueBetween(from, to uint64) []uint64 {
+	if !c.Enabled() || to <= from+1 {
+		return nil
+	}
+	first := from + 1
+	if c.Retention > 0 && to > c.Retention && to-c.Retention > first {
+		first = to - c.Retention
+	}
+	var blocks []uint64
+	for blockNum := (to - 1) / c.Interval * c.Interval; blockNum >= first; blockNum -= c.Interval {
+		blocks = append(blocks, blockNum)
+	}
+	return blocks
+}
+
+func (c Config) retains(address libcommon.Address) bool {
+	for _, a := range c.Addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Config) retainsStorage(key libcommon.Hash) bool {
+	for _, k := range c.StorageKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Store reads and writes retained proofs.
+type Store struct {
+	db  kv.RwDB
+	cfg Config
+}
+
+func NewStore(db kv.RwDB, cfg Config) *Store {
+	return &Store{db: db, cfg: cfg}
+}
+
+func (s *Store) Config() Config { return s.cfg }
+
+func proofKey(blockNum uint64, address libcommon.Address) []byte {
+	k := make([]byte, dbutils.NumberLength+length.Addr)
+	copy(k, dbutils.EncodeBlockNumber(blockNum))
+	copy(k[dbutils.NumberLength:], address[:])
+	return k
+}
+
+// Compute builds the proof of address and its storageKeys from the hashed state and the
+// trie of tx. reader must read the state the trie was computed for.
+func Compute(tx kv.Tx, reader state.StateReader, address libcommon.Address, storageKeys []string) (*accounts.AccProofResult, error) {
+	addrHash, err := common.HashData(address[:])
+	if err != nil {
+		return nil, err
+	}
+	rl := trie.NewRetainList(0)
+	rl.AddKey(addrHash[:])
+
+	loader := trie.NewFlatDBTrieLoader("getProof")
+	if err := loader.Reset(rl, nil, nil, false); err != nil {
+		return nil, err
+	}
+
+	// Fill in the Account fields here to reduce the code changes
+	// needed in turbo/trie/hashbuilder.go
+	accProof := &accounts.AccProofResult{Address: address}
+	a, err := reader.ReadAccountData(address)
+	if err != nil {
+		return nil, err
+	}
+	var inc uint64
+	if a != nil {
+		accProof.Balance = (*hexutil.Big)(a.Balance.ToBig())
+		accProof.CodeHash = a.CodeHash
+		accProof.Nonce = hexutil.Uint64(a.Nonce)
+		accProof.StorageHash = a.Root
+		inc = a.Incarnation
+	}
+
+	loader.SetProofReturn(accProof)
+	if _, err = loader.CalcTrieRoot(tx, nil, nil); err != nil {
+		return nil, err
+	}
+
+	sp := make([]accounts.StorProofResult, len(storageKeys))
+	if len(storageKeys) > 0 {
+		for idx := range storageKeys {
+			sp[idx].Key = storageKeys[idx]
+		}
+		if err = loader.CalcStorageProof(tx, addrHash, inc, accProof.StorageHash, &sp); err != nil {
+			return nil, err
+		}
+	}
+	accProof.StorageProof = sp
+	return accProof, nil
+}
+
+// Retain takes the proofs of the configured accounts at blockNum, whose state and trie tx
+// must hold, and prunes the proofs that fell out of the retention window.
+func (s *Store) Retain(ctx context.Context, tx kv.Tx, blockNum uint64) error {
+	return s.retain(ctx, tx, state.NewPlainStateReader(tx), blockNum)
+}
+
+// RetainAt takes the proofs at a past blockNum, from the hashed state and the trie of tx that
+// are rewound to blockNum, with the accounts read from the state history of historyTx.
+func (s *Store) RetainAt(ctx context.Context, tx, historyTx kv.Tx, blockNum uint64, historyV3 bool) error {
+	reader, err := rpchelper.CreateStateReaderFromBlockNumber(ctx, historyTx, blockNum, false, 0, nil, historyV3, "")
+	if err != nil {
+		return err
+	}
+	return s.retain(ctx, tx, reader, blockNum)
+}
+
+func (s *Store) retain(ctx context.Context, tx kv.Tx, reader state.StateReader, blockNum uint64) error {
+	if !s.cfg.Due(blockNum) {
+		return nil
+	}
+	storageKeys := make([]string, len(s.cfg.StorageKeys))
+	for i, k := range s.cfg.StorageKeys {
+		storageKeys[i] = k.Hex()
+	}
+	values := make([][]byte, len(s.cfg.Addresses))
+	for i, address := range s.cfg.Addresses {
+		accProof, err := Compute(tx, reader, address, storageKeys)
+		if err != nil {
+			return fmt.Errorf("proof of %x at block %d: %w", address, blockNum, err)
+		}
+		if values[i], err = json.Marshal(accProof); err != nil {
+			return err
+		}
+	}
+	return s.db.Update(ctx, func(rwTx kv.RwTx) error {
+		for i, address := range s.cfg.Addresses {
+			if err := rwTx.Put(Proof, proofKey(blockNum, address), values[i]); err != nil {
+				return err
+			}
+		}
+		return s.prune(rwTx, blockNum)
+	})
+}
+
+// prune deletes the proofs of blocks more than Retention blocks behind head.
+func (s *Store) prune(tx kv.RwTx, head uint64) error {
+	if s.cfg.Retention == 0 || head <= s.cfg.Retention {
+		return nil
+	}
+	pruneTo := head - s.cfg.Retention
+	c, err := tx.RwCursor(Proof)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint64(k) >= pruneTo {
+			break
+		}
+		if err = c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unwind deletes the proofs of the blocks after unwindPoint.
+func (s *Store) Unwind(ctx context.Context, unwindPoint uint64) error {
+	return s.db.Update(ctx, func(tx kv.RwTx) error {
+		c, err := tx.RwCursor(Proof)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		for k, _, err := c.Seek(dbutils.EncodeBlockNumber(unwindPoint + 1)); k != nil; k, _, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			if err = c.DeleteCurrent(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// window returns the first and last block proofs are retained for.
+func window(tx kv.Tx) (first, last uint64, ok bool, err error) {
+	c, err := tx.Cursor(Proof)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer c.Close()
+	k, _, err := c.First()
+	if err != nil || k == nil {
+		return 0, 0, false, err
+	}
+	first = binary.BigEndian.Uint64(k)
+	if k, _, err = c.Last(); err != nil {
+		return 0, 0, false, err
+	}
+	last = binary.BigEndian.Uint64(k)
+	return first, last, true, nil
+}
+
+// Read returns the retained proof of address at blockNum, with the proofs of storageKeys only.
+func (s *Store) Read(ctx context.Context, address libcommon.Address, storageKeys []string, blockNum uint64) (*accounts.AccProofResult, error) {
+	if !s.cfg.retains(address) {
+		return nil, fmt.Errorf("%w: proofs of %x are not retained", ErrNotRetained, address)
+	}
+	for _, key := range storageKeys {
+		if !s.cfg.retainsStorage(libcommon.HexToHash(key)) {
+			return nil, fmt.Errorf("%w: storage slot %s of %x is not retained", ErrNotRetained, key, address)
+		}
+	}
+
+	var accProof accounts.AccProofResult
+	if err := s.db.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(Proof, proofKey(blockNum, address))
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			first, last, ok, err := window(tx)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%w: block %d, no proofs retained yet", ErrNotRetained, blockNum)
+			}
+			return fmt.Errorf("%w: block %d, proofs are retained every %d blocks from %d to %d", ErrNotRetained, blockNum, s.cfg.Interval, first, last)
+		}
+		return json.Unmarshal(v, &accProof)
+	}); err != nil {
+		return nil, err
+	}
+
+	// Only return the requested storage proofs, in the requested order
+	retained := accProof.StorageProof
+	accProof.StorageProof = make([]accounts.StorProofResult, len(storageKeys))
+	for i, key := range storageKeys {
+		for _, sp := range retained {
+			if libcommon.HexToHash(sp.Key) == libcommon.HexToHash(key) {
+				accProof.StorageProof[i] = sp
+				accProof.StorageProof[i].Key = key
+				break
+			}
+		}
+	}
+	return &accProof, nil
+}
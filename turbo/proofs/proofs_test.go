@@ -0,0 +1,130 @@
+package proofs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+)
+
+var (
+	passer = libcommon.HexToAddress("0x4200000000000000000000000000000000000016")
+	slot1  = libcommon.HexToHash("0x01")
+	slot2  = libcommon.HexToHash("0x02")
+)
+
+func newTestStore(t *testing.T, cfg Config) *Store {
+	db := mdbx.NewMDBX(log.New()).InMem(t.TempDir()).Label(kv.AcctProofDB).
+		WithTableCfg(func(kv.TableCfg) kv.TableCfg { return TablesCfg }).MustOpen()
+	t.Cleanup(db.Close)
+	return NewStore(db, cfg)
+}
+
+// put stores a proof of passer at blockNum as Retain would, without building it from a trie.
+func put(t *testing.T, s *Store, blockNum uint64) {
+	accProof := accounts.AccProofResult{
+		Address:      passer,
+		AccountProof: []string{"0xaa"},
+		StorageProof: []accounts.StorProofResult{
+			{Key: slot1.Hex(), Proof: []string{"0x11"}},
+			{Key: slot2.Hex(), Proof: []string{"0x22"}},
+		},
+	}
+	v, err := json.Marshal(accProof)
+	require.NoError(t, err)
+	require.NoError(t, s.db.Update(context.Background(), func(tx kv.RwTx) error {
+		if err := tx.Put(Proof, proofKey(blockNum, passer), v); err != nil {
+			return err
+		}
+		return s.prune(tx, blockNum)
+	}))
+}
+
+func TestReadRetainedProof(t *testing.T) {
+	s := newTestStore(t, Config{
+		Addresses:   []libcommon.Address{passer},
+		StorageKeys: []libcommon.Hash{slot1, slot2},
+		Interval:    10,
+		Retention:   100,
+	})
+	ctx := context.Background()
+
+	_, err := s.Read(ctx, passer, nil, 10)
+	require.ErrorIs(t, err, ErrNotRetained)
+
+	put(t, s, 10)
+	put(t, s, 20)
+
+	accProof, err := s.Read(ctx, passer, []string{"0x2"}, 20)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0xaa"}, accProof.AccountProof)
+	require.Len(t, accProof.StorageProof, 1)
+	require.Equal(t, "0x2", accProof.StorageProof[0].Key)
+	require.Equal(t, []string{"0x22"}, accProof.StorageProof[0].Proof)
+
+	// Between intervals, unknown accounts and unknown slots are not retained
+	_, err = s.Read(ctx, passer, nil, 15)
+	require.ErrorIs(t, err, ErrNotRetained)
+	_, err = s.Read(ctx, libcommon.HexToAddress("0x01"), nil, 20)
+	require.ErrorIs(t, err, ErrNotRetained)
+	_, err = s.Read(ctx, passer, []string{"0x3"}, 20)
+	require.ErrorIs(t, err, ErrNotRetained)
+}
+
+func TestPruneAndUnwindRetainedProofs(t *testing.T) {
+	s := newTestStore(t, Config{
+		Addresses: []libcommon.Address{passer},
+		Interval:  10,
+		Retention: 20,
+	})
+	ctx := context.Background()
+
+	for blockNum := uint64(10); blockNum <= 50; blockNum += 10 {
+		put(t, s, blockNum)
+	}
+	// Blocks more than 20 blocks behind the head are pruned
+	_, err := s.Read(ctx, passer, nil, 20)
+	require.ErrorIs(t, err, ErrNotRetained)
+	for _, blockNum := range []uint64{30, 40, 50} {
+		_, err = s.Read(ctx, passer, nil, blockNum)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, s.Unwind(ctx, 39))
+	_, err = s.Read(ctx, passer, nil, 30)
+	require.NoError(t, err)
+	for _, blockNum := range []uint64{40, 50} {
+		_, err = s.Read(ctx, passer, nil, blockNum)
+		require.ErrorIs(t, err, ErrNotRetained)
+	}
+}
+
+func TestConfigDue(t *testing.T) {
+	require.False(t, Config{Interval: 10}.Due(10))
+	cfg := Config{Addresses: []libcommon.Address{passer}, Interval: 10}
+	require.True(t, cfg.Due(0))
+	require.False(t, cfg.Due(15))
+	require.True(t, cfg.Due(20))
+	require.False(t, Config{Addresses: cfg.Addresses}.Due(20))
+}
+
+func TestConfigDueBetween(t *testing.T) {
+	cfg := Config{Addresses: []libcommon.Address{passer}, Interval: 10}
+	require.Equal(t, []uint64{40, 30, 20, 10}, cfg.DueBetween(0, 45))
+	require.Equal(t, []uint64{30, 20}, cfg.DueBetween(10, 40))
+	require.Empty(t, cfg.DueBetween(20, 21))
+	require.Empty(t, cfg.DueBetween(21, 29))
+
+	// Only the blocks within the retention window of to
+	cfg.Retention = 20
+	require.Equal(t, []uint64{40, 30}, cfg.DueBetween(0, 45))
+	require.Equal(t, []uint64{10}, cfg.DueBetween(0, 15))
+	require.Empty(t, Config{Interval: 10}.DueBetween(0, 45))
+}
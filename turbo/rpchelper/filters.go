@@ -43,9 +43,13 @@ type Filters struct {
 	logsStores         *SyncMap[LogsSubID, []*types.Log]
 	pendingHeadsStores *SyncMap[HeadsSubID, []*types.Header]
 	pendingTxsStores   *SyncMap[PendingTxsSubID, [][]types.Transaction]
+
+	config         FiltersConfig
+	pollingMu      sync.Mutex
+	pollingFilters map[string]*pollingFilter
 }
 
-func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, onNewSnapshot func()) *Filters {
+func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, onNewSnapshot func(), config FiltersConfig) *Filters {
 	log.Info("rpc filters: subscribing to Erigon events")
 
 	ff := &Filters{
@@ -58,6 +62,12 @@ func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient,
 		logsStores:         NewSyncMap[LogsSubID, []*types.Log](),
 		pendingHeadsStores: NewSyncMap[HeadsSubID, []*types.Header](),
 		pendingTxsStores:   NewSyncMap[PendingTxsSubID, [][]types.Transaction](),
+		config:             config,
+		pollingFilters:     map[string]*pollingFilter{},
+	}
+
+	if config.Timeout > 0 {
+		go ff.expirePollingFiltersLoop(ctx)
 	}
 
 	go func() {
@@ -310,6 +320,7 @@ func (ff *Filters) UnsubscribeHeads(id HeadsSubID) bool {
 		return false
 	}
 	ff.pendingHeadsStores.Delete(id)
+	ff.forgetPollingFilter(string(id))
 	return true
 }
 
@@ -362,6 +373,7 @@ func (ff *Filters) UnsubscribePendingTxs(id PendingTxsSubID) bool {
 		return false
 	}
 	ff.pendingTxsStores.Delete(id)
+	ff.forgetPollingFilter(string(id))
 	return true
 }
 
@@ -417,6 +429,7 @@ func (ff *Filters) loadLogsRequester() any {
 }
 
 func (ff *Filters) UnsubscribeLogs(id LogsSubID) bool {
+	ff.forgetPollingFilter(string(id))
 	isDeleted := ff.logsSubs.removeLogsFilter(id)
 	// if any filters in the aggregate need all addresses or all topics then the request to the central
 	// log subscription needs to honour this
@@ -551,7 +564,7 @@ func (ff *Filters) AddLogs(id LogsSubID, logs *types.Log) {
 			st = make([]*types.Log, 0)
 		}
 		st = append(st, logs)
-		return st
+		return capBuffer(st, ff.config.MaxLogs)
 	})
 }
 
@@ -569,7 +582,7 @@ func (ff *Filters) AddPendingBlock(id HeadsSubID, block *types.Header) {
 			st = make([]*types.Header, 0)
 		}
 		st = append(st, block)
-		return st
+		return capBuffer(st, ff.config.MaxHeaders)
 	})
 }
 
@@ -587,6 +600,19 @@ func (ff *Filters) AddPendingTxs(id PendingTxsSubID, txs []types.Transaction) {
 			st = make([][]types.Transaction, 0)
 		}
 		st = append(st, txs)
+		// Drop the oldest batches of transactions beyond the limit
+		if ff.config.MaxTxs > 0 {
+			count := 0
+			for i := len(st) - 1; i >= 0; i-- {
+				if count += len(st[i]); count > ff.config.MaxTxs {
+					if i == len(st)-1 {
+						// The newest batch alone is beyond the limit, keep its newest transactions
+						return [][]types.Transaction{st[i][len(st[i])-ff.config.MaxTxs:]}
+					}
+					return st[i+1:]
+				}
+			}
+		}
 		return st
 	})
 }
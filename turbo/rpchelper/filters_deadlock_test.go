@@ -15,7 +15,7 @@ import (
 )
 
 func TestFiltersDeadlock_Test(t *testing.T) {
-	f := rpchelper.New(context.TODO(), nil, nil, nil, func() {})
+	f := rpchelper.New(context.TODO(), nil, nil, nil, func() {}, rpchelper.DefaultFiltersConfig)
 	crit := filters.FilterCriteria{
 		Addresses: nil,
 		Topics:    [][]libcommon.Hash{},
@@ -3,13 +3,17 @@ package rpchelper
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/holiman/uint256"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/stretchr/testify/require"
 
 	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
 
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/filters"
 )
 
@@ -55,7 +59,7 @@ func TestFilters_GenerateSubscriptionID(t *testing.T) {
 }
 
 func TestFilters_SingleSubscription_OnlyTopicsSubscribedAreBroadcast(t *testing.T) {
-	f := New(context.TODO(), nil, nil, nil, func() {})
+	f := New(context.TODO(), nil, nil, nil, func() {}, DefaultFiltersConfig)
 
 	subbedTopic := libcommon.BytesToHash([]byte{10, 20})
 
@@ -86,7 +90,7 @@ func TestFilters_SingleSubscription_OnlyTopicsSubscribedAreBroadcast(t *testing.
 }
 
 func TestFilters_SingleSubscription_EmptyTopicsInCriteria_OnlyTopicsSubscribedAreBroadcast(t *testing.T) {
-	f := New(context.TODO(), nil, nil, nil, func() {})
+	f := New(context.TODO(), nil, nil, nil, func() {}, DefaultFiltersConfig)
 
 	var nilTopic libcommon.Hash
 	subbedTopic := libcommon.BytesToHash([]byte{10, 20})
@@ -118,7 +122,7 @@ func TestFilters_SingleSubscription_EmptyTopicsInCriteria_OnlyTopicsSubscribedAr
 }
 
 func TestFilters_TwoSubscriptionsWithDifferentCriteria(t *testing.T) {
-	f := New(context.TODO(), nil, nil, nil, func() {})
+	f := New(context.TODO(), nil, nil, nil, func() {}, DefaultFiltersConfig)
 
 	criteria1 := filters.FilterCriteria{
 		Addresses: nil,
@@ -157,7 +161,7 @@ func TestFilters_TwoSubscriptionsWithDifferentCriteria(t *testing.T) {
 }
 
 func TestFilters_ThreeSubscriptionsWithDifferentCriteria(t *testing.T) {
-	f := New(context.TODO(), nil, nil, nil, func() {})
+	f := New(context.TODO(), nil, nil, nil, func() {}, DefaultFiltersConfig)
 
 	criteria1 := filters.FilterCriteria{
 		Addresses: nil,
@@ -231,7 +235,7 @@ func TestFilters_SubscribeLogsGeneratesCorrectLogFilterRequest(t *testing.T) {
 		return nil
 	}
 
-	f := New(context.TODO(), nil, nil, nil, func() {})
+	f := New(context.TODO(), nil, nil, nil, func() {}, DefaultFiltersConfig)
 	f.logsRequestor.Store(loadRequester)
 
 	// first request has no filters
@@ -340,3 +344,68 @@ func TestFilters_SubscribeLogsGeneratesCorrectLogFilterRequest(t *testing.T) {
 		t.Error("5: expected topics to be empty")
 	}
 }
+
+func TestFilters_PollingFilterLimitsAndExpiry(t *testing.T) {
+	f := New(context.TODO(), nil, nil, nil, func() {}, FiltersConfig{MaxFilters: 2, MaxLogs: 2, MaxTxs: 3})
+
+	logsID, err := f.NewLogsFilter(filters.FilterCriteria{})
+	require.NoError(t, err)
+	headsID, err := f.NewBlockFilter()
+	require.NoError(t, err)
+	_, err = f.NewPendingTxsFilter()
+	require.ErrorIs(t, err, ErrTooManyFilters)
+
+	// Only the newest logs are buffered between polls
+	for i := 0; i < 3; i++ {
+		f.AddLogs(logsID, &types.Log{Index: uint(i)})
+	}
+	logs, ok := f.ReadLogs(logsID)
+	require.True(t, ok)
+	require.Len(t, logs, 2)
+	require.Equal(t, uint(1), logs[0].Index)
+
+	// Filters not polled since the deadline expire
+	time.Sleep(10 * time.Millisecond)
+	deadline := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	_, err = f.PollFilter(string(logsID))
+	require.NoError(t, err)
+	f.expirePollingFilters(deadline)
+	kind, err := f.PollFilter(string(logsID))
+	require.NoError(t, err)
+	require.Equal(t, LogsPollingFilter, kind)
+	_, err = f.PollFilter(string(headsID))
+	require.ErrorIs(t, err, ErrFilterNotFound)
+	_, ok = f.headsSubs.Get(headsID)
+	require.False(t, ok)
+
+	// The expired filter made room for another one, which drops whole batches of transactions beyond the limit
+	txsID, err := f.NewPendingTxsFilter()
+	require.NoError(t, err)
+	newTx := func(nonce uint64) types.Transaction {
+		return types.NewTransaction(nonce, libcommon.Address{}, uint256.NewInt(0), 21_000, uint256.NewInt(0), nil)
+	}
+	f.AddPendingTxs(txsID, []types.Transaction{newTx(0), newTx(1)})
+	f.AddPendingTxs(txsID, []types.Transaction{newTx(2), newTx(3)})
+	txs, ok := f.ReadPendingTxs(txsID)
+	require.True(t, ok)
+	require.Len(t, txs, 1)
+	require.Equal(t, uint64(2), txs[0][0].GetNonce())
+
+	// A batch beyond the limit on its own is cut to its newest transactions
+	f.AddPendingTxs(txsID, []types.Transaction{newTx(4), newTx(5), newTx(6), newTx(7)})
+	txs, ok = f.ReadPendingTxs(txsID)
+	require.True(t, ok)
+	require.Len(t, txs, 1)
+	require.Len(t, txs[0], 3)
+	require.Equal(t, uint64(5), txs[0][0].GetNonce())
+
+	require.True(t, f.UnsubscribeLogs(logsID))
+	_, err = f.PollFilter(string(logsID))
+	require.ErrorIs(t, err, ErrFilterNotFound)
+
+	// Subscriptions aren't polling filters
+	_, subID := f.SubscribeLogs(1, filters.FilterCriteria{})
+	_, err = f.PollFilter(string(subID))
+	require.ErrorIs(t, err, ErrFilterNotFound)
+}
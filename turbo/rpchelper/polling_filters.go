@@ -0,0 +1,181 @@
+package rpchelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/ledgerwatch/erigon/eth/filters"
+)
+
+// FiltersConfig limits the filters installed by eth_newFilter, eth_newBlockFilter and
+// eth_newPendingTransactionFilter, which are polled by clients with eth_getFilterChanges.
+type FiltersConfig struct {
+	Timeout    time.Duration // Filters not polled for Timeout are uninstalled, 0 keeps them until uninstalled
+	MaxFilters int           // Maximum number of installed filters, 0 is unlimited
+	MaxLogs    int           // Maximum number of logs buffered per filter between polls, 0 is unlimited
+	MaxHeaders int           // Maximum number of headers buffered per filter between polls, 0 is unlimited
+	MaxTxs     int           // Maximum number of transactions buffered per filter between polls, 0 is unlimited
+}
+
+var DefaultFiltersConfig = FiltersConfig{
+	Timeout:    5 * time.Minute,
+	MaxFilters: 10_000,
+	MaxLogs:    10_000,
+	MaxHeaders: 1_000,
+	MaxTxs:     10_000,
+}
+
+// Kinds of polling filters
+const (
+	LogsPollingFilter       = "logs"
+	BlocksPollingFilter     = "blocks"
+	PendingTxsPollingFilter = "pending_txs"
+)
+
+var (
+	ErrFilterNotFound  = errors.New("filter not found")
+	ErrTooManyFilters  = errors.New("too many filters installed")
+	pollingFilterGauge = map[string]*metrics.Counter{
+		LogsPollingFilter:       metrics.GetOrCreateCounter(`rpc_polling_filters{type="logs"}`),
+		BlocksPollingFilter:     metrics.GetOrCreateCounter(`rpc_polling_filters{type="blocks"}`),
+		PendingTxsPollingFilter: metrics.GetOrCreateCounter(`rpc_polling_filters{type="pending_txs"}`),
+	}
+	expiredFiltersCounter = metrics.GetOrCreateCounter(`rpc_polling_filters_expired_total`)
+)
+
+type pollingFilter struct {
+	kind      string // One of LogsPollingFilter, BlocksPollingFilter and PendingTxsPollingFilter
+	lastPoll  time.Time
+	uninstall func()
+}
+
+// installPollingFilter starts tracking the filter id, uninstall is called when it expires.
+func (ff *Filters) installPollingFilter(id string, kind string, uninstall func()) error {
+	ff.pollingMu.Lock()
+	defer ff.pollingMu.Unlock()
+	if ff.config.MaxFilters > 0 && len(ff.pollingFilters) >= ff.config.MaxFilters {
+		return fmt.Errorf("%w: limit is %d", ErrTooManyFilters, ff.config.MaxFilters)
+	}
+	ff.pollingFilters[id] = &pollingFilter{kind: kind, lastPoll: time.Now(), uninstall: uninstall}
+	pollingFilterGauge[kind].Inc()
+	return nil
+}
+
+// forgetPollingFilter stops tracking the filter id, if it is a polling filter.
+func (ff *Filters) forgetPollingFilter(id string) {
+	ff.pollingMu.Lock()
+	defer ff.pollingMu.Unlock()
+	if f, ok := ff.pollingFilters[id]; ok {
+		delete(ff.pollingFilters, id)
+		pollingFilterGauge[f.kind].Dec()
+	}
+}
+
+// PollFilter marks the filter id as polled, postponing its expiry, and returns its kind. It
+// returns ErrFilterNotFound for filters that were never installed, were uninstalled or have
+// expired, and for subscriptions, which aren't polled.
+func (ff *Filters) PollFilter(id string) (string, error) {
+	ff.pollingMu.Lock()
+	defer ff.pollingMu.Unlock()
+	f, ok := ff.pollingFilters[id]
+	if !ok {
+		return "", ErrFilterNotFound
+	}
+	f.lastPoll = time.Now()
+	return f.kind, nil
+}
+
+// expirePollingFilters uninstalls the filters last polled before deadline.
+func (ff *Filters) expirePollingFilters(deadline time.Time) {
+	var expired []func()
+	ff.pollingMu.Lock()
+	for _, f := range ff.pollingFilters {
+		if f.lastPoll.Before(deadline) {
+			expired = append(expired, f.uninstall)
+		}
+	}
+	ff.pollingMu.Unlock()
+
+	// Uninstalling forgets the filter, so it must be done without holding the lock
+	for _, uninstall := range expired {
+		uninstall()
+	}
+	expiredFiltersCounter.Add(len(expired))
+}
+
+// expiryChecksPerTimeout is how many times expiry is checked per Timeout, filters are uninstalled
+// after at most Timeout plus a fraction of it without polls.
+const expiryChecksPerTimeout = 10
+
+func (ff *Filters) expirePollingFiltersLoop(ctx context.Context) {
+	ticker := time.NewTicker(ff.config.Timeout / expiryChecksPerTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			ff.expirePollingFilters(now.Add(-ff.config.Timeout))
+		}
+	}
+}
+
+// NewPendingTxsFilter installs a polling filter buffering the new pending transactions until
+// they are read by ReadPendingTxs.
+func (ff *Filters) NewPendingTxsFilter() (PendingTxsSubID, error) {
+	txsCh, id := ff.SubscribePendingTxs(32)
+	if err := ff.installPollingFilter(string(id), PendingTxsPollingFilter, func() { ff.UnsubscribePendingTxs(id) }); err != nil {
+		ff.UnsubscribePendingTxs(id)
+		return "", err
+	}
+	go func() {
+		for txs := range txsCh {
+			ff.AddPendingTxs(id, txs)
+		}
+	}()
+	return id, nil
+}
+
+// NewBlockFilter installs a polling filter buffering the new headers until they are read by
+// ReadPendingBlocks.
+func (ff *Filters) NewBlockFilter() (HeadsSubID, error) {
+	ch, id := ff.SubscribeNewHeads(32)
+	if err := ff.installPollingFilter(string(id), BlocksPollingFilter, func() { ff.UnsubscribeHeads(id) }); err != nil {
+		ff.UnsubscribeHeads(id)
+		return "", err
+	}
+	go func() {
+		for block := range ch {
+			ff.AddPendingBlock(id, block)
+		}
+	}()
+	return id, nil
+}
+
+// NewLogsFilter installs a polling filter buffering the new logs matching crit until they are
+// read by ReadLogs.
+func (ff *Filters) NewLogsFilter(crit filters.FilterCriteria) (LogsSubID, error) {
+	logs, id := ff.SubscribeLogs(256, crit)
+	if err := ff.installPollingFilter(string(id), LogsPollingFilter, func() { ff.UnsubscribeLogs(id) }); err != nil {
+		ff.UnsubscribeLogs(id)
+		return "", err
+	}
+	go func() {
+		for lg := range logs {
+			ff.AddLogs(id, lg)
+		}
+	}()
+	return id, nil
+}
+
+// capBuffer drops the oldest items of a filter buffer holding more than max items.
+func capBuffer[T any](st []T, max int) []T {
+	if max > 0 && len(st) > max {
+		return st[len(st)-max:]
+	}
+	return st
+}
@@ -56,3 +56,12 @@ type FullBlockReader interface {
 	TxnReader
 	CanonicalReader
 }
+
+// ReceiptsReader reads the receipts of the blocks retired to the snapshots of receipts.
+type ReceiptsReader interface {
+	// RawReceipts returns the receipts of a block without their fields derived from the block, nil
+	// when the block is not in the snapshots of receipts.
+	RawReceipts(ctx context.Context, blockHeight uint64) (types.Receipts, error)
+	// ReceiptsTo returns the block following the snapshots of receipts, 0 without them.
+	ReceiptsTo() uint64
+}
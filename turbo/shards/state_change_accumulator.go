@@ -15,6 +15,7 @@ type Accumulator struct {
 	latestChange       *remote.StateChange
 	accountChangeIndex map[libcommon.Address]int // For the latest changes, allows finding account change by account's address
 	storageChangeIndex map[libcommon.Address]map[libcommon.Hash]int
+	removedLogs        []*remote.SubscribeLogsReply // Logs of unwound blocks, sent to subscribers once the unwind is committed
 }
 
 func NewAccumulator() *Accumulator {
@@ -30,6 +31,7 @@ func (a *Accumulator) Reset(plainStateID uint64) {
 	a.latestChange = nil
 	a.accountChangeIndex = nil
 	a.storageChangeIndex = nil
+	a.removedLogs = nil
 	a.plainStateID = plainStateID
 }
 func (a *Accumulator) SendAndReset(ctx context.Context, c StateChangeConsumer, pendingBaseFee uint64, blockGasLimit uint64) {
@@ -45,6 +47,19 @@ func (a *Accumulator) SetStateID(stateID uint64) {
 	a.plainStateID = stateID
 }
 
+// RemoveLogs records the logs of unwound blocks, they are expected to have Removed set
+func (a *Accumulator) RemoveLogs(logs []*remote.SubscribeLogsReply) {
+	a.removedLogs = append(a.removedLogs, logs...)
+}
+
+// RemovedLogs returns the logs recorded by RemoveLogs since the last reset
+func (a *Accumulator) RemovedLogs() []*remote.SubscribeLogsReply {
+	if a == nil {
+		return nil
+	}
+	return a.removedLogs
+}
+
 // StartChange begins accumulation of changes for a new block
 func (a *Accumulator) StartChange(blockHeight uint64, blockHash libcommon.Hash, txs [][]byte, unwind bool) {
 	a.changes = append(a.changes, &remote.StateChange{})
@@ -770,6 +770,47 @@ func (back *BlockReaderWithSnapshots) TxnByIdxInBlock(ctx context.Context, tx kv
 	return txn, nil
 }
 
+// RawReceipts - receipts of the block from the snapshots of receipts, without their fields derived from the block.
+// return nil if the block is not in the snapshots of receipts
+func (back *BlockReaderWithSnapshots) RawReceipts(ctx context.Context, blockHeight uint64) (receipts types.Receipts, err error) {
+	_, err = back.sn.ViewReceipts(blockHeight, func(segment *ReceiptSegment) error {
+		receipts, _, err = back.receiptsFromSnapshot(blockHeight, segment, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+func (back *BlockReaderWithSnapshots) ReceiptsTo() uint64 { return back.sn.ReceiptsTo() }
+
+func (back *BlockReaderWithSnapshots) receiptsFromSnapshot(blockHeight uint64, sn *ReceiptSegment, buf []byte) (types.Receipts, []byte, error) {
+	if sn.idxReceiptsNumber == nil {
+		return nil, buf, nil
+	}
+	receiptsOffset := sn.idxReceiptsNumber.OrdinalLookup(blockHeight - sn.idxReceiptsNumber.BaseDataID())
+
+	gg := sn.seg.MakeGetter()
+	gg.Reset(receiptsOffset)
+	if !gg.HasNext() {
+		return nil, buf, nil
+	}
+	buf, _ = gg.Next(buf[:0])
+	if len(buf) == 0 {
+		return nil, buf, nil
+	}
+	var stored types.ReceiptsForStorage
+	if err := rlp.DecodeBytes(buf, &stored); err != nil {
+		return nil, buf, fmt.Errorf("receipts of block %d: %w, %s", blockHeight, err, sn.seg.FilePath())
+	}
+	receipts := make(types.Receipts, len(stored))
+	for i, receipt := range stored {
+		receipts[i] = (*types.Receipt)(receipt)
+	}
+	return receipts, buf, nil
+}
+
 // TxnLookup - find blockNumber and txnID by txnHash
 func (back *BlockReaderWithSnapshots) TxnLookup(ctx context.Context, tx kv.Getter, txnHash libcommon.Hash) (uint64, bool, error) {
 	n, err := rawdb.ReadTxLookupEntry(tx, txnHash)
@@ -359,9 +359,10 @@ type RoSnapshots struct {
 	indicesReady  atomic.Bool
 	segmentsReady atomic.Bool
 
-	Headers *headerSegments
-	Bodies  *bodySegments
-	Txs     *txnSegments
+	Headers  *headerSegments
+	Bodies   *bodySegments
+	Txs      *txnSegments
+	Receipts *receiptSegments // opened from the snapshots folder, see reopenReceipts
 
 	dir         string
 	segmentsMax atomic.Uint64 // all types of .seg files are available - up to this number
@@ -375,7 +376,7 @@ type RoSnapshots struct {
 //   - gaps are not allowed
 //   - segment have [from:to) semantic
 func NewRoSnapshots(cfg ethconfig.Snapshot, snapDir string) *RoSnapshots {
-	return &RoSnapshots{dir: snapDir, cfg: cfg, Headers: &headerSegments{}, Bodies: &bodySegments{}, Txs: &txnSegments{}}
+	return &RoSnapshots{dir: snapDir, cfg: cfg, Headers: &headerSegments{}, Bodies: &bodySegments{}, Txs: &txnSegments{}, Receipts: &receiptSegments{}}
 }
 
 func (s *RoSnapshots) Cfg() ethconfig.Snapshot { return s.cfg }
@@ -694,7 +695,7 @@ Loop:
 	s.idxMax.Store(s.idxAvailability())
 	s.indicesReady.Store(true)
 
-	return nil
+	return s.reopenReceipts(optimistic)
 }
 
 func (s *RoSnapshots) Ranges() (ranges []Range) {
@@ -742,6 +743,7 @@ func (s *RoSnapshots) Close() {
 	s.Txs.lock.Lock()
 	defer s.Txs.lock.Unlock()
 	s.closeWhatNotInList(nil)
+	s.closeReceipts()
 }
 
 func (s *RoSnapshots) closeWhatNotInList(l []string) {
@@ -1172,6 +1174,13 @@ func retireBlocks(ctx context.Context, blockFrom, blockTo uint64, chainID uint25
 		notifier.OnNewSnapshot()
 	}
 	merger := NewMerger(tmpDir, workers, lvl, chainID, notifier)
+	if snapshots.Cfg().Receipts {
+		// the receipts are retired with the blocks, but never hold back the blocks
+		if err := retireReceipts(ctx, tmpDir, snapshots, db, workers, lvl, merger); err != nil {
+			retireReceiptsFailed.Inc()
+			log.Warn("[snapshots] retire receipts", "err", err)
+		}
+	}
 	rangesToMerge := merger.FindMergeRanges(snapshots.Ranges())
 	if len(rangesToMerge) == 0 {
 		return nil
@@ -1332,11 +1341,21 @@ func DumpTxs(ctx context.Context, db kv.RoDB, segmentFile, tmpDir string, blockF
 	slot := types2.TxSlot{}
 	var sender [20]byte
 	parse := func(v, valueBuf []byte, senders []common2.Address, j int) ([]byte, error) {
-		if _, err := parseCtx.ParseTransaction(v, 0, &slot, sender[:], false /* hasEnvelope */, nil); err != nil {
-			return valueBuf, err
-		}
-		if len(senders) > 0 {
-			sender = senders[j]
+		if isDepositTxRlp(v) {
+			// deposits are not signed, their sender is explicit
+			hash, from, err := parseDepositTx(v)
+			if err != nil {
+				return valueBuf, err
+			}
+			copy(slot.IDHash[:], hash[:])
+			sender = from
+		} else {
+			if _, err := parseCtx.ParseTransaction(v, 0, &slot, sender[:], false /* hasEnvelope */, nil); err != nil {
+				return valueBuf, err
+			}
+			if len(senders) > 0 {
+				sender = senders[j]
+			}
 		}
 
 		valueBuf = valueBuf[:0]
@@ -1649,6 +1668,49 @@ func expectedTxsAmount(snapDir string, blockFrom, blockTo uint64) (firstTxID, ex
 	return
 }
 
+// isDepositTxRlp returns whether txRlp is the RLP of a deposit transaction, which the txpool
+// parser doesn't know about.
+func isDepositTxRlp(txRlp []byte) bool {
+	kind, content, _, err := rlp.Split(txRlp)
+	return err == nil && kind == rlp.String && len(content) > 0 && content[0] == types.DepositTxType
+}
+
+// parseDepositTx returns the hash and the sender of the deposit transaction of txRlp.
+func parseDepositTx(txRlp []byte) (common2.Hash, common2.Address, error) {
+	txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(txRlp), uint64(len(txRlp))))
+	if err != nil {
+		return common2.Hash{}, common2.Address{}, err
+	}
+	deposit, ok := txn.(*types.DepositTransaction)
+	if !ok || deposit.From == nil {
+		return common2.Hash{}, common2.Address{}, fmt.Errorf("invalid deposit transaction %x", txn.Hash())
+	}
+	return deposit.Hash(), *deposit.From, nil
+}
+
+// segmentTxnHash parses the hash of the transaction txnID of its word in a transactions segment to
+// slot.IDHash, the way the transactions are indexed.
+func segmentTxnHash(parseCtx *types2.TxParseContext, slot *types2.TxSlot, word []byte, txnID uint64) error {
+	firstTxByteAndlengthOfAddress := 21
+	isSystemTx := len(word) == 0
+	if isSystemTx { // system-txs hash:pad32(txnID)
+		binary.BigEndian.PutUint64(slot.IDHash[:], txnID)
+		return nil
+	}
+	if isDepositTxRlp(word[firstTxByteAndlengthOfAddress:]) {
+		hash, _, err := parseDepositTx(word[firstTxByteAndlengthOfAddress:])
+		if err != nil {
+			return fmt.Errorf("parseDepositTx: %w", err)
+		}
+		copy(slot.IDHash[:], hash[:])
+		return nil
+	}
+	if _, err := parseCtx.ParseTransaction(word[firstTxByteAndlengthOfAddress:], 0, slot, nil, true /* hasEnvelope */, nil /* validateHash */); err != nil {
+		return fmt.Errorf("ParseTransaction: %w", err)
+	}
+	return nil
+}
+
 func TransactionsIdx(ctx context.Context, chainID uint256.Int, blockFrom, blockTo uint64, snapDir string, tmpDir string, p *background.Progress, lvl log.Lvl) (err error) {
 	defer func() {
 		if rec := recover(); rec != nil {
@@ -1749,14 +1811,8 @@ RETRY:
 
 			blockNum++
 		}
-		firstTxByteAndlengthOfAddress := 21
-		isSystemTx := len(word) == 0
-		if isSystemTx { // system-txs hash:pad32(txnID)
-			binary.BigEndian.PutUint64(slot.IDHash[:], firstTxID+i)
-		} else {
-			if _, err = parseCtx.ParseTransaction(word[firstTxByteAndlengthOfAddress:], 0, &slot, nil, true /* hasEnvelope */, nil /* validateHash */); err != nil {
-				return fmt.Errorf("ParseTransaction: %w, blockNum: %d, i: %d", err, blockNum, i)
-			}
+		if err := segmentTxnHash(parseCtx, &slot, word, firstTxID+i); err != nil {
+			return fmt.Errorf("%w, blockNum: %d, i: %d", err, blockNum, i)
 		}
 
 		if err := txnHashIdx.AddKey(slot.IDHash[:], offset); err != nil {
@@ -1986,7 +2042,7 @@ func (*Merger) FindMergeRanges(currentRanges []Range) (toMerge []Range) {
 			}
 			aggFrom := r.to - span
 			toMerge = append(toMerge, Range{from: aggFrom, to: r.to})
-			for currentRanges[i].from > aggFrom {
+			for i > 0 && currentRanges[i].from > aggFrom {
 				i--
 			}
 			break
@@ -2,20 +2,29 @@ package snapshotsync
 
 import (
 	"context"
+	"math/big"
 	"path/filepath"
 	"testing"
 	"testing/fstest"
 
 	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/compress"
 	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon-lib/recsplit"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/stretchr/testify/require"
 
 	"github.com/ledgerwatch/erigon/common/math"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/params/networkname"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapcfg"
 )
 
@@ -238,3 +247,118 @@ func TestParseCompressedFileName(t *testing.T) {
 	require.Equal(1_000, int(f.From))
 	require.Equal(2_000, int(f.To))
 }
+
+func TestParseDepositTx(t *testing.T) {
+	require := require.New(t)
+	sourceHash, from, to := libcommon.HexToHash("0x01"), libcommon.HexToAddress("0x02"), libcommon.HexToAddress("0x03")
+	deposit := &types.DepositTransaction{
+		SourceHash: &sourceHash,
+		From:       &from,
+		To:         &to,
+		Mint:       uint256.NewInt(10),
+		Value:      uint256.NewInt(5),
+		GasLimit:   21_000,
+	}
+	depositRlp, err := rlp.EncodeToBytes(deposit)
+	require.NoError(err)
+	require.True(isDepositTxRlp(depositRlp))
+	hash, sender, err := parseDepositTx(depositRlp)
+	require.NoError(err)
+	require.Equal(deposit.Hash(), hash)
+	require.Equal(from, sender)
+
+	legacy := types.NewTransaction(0, to, uint256.NewInt(1), 21_000, uint256.NewInt(1), nil)
+	legacyRlp, err := rlp.EncodeToBytes(legacy)
+	require.NoError(err)
+	require.False(isDepositTxRlp(legacyRlp))
+}
+
+func TestDumpDepositTxs(t *testing.T) {
+	require, ctx := require.New(t), context.Background()
+	snapDir, tmpDir := t.TempDir(), t.TempDir()
+	chainConfig := params.TestChainConfig
+	key, _ := crypto.GenerateKey()
+	signer := types.LatestSignerForChainID(chainConfig.ChainID)
+	sourceHash, from, to := libcommon.HexToHash("0x01"), libcommon.HexToAddress("0x02"), libcommon.HexToAddress("0x03")
+	deposit := &types.DepositTransaction{
+		SourceHash: &sourceHash,
+		From:       &from,
+		To:         &to,
+		Mint:       uint256.NewInt(10),
+		Value:      uint256.NewInt(5),
+		GasLimit:   21_000,
+		Data:       []byte{1, 2, 3},
+	}
+	legacy, err := types.SignTx(types.NewTransaction(0, to, uint256.NewInt(1), 21_000, uint256.NewInt(1), nil), *signer, key)
+	require.NoError(err)
+
+	// A segment of empty blocks, but block 1 starting with the deposit
+	db := memdb.NewTestDB(t)
+	var blocks []*types.Block
+	err = db.Update(ctx, func(tx kv.RwTx) error {
+		var parentHash libcommon.Hash
+		for i := int64(0); i < 1_000; i++ {
+			var txs []types.Transaction
+			if i == 1 {
+				txs = []types.Transaction{deposit, legacy}
+			}
+			block := types.NewBlock(&types.Header{Number: big.NewInt(i), ParentHash: parentHash, Difficulty: big.NewInt(1)}, txs, nil, nil, nil)
+			if err := rawdb.WriteBlock(tx, block); err != nil {
+				return err
+			}
+			if err := rawdb.WriteCanonicalHash(tx, block.Hash(), block.NumberU64()); err != nil {
+				return err
+			}
+			if i == 1 {
+				if err := rawdb.WriteSenders(tx, block.Hash(), block.NumberU64(), []libcommon.Address{from, crypto.PubkeyToAddress(key.PublicKey)}); err != nil {
+					return err
+				}
+			}
+			parentHash = block.Hash()
+			blocks = append(blocks, block)
+		}
+		return rawdb.WriteChainConfig(tx, blocks[0].Hash(), chainConfig)
+	})
+	require.NoError(err)
+
+	err = DumpBlocks(ctx, 0, 1_000, 1_000, tmpDir, snapDir, db, 1, log.LvlDebug)
+	require.NoError(err)
+	s := NewRoSnapshots(ethconfig.Snapshot{Enabled: true}, snapDir)
+	defer s.Close()
+	err = s.ReopenFolder()
+	require.NoError(err)
+	reader := NewBlockReaderWithSnapshots(s)
+
+	// The deposit is read back intact from the segment, by hash and within its block
+	emptyDB := memdb.NewTestDB(t)
+	err = emptyDB.View(ctx, func(tx kv.Tx) error {
+		blockNum, ok, err := reader.TxnLookup(ctx, tx, deposit.Hash())
+		require.NoError(err)
+		require.True(ok)
+		require.Equal(uint64(1), blockNum)
+		blockNum, ok, err = reader.TxnLookup(ctx, tx, legacy.Hash())
+		require.NoError(err)
+		require.True(ok)
+		require.Equal(uint64(1), blockNum)
+
+		txn, err := reader.TxnByIdxInBlock(ctx, tx, 1, 0)
+		require.NoError(err)
+		require.Equal(deposit.Hash(), txn.Hash())
+		sender, ok := txn.GetSender()
+		require.True(ok)
+		require.Equal(from, sender)
+
+		body, err := reader.BodyWithTransactions(ctx, tx, blocks[1].Hash(), 1)
+		require.NoError(err)
+		require.Len(body.Transactions, 2)
+		depositRlp, err := rlp.EncodeToBytes(deposit)
+		require.NoError(err)
+		txnRlp, err := rlp.EncodeToBytes(body.Transactions[0])
+		require.NoError(err)
+		require.Equal(depositRlp, txnRlp)
+		require.Equal(legacy.Hash(), body.Transactions[1].Hash())
+		require.Equal([]libcommon.Address{from, crypto.PubkeyToAddress(key.PublicKey)}, body.SendersFromTxs())
+		return nil
+	})
+	require.NoError(err)
+}
@@ -0,0 +1,516 @@
+package snapshotsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	common2 "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/background"
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
+	"github.com/ledgerwatch/erigon-lib/common/dbg"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/recsplit"
+	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/exp/slices"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// receiptsFileType is the type of the snapshots of receipts. They are not snapshot types of the
+// downloader: the nodes retiring their receipts produce them locally, and they are not listed in
+// the db with the segments of blocks.
+const receiptsFileType = "receipts"
+
+var retireReceiptsFailed = metrics.GetOrCreateCounter(`snapshots_retire_failed{type="receipts"}`)
+
+func receiptsSegmentFileName(from, to uint64) string {
+	return fmt.Sprintf("v1-%06d-%06d-%s.seg", from/1_000, to/1_000, receiptsFileType)
+}
+
+func receiptsIdxFileName(from, to uint64) string {
+	return snaptype.IdxFileName(from, to, receiptsFileType)
+}
+
+// parseReceiptsFileName returns the blocks range of a receipts segment, false for the other files.
+func parseReceiptsFileName(fileName string) (from, to uint64, ok bool) {
+	if filepath.Ext(fileName) != ".seg" {
+		return 0, 0, false
+	}
+	parts := strings.Split(strings.TrimSuffix(fileName, ".seg"), "-")
+	if len(parts) != 4 || parts[0] != "v1" || parts[3] != receiptsFileType {
+		return 0, 0, false
+	}
+	from, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	to, err = strconv.ParseUint(parts[2], 10, 64)
+	if err != nil || to <= from {
+		return 0, 0, false
+	}
+	return from * 1_000, to * 1_000, true
+}
+
+// receiptsSegmentRanges returns the ranges of the receipts segments of dir. The merged segments
+// replace the ones they cover, which are removed after the merge.
+func receiptsSegmentRanges(dir string) ([]Range, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ranges []Range
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if from, to, ok := parseReceiptsFileName(f.Name()); ok {
+			ranges = append(ranges, Range{from, to})
+		}
+	}
+	slices.SortFunc(ranges, func(i, j Range) bool {
+		if i.from != j.from {
+			return i.from < j.from
+		}
+		return i.to > j.to
+	})
+	res := make([]Range, 0, len(ranges))
+	for _, r := range ranges {
+		if len(res) > 0 && r.from < res[len(res)-1].to {
+			continue
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+type ReceiptSegment struct {
+	seg               *compress.Decompressor // value: rlp(types.ReceiptsForStorage)
+	idxReceiptsNumber *recsplit.Index        // block_num_u64     -> receipts_segment_offset
+	ranges            Range
+}
+
+func (sn *ReceiptSegment) closeSeg() {
+	if sn.seg != nil {
+		sn.seg.Close()
+		sn.seg = nil
+	}
+}
+func (sn *ReceiptSegment) closeIdx() {
+	if sn.idxReceiptsNumber != nil {
+		sn.idxReceiptsNumber.Close()
+		sn.idxReceiptsNumber = nil
+	}
+}
+func (sn *ReceiptSegment) close() {
+	sn.closeSeg()
+	sn.closeIdx()
+}
+func (sn *ReceiptSegment) reopenSeg(dir string) (err error) {
+	sn.closeSeg()
+	fileName := receiptsSegmentFileName(sn.ranges.from, sn.ranges.to)
+	sn.seg, err = compress.NewDecompressor(path.Join(dir, fileName))
+	if err != nil {
+		return fmt.Errorf("%w, fileName: %s", err, fileName)
+	}
+	return nil
+}
+func (sn *ReceiptSegment) reopenIdxIfNeed(dir string, optimistic bool) (err error) {
+	if sn.idxReceiptsNumber != nil {
+		return nil
+	}
+	err = sn.reopenIdx(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			if optimistic {
+				log.Warn("[snapshots] open index", "err", err)
+			} else {
+				return err
+			}
+		}
+	}
+	return nil
+}
+func (sn *ReceiptSegment) reopenIdx(dir string) (err error) {
+	sn.closeIdx()
+	if sn.seg == nil {
+		return nil
+	}
+	fileName := receiptsIdxFileName(sn.ranges.from, sn.ranges.to)
+	sn.idxReceiptsNumber, err = recsplit.OpenIndex(path.Join(dir, fileName))
+	if err != nil {
+		return fmt.Errorf("%w, fileName: %s", err, fileName)
+	}
+	if sn.idxReceiptsNumber.ModTime().Before(sn.seg.ModTime()) {
+		// Index has been created before the segment file, needs to be ignored (and rebuilt) as inconsistent
+		sn.idxReceiptsNumber.Close()
+		sn.idxReceiptsNumber = nil
+	}
+	return nil
+}
+
+type receiptSegments struct {
+	lock     sync.RWMutex
+	segments []*ReceiptSegment
+}
+
+func (s *receiptSegments) View(f func([]*ReceiptSegment) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return f(s.segments)
+}
+func (s *receiptSegments) ViewSegment(blockNum uint64, f func(*ReceiptSegment) error) (found bool, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, seg := range s.segments {
+		if !(blockNum >= seg.ranges.from && blockNum < seg.ranges.to) {
+			continue
+		}
+		if seg.idxReceiptsNumber == nil {
+			return false, nil
+		}
+		return true, f(seg)
+	}
+	return false, nil
+}
+
+// reopenReceipts opens the receipts segments of the snapshots folder, and closes the ones which
+// are not in it anymore.
+func (s *RoSnapshots) reopenReceipts(optimistic bool) error {
+	ranges, err := receiptsSegmentRanges(s.dir)
+	if err != nil {
+		return err
+	}
+
+	s.Receipts.lock.Lock()
+	defer s.Receipts.lock.Unlock()
+	opened := make(map[Range]*ReceiptSegment, len(s.Receipts.segments))
+	for _, sn := range s.Receipts.segments {
+		opened[sn.ranges] = sn
+	}
+	segments := make([]*ReceiptSegment, 0, len(ranges))
+	for _, r := range ranges {
+		if sn, ok := opened[r]; ok {
+			if err := sn.reopenIdxIfNeed(s.dir, optimistic); err != nil {
+				return err
+			}
+			delete(opened, r)
+			segments = append(segments, sn)
+			continue
+		}
+		sn := &ReceiptSegment{ranges: r}
+		if err := sn.reopenSeg(s.dir); err != nil {
+			if optimistic {
+				log.Warn("[snapshots] open segment", "err", err)
+				continue
+			}
+			return err
+		}
+		if err := sn.reopenIdxIfNeed(s.dir, optimistic); err != nil {
+			sn.close()
+			return err
+		}
+		segments = append(segments, sn)
+	}
+	for _, sn := range opened {
+		sn.close()
+	}
+	s.Receipts.segments = segments
+	return nil
+}
+
+func (s *RoSnapshots) closeReceipts() {
+	s.Receipts.lock.Lock()
+	defer s.Receipts.lock.Unlock()
+	for _, sn := range s.Receipts.segments {
+		sn.close()
+	}
+	s.Receipts.segments = nil
+}
+
+// ReceiptsRanges returns the ranges of the open receipts segments.
+func (s *RoSnapshots) ReceiptsRanges() (ranges []Range) {
+	_ = s.Receipts.View(func(segments []*ReceiptSegment) error {
+		for _, sn := range segments {
+			ranges = append(ranges, sn.ranges)
+		}
+		return nil
+	})
+	return ranges
+}
+
+// ReceiptsTo returns the block following the last receipts segment, 0 without receipts segments.
+func (s *RoSnapshots) ReceiptsTo() (blockTo uint64) {
+	_ = s.Receipts.View(func(segments []*ReceiptSegment) error {
+		if len(segments) > 0 {
+			blockTo = segments[len(segments)-1].ranges.to
+		}
+		return nil
+	})
+	return blockTo
+}
+
+func (s *RoSnapshots) ViewReceipts(blockNum uint64, f func(sn *ReceiptSegment) error) (found bool, err error) {
+	return s.Receipts.ViewSegment(blockNum, f)
+}
+
+// receiptsFilesByRange returns the receipts segments from..to, false when they don't cover it.
+func (s *RoSnapshots) receiptsFilesByRange(from, to uint64) (toMerge []string, ok bool) {
+	next := from
+	_ = s.Receipts.View(func(segments []*ReceiptSegment) error {
+		for _, sn := range segments {
+			if sn.ranges.from < from {
+				continue
+			}
+			if sn.ranges.to > to || sn.ranges.from != next {
+				break
+			}
+			toMerge = append(toMerge, sn.seg.FilePath())
+			next = sn.ranges.to
+		}
+		return nil
+	})
+	return toMerge, next == to
+}
+
+// DumpReceipts writes the receipts of the blocks from blockFrom to blockTo in the segment, one
+// word of rlp(types.ReceiptsForStorage) by block. All the receipts must be in the db.
+func DumpReceipts(ctx context.Context, db kv.RoDB, segmentFilePath, tmpDir string, blockFrom, blockTo uint64, workers int, lvl log.Lvl) error {
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	f, err := compress.NewCompressor(ctx, "Snapshot Receipts", segmentFilePath, tmpDir, compress.MinPatternScore, workers, log.LvlTrace)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := bytes.NewBuffer(nil)
+	expected := blockFrom
+	from := hexutility.EncodeTs(blockFrom)
+	if err := kv.BigChunks(db, kv.Receipts, from, func(tx kv.Tx, k, v []byte) (bool, error) {
+		blockNum := binary.BigEndian.Uint64(k)
+		if blockNum >= blockTo {
+			return false, nil
+		}
+		if blockNum != expected {
+			return false, fmt.Errorf("receipts of block %d not found", expected)
+		}
+		expected++
+
+		receipts := rawdb.ReadRawReceipts(tx, blockNum)
+		stored := make(types.ReceiptsForStorage, len(receipts))
+		for i, receipt := range receipts {
+			stored[i] = (*types.ReceiptForStorage)(receipt)
+		}
+		buf.Reset()
+		if err := rlp.Encode(buf, stored); err != nil {
+			return false, err
+		}
+		if err := f.AddWord(buf.Bytes()); err != nil {
+			return false, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-logEvery.C:
+			var m runtime.MemStats
+			if lvl >= log.LvlInfo {
+				dbg.ReadMemStats(&m)
+			}
+			log.Log(lvl, "[snapshots] Wrote into file", "block num", blockNum,
+				"alloc", common2.ByteCount(m.Alloc), "sys", common2.ByteCount(m.Sys),
+			)
+		default:
+		}
+		return true, nil
+	}); err != nil {
+		return err
+	}
+	if expected != blockTo {
+		return fmt.Errorf("receipts of block %d not found", expected)
+	}
+	if err := f.Compress(); err != nil {
+		return fmt.Errorf("compress: %w", err)
+	}
+	return nil
+}
+
+func ReceiptsIdx(ctx context.Context, segmentFilePath string, firstBlockNumInSegment uint64, tmpDir string, p *background.Progress, lvl log.Lvl) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			_, fName := filepath.Split(segmentFilePath)
+			err = fmt.Errorf("ReceiptsIdx: at=%s, %v, %s", fName, rec, dbg.Stack())
+		}
+	}()
+
+	num := make([]byte, 8)
+
+	d, err := compress.NewDecompressor(segmentFilePath)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	_, fname := filepath.Split(segmentFilePath)
+	p.Name.Store(fname)
+	p.Total.Store(uint64(d.Count()))
+
+	if err := Idx(ctx, d, firstBlockNumInSegment, tmpDir, log.LvlDebug, func(idx *recsplit.RecSplit, i, offset uint64, word []byte) error {
+		p.Processed.Inc()
+		n := binary.PutUvarint(num, i)
+		if err := idx.AddKey(num[:n], offset); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("ReceiptsNumberIdx: %w", err)
+	}
+	return nil
+}
+
+func dumpReceiptsRange(ctx context.Context, blockFrom, blockTo uint64, tmpDir, snapDir string, chainDB kv.RoDB, workers int, lvl log.Lvl) error {
+	segPath := filepath.Join(snapDir, receiptsSegmentFileName(blockFrom, blockTo))
+	if err := DumpReceipts(ctx, chainDB, segPath, tmpDir, blockFrom, blockTo, workers, lvl); err != nil {
+		return fmt.Errorf("DumpReceipts: %w", err)
+	}
+	p := &background.Progress{}
+	return ReceiptsIdx(ctx, segPath, blockFrom, tmpDir, p, lvl)
+}
+
+// buildMissedReceiptsIndices builds the indices of the receipts segments which don't have one.
+func buildMissedReceiptsIndices(ctx context.Context, snapDir, tmpDir string, lvl log.Lvl) error {
+	ranges, err := receiptsSegmentRanges(snapDir)
+	if err != nil {
+		return err
+	}
+	for _, r := range ranges {
+		_, err := os.Stat(filepath.Join(snapDir, receiptsIdxFileName(r.from, r.to)))
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		p := &background.Progress{}
+		segPath := filepath.Join(snapDir, receiptsSegmentFileName(r.from, r.to))
+		if err := ReceiptsIdx(ctx, segPath, r.from, tmpDir, p, lvl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retireReceipts dumps the receipts of the executed blocks retired to the snapshots, from the end of
+// the receipts segments (or the first receipts in the db when there are no segments yet), and merges
+// them. It fails when the db has already pruned receipts after the end of the segments: they must be
+// executed again, retiring the receipts after them would leave a gap in the segments.
+func retireReceipts(ctx context.Context, tmpDir string, snapshots *RoSnapshots, db kv.RoDB, workers int, lvl log.Lvl, merger *Merger) error {
+	if err := buildMissedReceiptsIndices(ctx, snapshots.Dir(), tmpDir, lvl); err != nil {
+		return err
+	}
+	if err := snapshots.reopenReceipts(false); err != nil {
+		return fmt.Errorf("reopen: %w", err)
+	}
+
+	var dbFrom, executed uint64
+	var inDB bool
+	if err := db.View(ctx, func(tx kv.Tx) (err error) {
+		if executed, err = stages.GetStageProgress(tx, stages.Execution); err != nil {
+			return err
+		}
+		c, err := tx.Cursor(kv.Receipts)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		k, _, err := c.First()
+		if err != nil {
+			return err
+		}
+		if k != nil {
+			dbFrom, inDB = binary.BigEndian.Uint64(k), true
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	blockFrom := snapshots.ReceiptsTo()
+	if blockFrom == 0 {
+		blockFrom = dbFrom
+	}
+	blockFrom = (blockFrom + 999) / 1_000 * 1_000
+	blockTo := cmp.Min(snapshots.BlocksAvailable(), executed) + 1
+	blockTo = blockTo / 1_000 * 1_000
+	if blockTo <= blockFrom {
+		return nil
+	}
+	if !inDB {
+		return fmt.Errorf("receipts of blocks %d-%d pruned from the db before they were retired", blockFrom, blockTo)
+	}
+	if dbFrom > blockFrom {
+		return fmt.Errorf("receipts of blocks %d-%d pruned from the db before they were retired", blockFrom, dbFrom)
+	}
+
+	log.Log(lvl, "[snapshots] Retire Receipts", "range", fmt.Sprintf("%dk-%dk", blockFrom/1000, blockTo/1000))
+	for i := blockFrom; i < blockTo; i = chooseSegmentEnd(i, blockTo, snaptype.Erigon2SegmentSize) {
+		if err := dumpReceiptsRange(ctx, i, chooseSegmentEnd(i, blockTo, snaptype.Erigon2SegmentSize), tmpDir, snapshots.Dir(), db, workers, lvl); err != nil {
+			return err
+		}
+	}
+	if err := snapshots.reopenReceipts(false); err != nil {
+		return fmt.Errorf("reopen: %w", err)
+	}
+	return merger.MergeReceipts(ctx, snapshots, merger.FindMergeRanges(snapshots.ReceiptsRanges()), snapshots.Dir())
+}
+
+// MergeReceipts does merge receipts segments in given ranges, skipping the ranges with gaps
+func (m *Merger) MergeReceipts(ctx context.Context, snapshots *RoSnapshots, mergeRanges []Range, snapDir string) error {
+	logEvery := time.NewTicker(30 * time.Second)
+	defer logEvery.Stop()
+	for _, r := range mergeRanges {
+		toMerge, ok := snapshots.receiptsFilesByRange(r.from, r.to)
+		if !ok {
+			continue
+		}
+		log.Log(m.lvl, "[snapshots] Merge receipts segments", "range", r.String())
+		segPath := filepath.Join(snapDir, receiptsSegmentFileName(r.from, r.to))
+		if err := m.merge(ctx, toMerge, segPath, logEvery); err != nil {
+			return fmt.Errorf("mergeByAppendSegments: %w", err)
+		}
+		p := &background.Progress{}
+		if err := ReceiptsIdx(ctx, segPath, r.from, m.tmpDir, p, m.lvl); err != nil {
+			return err
+		}
+		if err := snapshots.reopenReceipts(false); err != nil {
+			return fmt.Errorf("reopen: %w", err)
+		}
+		if m.notifier != nil { // notify about new snapshots of any size
+			m.notifier.OnNewSnapshot()
+			time.Sleep(1 * time.Second) // i working on blocking API - to ensure client does not use old snapsthos - and then delete them
+		}
+		m.removeOldFiles(toMerge, snapDir)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package snapshotsync
+
+import (
+	"context"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/background"
+	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+func createTestReceiptsSegmentFile(t *testing.T, from, to uint64, dir string) {
+	segPath := filepath.Join(dir, receiptsSegmentFileName(from, to))
+	c, err := compress.NewCompressor(context.Background(), "test", segPath, dir, 100, 1, log.LvlDebug)
+	require.NoError(t, err)
+	defer c.Close()
+	for blockNum := from; blockNum < to; blockNum++ {
+		receipts := types.ReceiptsForStorage{{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: blockNum,
+			Logs:              []*types.Log{{Address: libcommon.Address{1}, Data: []byte{2}}},
+		}}
+		word, err := rlp.EncodeToBytes(receipts)
+		require.NoError(t, err)
+		require.NoError(t, c.AddWord(word))
+	}
+	require.NoError(t, c.Compress())
+	require.NoError(t, ReceiptsIdx(context.Background(), segPath, from, dir, &background.Progress{}, log.LvlDebug))
+}
+
+func TestReceiptsSnapshots(t *testing.T) {
+	dir, require := t.TempDir(), require.New(t)
+	ctx := context.Background()
+	createTestReceiptsSegmentFile(t, 0, 1_000, dir)
+	createTestReceiptsSegmentFile(t, 1_000, 2_000, dir)
+
+	s := NewRoSnapshots(ethconfig.Snapshot{Enabled: true}, dir)
+	defer s.Close()
+	require.NoError(s.ReopenFolder())
+	require.Equal([]Range{{0, 1_000}, {1_000, 2_000}}, s.ReceiptsRanges())
+	require.Equal(uint64(2_000), s.ReceiptsTo())
+
+	reader := NewBlockReaderWithSnapshots(s)
+	receipts, err := reader.RawReceipts(ctx, 1_500)
+	require.NoError(err)
+	require.Len(receipts, 1)
+	require.Equal(uint64(1_500), receipts[0].CumulativeGasUsed)
+	require.Equal(types.ReceiptStatusSuccessful, receipts[0].Status)
+	require.Len(receipts[0].Logs, 1)
+	require.Equal(libcommon.Address{1}, receipts[0].Logs[0].Address)
+
+	receipts, err = reader.RawReceipts(ctx, 2_000)
+	require.NoError(err)
+	require.Nil(receipts)
+
+	merger := NewMerger(dir, 1, log.LvlInfo, uint256.Int{}, nil)
+	// the ranges with gaps are not merged
+	require.NoError(merger.MergeReceipts(ctx, s, []Range{{0, 10_000}}, dir))
+	require.Equal([]Range{{0, 1_000}, {1_000, 2_000}}, s.ReceiptsRanges())
+
+	require.NoError(merger.MergeReceipts(ctx, s, []Range{{0, 2_000}}, dir))
+	require.Equal([]Range{{0, 2_000}}, s.ReceiptsRanges())
+	receipts, err = reader.RawReceipts(ctx, 1_500)
+	require.NoError(err)
+	require.Len(receipts, 1)
+	require.Equal(uint64(1_500), receipts[0].CumulativeGasUsed)
+}
+
+func TestRetireReceiptsPrunedGap(t *testing.T) {
+	dir, require := t.TempDir(), require.New(t)
+	ctx := context.Background()
+	createTestBlocksSegments(t, 0, 1_000, dir)
+	createTestBlocksSegments(t, 1_000, 2_000, dir)
+	createTestReceiptsSegmentFile(t, 0, 1_000, dir)
+
+	s := NewRoSnapshots(ethconfig.Snapshot{Enabled: true, Receipts: true}, dir)
+	defer s.Close()
+	require.NoError(s.ReopenFolder())
+
+	// The db pruned the receipts of the blocks 1_000-1_500 before they were retired
+	db := memdb.NewTestDB(t)
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		if err := stages.SaveStageProgress(tx, stages.Execution, 1_999); err != nil {
+			return err
+		}
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, 1_500)
+		return tx.Put(kv.Receipts, k, []byte{0xc0})
+	}))
+	merger := NewMerger(dir, 1, log.LvlInfo, uint256.Int{}, nil)
+	err := retireReceipts(ctx, dir, s, db, 1, log.LvlDebug, merger)
+	require.ErrorContains(err, "1000-1500")
+	require.Equal(uint64(1_000), s.ReceiptsTo())
+}
+
+func TestParseReceiptsFileName(t *testing.T) {
+	require := require.New(t)
+	from, to, ok := parseReceiptsFileName("v1-001000-002000-receipts.seg")
+	require.True(ok)
+	require.Equal(uint64(1_000_000), from)
+	require.Equal(uint64(2_000_000), to)
+
+	for _, name := range []string{"v1-001000-002000-receipts.idx", "v1-001000-002000-bodies.seg", "v1-002000-001000-receipts.seg", "receipts.seg"} {
+		_, _, ok = parseReceiptsFileName(name)
+		require.False(ok, name)
+	}
+}
@@ -2,9 +2,13 @@ package snapcfg
 
 import (
 	_ "embed"
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	snapshothashes "github.com/ledgerwatch/erigon-snapshot"
 	"github.com/ledgerwatch/erigon/params/networkname"
@@ -40,11 +44,18 @@ type Preverified []PreverifiedItem
 type preverified map[string]string
 
 func fromToml(in []byte) (out Preverified) {
+	out, err := parseToml(in)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+func parseToml(in []byte) (Preverified, error) {
 	var outMap preverified
 	if err := toml.Unmarshal(in, &outMap); err != nil {
-		panic(err)
+		return nil, err
 	}
-	return doSort(outMap)
+	return doSort(outMap), nil
 }
 func doSort(in preverified) Preverified {
 	out := make(Preverified, 0, len(in))
@@ -106,6 +117,9 @@ type Cfg struct {
 	PreverifiedHistory Preverified
 }
 
+// knownCfgsLock guards KnownCfgs, to which LoadLocalCfg adds the custom networks.
+var knownCfgsLock sync.RWMutex
+
 var KnownCfgs = map[string]*Cfg{
 	networkname.MainnetChainName:    MainnetChainSnapshotCfg,
 	networkname.SepoliaChainName:    SepoliaChainSnapshotCfg,
@@ -119,7 +133,9 @@ var KnownCfgs = map[string]*Cfg{
 
 // KnownCfg return list of preverified hashes for given network, but apply whiteList filter if it's not empty
 func KnownCfg(networkName string, whiteList, whiteListHistory []string) *Cfg {
+	knownCfgsLock.RLock()
 	c, ok := KnownCfgs[networkName]
+	knownCfgsLock.RUnlock()
 	if !ok {
 		return newCfg(Preverified{}, Preverified{})
 	}
@@ -161,3 +177,62 @@ func KnownCfg(networkName string, whiteList, whiteListHistory []string) *Cfg {
 
 	return newCfg(result, result2)
 }
+
+// LoadLocalCfg registers the preverified snapshots of a custom network, published in a local
+// TOML file of names and info-hashes, or in a directory laid out as the embedded lists: the
+// <networkName>.toml of the segments and the optional history/<networkName>.toml.
+func LoadLocalCfg(networkName, path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	var preverifiedFile, historyFile string
+	if stat.IsDir() {
+		preverifiedFile = filepath.Join(path, networkName+".toml")
+		historyFile = filepath.Join(path, "history", networkName+".toml")
+	} else {
+		preverifiedFile = path
+	}
+
+	preverified, err := readTomlFile(preverifiedFile)
+	if err != nil {
+		return err
+	}
+	for _, p := range preverified {
+		if filepath.Ext(p.Name) != ".seg" {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(p.Name, ".seg"), "-")
+		if len(parts) != 4 || parts[0] != "v1" {
+			return fmt.Errorf("invalid preverified snapshot %s in %s", p.Name, preverifiedFile)
+		}
+		if _, err := strconv.ParseUint(parts[2], 10, 64); err != nil {
+			return fmt.Errorf("invalid preverified snapshot %s in %s: %w", p.Name, preverifiedFile, err)
+		}
+	}
+	preverifiedHistory := Preverified{}
+	if historyFile != "" {
+		if preverifiedHistory, err = readTomlFile(historyFile); errors.Is(err, os.ErrNotExist) {
+			preverifiedHistory = Preverified{}
+		} else if err != nil {
+			return err
+		}
+	}
+
+	knownCfgsLock.Lock()
+	defer knownCfgsLock.Unlock()
+	KnownCfgs[networkName] = newCfg(preverified, preverifiedHistory)
+	return nil
+}
+
+func readTomlFile(path string) (Preverified, error) {
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	out, err := parseToml(in)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return out, nil
+}
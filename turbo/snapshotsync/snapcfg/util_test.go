@@ -0,0 +1,38 @@
+package snapcfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLocalCfg(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+	preverified := []byte(`'v1-000000-000500-bodies.seg' = 'f52a90e9e7dd8a625a91f267a01f1ed3c9341c35'
+'v1-000000-000500-headers.seg' = '35347e40209c8c4ab51ee2a028912e4c8b212e34'
+'v1-000500-001000-headers.seg' = '51083fe00f949d025259ed59b05e372c5ce62811'
+`)
+	require.NoError(os.WriteFile(filepath.Join(dir, "private.toml"), preverified, 0644))
+
+	// from a directory
+	require.NoError(LoadLocalCfg("private", dir))
+	defer delete(KnownCfgs, "private")
+	cfg := KnownCfg("private", nil, nil)
+	require.Len(cfg.Preverified, 3)
+	require.Empty(cfg.PreverifiedHistory)
+	require.Equal(uint64(999_999), cfg.ExpectBlocks)
+	require.Equal("v1-000000-000500-bodies.seg", cfg.Preverified[0].Name)
+
+	// from a file
+	require.NoError(LoadLocalCfg("private-file", filepath.Join(dir, "private.toml")))
+	defer delete(KnownCfgs, "private-file")
+	require.Len(KnownCfg("private-file", nil, nil).Preverified, 3)
+
+	// invalid names are rejected
+	require.NoError(os.WriteFile(filepath.Join(dir, "invalid.toml"), []byte(`'headers.seg' = 'aa'`), 0644))
+	require.Error(LoadLocalCfg("invalid", dir))
+	require.Error(LoadLocalCfg("missing", dir))
+}
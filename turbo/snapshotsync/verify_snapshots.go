@@ -0,0 +1,572 @@
+package snapshotsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/background"
+	"github.com/ledgerwatch/erigon-lib/downloader/downloadercfg"
+	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
+	"github.com/ledgerwatch/erigon-lib/recsplit"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapcfg"
+)
+
+// QuarantineDir is the folder of the snapshots dir where Quarantine moves the corrupted segments.
+const QuarantineDir = "quarantine"
+
+var (
+	corruptedSegments = metrics.GetOrCreateCounter(`snapshots_corrupted{type="segment"}`)
+	corruptedIndices  = metrics.GetOrCreateCounter(`snapshots_corrupted{type="index"}`)
+)
+
+// SnapshotProblem is a corrupted file of the snapshots. A corrupted segment must be downloaded or
+// dumped again, a corrupted index is rebuilt from its segment.
+type SnapshotProblem struct {
+	Segment string // file name of the segment
+	Index   string // file name of the corrupted index of the segment, empty when the segment is corrupted
+	Err     error
+}
+
+func (p SnapshotProblem) File() string {
+	if p.Index != "" {
+		return p.Index
+	}
+	return p.Segment
+}
+
+func (p SnapshotProblem) String() string { return fmt.Sprintf("%s: %v", p.File(), p.Err) }
+
+// segmentInfoHash returns the info-hash of the torrent of a segment, the way the downloader builds it.
+func segmentInfoHash(segPath string) (string, error) {
+	info := &metainfo.Info{PieceLength: downloadercfg.DefaultPieceSize}
+	if err := info.BuildFromFilePath(segPath); err != nil {
+		return "", err
+	}
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+	return metainfo.HashBytes(infoBytes).HexString(), nil
+}
+
+// VerifyInfoHashes re-hashes the preverified segments of the snapshots dir, and reports the ones whose
+// info-hash is not the preverified one. The segments which are not in the dir are skipped.
+func VerifyInfoHashes(ctx context.Context, snapDir string, preverified snapcfg.Preverified) ([]SnapshotProblem, error) {
+	var problems []SnapshotProblem
+	for _, p := range preverified {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		segPath := filepath.Join(snapDir, p.Name)
+		if _, err := os.Stat(segPath); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		hash, err := segmentInfoHash(segPath)
+		if err != nil {
+			problems = append(problems, SnapshotProblem{Segment: p.Name, Err: err})
+			continue
+		}
+		if hash != p.Hash {
+			problems = append(problems, SnapshotProblem{Segment: p.Name, Err: fmt.Errorf("info-hash %s, preverified %s", hash, p.Hash)})
+		}
+	}
+	return problems, nil
+}
+
+// VerifySegments cross-checks the open segments of blocks with their content and indices: the hashes
+// and numbers of the headers, the transaction ids of the bodies, and the hashes and counts of the
+// transactions of the bodies. It reports the corrupted segments and indices. The segments are
+// verified on copies of them opened apart, so that the snapshots are not locked while they are read.
+func VerifySegments(ctx context.Context, s *RoSnapshots, chainID uint256.Int) ([]SnapshotProblem, error) {
+	headerRanges, bodyRanges, txRanges := s.openRanges()
+	var problems []SnapshotProblem
+	report := func(p *SnapshotProblem, err error) error {
+		if p != nil {
+			problems = append(problems, *p)
+		}
+		return err
+	}
+	for _, r := range headerRanges {
+		sn := &HeaderSegment{ranges: r}
+		ok, p, err := openApart(s.dir, snaptype.Headers, r, sn.reopenSeg, sn.reopenIdxIfNeed)
+		if ok && p == nil && err == nil {
+			p, err = verifyHeaders(ctx, s.dir, sn)
+		}
+		sn.close()
+		if err := report(p, err); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range bodyRanges {
+		if err := report(verifyBodiesAndTxs(ctx, s.dir, chainID, r, txRanges[r])); err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
+
+// verifyBodiesAndTxs verifies the segment of bodies of r, and its segment of transactions with it.
+func verifyBodiesAndTxs(ctx context.Context, dir string, chainID uint256.Int, r Range, withTxs bool) (*SnapshotProblem, error) {
+	bodies := &BodySegment{ranges: r}
+	defer bodies.close()
+	if ok, p, err := openApart(dir, snaptype.Bodies, r, bodies.reopenSeg, bodies.reopenIdxIfNeed); !ok || p != nil || err != nil {
+		return p, err
+	}
+	if p, err := verifyBodies(ctx, dir, bodies); p != nil || err != nil || !withTxs {
+		return p, err
+	}
+	sn := &TxnSegment{ranges: r}
+	defer sn.close()
+	if ok, p, err := openApart(dir, snaptype.Transactions, r, sn.reopenSeg, sn.reopenIdxIfNeed); !ok || p != nil || err != nil {
+		return p, err
+	}
+	return verifyTxs(ctx, dir, chainID, sn, bodies)
+}
+
+// openRanges returns the ranges of the open segments of each type.
+func (s *RoSnapshots) openRanges() (headers, bodies []Range, txs map[Range]bool) {
+	_ = s.Headers.View(func(segments []*HeaderSegment) error {
+		for _, sn := range segments {
+			if sn.seg != nil {
+				headers = append(headers, sn.ranges)
+			}
+		}
+		return nil
+	})
+	_ = s.Bodies.View(func(segments []*BodySegment) error {
+		for _, sn := range segments {
+			if sn.seg != nil {
+				bodies = append(bodies, sn.ranges)
+			}
+		}
+		return nil
+	})
+	txs = map[Range]bool{}
+	_ = s.Txs.View(func(segments []*TxnSegment) error {
+		for _, sn := range segments {
+			if sn.Seg != nil {
+				txs[sn.ranges] = true
+			}
+		}
+		return nil
+	})
+	return headers, bodies, txs
+}
+
+// openApart opens a segment of r and its indices with reopenSeg and reopenIdxIfNeed, apart from the
+// open snapshots. It returns false when the segment is gone since, merged or removed.
+func openApart(dir string, t snaptype.Type, r Range, reopenSeg func(dir string) error, reopenIdxIfNeed func(dir string, optimistic bool) error) (bool, *SnapshotProblem, error) {
+	segName := snaptype.SegmentFileName(r.from, r.to, t)
+	if _, err := os.Stat(filepath.Join(dir, segName)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	if err := reopenSeg(dir); err != nil {
+		return true, &SnapshotProblem{Segment: segName, Err: err}, nil
+	}
+	if err := reopenIdxIfNeed(dir, false); err != nil {
+		return true, &SnapshotProblem{Segment: segName, Index: snaptype.IdxFileName(r.from, r.to, t.String()), Err: err}, nil
+	}
+	return true, nil, nil
+}
+
+// indexLookup runs a lookup in an index, with an error instead of the panic of a corrupted index.
+func indexLookup(lookup func() uint64) (v uint64, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("lookup: %v", rec)
+		}
+	}()
+	return lookup(), nil
+}
+
+// unopenedIndex reports the index of a segment which is in the dir but was not opened: it can't be
+// opened or is older than its segment. The indices which are not built yet are not reported.
+func unopenedIndex(dir, segName, idxName string) (*SnapshotProblem, error) {
+	if _, err := os.Stat(filepath.Join(dir, idxName)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &SnapshotProblem{Segment: segName, Index: idxName, Err: errors.New("can't be opened or older than the segment")}, nil
+}
+
+func verifyHeaders(ctx context.Context, dir string, sn *HeaderSegment) (problem *SnapshotProblem, err error) {
+	segName := snaptype.SegmentFileName(sn.ranges.from, sn.ranges.to, snaptype.Headers)
+	idxName := snaptype.IdxFileName(sn.ranges.from, sn.ranges.to, snaptype.Headers.String())
+	defer func() {
+		if rec := recover(); rec != nil {
+			problem = &SnapshotProblem{Segment: segName, Err: fmt.Errorf("%v", rec)}
+		}
+	}()
+	if expected := sn.ranges.to - sn.ranges.from; uint64(sn.seg.Count()) != expected {
+		return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("%d headers, expected %d", sn.seg.Count(), expected)}, nil
+	}
+	if sn.idxHeaderHash == nil {
+		return unopenedIndex(dir, segName, idxName)
+	}
+	defer sn.seg.EnableMadvNormal().DisableReadAhead()
+
+	reader := recsplit.NewIndexReader(sn.idxHeaderHash)
+	g := sn.seg.MakeGetter()
+	var word []byte
+	var offset, nextPos uint64
+	for blockNum := sn.ranges.from; g.HasNext(); blockNum++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		word, nextPos = g.Next(word[:0])
+		if len(word) < 2 {
+			return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("header %d: empty", blockNum)}, nil
+		}
+		hash := crypto.Keccak256Hash(word[1:])
+		if word[0] != hash[0] {
+			return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("header %d: hash %x, first byte %x", blockNum, hash, word[0])}, nil
+		}
+		var h types.Header
+		if err := rlp.DecodeBytes(word[1:], &h); err != nil {
+			return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("header %d: %w", blockNum, err)}, nil
+		}
+		if h.Number == nil || h.Number.Uint64() != blockNum {
+			return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("header %d: number %d", blockNum, h.Number)}, nil
+		}
+		indexed, err := indexLookup(func() uint64 { return sn.idxHeaderHash.OrdinalLookup(reader.Lookup(hash[:])) })
+		if err != nil {
+			return &SnapshotProblem{Segment: segName, Index: idxName, Err: fmt.Errorf("header %d: %w", blockNum, err)}, nil
+		}
+		if indexed != offset {
+			return &SnapshotProblem{Segment: segName, Index: idxName, Err: fmt.Errorf("header %d: offset %d, indexed %d", blockNum, offset, indexed)}, nil
+		}
+		offset = nextPos
+	}
+	return nil, nil
+}
+
+func verifyBodies(ctx context.Context, dir string, sn *BodySegment) (problem *SnapshotProblem, err error) {
+	segName := snaptype.SegmentFileName(sn.ranges.from, sn.ranges.to, snaptype.Bodies)
+	idxName := snaptype.IdxFileName(sn.ranges.from, sn.ranges.to, snaptype.Bodies.String())
+	defer func() {
+		if rec := recover(); rec != nil {
+			problem = &SnapshotProblem{Segment: segName, Err: fmt.Errorf("%v", rec)}
+		}
+	}()
+	if expected := sn.ranges.to - sn.ranges.from; uint64(sn.seg.Count()) != expected {
+		return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("%d bodies, expected %d", sn.seg.Count(), expected)}, nil
+	}
+	if sn.idxBodyNumber == nil {
+		return unopenedIndex(dir, segName, idxName)
+	}
+	if sn.idxBodyNumber.BaseDataID() != sn.ranges.from {
+		return &SnapshotProblem{Segment: segName, Index: idxName, Err: fmt.Errorf("first block %d, expected %d", sn.idxBodyNumber.BaseDataID(), sn.ranges.from)}, nil
+	}
+	defer sn.seg.EnableMadvNormal().DisableReadAhead()
+
+	g := sn.seg.MakeGetter()
+	var word []byte
+	var offset, nextPos, nextTxID uint64
+	var b types.BodyForStorage
+	for i := uint64(0); g.HasNext(); i++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		blockNum := sn.ranges.from + i
+		word, nextPos = g.Next(word[:0])
+		if err := rlp.DecodeBytes(word, &b); err != nil {
+			return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("body %d: %w", blockNum, err)}, nil
+		}
+		if i > 0 && b.BaseTxId != nextTxID {
+			return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("body %d: first transaction %d, expected %d", blockNum, b.BaseTxId, nextTxID)}, nil
+		}
+		nextTxID = b.BaseTxId + uint64(b.TxAmount)
+		ordinal := i
+		indexed, err := indexLookup(func() uint64 { return sn.idxBodyNumber.OrdinalLookup(ordinal) })
+		if err != nil {
+			return &SnapshotProblem{Segment: segName, Index: idxName, Err: fmt.Errorf("body %d: %w", blockNum, err)}, nil
+		}
+		if indexed != offset {
+			return &SnapshotProblem{Segment: segName, Index: idxName, Err: fmt.Errorf("body %d: offset %d, indexed %d", blockNum, offset, indexed)}, nil
+		}
+		offset = nextPos
+	}
+	return nil, nil
+}
+
+func verifyTxs(ctx context.Context, dir string, chainID uint256.Int, sn *TxnSegment, bodies *BodySegment) (problem *SnapshotProblem, err error) {
+	segName := snaptype.SegmentFileName(sn.ranges.from, sn.ranges.to, snaptype.Transactions)
+	idxName := snaptype.IdxFileName(sn.ranges.from, sn.ranges.to, snaptype.Transactions.String())
+	idx2BlockName := snaptype.IdxFileName(sn.ranges.from, sn.ranges.to, snaptype.Transactions2Block.String())
+	bodiesName := snaptype.SegmentFileName(sn.ranges.from, sn.ranges.to, snaptype.Bodies)
+	defer func() {
+		if rec := recover(); rec != nil {
+			problem = &SnapshotProblem{Segment: segName, Err: fmt.Errorf("%v", rec)}
+		}
+	}()
+
+	type blockTxs struct{ blockNum, baseTxNum, txAmount uint64 }
+	var blocks []blockTxs
+	if err := bodies.Iterate(func(blockNum, baseTxNum, txAmount uint64) error {
+		blocks = append(blocks, blockTxs{blockNum, baseTxNum, txAmount})
+		return nil
+	}); err != nil {
+		return &SnapshotProblem{Segment: bodiesName, Err: err}, nil
+	}
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	firstTxID, last := blocks[0].baseTxNum, blocks[len(blocks)-1]
+	if expected := last.baseTxNum + last.txAmount - firstTxID; uint64(sn.Seg.Count()) != expected {
+		return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("%d transactions, bodies expect %d", sn.Seg.Count(), expected)}, nil
+	}
+	if sn.IdxTxnHash == nil {
+		return unopenedIndex(dir, segName, idxName)
+	}
+	if sn.IdxTxnHash2BlockNum == nil {
+		return unopenedIndex(dir, segName, idx2BlockName)
+	}
+	if sn.IdxTxnHash.BaseDataID() != firstTxID {
+		return &SnapshotProblem{Segment: segName, Index: idxName, Err: fmt.Errorf("first transaction %d, expected %d", sn.IdxTxnHash.BaseDataID(), firstTxID)}, nil
+	}
+	defer sn.Seg.EnableMadvNormal().DisableReadAhead()
+
+	parseCtx := types2.NewTxParseContext(chainID)
+	parseCtx.WithSender(false)
+	slot := types2.TxSlot{}
+	txnReader, blockReader := recsplit.NewIndexReader(sn.IdxTxnHash), recsplit.NewIndexReader(sn.IdxTxnHash2BlockNum)
+	g := sn.Seg.MakeGetter()
+	var word []byte
+	var offset, nextPos uint64
+	var b int
+	for txnID := firstTxID; g.HasNext(); txnID++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		word, nextPos = g.Next(word[:0])
+		for b < len(blocks) && blocks[b].baseTxNum+blocks[b].txAmount <= txnID { // skip empty blocks
+			b++
+		}
+		if b == len(blocks) {
+			return &SnapshotProblem{Segment: bodiesName, Err: fmt.Errorf("no body of transaction %d", txnID)}, nil
+		}
+		blockNum := blocks[b].blockNum
+		if err := segmentTxnHash(parseCtx, &slot, word, txnID); err != nil {
+			return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("transaction %d of block %d: %w", txnID, blockNum, err)}, nil
+		}
+		if len(word) > 0 && word[0] != slot.IDHash[0] {
+			return &SnapshotProblem{Segment: segName, Err: fmt.Errorf("transaction %d of block %d: hash %x, first byte %x", txnID, blockNum, slot.IDHash, word[0])}, nil
+		}
+		indexed, err := indexLookup(func() uint64 { return sn.IdxTxnHash.OrdinalLookup(txnReader.Lookup(slot.IDHash[:])) })
+		if err != nil {
+			return &SnapshotProblem{Segment: segName, Index: idxName, Err: fmt.Errorf("transaction %d of block %d: %w", txnID, blockNum, err)}, nil
+		}
+		if indexed != offset {
+			return &SnapshotProblem{Segment: segName, Index: idxName, Err: fmt.Errorf("transaction %d of block %d: offset %d, indexed %d", txnID, blockNum, offset, indexed)}, nil
+		}
+		indexed, err = indexLookup(func() uint64 { return blockReader.Lookup(slot.IDHash[:]) })
+		if err != nil {
+			return &SnapshotProblem{Segment: segName, Index: idx2BlockName, Err: fmt.Errorf("transaction %d of block %d: %w", txnID, blockNum, err)}, nil
+		}
+		if indexed != blockNum {
+			return &SnapshotProblem{Segment: segName, Index: idx2BlockName, Err: fmt.Errorf("transaction %d of block %d: indexed block %d", txnID, blockNum, indexed)}, nil
+		}
+		offset = nextPos
+	}
+	return nil, nil
+}
+
+// segmentIdxPaths returns the paths of the indices of a segment.
+func segmentIdxPaths(segPath string) []string {
+	withoutExt := strings.TrimSuffix(segPath, filepath.Ext(segPath))
+	paths := []string{withoutExt + ".idx"}
+	if strings.HasSuffix(withoutExt, snaptype.Transactions.String()) {
+		paths = append(paths, withoutExt+"-to-block.idx")
+	}
+	return paths
+}
+
+// Quarantine moves the corrupted segments of the snapshots dir, with their indices and torrents, to
+// its QuarantineDir, and removes the corrupted indices. The removed indices are rebuilt from their
+// segments by BuildMissedIndices. The preverified segments are downloaded again on the next start,
+// the others must be dumped again.
+func Quarantine(snapDir string, problems []SnapshotProblem) error {
+	quarantineDir := filepath.Join(snapDir, QuarantineDir)
+	for _, p := range problems {
+		segPath := filepath.Join(snapDir, p.Segment)
+		if p.Index != "" {
+			for _, idxPath := range segmentIdxPaths(segPath) {
+				if err := os.Remove(idxPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+			}
+			continue
+		}
+		if err := os.MkdirAll(quarantineDir, 0755); err != nil {
+			return err
+		}
+		for _, path := range append(segmentIdxPaths(segPath), segPath, segPath+".torrent") {
+			_, fName := filepath.Split(path)
+			if err := os.Rename(path, filepath.Join(quarantineDir, fName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RebuildIndices builds the indices of a segment again, and opens them instead of the open ones.
+// The old indices are removed first: the open ones stay readable until then.
+func (s *RoSnapshots) RebuildIndices(ctx context.Context, segment string, chainID uint256.Int, tmpDir string, lvl log.Lvl) error {
+	f, err := snaptype.ParseFileName(s.dir, segment)
+	if err != nil {
+		return err
+	}
+	for _, idxPath := range segmentIdxPaths(f.Path) {
+		if err := os.Remove(idxPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := buildIdx(ctx, f, chainID, tmpDir, &background.Progress{}, lvl); err != nil {
+		return err
+	}
+	return s.reopenSegmentIdx(f.T, Range{f.From, f.To})
+}
+
+func (s *RoSnapshots) reopenSegmentIdx(t snaptype.Type, r Range) error {
+	switch t {
+	case snaptype.Headers:
+		s.Headers.lock.Lock()
+		defer s.Headers.lock.Unlock()
+		for _, sn := range s.Headers.segments {
+			if sn.ranges == r {
+				return sn.reopenIdx(s.dir)
+			}
+		}
+	case snaptype.Bodies:
+		s.Bodies.lock.Lock()
+		defer s.Bodies.lock.Unlock()
+		for _, sn := range s.Bodies.segments {
+			if sn.ranges == r {
+				return sn.reopenIdx(s.dir)
+			}
+		}
+	case snaptype.Transactions:
+		s.Txs.lock.Lock()
+		defer s.Txs.lock.Unlock()
+		for _, sn := range s.Txs.segments {
+			if sn.ranges == r {
+				return sn.reopenIdx(s.dir)
+			}
+		}
+	}
+	return nil
+}
+
+// Scrubber periodically verifies the open snapshots: the info-hashes of the preverified segments,
+// and the segments against their indices. It rebuilds the corrupted indices, the corrupted segments
+// are only reported.
+type Scrubber struct {
+	snapshots   *RoSnapshots
+	chainID     uint256.Int
+	preverified snapcfg.Preverified
+	tmpDir      string
+	every       time.Duration
+}
+
+func NewScrubber(snapshots *RoSnapshots, chainID uint256.Int, preverified snapcfg.Preverified, tmpDir string, every time.Duration) *Scrubber {
+	return &Scrubber{snapshots: snapshots, chainID: chainID, preverified: preverified, tmpDir: tmpDir, every: every}
+}
+
+func (sc *Scrubber) Run(ctx context.Context) {
+	ticker := time.NewTicker(sc.every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := sc.Scrub(ctx); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Warn("[snapshots] Scrub", "err", err)
+			}
+		}
+	}
+}
+
+// Scrub verifies the open snapshots once, and rebuilds their corrupted indices. The snapshots are
+// only locked to reopen the rebuilt indices.
+func (sc *Scrubber) Scrub(ctx context.Context) error {
+	open := map[string]struct{}{}
+	for _, f := range sc.snapshots.Files() {
+		open[f] = struct{}{}
+	}
+	var preverified snapcfg.Preverified
+	for _, p := range sc.preverified {
+		if _, ok := open[p.Name]; ok {
+			preverified = append(preverified, p)
+		}
+	}
+	problems, err := VerifyInfoHashes(ctx, sc.snapshots.Dir(), preverified)
+	if err != nil {
+		return err
+	}
+	segmentProblems, err := VerifySegments(ctx, sc.snapshots, sc.chainID)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, segmentProblems...)
+
+	corrupted := map[string]struct{}{}
+	for _, p := range problems {
+		if p.Index == "" {
+			corrupted[p.Segment] = struct{}{}
+			corruptedSegments.Inc()
+			log.Error("[snapshots] Corrupted segment, download or dump it again", "file", p.Segment, "err", p.Err)
+		}
+	}
+	for _, p := range problems {
+		if p.Index == "" {
+			continue
+		}
+		corruptedIndices.Inc()
+		if _, ok := corrupted[p.Segment]; ok { // rebuilt with the segment, or already rebuilt
+			log.Warn("[snapshots] Corrupted index", "file", p.Index, "err", p.Err)
+			continue
+		}
+		log.Warn("[snapshots] Corrupted index, rebuilding it", "file", p.Index, "err", p.Err)
+		if err := sc.snapshots.RebuildIndices(ctx, p.Segment, sc.chainID, sc.tmpDir, log.LvlInfo); err != nil {
+			return fmt.Errorf("rebuild %s: %w", p.Index, err)
+		}
+		corrupted[p.Segment] = struct{}{} // all the indices of the segment are rebuilt
+	}
+	log.Debug("[snapshots] Scrubbed", "files", len(open), "corrupted", len(problems))
+	return nil
+}
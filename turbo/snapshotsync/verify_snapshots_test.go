@@ -0,0 +1,121 @@
+package snapshotsync
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/background"
+	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapcfg"
+)
+
+// createTestBlocksSegments creates the indexed segments of blocks from..to, with two system
+// transactions by block.
+func createTestBlocksSegments(t *testing.T, from, to uint64, dir string) {
+	ctx := context.Background()
+	write := func(name snaptype.Type, words [][]byte) {
+		c, err := compress.NewCompressor(ctx, "test", filepath.Join(dir, snaptype.SegmentFileName(from, to, name)), dir, 100, 1, log.LvlDebug)
+		require.NoError(t, err)
+		defer c.Close()
+		for _, word := range words {
+			require.NoError(t, c.AddWord(word))
+		}
+		require.NoError(t, c.Compress())
+	}
+	var headers, bodies, txs [][]byte
+	for blockNum := from; blockNum < to; blockNum++ {
+		headerRlp, err := rlp.EncodeToBytes(&types.Header{Number: new(big.Int).SetUint64(blockNum), Difficulty: big.NewInt(1)})
+		require.NoError(t, err)
+		hash := crypto.Keccak256(headerRlp)
+		headers = append(headers, append([]byte{hash[0]}, headerRlp...))
+		body, err := rlp.EncodeToBytes(&types.BodyForStorage{BaseTxId: blockNum * 2, TxAmount: 2})
+		require.NoError(t, err)
+		bodies = append(bodies, body)
+		txs = append(txs, nil, nil)
+	}
+	write(snaptype.Headers, headers)
+	write(snaptype.Bodies, bodies)
+	write(snaptype.Transactions, txs)
+
+	for _, name := range []snaptype.Type{snaptype.Headers, snaptype.Bodies, snaptype.Transactions} {
+		f, err := snaptype.ParseFileName(dir, snaptype.SegmentFileName(from, to, name))
+		require.NoError(t, err)
+		require.NoError(t, buildIdx(ctx, f, *uint256.NewInt(1), dir, &background.Progress{}, log.LvlDebug))
+	}
+}
+
+func TestVerifySegments(t *testing.T) {
+	dir, require := t.TempDir(), require.New(t)
+	ctx := context.Background()
+	createTestBlocksSegments(t, 0, 1_000, dir)
+	createTestBlocksSegments(t, 1_000, 2_000, dir)
+
+	s := NewRoSnapshots(ethconfig.Snapshot{Enabled: true}, dir)
+	defer s.Close()
+	require.NoError(s.ReopenFolder())
+	problems, err := VerifySegments(ctx, s, *uint256.NewInt(1))
+	require.NoError(err)
+	require.Empty(problems)
+
+	// replace an index by the one of another segment
+	idxName := snaptype.IdxFileName(1_000, 2_000, snaptype.Headers.String())
+	idx, err := os.ReadFile(filepath.Join(dir, snaptype.IdxFileName(0, 1_000, snaptype.Headers.String())))
+	require.NoError(err)
+	require.NoError(os.Remove(filepath.Join(dir, idxName)))
+	require.NoError(os.WriteFile(filepath.Join(dir, idxName), idx, 0644))
+	require.NoError(s.reopenSegmentIdx(snaptype.Headers, Range{1_000, 2_000}))
+
+	problems, err = VerifySegments(ctx, s, *uint256.NewInt(1))
+	require.NoError(err)
+	require.Len(problems, 1)
+	require.Equal(snaptype.SegmentFileName(1_000, 2_000, snaptype.Headers), problems[0].Segment)
+	require.Equal(idxName, problems[0].Index)
+
+	require.NoError(s.RebuildIndices(ctx, problems[0].Segment, *uint256.NewInt(1), dir, log.LvlDebug))
+	problems, err = VerifySegments(ctx, s, *uint256.NewInt(1))
+	require.NoError(err)
+	require.Empty(problems)
+
+	// the corrupted segments are quarantined with their indices, the corrupted indices are removed
+	txsName := snaptype.SegmentFileName(0, 1_000, snaptype.Transactions)
+	require.NoError(Quarantine(dir, []SnapshotProblem{
+		{Segment: txsName, Err: errors.New("corrupted")},
+		{Segment: snaptype.SegmentFileName(0, 1_000, snaptype.Bodies), Index: snaptype.IdxFileName(0, 1_000, snaptype.Bodies.String()), Err: errors.New("corrupted")},
+	}))
+	require.NoFileExists(filepath.Join(dir, txsName))
+	require.FileExists(filepath.Join(dir, QuarantineDir, txsName))
+	require.FileExists(filepath.Join(dir, QuarantineDir, snaptype.IdxFileName(0, 1_000, snaptype.Transactions2Block.String())))
+	require.NoFileExists(filepath.Join(dir, snaptype.IdxFileName(0, 1_000, snaptype.Bodies.String())))
+	require.FileExists(filepath.Join(dir, snaptype.SegmentFileName(0, 1_000, snaptype.Bodies)))
+}
+
+func TestVerifyInfoHashes(t *testing.T) {
+	dir, require := t.TempDir(), require.New(t)
+	createTestBlocksSegments(t, 0, 1_000, dir)
+
+	headersName := snaptype.SegmentFileName(0, 1_000, snaptype.Headers)
+	hash, err := segmentInfoHash(filepath.Join(dir, headersName))
+	require.NoError(err)
+	problems, err := VerifyInfoHashes(context.Background(), dir, snapcfg.Preverified{
+		{Name: headersName, Hash: hash},
+		{Name: snaptype.SegmentFileName(0, 1_000, snaptype.Bodies), Hash: hash},
+		{Name: snaptype.SegmentFileName(1_000, 2_000, snaptype.Headers), Hash: hash}, // not downloaded
+	})
+	require.NoError(err)
+	require.Len(problems, 1)
+	require.Equal(snaptype.SegmentFileName(0, 1_000, snaptype.Bodies), problems[0].Segment)
+	require.Empty(problems[0].Index)
+}
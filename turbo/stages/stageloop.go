@@ -31,6 +31,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/turbo/engineapi"
+	"github.com/ledgerwatch/erigon/turbo/proofs"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/shards"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
@@ -228,7 +229,7 @@ func StageLoopStep(ctx context.Context, chainConfig *chain.Config, db kv.RwDB, s
 		}
 
 		if notifications != nil && notifications.Events != nil {
-			if err = stagedsync.NotifyNewHeaders(ctx, finishProgressBefore, head, sync.PrevUnwindPoint(), notifications.Events, tx); err != nil {
+			if err = stagedsync.NotifyNewHeaders(ctx, finishProgressBefore, head, sync.PrevUnwindPoint(), notifications.Accumulator.RemovedLogs(), notifications.Events, tx); err != nil {
 				return nil
 			}
 		}
@@ -360,6 +361,7 @@ func NewDefaultStages(ctx context.Context,
 	agg *state.AggregatorV3,
 	forkValidator *engineapi.ForkValidator,
 	engine consensus.Engine,
+	proofStore *proofs.Store,
 ) []*stagedsync.Stage {
 	dirs := cfg.Dirs
 	var blockReader services.FullBlockReader
@@ -426,6 +428,7 @@ func NewDefaultStages(ctx context.Context,
 			controlServer.Engine,
 			&vm.Config{},
 			notifications.Accumulator,
+			notifications.Events,
 			cfg.StateStream,
 			/*stateStream=*/ false,
 			cfg.HistoryV3,
@@ -437,7 +440,7 @@ func NewDefaultStages(ctx context.Context,
 			agg,
 		),
 		stagedsync.StageHashStateCfg(db, dirs, cfg.HistoryV3, agg),
-		stagedsync.StageTrieCfg(db, true, true, false, dirs.Tmp, blockReader, controlServer.Hd, cfg.HistoryV3, agg),
+		stagedsync.StageTrieCfg(db, true, true, false, dirs.Tmp, blockReader, controlServer.Hd, cfg.HistoryV3, agg, proofStore),
 		stagedsync.StageHistoryCfg(db, cfg.Prune, dirs.Tmp),
 		stagedsync.StageLogIndexCfg(db, cfg.Prune, dirs.Tmp),
 		stagedsync.StageCallTracesCfg(db, cfg.Prune, 0, dirs.Tmp),
@@ -483,6 +486,7 @@ func NewInMemoryExecution(ctx context.Context, db kv.RwDB, cfg *ethconfig.Config
 				controlServer.Engine,
 				&vm.Config{},
 				notifications.Accumulator,
+				notifications.Events,
 				cfg.StateStream,
 				true,
 				cfg.HistoryV3,
@@ -494,7 +498,7 @@ func NewInMemoryExecution(ctx context.Context, db kv.RwDB, cfg *ethconfig.Config
 				agg,
 			),
 			stagedsync.StageHashStateCfg(db, dirs, cfg.HistoryV3, agg),
-			stagedsync.StageTrieCfg(db, true, true, true, dirs.Tmp, blockReader, controlServer.Hd, cfg.HistoryV3, agg)),
+			stagedsync.StageTrieCfg(db, true, true, true, dirs.Tmp, blockReader, controlServer.Hd, cfg.HistoryV3, agg, nil)),
 		stagedsync.StateUnwindOrder,
 		nil,
 	), nil
@@ -131,6 +131,7 @@ type ReusableCaller struct {
 	gasCap          uint64
 	baseFee         *uint256.Int
 	stateReader     state.StateReader
+	overrides       *ethapi2.StateOverrides
 	callTimeout     time.Duration
 	message         *types.Message
 }
@@ -155,6 +156,12 @@ func (r *ReusableCaller) DoCallWithNewGas(
 	// reset the EVM so that we can continue to use it with the new context
 	txCtx := core.NewEVMTxContext(r.message)
 	r.intraBlockState = state.New(r.stateReader)
+	// Every call starts from a fresh state, so the overrides are applied again
+	if r.overrides != nil {
+		if err := r.overrides.Override(r.intraBlockState); err != nil {
+			return nil, err
+		}
+	}
 	r.evm.Reset(txCtx, r.intraBlockState)
 
 	timedOut := false
@@ -182,6 +189,7 @@ func NewReusableCaller(
 	engine consensus.EngineReader,
 	stateReader state.StateReader,
 	overrides *ethapi2.StateOverrides,
+	blockOverrides *ethapi2.BlockOverrides,
 	header *types.Header,
 	initialArgs ethapi2.CallArgs,
 	gasCap uint64,
@@ -208,12 +216,18 @@ func NewReusableCaller(
 		}
 	}
 
+	blockCtx := NewEVMBlockContext(engine, header, blockNrOrHash.RequireCanonical, tx, headerReader)
+	if blockOverrides != nil {
+		blockOverrides.Override(&blockCtx)
+		if blockOverrides.BaseFee != nil {
+			baseFee = blockOverrides.BaseFee
+		}
+	}
+
 	msg, err := initialArgs.ToMessage(gasCap, baseFee)
 	if err != nil {
 		return nil, err
 	}
-
-	blockCtx := NewEVMBlockContext(engine, header, blockNrOrHash.RequireCanonical, tx, headerReader)
 	txCtx := core.NewEVMTxContext(msg)
 
 	evm := vm.NewEVM(blockCtx, txCtx, ibs, chainConfig, vm.Config{NoBaseFee: true})
@@ -225,6 +239,7 @@ func NewReusableCaller(
 		gasCap:          gasCap,
 		callTimeout:     callTimeout,
 		stateReader:     stateReader,
+		overrides:       overrides,
 		message:         &msg,
 	}, nil
 }
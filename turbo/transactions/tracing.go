@@ -117,62 +117,102 @@ func ComputeTxEnv(ctx context.Context, engine consensus.EngineReader, block *typ
 
 // TraceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
-// be tracer dependent.
+// be tracer dependent. tracerCtx locates the traced transaction in its block, it
+// is nil for calls.
 func TraceTx(
 	ctx context.Context,
 	message core.Message,
 	blockCtx evmtypes.BlockContext,
 	txCtx evmtypes.TxContext,
+	tracerCtx *tracers.Context,
 	ibs evmtypes.IntraBlockState,
 	config *tracers.TraceConfig,
 	chainConfig *chain.Config,
 	stream *jsoniter.Stream,
 	callTimeout time.Duration,
 ) error {
-	// Assemble the structured logger or the JavaScript tracer
-	var (
-		tracer vm.EVMLogger
-		err    error
-	)
-	var streaming bool
+	if tracerCtx == nil {
+		tracerCtx = &tracers.Context{TxHash: txCtx.TxHash}
+	}
+	tracer, streaming, cancel, err := AssembleTracer(ctx, config, tracerCtx, stream, callTimeout)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+	defer cancel()
+
+	if _, err = ExecuteTraceTx(blockCtx, txCtx, ibs, config, chainConfig, stream, tracer, streaming, message); err != nil {
+		return err
+	}
+	if !streaming {
+		r, err := tracer.(tracers.Tracer).GetResult()
+		if err != nil {
+			return err
+		}
+		stream.Write(r)
+	}
+	return nil
+}
+
+// AssembleTracer returns the structured logger or the tracer named by config,
+// and whether the logger streams its output. The returned cancel function must
+// be called once the tracing is done.
+func AssembleTracer(
+	ctx context.Context,
+	config *tracers.TraceConfig,
+	tracerCtx *tracers.Context,
+	stream *jsoniter.Stream,
+	callTimeout time.Duration,
+) (vm.EVMLogger, bool, context.CancelFunc, error) {
 	switch {
 	case config != nil && config.Tracer != nil:
 		// Define a meaningful timeout of a single transaction trace
 		timeout := callTimeout
 		if config.Timeout != nil {
+			var err error
 			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-				stream.WriteNil()
-				return err
+				return nil, false, func() {}, err
 			}
 		}
 		// Construct the JavaScript tracer to execute with
 		cfg := json.RawMessage("{}")
-		if config != nil && config.TracerConfig != nil {
+		if config.TracerConfig != nil {
 			cfg = *config.TracerConfig
 		}
-		if tracer, err = tracers.New(*config.Tracer, &tracers.Context{
-			TxHash: txCtx.TxHash,
-		}, cfg); err != nil {
-			stream.WriteNil()
-			return err
+		tracer, err := tracers.New(*config.Tracer, tracerCtx, cfg)
+		if err != nil {
+			return nil, false, func() {}, err
 		}
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(tracers.Tracer).Stop(errors.New("execution timeout"))
+			tracer.Stop(errors.New("execution timeout"))
 		}()
-		defer cancel()
-		streaming = false
+		return tracer, false, cancel, nil
 
 	case config == nil:
-		tracer = logger.NewJsonStreamLogger(nil, ctx, stream)
-		streaming = true
+		return logger.NewJsonStreamLogger(nil, ctx, stream), true, func() {}, nil
 
 	default:
-		tracer = logger.NewJsonStreamLogger(config.LogConfig, ctx, stream)
-		streaming = true
+		return logger.NewJsonStreamLogger(config.LogConfig, ctx, stream), true, func() {}, nil
 	}
+}
+
+// ExecuteTraceTx runs the given message with tracer in the provided environment.
+// The output of streaming loggers is written to stream, the result of other
+// tracers is left to the caller.
+func ExecuteTraceTx(
+	blockCtx evmtypes.BlockContext,
+	txCtx evmtypes.TxContext,
+	ibs evmtypes.IntraBlockState,
+	config *tracers.TraceConfig,
+	chainConfig *chain.Config,
+	stream *jsoniter.Stream,
+	tracer vm.EVMLogger,
+	streaming bool,
+	message core.Message,
+) (*core.ExecutionResult, error) {
 	// Run the transaction with tracing enabled.
 	vmenv := vm.NewEVM(blockCtx, txCtx, ibs, chainConfig, vm.Config{Debug: true, Tracer: tracer})
 	var refunds = true
@@ -192,7 +232,7 @@ func TraceTx(
 		} else {
 			stream.WriteNil()
 		}
-		return fmt.Errorf("tracing failed: %w", err)
+		return nil, fmt.Errorf("tracing failed: %w", err)
 	}
 	// Depending on the tracer type, format and return the output
 	if streaming {
@@ -212,12 +252,6 @@ func TraceTx(
 		stream.WriteObjectField("returnValue")
 		stream.WriteString(returnVal)
 		stream.WriteObjectEnd()
-	} else {
-		if r, err1 := tracer.(tracers.Tracer).GetResult(); err1 == nil {
-			stream.Write(r)
-		} else {
-			return err1
-		}
 	}
-	return nil
+	return result, nil
 }
@@ -228,7 +228,7 @@ func (l *FlatDBTrieLoader) CalcStorageProof(tx kv.Tx, addrHash libcommon.Hash, i
 	cnt := 0
 	for ihKS, ihVS, hasTreeS, err2 := storageTrie.SeekToAccount(accWithInc[:]); ; ihKS, ihVS, hasTreeS, err2 = storageTrie.Next() {
 		log.Debug("MMGP-1 Next", "cnt", cnt, "ihKS", hexutil.Bytes(ihKS), "ihVS", hexutil.Bytes(ihVS), "hasTreeS", hasTreeS, "err2", err2)
-		for vS, err3 := ss.SeekBothRange(accWithInc[:], storageTrie.FirstNotCoveredPrefix()); vS != nil && cnt < 100; _, vS, err3 = ss.NextDup() {
+		for vS, err3 := ss.SeekBothRange(accWithInc[:], storageTrie.FirstNotCoveredPrefix()); vS != nil; _, vS, err3 = ss.NextDup() {
 			sk := vS[:32]
 			sv := vS[32:]
 			log.Debug("MMGP-1 SeekBothRange", "cnt", cnt, "err3", err3, "sk", hexutil.Bytes(sk), "sv", hexutil.Bytes(sv))